@@ -2,14 +2,31 @@ package app
 
 import (
 	"BankingApp/internal/config"
+	"BankingApp/internal/jobs"
+	"BankingApp/internal/jwtkeys"
+	"BankingApp/internal/keystore"
+	"BankingApp/internal/model"
+	"BankingApp/internal/rates"
 	"BankingApp/internal/router"
 	"BankingApp/internal/service"
+	adminService "BankingApp/internal/service/admin"
+	aliasService "BankingApp/internal/service/aliases"
 	bankingService "BankingApp/internal/service/banking"
 	cardService "BankingApp/internal/service/cards"
 	creditService "BankingApp/internal/service/credit"
+	disputeService "BankingApp/internal/service/disputes"
+	fxService "BankingApp/internal/service/fx"
+	notificationService "BankingApp/internal/service/notification"
+	payeeService "BankingApp/internal/service/payees"
+	privacyService "BankingApp/internal/service/privacy"
+	profileService "BankingApp/internal/service/profile"
+	standingOrderService "BankingApp/internal/service/standingorders"
 	userService "BankingApp/internal/service/users"
 	storageImpl "BankingApp/internal/storage/postgres"
+	"BankingApp/pkg/clock"
 	"context"
+	"errors"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
@@ -20,10 +37,22 @@ import (
 type serviceProvider struct {
 	cfg            *config.Config
 	storage        *storageImpl.PostgresRepository
+	keyStore       *keystore.KeyStore
+	signingKeys    *jwtkeys.KeyRing
 	userService    service.UserService
 	bankingService service.BankingService
+	fxService      service.FXService
+	aliasService   service.AliasService
+	payeeService   service.PayeeService
+	standingOrders service.StandingOrderService
 	cardService    service.CardService
 	creditService  service.CreditService
+	adminService   service.AdminService
+	disputeService service.DisputeService
+	profileService service.ProfileService
+	privacyService service.PrivacyService
+	notifier       service.NotificationService
+	clock          clock.Clock
 	router         *router.Router
 	logger         *logrus.Logger
 	errG           *errgroup.Group
@@ -46,6 +75,7 @@ func (s *serviceProvider) Start() {
 		s.logger.Printf("starting server on port: %s", s.Config().ServerPort)
 		return s.router.Start()
 	})
+	s.startJobs()
 	if err := s.errG.Wait(); err != nil {
 		s.logger.Printf("exit reason: %s \n", err)
 	}
@@ -78,23 +108,131 @@ func (s *serviceProvider) Storage() *storageImpl.PostgresRepository {
 	return s.storage
 }
 
+// KeyStore — ключи шифрования карточных данных. Мастер-ключ берется из окружения или файла,
+// PGP-ключ нужен только для расшифровки PAN, зашифрованных до перехода на keystore.
+func (s *serviceProvider) KeyStore() *keystore.KeyStore {
+	if s.keyStore == nil {
+		cfg := s.Config().Keystore
+		var (
+			master keystore.MasterKey
+			err    error
+		)
+		switch cfg.MasterKeySource {
+		case "file":
+			master, err = keystore.NewFileMasterKey(cfg.MasterKeyFile)
+		default:
+			master, err = keystore.NewBase64MasterKey(config.GetMasterKey())
+		}
+		if err != nil {
+			s.logger.Fatalf("could not init master key: %s", err.Error())
+		}
+		var legacy *keystore.PGPDecrypter
+		if privateKey := config.GetEncryptionPrivateKey(); privateKey != "" {
+			legacy, err = keystore.NewPGPDecrypter(privateKey, config.GetEncryptionPassphrase())
+			if err != nil {
+				s.logger.Fatalf("could not init pgp key: %s", err.Error())
+			}
+		}
+		keyStore, err := keystore.New(context.Background(), s.Storage(), master, legacy)
+		if err != nil {
+			s.logger.Fatalf("could not init keystore: %s", err.Error())
+		}
+		s.keyStore = keyStore
+	}
+	return s.keyStore
+}
+
+// SigningKeys — ключи подписи JWT. Ключи переживают ротацию на время жизни access-токена,
+// чтобы уже выданные токены продолжали проверяться.
+func (s *serviceProvider) SigningKeys() *jwtkeys.KeyRing {
+	if s.signingKeys == nil {
+		cfg := s.Config().Auth
+		algorithm := cfg.JWT.Algorithm
+		if algorithm == "" {
+			algorithm = model.SigningAlgEdDSA
+		}
+		overlap := max(time.Duration(cfg.AccessTokenTTL), time.Hour)
+		keys, err := jwtkeys.New(context.Background(), s.Storage(), s.KeyStore(), algorithm, overlap)
+		if err != nil {
+			s.logger.Fatalf("could not init signing keys: %s", err.Error())
+		}
+		s.signingKeys = keys
+	}
+	return s.signingKeys
+}
+
 func (s *serviceProvider) UserService() service.UserService {
 	if s.userService == nil {
-		s.userService = userService.NewUserService(s.Storage(), s.Config())
+		users, err := userService.NewUserService(s.Storage(), s.Storage(), s.Storage(), s.Storage(), s.KeyStore(), s.SigningKeys(), s.NotificationService(), s.Config())
+		if err != nil {
+			s.logger.Fatalf("could not init user service: %s", err.Error())
+		}
+		s.userService = users
 	}
 	return s.userService
 }
 
 func (s *serviceProvider) BankingService() service.BankingService {
 	if s.bankingService == nil {
-		s.bankingService = bankingService.NewBankingService(s.Storage())
+		banking, err := bankingService.NewBankingService(s.Storage(), s.FXService(), s.NotificationService(), s.Clock(), s.Config())
+		if err != nil {
+			s.logger.Fatalf("could not init banking service: %s", err.Error())
+		}
+		s.bankingService = banking
 	}
 	return s.bankingService
 }
 
+func (s *serviceProvider) AliasService() service.AliasService {
+	if s.aliasService == nil {
+		s.aliasService = aliasService.NewAliasService(s.Storage(), s.Storage(), s.UserService(), s.ProfileService(), s.BankingService(),
+			s.KeyStore(), s.Clock(), s.Config())
+	}
+	return s.aliasService
+}
+
+func (s *serviceProvider) PayeeService() service.PayeeService {
+	if s.payeeService == nil {
+		s.payeeService = payeeService.NewPayeeService(s.Storage(), s.BankingService(), s.AliasService(), s.Config())
+	}
+	return s.payeeService
+}
+
+func (s *serviceProvider) StandingOrderService() service.StandingOrderService {
+	if s.standingOrders == nil {
+		orders, err := standingOrderService.NewStandingOrderService(s.Storage(), s.BankingService(), s.PayeeService(), s.AliasService(),
+			s.NotificationService(), s.Clock(), s.Config())
+		if err != nil {
+			s.logger.Fatalf("could not init standing order service: %s", err.Error())
+		}
+		s.standingOrders = orders
+	}
+	return s.standingOrders
+}
+
+func (s *serviceProvider) FXService() service.FXService {
+	if s.fxService == nil {
+		cfg := s.Config().FX
+		var provider rates.Provider
+		switch cfg.Provider {
+		case config.FXProviderStatic:
+			provider = rates.NewStatic(cfg.StaticFile)
+		default:
+			provider = rates.NewCBR(cfg.CBRURL)
+		}
+		cached := rates.NewCached(provider, time.Duration(cfg.RefreshInterval), time.Duration(cfg.MaxRateAge), s.Clock().Now)
+		s.fxService = fxService.NewFXService(cached, s.Storage(), s.Clock(), s.Config())
+	}
+	return s.fxService
+}
+
 func (s *serviceProvider) CardService() service.CardService {
 	if s.cardService == nil {
-		s.cardService = cardService.NewCardService(s.Storage())
+		cards, err := cardService.NewCardService(s.Storage(), s.KeyStore(), s.NotificationService(), s.Clock(), s.Config())
+		if err != nil {
+			s.logger.Fatalf("could not init card service: %s", err.Error())
+		}
+		s.cardService = cards
 	}
 	return s.cardService
 }
@@ -106,11 +244,160 @@ func (s *serviceProvider) CreditService() service.CreditService {
 	return s.creditService
 }
 
+func (s *serviceProvider) AdminService() service.AdminService {
+	if s.adminService == nil {
+		s.adminService = adminService.NewAdminService(s.Storage(), s.BankingService())
+	}
+	return s.adminService
+}
+
+func (s *serviceProvider) DisputeService() service.DisputeService {
+	if s.disputeService == nil {
+		s.disputeService = disputeService.NewDisputeService(s.Storage(), s.BankingService(), s.KeyStore(), s.NotificationService(), s.Clock())
+	}
+	return s.disputeService
+}
+
+func (s *serviceProvider) ProfileService() service.ProfileService {
+	if s.profileService == nil {
+		s.profileService = profileService.NewProfileService(s.Storage(), s.KeyStore(), s.Clock(), s.Config())
+	}
+	return s.profileService
+}
+
+func (s *serviceProvider) PrivacyService() service.PrivacyService {
+	if s.privacyService == nil {
+		s.privacyService = privacyService.NewPrivacyService(s.Storage(), s.UserService(), s.BankingService(), s.CardService(),
+			s.CreditService(), s.ProfileService(), s.AdminService(), s.NotificationService(), s.Clock())
+	}
+	return s.privacyService
+}
+
+func (s *serviceProvider) NotificationService() service.NotificationService {
+	if s.notifier == nil {
+		s.notifier = notificationService.NewNotificationService(s.Storage(), s.Config(), s.Logger())
+	}
+	return s.notifier
+}
+
+func (s *serviceProvider) Clock() clock.Clock {
+	if s.clock == nil {
+		s.clock = clock.Real{}
+	}
+	return s.clock
+}
+
+// accountNumberBatch — сколько счетов без номера обрабатывается за один запуск задачи
+const accountNumberBatch = 500
+
+// payeeAliasBatch — сколько получателей с открытым телефоном или email обрабатывается за один запуск задачи
+const payeeAliasBatch = 500
+
+// startJobs запускает фоновые задачи в общей errgroup
+func (s *serviceProvider) startJobs() {
+	keystoreCfg := s.Config().Keystore
+	s.errG.Go(func() error {
+		return jobs.Run(s.ctx, s.Logger(), "reencrypt_pans", time.Duration(keystoreCfg.ReencryptInterval), func(ctx context.Context) error {
+			rotated, err := s.KeyStore().RotateIfDue(ctx, time.Duration(keystoreCfg.RotationPeriod), s.Clock().Now())
+			if err != nil {
+				return err
+			}
+			if rotated {
+				s.logger.Printf("data key rotated, active version: %d", s.KeyStore().ActiveVersion())
+			}
+			// ошибка в одном наборе данных не останавливает перешифрование остальных
+			n, cardsErr := s.CardService().ReencryptPANs(ctx, keystoreCfg.ReencryptBatch)
+			if n > 0 {
+				s.logger.Printf("re-encrypted %d cards", n)
+			}
+			n, piiErr := s.ProfileService().ReencryptPII(ctx, keystoreCfg.ReencryptBatch)
+			if n > 0 {
+				s.logger.Printf("re-encrypted %d profiles and documents", n)
+			}
+			n, disputesErr := s.DisputeService().ReencryptAttachments(ctx, keystoreCfg.ReencryptBatch)
+			if n > 0 {
+				s.logger.Printf("re-encrypted %d dispute attachments", n)
+			}
+			return errors.Join(cardsErr, piiErr, disputesErr)
+		})
+	})
+	s.errG.Go(func() error {
+		return jobs.Run(s.ctx, s.Logger(), "card_expiry", time.Duration(s.Config().Cards.ExpiryJobInterval), func(ctx context.Context) error {
+			expired, renewed, err := s.CardService().ProcessExpiry(ctx)
+			if expired > 0 || renewed > 0 {
+				s.logger.Printf("cards expired: %d, renewed: %d", expired, renewed)
+			}
+			return err
+		})
+	})
+	s.errG.Go(func() error {
+		return jobs.Run(s.ctx, s.Logger(), "rotate_signing_keys", time.Hour, func(ctx context.Context) error {
+			rotated, err := s.SigningKeys().RotateIfDue(ctx, time.Duration(s.Config().Auth.JWT.RotationPeriod), s.Clock().Now())
+			if rotated {
+				s.logger.Println("jwt signing key rotated")
+			}
+			return err
+		})
+	})
+	s.errG.Go(func() error {
+		return jobs.Run(s.ctx, s.Logger(), "assign_account_numbers", time.Hour, func(ctx context.Context) error {
+			n, err := s.BankingService().AssignAccountNumbers(ctx, accountNumberBatch)
+			if n > 0 {
+				s.logger.Printf("assigned numbers to %d accounts", n)
+			}
+			return err
+		})
+	})
+	s.errG.Go(func() error {
+		return jobs.Run(s.ctx, s.Logger(), "seal_payee_aliases", time.Hour, func(ctx context.Context) error {
+			n, err := s.PayeeService().SealAliases(ctx, payeeAliasBatch)
+			if n > 0 {
+				s.logger.Printf("sealed aliases of %d payees", n)
+			}
+			return err
+		})
+	})
+	s.errG.Go(func() error {
+		return jobs.Run(s.ctx, s.Logger(), "standing_orders", time.Duration(s.Config().StandingOrders.JobInterval), func(ctx context.Context) error {
+			n, err := s.StandingOrderService().ExecuteDue(ctx)
+			if n > 0 {
+				s.logger.Printf("processed %d standing orders", n)
+			}
+			return err
+		})
+	})
+	s.errG.Go(func() error {
+		return jobs.Run(s.ctx, s.Logger(), "overdraft", time.Duration(s.Config().Overdraft.JobInterval), func(ctx context.Context) error {
+			accrued, posted, err := s.BankingService().ProcessOverdrafts(ctx)
+			if accrued > 0 || posted > 0 {
+				s.logger.Printf("overdraft interest accrued: %d, posted: %d", accrued, posted)
+			}
+			return err
+		})
+	})
+	s.errG.Go(func() error {
+		return jobs.Run(s.ctx, s.Logger(), "interest", time.Duration(s.Config().Products.JobInterval), func(ctx context.Context) error {
+			accrued, paid, err := s.BankingService().ProcessInterest(ctx)
+			if accrued > 0 || paid > 0 {
+				s.logger.Printf("deposit interest accrued: %d, paid: %d", accrued, paid)
+			}
+			return err
+		})
+	})
+	s.errG.Go(func() error {
+		return jobs.Run(s.ctx, s.Logger(), "purge_tokens", time.Duration(s.Config().Auth.CleanupInterval), func(ctx context.Context) error {
+			_, err := s.UserService().PurgeExpiredTokens(ctx)
+			return err
+		})
+	})
+}
+
 // Инициализация http-сервера.Для каждой области отдельная функция инициализации
 func (s *serviceProvider) Router() *router.Router {
 	if s.router == nil {
 		s.router = router.NewRouter(s.Logger(), s.Config())
-		s.router.InitRoutes(s.UserService(), s.BankingService(), s.CardService(), s.CreditService())
+		s.router.InitRoutes(s.UserService(), s.BankingService(), s.FXService(), s.AliasService(), s.PayeeService(), s.StandingOrderService(),
+			s.CardService(), s.CreditService(), s.AdminService(), s.DisputeService(), s.ProfileService(), s.PrivacyService())
 		s.errG.Go(func() error {
 			<-s.ctx.Done()
 			s.logger.Println("shutting down server...")
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log"
 	"os"
+	"time"
 )
 
 type Config struct {
@@ -11,6 +12,133 @@ type Config struct {
 	JWTSecret  string `json:"jwt_secret" yaml:"jwt_secret"`
 	LogLevel   string `json:"log_level" yaml:"log_level"`
 	Postgres   `json:"postgres" yaml:"postgres"`
+	Auth       Auth     `json:"auth" yaml:"auth"`
+	Password   Password `json:"password" yaml:"password"`
+	Keystore   Keystore `json:"keystore" yaml:"keystore"`
+	Cards      Cards    `json:"cards" yaml:"cards"`
+	KYC        KYC      `json:"kyc" yaml:"kyc"`
+	SMTP       SMTP     `json:"smtp" yaml:"smtp"`
+	Banking    Banking  `json:"banking" yaml:"banking"`
+	FX         FX       `json:"fx" yaml:"fx"`
+	// StandingOrders — регулярные переводы по расписанию
+	StandingOrders StandingOrders `json:"standing_orders" yaml:"standing_orders"`
+	Limits         Limits         `json:"limits" yaml:"limits"`
+	Overdraft      Overdraft      `json:"overdraft" yaml:"overdraft"`
+	Products       Products       `json:"products" yaml:"products"`
+}
+
+// Products — сберегательные счета и вклады. Условия продукта копируются в счет при открытии: новая ставка
+// сберегательного продукта применяется к открытым счетам, вклада — только с пролонгации.
+type Products struct {
+	// JobInterval — период запуска начисления процентов; за каждый день проценты начисляются один раз
+	JobInterval Duration  `json:"job_interval" yaml:"job_interval"`
+	Batch       int       `json:"batch" yaml:"batch"`
+	Items       []Product `json:"items" yaml:"items"`
+}
+
+// Product — продукт с типом savings или term_deposit, ставки — годовые доли
+type Product struct {
+	Code      string  `json:"code" yaml:"code"`
+	Type      string  `json:"type" yaml:"type"`
+	Name      string  `json:"name" yaml:"name"`
+	Currency  string  `json:"currency" yaml:"currency"`
+	Rate      float64 `json:"rate" yaml:"rate"`
+	MinAmount float64 `json:"min_amount" yaml:"min_amount"`
+	// TermDays — срок вклада; EarlyPenalty — доля процентов срока, теряемая при досрочном закрытии;
+	// Rollover — продление по умолчанию, клиент может изменить его при открытии и позже
+	TermDays     int     `json:"term_days" yaml:"term_days"`
+	EarlyPenalty float64 `json:"early_penalty" yaml:"early_penalty"`
+	Rollover     bool    `json:"rollover" yaml:"rollover"`
+}
+
+// Overdraft — овердрафт по текущим счетам. Лимит и ставку счета устанавливает бэк-офис.
+type Overdraft struct {
+	// JobInterval — период запуска начисления процентов; за каждый день проценты начисляются один раз
+	JobInterval Duration `json:"job_interval" yaml:"job_interval"`
+	Batch       int      `json:"batch" yaml:"batch"`
+	// MaxRate — предельная годовая ставка, доля
+	MaxRate float64 `json:"max_rate" yaml:"max_rate"`
+	// WarningThresholds — доли использованного лимита, при пересечении которых клиент получает предупреждение
+	WarningThresholds []float64 `json:"warning_thresholds" yaml:"warning_thresholds"`
+}
+
+// Limits — лимиты снятий и переводов. Для счета действует первое правило, подходящее
+// по типу счета, статусу KYC владельца и валюте; без подходящего правила лимитов нет.
+type Limits struct {
+	Rules []LimitRule `json:"rules" yaml:"rules"`
+	// MaxOverridePeriod — на сколько клиент может снизить себе лимиты за один раз
+	MaxOverridePeriod Duration `json:"max_override_period" yaml:"max_override_period"`
+}
+
+// LimitRule — лимиты в валюте счета; пустые AccountType, KYCStatus и Currency подходят к любому счету
+type LimitRule struct {
+	AccountType string          `json:"account_type" yaml:"account_type"`
+	KYCStatus   string          `json:"kyc_status" yaml:"kyc_status"`
+	Currency    string          `json:"currency" yaml:"currency"`
+	Withdraw    OperationLimits `json:"withdraw" yaml:"withdraw"`
+	Transfer    OperationLimits `json:"transfer" yaml:"transfer"`
+}
+
+// OperationLimits — лимиты одной операции, 0 — без ограничения
+type OperationLimits struct {
+	PerOperation float64 `json:"per_operation" yaml:"per_operation"`
+	// Daily и Monthly — сумма списаний за последние сутки и 30 дней
+	Daily   float64 `json:"daily" yaml:"daily"`
+	Monthly float64 `json:"monthly" yaml:"monthly"`
+	// DailyCount и MonthlyCount — число операций за те же окна
+	DailyCount   int `json:"daily_count" yaml:"daily_count"`
+	MonthlyCount int `json:"monthly_count" yaml:"monthly_count"`
+}
+
+// StandingOrders — исполнение регулярных переводов
+type StandingOrders struct {
+	// JobInterval — период запуска исполнителя
+	JobInterval Duration `json:"job_interval" yaml:"job_interval"`
+	// Batch — сколько поручений исполнитель берет за один запуск
+	Batch int `json:"batch" yaml:"batch"`
+	// RetryInterval и MaxRetries — повторы при нехватке средств, после них платеж периода пропускается
+	RetryInterval Duration `json:"retry_interval" yaml:"retry_interval"`
+	MaxRetries    int      `json:"max_retries" yaml:"max_retries"`
+	// Holidays — нерабочие дни помимо суббот и воскресений, в формате 2006-01-02
+	Holidays []string `json:"holidays" yaml:"holidays"`
+}
+
+// Источники курсов валют
+const (
+	FXProviderCBR    = "cbr"
+	FXProviderStatic = "static"
+)
+
+// FX — курсы валют и конвертация при переводах между счетами в разных валютах
+type FX struct {
+	// Provider — cbr (официальные курсы ЦБ РФ) или static (файл StaticFile, для тестов)
+	Provider   string `json:"provider" yaml:"provider"`
+	CBRURL     string `json:"cbr_url" yaml:"cbr_url"`
+	StaticFile string `json:"static_file" yaml:"static_file"`
+	// RefreshInterval — как часто перезапрашивать курсы у источника
+	RefreshInterval Duration `json:"refresh_interval" yaml:"refresh_interval"`
+	// MaxRateAge — сколько использовать последние полученные курсы, если источник недоступен
+	MaxRateAge Duration `json:"max_rate_age" yaml:"max_rate_age"`
+	// Spread — доля, на которую курс клиента хуже биржевого, например 0.01
+	Spread float64 `json:"spread" yaml:"spread"`
+	// QuoteTTL — сколько действует котировка, выданная клиенту
+	QuoteTTL Duration `json:"quote_ttl" yaml:"quote_ttl"`
+}
+
+// Banking — параметры операций по счетам
+type Banking struct {
+	// StepUpThreshold — сумма перевода, начиная с которой требуется повторное подтверждение, 0 — не требуется
+	StepUpThreshold float64 `json:"step_up_threshold" yaml:"step_up_threshold"`
+	// BIC — БИК банка, участвует в расчете защитного ключа номеров счетов
+	BIC string `json:"bic" yaml:"bic"`
+	// Branch — четырехзначный код подразделения в номерах счетов
+	Branch string `json:"branch" yaml:"branch"`
+	// BalanceAccount — балансовый счет второго порядка для счетов клиентов, например 40817
+	BalanceAccount string `json:"balance_account" yaml:"balance_account"`
+	// AliasLookupLimit — сколько поисков получателя по телефону или email доступно клиенту за AliasLookupWindow;
+	// при превышении поиск блокируется на то же окно
+	AliasLookupLimit  int      `json:"alias_lookup_limit" yaml:"alias_lookup_limit"`
+	AliasLookupWindow Duration `json:"alias_lookup_window" yaml:"alias_lookup_window"`
 }
 
 type Postgres struct {
@@ -21,6 +149,144 @@ type Postgres struct {
 	Database string `json:"database" yaml:"database"`
 }
 
+// Auth — параметры токенов доступа
+type Auth struct {
+	AccessTokenTTL  Duration `json:"access_token_ttl" yaml:"access_token_ttl"`
+	RefreshTokenTTL Duration `json:"refresh_token_ttl" yaml:"refresh_token_ttl"`
+	// CleanupInterval — период удаления истекших refresh-токенов и записей об отзыве
+	CleanupInterval Duration `json:"cleanup_interval" yaml:"cleanup_interval"`
+	Lockout         Lockout  `json:"lockout" yaml:"lockout"`
+	// Verification — подтверждение email после регистрации
+	Verification Verification `json:"verification" yaml:"verification"`
+	JWT          JWT          `json:"jwt" yaml:"jwt"`
+	// Clients — машинные клиенты (API-ключи и OAuth2 client credentials)
+	Clients Clients `json:"clients" yaml:"clients"`
+}
+
+// Режимы подписи JWT на время перехода с HS256 на асимметричные ключи
+const (
+	// JWTModeHS256 — подпись и проверка общим секретом JWT_SECRET_KEY, как до перехода
+	JWTModeHS256 = "hs256"
+	// JWTModeCompat — подпись асимметричным ключом, токены HS256 еще принимаются
+	JWTModeCompat = "compat"
+	// JWTModeAsymmetric — только асимметричные ключи, JWT_SECRET_KEY не нужен
+	JWTModeAsymmetric = "asymmetric"
+)
+
+// JWT — подпись access-токенов
+type JWT struct {
+	Mode string `json:"mode" yaml:"mode"`
+	// Algorithm — EdDSA или RS256
+	Algorithm string `json:"algorithm" yaml:"algorithm"`
+	Issuer    string `json:"issuer" yaml:"issuer"`
+	Audience  string `json:"audience" yaml:"audience"`
+	// RotationPeriod — возраст ключа подписи, после которого выпускается новый
+	RotationPeriod Duration `json:"rotation_period" yaml:"rotation_period"`
+}
+
+// Clients — параметры машинных клиентов
+type Clients struct {
+	TokenTTL Duration `json:"token_ttl" yaml:"token_ttl"`
+	// DefaultRateLimit и MaxRateLimit — запросов в минуту на клиента
+	DefaultRateLimit int `json:"default_rate_limit" yaml:"default_rate_limit"`
+	MaxRateLimit     int `json:"max_rate_limit" yaml:"max_rate_limit"`
+	// SecretRotationGrace — сколько старый секрет действует после ротации
+	SecretRotationGrace Duration `json:"secret_rotation_grace" yaml:"secret_rotation_grace"`
+}
+
+// Verification — параметры ссылки подтверждения email
+type Verification struct {
+	// LinkBaseURL — адрес API вместе с префиксом маршрутов /api/v1, к нему добавляется /user/verify
+	LinkBaseURL string   `json:"link_base_url" yaml:"link_base_url"`
+	LinkTTL     Duration `json:"link_ttl" yaml:"link_ttl"`
+	// ResendInterval — минимальный интервал между повторными отправками письма
+	ResendInterval Duration `json:"resend_interval" yaml:"resend_interval"`
+}
+
+// Lockout — прогрессивная блокировка входа после неудачных попыток.
+// После Threshold неудач подряд вход блокируется на BaseDelay, каждая следующая неудача
+// удваивает срок блокировки, но не больше MaxDelay.
+type Lockout struct {
+	AccountThreshold int      `json:"account_threshold" yaml:"account_threshold"`
+	IPThreshold      int      `json:"ip_threshold" yaml:"ip_threshold"`
+	BaseDelay        Duration `json:"base_delay" yaml:"base_delay"`
+	MaxDelay         Duration `json:"max_delay" yaml:"max_delay"`
+	// FailureWindow — через сколько времени без неудач счетчик начинается заново
+	FailureWindow Duration `json:"failure_window" yaml:"failure_window"`
+}
+
+// Password — требования к паролям и сброс пароля
+type Password struct {
+	MinLength     int  `json:"min_length" yaml:"min_length"`
+	RequireUpper  bool `json:"require_upper" yaml:"require_upper"`
+	RequireLower  bool `json:"require_lower" yaml:"require_lower"`
+	RequireDigit  bool `json:"require_digit" yaml:"require_digit"`
+	RequireSymbol bool `json:"require_symbol" yaml:"require_symbol"`
+	// BreachedListFile — файл со скомпрометированными паролями, по одному в строке. Пустой — проверка отключена.
+	BreachedListFile string `json:"breached_list_file" yaml:"breached_list_file"`
+	// ResetTokenTTL — время жизни одноразового токена сброса пароля
+	ResetTokenTTL Duration `json:"reset_token_ttl" yaml:"reset_token_ttl"`
+}
+
+// Keystore — настройки управления ключами шифрования карточных данных
+type Keystore struct {
+	// MasterKeySource — откуда читать мастер-ключ: "env" (MASTER_KEY) или "file"
+	MasterKeySource string `json:"master_key_source" yaml:"master_key_source"`
+	MasterKeyFile   string `json:"master_key_file" yaml:"master_key_file"`
+	// RotationPeriod — возраст ключа, после которого выпускается новая версия
+	RotationPeriod Duration `json:"rotation_period" yaml:"rotation_period"`
+	// ReencryptInterval — период запуска фонового перешифрования
+	ReencryptInterval Duration `json:"reencrypt_interval" yaml:"reencrypt_interval"`
+	ReencryptBatch    int      `json:"reencrypt_batch" yaml:"reencrypt_batch"`
+}
+
+// Cards — параметры выпуска и обслуживания карт
+type Cards struct {
+	// PINMaxAttempts — число неверных вводов PIN подряд, после которого PIN блокируется
+	PINMaxAttempts int `json:"pin_max_attempts" yaml:"pin_max_attempts"`
+	// DynamicCVVWindow — время жизни динамического CVV виртуальной карты
+	DynamicCVVWindow Duration `json:"dynamic_cvv_window" yaml:"dynamic_cvv_window"`
+	// ExpiryJobInterval — период запуска задачи истечения и перевыпуска карт
+	ExpiryJobInterval Duration `json:"expiry_job_interval" yaml:"expiry_job_interval"`
+	// RenewalLeadDays — за сколько дней до истечения перевыпускать карту
+	RenewalLeadDays int `json:"renewal_lead_days" yaml:"renewal_lead_days"`
+	// RenewalKeepPAN — политика продукта: перевыпуск с тем же номером или с новым
+	RenewalKeepPAN bool `json:"renewal_keep_pan" yaml:"renewal_keep_pan"`
+}
+
+// KYC — анкета клиента и проверка личности
+type KYC struct {
+	// MinAge — минимальный возраст клиента, полных лет
+	MinAge int `json:"min_age" yaml:"min_age"`
+	// MaxDocumentSize — максимальный размер загружаемого документа, байт
+	MaxDocumentSize int64 `json:"max_document_size" yaml:"max_document_size"`
+}
+
+// SMTP — почтовый сервер для уведомлений. Пустой Host — письма пишутся в лог.
+type SMTP struct {
+	Host     string `json:"host" yaml:"host"`
+	Port     string `json:"port" yaml:"port"`
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+	From     string `json:"from" yaml:"from"`
+}
+
+// Duration — time.Duration, читаемый из строки вида "24h"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
 func InitConfig() (*Config, error) {
 	c := &Config{}
 	data, err := os.ReadFile("./configs/app_config.json")
@@ -34,22 +300,24 @@ func InitConfig() (*Config, error) {
 	return c, nil
 }
 
-
 func GetJWTSecretKey() string {
 	return getEnv("JWT_SECRET_KEY")
 }
 
-func GetCardSecretKey() string {
-	return getEnv("CARD_SECRET_KEY")
+func GetMasterKey() string {
+	return getEnv("MASTER_KEY")
 }
 
-func GetEncryptionPublicKey() string {
-	return getEnv("ENCRYPTION_PUBLIC_KEY")
+// GetEncryptionPrivateKey возвращает PGP-ключ, которым зашифрованы PAN до
+// перехода на keystore. Пустая строка — старых шифртекстов нет.
+func GetEncryptionPrivateKey() string {
+	return os.Getenv("ENCRYPTION_PRIVATE_KEY")
 }
 
-func GetEncryptionPrivateKey() string {
-	return getEnv("ENCRYPTION_PRIVATE_KEY")
+func GetEncryptionPassphrase() string {
+	return getEnv("ENCRYPTION_PASSPHRASE")
 }
+
 func getEnv(param string) string {
 	val := os.Getenv(param)
 	if val == "" {
@@ -57,4 +325,3 @@ func getEnv(param string) string {
 	}
 	return val
 }
- 
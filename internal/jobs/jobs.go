@@ -0,0 +1,29 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Run выполняет fn каждые interval до отмены контекста.
+// Ошибка отдельного запуска логируется и не останавливает задачу.
+func Run(ctx context.Context, logger *logrus.Logger, name string, interval time.Duration, fn func(context.Context) error) error {
+	if interval <= 0 {
+		logger.WithField("job", name).Warn("job disabled: interval not set")
+		return nil
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := fn(ctx); err != nil {
+				logger.WithError(err).WithField("job", name).Error("job failed")
+			}
+		}
+	}
+}
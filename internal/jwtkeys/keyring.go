@@ -0,0 +1,278 @@
+package jwtkeys
+
+import (
+	"BankingApp/internal/keystore"
+	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	rsaKeyBits = 2048
+	// reloadInterval ограничивает перечитывание ключей при запросах с неизвестным kid
+	reloadInterval = 10 * time.Second
+)
+
+var (
+	ErrUnknownKey        = errors.New("unknown signing key")
+	ErrUnsupportedAlg    = errors.New("unsupported signing algorithm")
+	ErrAlgorithmMismatch = errors.New("signing algorithm does not match key")
+)
+
+// KeyRing подписывает токены текущим ключом и хранит открытые ключи всех неистекших версий.
+// Закрытые ключи хранятся в базе зашифрованными keystore, поэтому ротацию видят все экземпляры приложения.
+type KeyRing struct {
+	storage   storage.SigningKeyStorage
+	keys      *keystore.KeyStore
+	algorithm string
+	// overlap — сколько ключ остается в JWKS после ротации, не меньше времени жизни выданных им токенов
+	overlap time.Duration
+
+	mu         sync.RWMutex
+	current    *signingKey
+	public     map[string]*model.SigningKey
+	parsed     map[string]crypto.PublicKey
+	lastReload time.Time
+}
+
+type signingKey struct {
+	meta    *model.SigningKey
+	private crypto.Signer
+}
+
+// New загружает ключи подписи и выпускает первый, если ключей еще нет
+func New(ctx context.Context, storage storage.SigningKeyStorage, keys *keystore.KeyStore, algorithm string, overlap time.Duration) (*KeyRing, error) {
+	if algorithm != model.SigningAlgEdDSA && algorithm != model.SigningAlgRS256 {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlg, algorithm)
+	}
+	k := &KeyRing{
+		storage:   storage,
+		keys:      keys,
+		algorithm: algorithm,
+		overlap:   overlap,
+	}
+	if err := k.reload(ctx); err != nil {
+		return nil, err
+	}
+	// смена алгоритма в конфигурации тоже приводит к ротации
+	if k.current == nil || k.current.meta.Algorithm != algorithm {
+		if _, err := k.Rotate(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// Sign подписывает claims текущим ключом и указывает его kid в заголовке
+func (k *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	k.mu.RLock()
+	current := k.current
+	k.mu.RUnlock()
+
+	token := jwt.NewWithClaims(signingMethod(current.meta.Algorithm), claims)
+	token.Header["kid"] = current.meta.KID
+	return token.SignedString(current.private)
+}
+
+// VerificationKey возвращает открытый ключ по kid. Неизвестный kid мог выпустить
+// другой экземпляр приложения, поэтому при промахе ключи перечитываются.
+func (k *KeyRing) VerificationKey(ctx context.Context, kid, alg string) (crypto.PublicKey, error) {
+	key, meta, ok := k.lookup(kid)
+	if !ok {
+		if err := k.reloadThrottled(ctx); err != nil {
+			return nil, err
+		}
+		if key, meta, ok = k.lookup(kid); !ok {
+			return nil, ErrUnknownKey
+		}
+	}
+	if meta.Algorithm != alg {
+		return nil, ErrAlgorithmMismatch
+	}
+	if meta.ExpiresAt != nil && !time.Now().Before(*meta.ExpiresAt) {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// JWKS — открытые ключи всех неистекших версий для проверки токенов другими сервисами
+func (k *KeyRing) JWKS() *model.JWKSet {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := &model.JWKSet{Keys: make([]model.JWK, 0, len(k.public))}
+	now := time.Now()
+	for kid, meta := range k.public {
+		if meta.ExpiresAt != nil && !now.Before(*meta.ExpiresAt) {
+			continue
+		}
+		jwk := model.JWK{KID: kid, Alg: meta.Algorithm, Use: "sig"}
+		switch pub := k.parsed[kid].(type) {
+		case ed25519.PublicKey:
+			jwk.Kty, jwk.Crv, jwk.X = "OKP", "Ed25519", base64.RawURLEncoding.EncodeToString(pub)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// Rotate выпускает новый ключ подписи. Предыдущие ключи остаются в JWKS еще overlap,
+// поэтому уже выданные токены продолжают проверяться.
+func (k *KeyRing) Rotate(ctx context.Context) (string, error) {
+	private, err := generateKey(k.algorithm)
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", err
+	}
+	encrypted, _, err := k.keys.Encrypt(der)
+	if err != nil {
+		return "", fmt.Errorf("encrypt signing key: %w", err)
+	}
+	public, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return "", err
+	}
+	meta := &model.SigningKey{
+		KID:        uuid.New().String(),
+		Algorithm:  k.algorithm,
+		PrivateKey: encrypted,
+		PublicKey:  public,
+	}
+	if err := k.storage.CreateSigningKey(ctx, meta, time.Now().Add(k.overlap)); err != nil {
+		return "", err
+	}
+	if err := k.reload(ctx); err != nil {
+		return "", err
+	}
+	return meta.KID, nil
+}
+
+// RotateIfDue выпускает новый ключ, если текущий старше maxAge
+func (k *KeyRing) RotateIfDue(ctx context.Context, maxAge time.Duration, now time.Time) (bool, error) {
+	if maxAge <= 0 {
+		return false, nil
+	}
+	k.mu.RLock()
+	createdAt := k.current.meta.CreatedAt
+	k.mu.RUnlock()
+	if now.Sub(createdAt) < maxAge {
+		// ключ мог ротировать другой экземпляр — подхватываем его
+		return false, k.reload(ctx)
+	}
+	if _, err := k.Rotate(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (k *KeyRing) lookup(kid string) (crypto.PublicKey, *model.SigningKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	meta, ok := k.public[kid]
+	if !ok {
+		return nil, nil, false
+	}
+	return k.parsed[kid], meta, true
+}
+
+func (k *KeyRing) reloadThrottled(ctx context.Context) error {
+	k.mu.RLock()
+	recent := time.Since(k.lastReload) < reloadInterval
+	k.mu.RUnlock()
+	if recent {
+		return nil
+	}
+	return k.reload(ctx)
+}
+
+func (k *KeyRing) reload(ctx context.Context) error {
+	stored, err := k.storage.GetSigningKeys(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	public := make(map[string]*model.SigningKey, len(stored))
+	parsed := make(map[string]crypto.PublicKey, len(stored))
+	var current *model.SigningKey
+	for _, key := range stored {
+		pub, err := x509.ParsePKIXPublicKey(key.PublicKey)
+		if err != nil {
+			return fmt.Errorf("parse public key %s: %w", key.KID, err)
+		}
+		public[key.KID] = key
+		parsed[key.KID] = pub
+		if key.ExpiresAt == nil {
+			current = key
+		}
+	}
+	var signer *signingKey
+	if current != nil {
+		signer, err = k.decryptSigner(ctx, current)
+		if err != nil {
+			return err
+		}
+	}
+	k.mu.Lock()
+	k.public = public
+	k.parsed = parsed
+	k.current = signer
+	k.lastReload = time.Now()
+	k.mu.Unlock()
+	return nil
+}
+
+func (k *KeyRing) decryptSigner(ctx context.Context, meta *model.SigningKey) (*signingKey, error) {
+	der, err := k.keys.Decrypt(ctx, meta.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt signing key %s: %w", meta.KID, err)
+	}
+	private, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse signing key %s: %w", meta.KID, err)
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlg, meta.Algorithm)
+	}
+	return &signingKey{meta: meta, private: signer}, nil
+}
+
+func generateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case model.SigningAlgEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	case model.SigningAlgRS256:
+		return rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlg, algorithm)
+}
+
+func signingMethod(algorithm string) jwt.SigningMethod {
+	if algorithm == model.SigningAlgRS256 {
+		return jwt.SigningMethodRS256
+	}
+	return jwt.SigningMethodEdDSA
+}
@@ -0,0 +1,285 @@
+package keystore
+
+import (
+	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Формат шифртекста: 'K' | версия ключа (uint32, big-endian) | nonce | AES-GCM
+const (
+	formatMarker = 'K'
+	headerSize   = 1 + 4
+
+	// LegacyVersion — версия данных, зашифрованных PGP-ключом
+	LegacyVersion = 0
+)
+
+var (
+	ErrUnknownKeyVersion = errors.New("unknown key version")
+	ErrMalformed         = errors.New("malformed ciphertext")
+)
+
+// KeyStore хранит расшифрованные версии DEK и шифрует ими данные.
+// Новые данные шифруются активной версией, старые версии используются только для расшифровки.
+// Рядом хранится ключ HMAC для поисковых отпечатков, он не ротируется вместе с DEK.
+type KeyStore struct {
+	storage storage.KeyStorage
+	master  MasterKey
+	legacy  *PGPDecrypter
+
+	mu             sync.RWMutex
+	keys           map[int]cipher.AEAD
+	active         *model.DataKey
+	fingerprintKey []byte
+}
+
+// New загружает ключи из хранилища и создает первую версию, если ключей еще нет.
+// legacy может быть nil, если PGP-шифртекстов в базе нет.
+func New(ctx context.Context, keyStorage storage.KeyStorage, master MasterKey, legacy *PGPDecrypter) (*KeyStore, error) {
+	ks := &KeyStore{
+		storage: keyStorage,
+		master:  master,
+		legacy:  legacy,
+	}
+	if err := ks.reload(ctx); err != nil {
+		return nil, err
+	}
+	if ks.active == nil {
+		if _, err := ks.Rotate(ctx); err != nil && !errors.Is(err, storage.ErrAlreadyExists) {
+			return nil, err
+		}
+	}
+	if ks.fingerprintKey == nil {
+		if err := ks.createFingerprintKey(ctx); err != nil && !errors.Is(err, storage.ErrAlreadyExists) {
+			return nil, err
+		}
+	}
+	// первые ключи мог одновременно создать другой экземпляр — берем те, что сохранены в базе
+	if ks.active == nil || ks.fingerprintKey == nil {
+		if err := ks.reload(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return ks, nil
+}
+
+// Fingerprint возвращает детерминированный HMAC-SHA256 от данных для поиска по равенству
+func (ks *KeyStore) Fingerprint(data []byte) []byte {
+	ks.mu.RLock()
+	mac := hmac.New(sha256.New, ks.fingerprintKey)
+	ks.mu.RUnlock()
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// ActiveVersion — версия ключа, которой шифруются новые данные
+func (ks *KeyStore) ActiveVersion() int {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.active.Version
+}
+
+// Encrypt шифрует данные активной версией ключа
+func (ks *KeyStore) Encrypt(plaintext []byte) ([]byte, int, error) {
+	ks.mu.RLock()
+	version := ks.active.Version
+	gcm := ks.keys[version]
+	ks.mu.RUnlock()
+
+	out := make([]byte, headerSize+gcm.NonceSize(), headerSize+gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	out[0] = formatMarker
+	binary.BigEndian.PutUint32(out[1:headerSize], uint32(version))
+	nonce := out[headerSize:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, 0, err
+	}
+	return gcm.Seal(out, nonce, plaintext, nil), version, nil
+}
+
+// Decrypt расшифровывает данные версией ключа, указанной в заголовке шифртекста
+func (ks *KeyStore) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	if isPGPMessage(ciphertext) {
+		if ks.legacy == nil {
+			return nil, fmt.Errorf("%w: %d", ErrUnknownKeyVersion, LegacyVersion)
+		}
+		return ks.legacy.Decrypt(ciphertext)
+	}
+	version, err := KeyVersion(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := ks.key(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	body := ciphertext[headerSize:]
+	if len(body) < gcm.NonceSize() {
+		return nil, ErrMalformed
+	}
+	return gcm.Open(nil, body[:gcm.NonceSize()], body[gcm.NonceSize():], nil)
+}
+
+// KeyVersion возвращает версию ключа, которой зашифрованы данные
+func KeyVersion(ciphertext []byte) (int, error) {
+	if isPGPMessage(ciphertext) {
+		return LegacyVersion, nil
+	}
+	if len(ciphertext) < headerSize || ciphertext[0] != formatMarker {
+		return 0, ErrMalformed
+	}
+	return int(binary.BigEndian.Uint32(ciphertext[1:headerSize])), nil
+}
+
+// Rotate выпускает новую версию DEK и делает ее активной.
+// storage.ErrAlreadyExists — активную версию уже сменил другой экземпляр приложения.
+func (ks *KeyStore) Rotate(ctx context.Context) (int, error) {
+	previous := 0
+	ks.mu.RLock()
+	if ks.active != nil {
+		previous = ks.active.Version
+	}
+	ks.mu.RUnlock()
+	dek, dataKey, err := ks.createKey(ctx, model.KeyPurposeEncryption, previous)
+	if err != nil {
+		return 0, err
+	}
+	gcm, err := newGCM(dek)
+	if err != nil {
+		return 0, err
+	}
+	ks.mu.Lock()
+	ks.keys[dataKey.Version] = gcm
+	ks.active = dataKey
+	ks.mu.Unlock()
+	return dataKey.Version, nil
+}
+
+// RotateIfDue выпускает новую версию, если активная старше maxAge. Решение принимается по состоянию базы,
+// а не по кэшу экземпляра: ключ мог уже ротировать другой экземпляр.
+func (ks *KeyStore) RotateIfDue(ctx context.Context, maxAge time.Duration, now time.Time) (bool, error) {
+	if maxAge <= 0 {
+		return false, nil
+	}
+	if err := ks.reload(ctx); err != nil {
+		return false, err
+	}
+	ks.mu.RLock()
+	createdAt := ks.active.CreatedAt
+	ks.mu.RUnlock()
+	if now.Sub(createdAt) < maxAge {
+		return false, nil
+	}
+	_, err := ks.Rotate(ctx)
+	if errors.Is(err, storage.ErrAlreadyExists) {
+		// другой экземпляр успел раньше — подхватываем его версию
+		return false, ks.reload(ctx)
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (ks *KeyStore) createFingerprintKey(ctx context.Context) error {
+	key, _, err := ks.createKey(ctx, model.KeyPurposeFingerprint, 0)
+	if err != nil {
+		return err
+	}
+	ks.mu.Lock()
+	ks.fingerprintKey = key
+	ks.mu.Unlock()
+	return nil
+}
+
+func (ks *KeyStore) createKey(ctx context.Context, purpose string, previous int) ([]byte, *model.DataKey, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := ks.master.Wrap(ctx, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("wrap %s key: %w", purpose, err)
+	}
+	dataKey, err := ks.storage.CreateDataKey(ctx, purpose, wrapped, previous)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, dataKey, nil
+}
+
+// key возвращает ключ версии, при промахе перечитывает хранилище —
+// новую версию мог выпустить другой экземпляр приложения
+func (ks *KeyStore) key(ctx context.Context, version int) (cipher.AEAD, error) {
+	ks.mu.RLock()
+	gcm, ok := ks.keys[version]
+	ks.mu.RUnlock()
+	if ok {
+		return gcm, nil
+	}
+	if err := ks.reload(ctx); err != nil {
+		return nil, err
+	}
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	if gcm, ok = ks.keys[version]; !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownKeyVersion, version)
+	}
+	return gcm, nil
+}
+
+func (ks *KeyStore) reload(ctx context.Context) error {
+	dataKeys, err := ks.storage.GetDataKeys(ctx)
+	if err != nil {
+		return err
+	}
+	keys := make(map[int]cipher.AEAD, len(dataKeys))
+	var (
+		active         *model.DataKey
+		fingerprintKey []byte
+	)
+	for _, dk := range dataKeys {
+		dek, err := ks.master.Unwrap(ctx, dk.WrappedKey)
+		if err != nil {
+			return fmt.Errorf("unwrap data key v%d: %w", dk.Version, err)
+		}
+		if dk.Purpose == model.KeyPurposeFingerprint {
+			if dk.Status == model.DataKeyActive {
+				fingerprintKey = dek
+			}
+			continue
+		}
+		gcm, err := newGCM(dek)
+		if err != nil {
+			return err
+		}
+		keys[dk.Version] = gcm
+		if dk.Status == model.DataKeyActive {
+			active = dk
+		}
+	}
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.active = active
+	ks.fingerprintKey = fingerprintKey
+	ks.mu.Unlock()
+	return nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
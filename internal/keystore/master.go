@@ -0,0 +1,75 @@
+package keystore
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// MasterKey — ключ шифрования ключей (KEK). Им оборачиваются DEK перед
+// сохранением в БД. Для Vault/KMS достаточно реализовать этот интерфейс.
+type MasterKey interface {
+	Wrap(ctx context.Context, dek []byte) ([]byte, error)
+	Unwrap(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// localMasterKey хранит мастер-ключ в памяти процесса и оборачивает DEK через AES-256-GCM
+type localMasterKey struct {
+	gcm cipher.AEAD
+}
+
+// NewLocalMasterKey создает мастер-ключ из 32 байт
+func NewLocalMasterKey(key []byte) (MasterKey, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("master key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &localMasterKey{gcm: gcm}, nil
+}
+
+// NewBase64MasterKey создает мастер-ключ из строки base64 (например, из переменной окружения)
+func NewBase64MasterKey(encoded string) (MasterKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("master key decode: %w", err)
+	}
+	return NewLocalMasterKey(key)
+}
+
+// NewFileMasterKey читает мастер-ключ в base64 из файла
+func NewFileMasterKey(path string) (MasterKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("master key read: %w", err)
+	}
+	return NewBase64MasterKey(string(data))
+}
+
+func (m *localMasterKey) Wrap(_ context.Context, dek []byte) ([]byte, error) {
+	nonce := make([]byte, m.gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return m.gcm.Seal(nonce, nonce, dek, nil), nil
+}
+
+func (m *localMasterKey) Unwrap(_ context.Context, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < m.gcm.NonceSize() {
+		return nil, errors.New("wrapped key too short")
+	}
+	nonce, sealed := wrapped[:m.gcm.NonceSize()], wrapped[m.gcm.NonceSize():]
+	return m.gcm.Open(nil, nonce, sealed, nil)
+}
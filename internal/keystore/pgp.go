@@ -0,0 +1,40 @@
+package keystore
+
+import (
+	"bytes"
+
+	"github.com/ProtonMail/gopenpgp/v3/crypto"
+)
+
+var pgpArmorHeader = []byte("-----BEGIN PGP MESSAGE-----")
+
+// PGPDecrypter расшифровывает данные, зашифрованные до появления keystore.
+// Такие шифртексты считаются версией 0 и перешифровываются фоновой задачей.
+type PGPDecrypter struct {
+	privateKey *crypto.Key
+}
+
+func NewPGPDecrypter(armoredPrivateKey, passphrase string) (*PGPDecrypter, error) {
+	privateKey, err := crypto.NewPrivateKeyFromArmored(armoredPrivateKey, []byte(passphrase))
+	if err != nil {
+		return nil, err
+	}
+	return &PGPDecrypter{privateKey: privateKey}, nil
+}
+
+func (d *PGPDecrypter) Decrypt(encrypted []byte) ([]byte, error) {
+	pgp := crypto.PGP()
+	decHandle, err := pgp.Decryption().DecryptionKey(d.privateKey).New()
+	if err != nil {
+		return nil, err
+	}
+	decrypted, err := decHandle.Decrypt(encrypted, crypto.Armor)
+	if err != nil {
+		return nil, err
+	}
+	return decrypted.Bytes(), nil
+}
+
+func isPGPMessage(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), pgpArmorHeader)
+}
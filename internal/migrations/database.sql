@@ -1,59 +1,520 @@
+-- Файл выполняется при создании базы и повторно при обновлении (make migrate): таблицы создаются с IF NOT EXISTS,
+-- а колонки, добавленные в уже существующие таблицы, — через ALTER TABLE ... ADD COLUMN IF NOT EXISTS.
+-- Индексы по новым колонкам создаются после соответствующих ALTER TABLE.
 
 -- USERS
 CREATE TABLE IF NOT EXISTS users (
     uuid VARCHAR(255) PRIMARY KEY,
     email VARCHAR(255) NOT NULL UNIQUE,
+    username VARCHAR(32) NOT NULL,
     password VARCHAR(255) NOT NULL,
     full_name VARCHAR(255) NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'pending_verification',
+    role VARCHAR(32) NOT NULL DEFAULT 'customer', -- customer, support, back_office, admin; первый admin назначается вручную в базе
+    verified_at TIMESTAMP WITH TIME ZONE,
+    verification_sent_at TIMESTAMP WITH TIME ZONE,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
 );
 
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username VARCHAR(32);
+-- пользователи, зарегистрированные до появления username, получают уникальное имя по UUID и могут входить по email
+UPDATE users SET username = 'user_' || left(replace(uuid, '-', ''), 16) WHERE username IS NULL;
+ALTER TABLE users ALTER COLUMN username SET NOT NULL;
+-- пользователи, зарегистрированные до подтверждения email, остаются активными; новые ждут подтверждения
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status VARCHAR(32) NOT NULL DEFAULT 'active';
+ALTER TABLE users ALTER COLUMN status SET DEFAULT 'pending_verification';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(32) NOT NULL DEFAULT 'customer';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verified_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verification_sent_at TIMESTAMP WITH TIME ZONE;
+
+-- email и username хранятся в нормализованном виде (нижний регистр)
+CREATE UNIQUE INDEX IF NOT EXISTS uidx_users_username ON users(username);
+
+-- USER_TOTP: второй фактор, секрет зашифрован ключами keystore
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id VARCHAR(255) PRIMARY KEY REFERENCES users(uuid) ON DELETE CASCADE,
+    secret_encrypted BYTEA NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    last_counter BIGINT NOT NULL DEFAULT 0,
+    enrolled_at TIMESTAMP WITH TIME ZONE
+);
+
+-- RECOVERY_CODES: одноразовые коды восстановления, хранится только HMAC
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    user_id VARCHAR(255) NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
+    code_hash BYTEA NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (user_id, code_hash)
+);
+
+-- MFA_CHALLENGES: выданные частичные токены входа; токен одноразовый и отзывается после нескольких неверных кодов
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    jti VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- SESSIONS
+CREATE TABLE IF NOT EXISTS sessions (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+
+-- REFRESH_TOKENS: хранится только SHA-256, использованные токены остаются для обнаружения повторов
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    token_hash BYTEA PRIMARY KEY,
+    session_id VARCHAR(64) NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE
+);
+
+-- REVOKED_TOKENS: отозванные до истечения access-токены (по jti)
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- PASSWORD_RESET_TOKENS: одноразовые токены сброса пароля, хранится только SHA-256
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    token_hash BYTEA PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
+
+-- LOGIN_FAILURES: счетчики неудачных входов по аккаунту и по IP-адресу
+CREATE TABLE IF NOT EXISTS login_failures (
+    scope VARCHAR(16) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    last_failure_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (scope, key)
+);
+
+-- USER_PROFILES: персональные данные зашифрованы ключом keystore, как номера карт;
+-- отпечатки HMAC не дают завести один паспорт, ИНН или телефон на нескольких клиентов
+CREATE TABLE IF NOT EXISTS user_profiles (
+    user_id VARCHAR(255) PRIMARY KEY REFERENCES users(uuid) ON DELETE CASCADE,
+    encrypted_data BYTEA NOT NULL,
+    key_version INT NOT NULL,
+    phone_fingerprint BYTEA,
+    passport_fingerprint BYTEA,
+    inn_fingerprint BYTEA,
+    kyc_status VARCHAR(16) NOT NULL DEFAULT 'not_started', -- not_started, pending, verified, rejected
+    kyc_comment TEXT,
+    kyc_submitted_at TIMESTAMP WITH TIME ZONE,
+    kyc_reviewed_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uidx_user_profiles_phone ON user_profiles(phone_fingerprint);
+CREATE UNIQUE INDEX IF NOT EXISTS uidx_user_profiles_passport ON user_profiles(passport_fingerprint);
+CREATE UNIQUE INDEX IF NOT EXISTS uidx_user_profiles_inn ON user_profiles(inn_fingerprint);
+CREATE INDEX IF NOT EXISTS idx_user_profiles_key_version ON user_profiles(key_version);
+CREATE INDEX IF NOT EXISTS idx_user_profiles_kyc_pending ON user_profiles(kyc_submitted_at) WHERE kyc_status = 'pending';
+
+-- KYC_DOCUMENTS: сканы документов, зашифрованные ключом keystore
+CREATE TABLE IF NOT EXISTS kyc_documents (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
+    doc_type VARCHAR(32) NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(64) NOT NULL,
+    size INT NOT NULL,
+    encrypted_content BYTEA NOT NULL,
+    key_version INT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_kyc_documents_user_id ON kyc_documents(user_id);
+CREATE INDEX IF NOT EXISTS idx_kyc_documents_key_version ON kyc_documents(key_version);
+
+-- API_CLIENTS: машинные клиенты, действующие от имени владельца в пределах scopes
+CREATE TABLE IF NOT EXISTS api_clients (
+    id VARCHAR(64) PRIMARY KEY,
+    owner_id VARCHAR(255) NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    scopes TEXT[] NOT NULL,
+    rate_limit INT NOT NULL,
+    status VARCHAR(16) NOT NULL,          -- active, revoked
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_clients_owner_id ON api_clients(owner_id);
+
+-- API_CLIENT_SECRETS: хранится только SHA-256; при ротации старый секрет действует до expires_at
+CREATE TABLE IF NOT EXISTS api_client_secrets (
+    secret_hash BYTEA PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL REFERENCES api_clients(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
 -- ACCOUNTS
 CREATE TABLE IF NOT EXISTS accounts (
     id BIGSERIAL PRIMARY KEY,
     user_id VARCHAR(256) NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
-    -- number VARCHAR(34) NOT NULL,
-    balance NUMERIC(18,2) NOT NULL DEFAULT 0,
+    number VARCHAR(20),            -- 20-значный номер счета, у счетов до появления номеров выдается фоновой задачей
+    balance NUMERIC(19,3) NOT NULL DEFAULT 0,
     currency VARCHAR(8) NOT NULL,
+    type VARCHAR(16) NOT NULL DEFAULT 'current',  -- тип счета, от него зависят лимиты
     is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    is_frozen BOOLEAN NOT NULL DEFAULT FALSE,  -- заморожен сотрудником банка
+    owner_frozen BOOLEAN NOT NULL DEFAULT FALSE, -- заморожен владельцем
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    closed_at TIMESTAMP WITH TIME ZONE,
+    overdraft_limit NUMERIC(19,3) NOT NULL DEFAULT 0,     -- согласованный овердрафт, остаток может уйти в минус на эту сумму
+    overdraft_rate NUMERIC(7,4) NOT NULL DEFAULT 0,       -- годовая ставка по овердрафту, доля
+    overdraft_interest NUMERIC(18,6) NOT NULL DEFAULT 0,  -- начисленные и еще не списанные проценты
+    overdraft_interest_since DATE,                        -- первый день, за который начислены несписанные проценты
+    overdraft_accrued_on DATE,                            -- последний день, за который начислены проценты
+    overdraft_warned SMALLINT NOT NULL DEFAULT 0,         -- сколько порогов использования пересечено при последнем предупреждении
+    product VARCHAR(32),                                  -- код сберегательного продукта, условия копируются при открытии
+    interest_rate NUMERIC(7,4) NOT NULL DEFAULT 0,        -- годовая ставка на остаток, доля
+    interest_accrued NUMERIC(18,6) NOT NULL DEFAULT 0,    -- начисленные и еще не выплаченные проценты
+    interest_since DATE,                                  -- первый день, за который начислены невыплаченные проценты
+    interest_accrued_on DATE,                             -- последний день, за который начислены проценты
+    term_days INT NOT NULL DEFAULT 0,                     -- срок вклада
+    matures_on DATE,                                      -- окончание срока вклада, с этого дня проценты не начисляются
+    early_penalty NUMERIC(5,4) NOT NULL DEFAULT 0,        -- доля процентов срока, теряемая при досрочном закрытии
+    rollover BOOLEAN NOT NULL DEFAULT FALSE,              -- продлевать вклад по окончании срока
+    payout_account_id BIGINT REFERENCES accounts(id)      -- куда вернуть средства вклада
+);
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS number VARCHAR(20);
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS type VARCHAR(16) NOT NULL DEFAULT 'current';
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS is_frozen BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS owner_frozen BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS closed_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS overdraft_limit NUMERIC(19,3) NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS overdraft_rate NUMERIC(7,4) NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS overdraft_interest NUMERIC(18,6) NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS overdraft_interest_since DATE;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS overdraft_accrued_on DATE;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS overdraft_warned SMALLINT NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS product VARCHAR(32);
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS interest_rate NUMERIC(7,4) NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS interest_accrued NUMERIC(18,6) NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS interest_since DATE;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS interest_accrued_on DATE;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS term_days INT NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS matures_on DATE;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS early_penalty NUMERIC(5,4) NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS rollover BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS payout_account_id BIGINT REFERENCES accounts(id);
+
+-- начисление овердрафта догоняет пропущенные дни от overdraft_accrued_on; счетам со ставкой, которым еще ничего
+-- не начислялось, отметка ставится на вчера, чтобы проценты не начислились задним числом с открытия счета
+UPDATE accounts SET overdraft_accrued_on = CURRENT_DATE - 1 WHERE overdraft_rate > 0 AND overdraft_accrued_on IS NULL;
+-- так же для процентов по продуктам: отметку двигает каждое начисление, и NULL остается только у счетов,
+-- открытых до этого изменения и ни разу не получивших проценты
+UPDATE accounts SET interest_accrued_on = CURRENT_DATE - 1 WHERE product IS NOT NULL AND interest_accrued_on IS NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uidx_account_number ON accounts(number);
+CREATE INDEX IF NOT EXISTS idx_accounts_overdraft_used ON accounts(id) WHERE balance < 0;
+CREATE INDEX IF NOT EXISTS idx_accounts_overdraft_interest ON accounts(overdraft_interest_since) WHERE overdraft_interest_since IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_accounts_interest_since ON accounts(interest_since) WHERE interest_since IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_accounts_matures_on ON accounts(matures_on) WHERE is_active AND matures_on IS NOT NULL;
+
+-- ACCOUNT LIMIT OVERRIDES: лимиты, временно сниженные владельцем счета; 0 — ограничение по тарифу
+CREATE TABLE IF NOT EXISTS account_limit_overrides (
+    account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    operation VARCHAR(16) NOT NULL,  -- withdraw, transfer
+    per_operation NUMERIC(19,3) NOT NULL DEFAULT 0,
+    daily_amount NUMERIC(19,3) NOT NULL DEFAULT 0,
+    monthly_amount NUMERIC(19,3) NOT NULL DEFAULT 0,
+    daily_count INT NOT NULL DEFAULT 0,
+    monthly_count INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (account_id, operation)
+);
+
+-- ALIASES: телефон или email для переводов на счет клиента по умолчанию, как в СБП.
+-- Значение не хранится: отпечаток HMAC для поиска и маскированная форма для владельца
+CREATE TABLE IF NOT EXISTS aliases (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
+    type VARCHAR(16) NOT NULL,
+    fingerprint BYTEA NOT NULL UNIQUE,
+    masked_value VARCHAR(255) NOT NULL,
+    account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    UNIQUE (user_id, type)
+);
+
+-- PAYEES: сохраненные получатели клиента — счет нашего банка, счет в другом банке или псевдоним
+CREATE TABLE IF NOT EXISTS payees (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
+    nickname VARCHAR(64) NOT NULL,
+    type VARCHAR(16) NOT NULL,
+    account_number VARCHAR(20),
+    bic VARCHAR(9),
+    recipient_name VARCHAR(255),
+    alias_type VARCHAR(16),
+    -- alias_value — открытое значение у получателей, сохраненных до отпечатков; фоновая задача заменяет его на alias_fingerprint
+    alias_value VARCHAR(255),
+    alias_fingerprint BYTEA,
+    alias_masked VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    UNIQUE (user_id, nickname)
+);
+
+ALTER TABLE payees ADD COLUMN IF NOT EXISTS alias_fingerprint BYTEA;
+ALTER TABLE payees ADD COLUMN IF NOT EXISTS alias_masked VARCHAR(255);
+
+-- PAYMENT_TEMPLATES: шаблоны платежей сохраненным получателям
+CREATE TABLE IF NOT EXISTS payment_templates (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    payee_id BIGINT NOT NULL REFERENCES payees(id) ON DELETE CASCADE,
+    from_account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    amount NUMERIC(19,3) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    UNIQUE (user_id, name)
+);
+
+-- STANDING_ORDERS: регулярные переводы сохраненному получателю по расписанию.
+-- scheduled_for — плановая дата текущего платежа до переноса на рабочий день, next_run_at — когда его исполнить
+CREATE TABLE IF NOT EXISTS standing_orders (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
+    from_account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    payee_id BIGINT NOT NULL REFERENCES payees(id) ON DELETE CASCADE,
+    amount NUMERIC(19,3) NOT NULL,
+    description TEXT,
+    frequency VARCHAR(16) NOT NULL,
+    day_of_month INT,
+    weekday INT,
+    business_day VARCHAR(16) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    max_occurrences INT,
+    occurrences INT NOT NULL DEFAULT 0,
+    status VARCHAR(16) NOT NULL,
+    scheduled_for DATE,
+    next_run_at TIMESTAMP WITH TIME ZONE,
+    retry_count INT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_standing_orders_due ON standing_orders(next_run_at) WHERE status = 'active';
+
+-- STANDING_ORDER_EXECUTIONS: история исполнения, включая неудачные попытки
+CREATE TABLE IF NOT EXISTS standing_order_executions (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES standing_orders(id) ON DELETE CASCADE,
+    scheduled_for DATE NOT NULL,
+    attempt INT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    amount NUMERIC(19,3) NOT NULL,
+    error TEXT,
+    executed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_standing_order_executions_order ON standing_order_executions(order_id);
+
+-- ADMIN_ACTIONS: журнал действий сотрудников с обязательным кодом причины
+CREATE TABLE IF NOT EXISTS admin_actions (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id VARCHAR(255) NOT NULL REFERENCES users(uuid),
+    action VARCHAR(32) NOT NULL,
+    target_type VARCHAR(16) NOT NULL,     -- user, account, transaction, dispute
+    target_id VARCHAR(255) NOT NULL,
+    amount NUMERIC(19,3),
+    reason_code VARCHAR(32) NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
 );
 
--- CREATE UNIQUE INDEX IF NOT EXISTS uidx_account_number ON accounts(number);
+CREATE INDEX IF NOT EXISTS idx_admin_actions_target ON admin_actions(target_type, target_id);
 
 -- CARDS
 CREATE TABLE IF NOT EXISTS cards (
     id BIGSERIAL PRIMARY KEY,
     account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
     encrypted_pan BYTEA NOT NULL,
+    key_version INT NOT NULL DEFAULT 0,
+    pan_fingerprint BYTEA,                -- HMAC-SHA256 от PAN для поиска без расшифровки
     expiry_month INT NOT NULL CHECK (expiry_month >= 1 AND expiry_month <= 12),
     expiry_year INT NOT NULL,
     -- encrypted_cvv VARCHAR(255) NOT NULL,
     cardholder_name VARCHAR(255) NOT NULL,
+    dynamic_cvv BOOLEAN NOT NULL DEFAULT FALSE,
     is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    status VARCHAR(16) NOT NULL DEFAULT 'active', -- active, expired
+    replaced_by_id BIGINT REFERENCES cards(id),
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
 );
 
--- CREATE UNIQUE INDEX IF NOT EXISTS uidx_card_number ON cards(number);
+-- карты, выпущенные до keystore, имеют key_version = 0 и расшифровываются прежним ключом до перешифрования
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS key_version INT NOT NULL DEFAULT 0;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS pan_fingerprint BYTEA;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS dynamic_cvv BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'active';
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS replaced_by_id BIGINT REFERENCES cards(id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uidx_card_pan_fingerprint ON cards(pan_fingerprint);
+
+CREATE INDEX IF NOT EXISTS idx_cards_key_version ON cards(key_version);
+CREATE INDEX IF NOT EXISTS idx_cards_expiry ON cards(expiry_year, expiry_month) WHERE status = 'active';
+
+-- CARD_PINS: хэши PIN хранятся отдельно от номеров карт
+CREATE TABLE IF NOT EXISTS card_pins (
+    card_id BIGINT PRIMARY KEY REFERENCES cards(id) ON DELETE CASCADE,
+    pin_hash VARCHAR(255) NOT NULL,
+    failed_attempts INT NOT NULL DEFAULT 0,
+    locked BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- CARD_TOKENS: токены карт для кошельков и мерчантов, сам токен не хранится
+CREATE TABLE IF NOT EXISTS card_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    card_id BIGINT NOT NULL REFERENCES cards(id) ON DELETE CASCADE,
+    token_fingerprint BYTEA NOT NULL UNIQUE,
+    last_four VARCHAR(4) NOT NULL,
+    requestor_type VARCHAR(16) NOT NULL,  -- device, merchant
+    requestor_id VARCHAR(255) NOT NULL,
+    status VARCHAR(16) NOT NULL,          -- active, suspended, deleted
+    max_amount NUMERIC(19,3) NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_card_tokens_card_id ON card_tokens(card_id);
+
+-- DATA_KEYS: версии ключей шифрования данных, обёрнутые мастер-ключом
+CREATE TABLE IF NOT EXISTS data_keys (
+    version SERIAL PRIMARY KEY,
+    purpose VARCHAR(16) NOT NULL DEFAULT 'encryption', -- encryption, fingerprint
+    wrapped_key BYTEA NOT NULL,
+    status VARCHAR(16) NOT NULL,          -- active, retired
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+ALTER TABLE data_keys ADD COLUMN IF NOT EXISTS purpose VARCHAR(16) NOT NULL DEFAULT 'encryption';
+
+-- прежний индекс допускал один активный ключ на все назначения и не дал бы создать ключ отпечатков
+DROP INDEX IF EXISTS uidx_data_keys_active;
+CREATE UNIQUE INDEX IF NOT EXISTS uidx_data_keys_active_purpose ON data_keys(purpose) WHERE status = 'active';
+
+-- SIGNING_KEYS: ключи подписи JWT; закрытый ключ зашифрован keystore, открытый публикуется в JWKS.
+-- Ключ без expires_at подписывает новые токены, остальные только проверяют ранее выданные.
+CREATE TABLE IF NOT EXISTS signing_keys (
+    kid VARCHAR(64) PRIMARY KEY,
+    algorithm VARCHAR(16) NOT NULL,       -- EdDSA, RS256
+    private_key BYTEA NOT NULL,
+    public_key BYTEA NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP WITH TIME ZONE
+);
 
 -- TRANSACTIONS
 CREATE TABLE IF NOT EXISTS transactions (
     id BIGSERIAL PRIMARY KEY,
     account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
-    amount NUMERIC(18,2) NOT NULL,
+    amount NUMERIC(19,3) NOT NULL,
     currency VARCHAR(8) NOT NULL,
     type VARCHAR(32) NOT NULL,     -- deposit, withdraw, transfer, payment, etc.
     status VARCHAR(32) NOT NULL,   -- success, pending, failed, etc.
     description TEXT,
     related_entity_id BIGINT,
+    fx_rate NUMERIC(24,10),        -- курс перевода между валютами, одинаковый на обеих ногах
+    counterpart_id BIGINT,         -- вторая нога перевода
+    reversal_of BIGINT REFERENCES transactions(id), -- исходная операция для сторно
+    reversed_amount NUMERIC(19,3) NOT NULL DEFAULT 0, -- сколько исходной суммы уже сторнировано
+    idempotency_key VARCHAR(64),   -- ключ повторяемого перевода, хранится на ноге списания
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
 );
 
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS fx_rate NUMERIC(24,10);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS counterpart_id BIGINT;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS reversal_of BIGINT REFERENCES transactions(id);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS reversed_amount NUMERIC(19,3) NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS idempotency_key VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_reversal_of ON transactions(reversal_of) WHERE reversal_of IS NOT NULL;
+-- для подсчета использованных лимитов за скользящие окна
+CREATE INDEX IF NOT EXISTS idx_transactions_account_created ON transactions(account_id, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS uidx_transactions_idempotency_key ON transactions(idempotency_key);
+
+-- DISPUTES: обращения клиентов по операциям. Открытым может быть только одно обращение на операцию
+CREATE TABLE IF NOT EXISTS disputes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
+    transaction_id BIGINT NOT NULL REFERENCES transactions(id),
+    reason VARCHAR(32) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL,
+    resolution_comment TEXT NOT NULL DEFAULT '',
+    reversed_amount NUMERIC(19,3),
+    resolved_by VARCHAR(255) REFERENCES users(uuid),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_disputes_user_id ON disputes(user_id);
+CREATE INDEX IF NOT EXISTS idx_disputes_status ON disputes(status);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_disputes_open_transaction ON disputes(transaction_id) WHERE status IN ('open', 'in_review');
+
+-- DISPUTE_ATTACHMENTS: подтверждающие документы, содержимое зашифровано как сканы KYC
+CREATE TABLE IF NOT EXISTS dispute_attachments (
+    id BIGSERIAL PRIMARY KEY,
+    dispute_id BIGINT NOT NULL REFERENCES disputes(id) ON DELETE CASCADE,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(64) NOT NULL,
+    size INT NOT NULL,
+    encrypted_content BYTEA NOT NULL,
+    key_version INT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dispute_attachments_dispute_id ON dispute_attachments(dispute_id);
+CREATE INDEX IF NOT EXISTS idx_dispute_attachments_key_version ON dispute_attachments(key_version);
+
+-- FX_QUOTES: котировки конвертации, фиксирующие курс для клиента до expires_at
+CREATE TABLE IF NOT EXISTS fx_quotes (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
+    from_currency VARCHAR(8) NOT NULL,
+    to_currency VARCHAR(8) NOT NULL,
+    amount NUMERIC(19,3) NOT NULL,
+    converted_amount NUMERIC(19,3) NOT NULL,
+    rate NUMERIC(24,10) NOT NULL,
+    mid_rate NUMERIC(24,10) NOT NULL,
+    spread NUMERIC(8,6) NOT NULL,
+    rates_date DATE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE
+);
+
 -- CREDITS
 CREATE TABLE IF NOT EXISTS credits (
     id BIGSERIAL PRIMARY KEY,
     user_id VARCHAR(256) NOT NULL REFERENCES users(uuid) ON DELETE CASCADE,
-    amount NUMERIC(18,2) NOT NULL,
+    amount NUMERIC(19,3) NOT NULL,
     currency VARCHAR(8) NOT NULL,
     monthly_rate NUMERIC(8,3) NOT NULL,           -- процентная ставка
     term_months INT NOT NULL,
@@ -67,9 +528,45 @@ CREATE TABLE IF NOT EXISTS payment_schedules (
     credit_id BIGINT NOT NULL REFERENCES credits(id) ON DELETE CASCADE,
     payment_num INT NOT NULL,
     due_date TIMESTAMP WITH TIME ZONE NOT NULL,
-    amount NUMERIC(18,2) NOT NULL,
+    amount NUMERIC(19,3) NOT NULL,
     is_paid BOOLEAN NOT NULL DEFAULT FALSE,
     paid_at TIMESTAMP WITH TIME ZONE
 );
 
 CREATE INDEX IF NOT EXISTS idx_payment_schedule_credit_id ON payment_schedules(credit_id);
+
+-- Суммы хранятся с тремя знаками после запятой: у BHD, KWD, JOD, OMR, TND, IQD и LYD три разряда дробной части.
+-- Базы, созданные с NUMERIC(18,2), расширяются здесь до NUMERIC(19,3) без потери разрядов целой части.
+-- Тип меняется только у колонок, которые еще не расширены: ALTER TYPE переписывает таблицу под эксклюзивной блокировкой.
+DO $$
+DECLARE
+    col RECORD;
+BEGIN
+    FOR col IN
+        SELECT c.table_name, c.column_name
+        FROM information_schema.columns c
+        JOIN (VALUES
+            ('accounts', 'balance'),
+            ('accounts', 'overdraft_limit'),
+            ('account_limit_overrides', 'per_operation'),
+            ('account_limit_overrides', 'daily_amount'),
+            ('account_limit_overrides', 'monthly_amount'),
+            ('payment_templates', 'amount'),
+            ('standing_orders', 'amount'),
+            ('standing_order_executions', 'amount'),
+            ('admin_actions', 'amount'),
+            ('card_tokens', 'max_amount'),
+            ('transactions', 'amount'),
+            ('transactions', 'reversed_amount'),
+            ('disputes', 'reversed_amount'),
+            ('fx_quotes', 'amount'),
+            ('fx_quotes', 'converted_amount'),
+            ('credits', 'amount'),
+            ('payment_schedules', 'amount')
+        ) AS m(table_name, column_name) ON m.table_name = c.table_name AND m.column_name = c.column_name
+        WHERE c.table_schema = current_schema()
+          AND (c.numeric_precision, c.numeric_scale) IS DISTINCT FROM (19, 3)
+    LOOP
+        EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE NUMERIC(19,3)', col.table_name, col.column_name);
+    END LOOP;
+END $$;
@@ -2,6 +2,15 @@ package model
 
 import "time"
 
+// Типы счетов
+const (
+	AccountCurrent = "current"
+	// AccountSavings — сберегательный счет: проценты на ежедневный остаток капитализируются раз в месяц
+	AccountSavings = "savings"
+	// AccountTermDeposit — срочный вклад: без пополнений и снятий до конца срока
+	AccountTermDeposit = "term_deposit"
+)
+
 // Account — банковский счет пользователя
 type Account struct {
 	ID        int64     `json:"id"`
@@ -9,6 +18,52 @@ type Account struct {
 	UserID    string    `json:"user_id"`
 	Number    string    `json:"number"`
 	Currency  string    `json:"currency"`
+	Type      string    `json:"type"`
 	CreatedAt time.Time `json:"created_at"`
 	IsActive  bool      `json:"is_active"`
+	// Frozen — счет заморожен сотрудником банка, списания запрещены
+	Frozen bool `json:"frozen"`
+	// OwnerFrozen — счет заморожен владельцем, списания запрещены, пока он сам не разморозит счет
+	OwnerFrozen bool       `json:"owner_frozen"`
+	ClosedAt    *time.Time `json:"closed_at,omitempty"`
+	// OverdraftLimit — согласованный овердрафт: остаток может уйти в минус не больше чем на эту сумму
+	OverdraftLimit float64 `json:"overdraft_limit"`
+	// OverdraftRate — годовая ставка по овердрафту, доля
+	OverdraftRate float64 `json:"overdraft_rate,omitempty"`
+	// OverdraftInterest — начисленные проценты, которые еще не списаны со счета
+	OverdraftInterest float64 `json:"overdraft_interest,omitempty"`
+	// OverdraftWarned — сколько порогов использования овердрафта было пересечено при последнем предупреждении
+	OverdraftWarned int `json:"-"`
+	// Product — код продукта сберегательного счета или вклада; условия продукта копируются в счет при открытии
+	Product string `json:"product,omitempty"`
+	// InterestRate — годовая ставка на остаток, доля
+	InterestRate float64 `json:"interest_rate,omitempty"`
+	// Accrued — начисленные и еще не выплаченные проценты
+	Accrued float64 `json:"accrued_interest,omitempty"`
+	// TermDays, MaturesOn и EarlyPenalty — срок вклада в днях, дата его окончания и доля процентов текущего срока,
+	// которая теряется при досрочном закрытии
+	TermDays     int        `json:"term_days,omitempty"`
+	MaturesOn    *time.Time `json:"matures_on,omitempty"`
+	EarlyPenalty float64    `json:"early_penalty,omitempty"`
+	// Rollover — по окончании срока вклад продлевается на тот же срок, иначе средства возвращаются на PayoutID
+	Rollover bool   `json:"rollover,omitempty"`
+	PayoutID *int64 `json:"payout_account_id,omitempty"`
+}
+
+// Available — сколько можно списать с учетом овердрафта
+func (a *Account) Available() float64 {
+	return a.Balance + a.OverdraftLimit
+}
+
+// OverdraftUsage — доля использованного овердрафта, 0 — овердрафт не используется
+func (a *Account) OverdraftUsage() float64 {
+	if a.Balance >= 0 || a.OverdraftLimit <= 0 {
+		return 0
+	}
+	return -a.Balance / a.OverdraftLimit
+}
+
+// DebitBlocked — списания со счета запрещены
+func (a *Account) DebitBlocked() bool {
+	return a.Frozen || a.OwnerFrozen
 }
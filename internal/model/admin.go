@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// Действия сотрудников, попадающие в журнал
+const (
+	AdminActionFreezeAccount   = "freeze_account"
+	AdminActionUnfreezeAccount = "unfreeze_account"
+	AdminActionAdjustBalance   = "adjust_balance"
+	AdminActionSetRole         = "set_role"
+	AdminActionApproveKYC      = "approve_kyc"
+	AdminActionRejectKYC       = "reject_kyc"
+	AdminActionEraseUser       = "erase_user"
+	AdminActionReverse         = "reverse_transaction"
+	AdminActionResolveDispute  = "resolve_dispute"
+	AdminActionRejectDispute   = "reject_dispute"
+	AdminActionSetOverdraft    = "set_overdraft"
+)
+
+// Объекты действий сотрудников
+const (
+	AdminTargetUser        = "user"
+	AdminTargetAccount     = "account"
+	AdminTargetTransaction = "transaction"
+	AdminTargetDispute     = "dispute"
+)
+
+// Коды причин, обязательные для действий сотрудников
+const (
+	ReasonCustomerRequest  = "customer_request"
+	ReasonFraudSuspected   = "fraud_suspected"
+	ReasonCourtOrder       = "court_order"
+	ReasonComplianceReview = "compliance_review"
+	ReasonErrorCorrection  = "error_correction"
+	ReasonFeeRefund        = "fee_refund"
+	ReasonGoodwill         = "goodwill"
+	ReasonStaffChange      = "staff_change"
+)
+
+// ValidReasonCode — входит ли код причины в справочник
+func ValidReasonCode(code string) bool {
+	switch code {
+	case ReasonCustomerRequest, ReasonFraudSuspected, ReasonCourtOrder, ReasonComplianceReview,
+		ReasonErrorCorrection, ReasonFeeRefund, ReasonGoodwill, ReasonStaffChange:
+		return true
+	}
+	return false
+}
+
+// AdminReason — обоснование действия сотрудника
+type AdminReason struct {
+	ReasonCode string `json:"reason_code"`
+	Comment    string `json:"comment"`
+}
+
+// AdminAction — запись журнала действий сотрудников
+type AdminAction struct {
+	ID         int64    `json:"id"`
+	ActorID    string   `json:"actor_id"`
+	Action     string   `json:"action"`
+	TargetType string   `json:"target_type"`
+	TargetID   string   `json:"target_id"`
+	Amount     *float64 `json:"amount,omitempty"`
+	AdminReason
+	CreatedAt time.Time `json:"created_at"`
+}
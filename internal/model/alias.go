@@ -0,0 +1,80 @@
+package model
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Типы псевдонимов для переводов
+const (
+	AliasPhone = "phone"
+	AliasEmail = "email"
+)
+
+// ValidAliasType — известен ли тип псевдонима
+func ValidAliasType(aliasType string) bool {
+	return aliasType == AliasPhone || aliasType == AliasEmail
+}
+
+// Alias — телефон или email клиента, по которому ему можно перевести деньги на счет по умолчанию.
+// Само значение не хранится: только отпечаток HMAC для поиска и маскированная форма для отображения.
+type Alias struct {
+	ID          int64     `json:"id"`
+	UserID      string    `json:"-"`
+	Type        string    `json:"type"`
+	MaskedValue string    `json:"masked_value"`
+	AccountID   int64     `json:"account_id"`
+	Fingerprint []byte    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// AliasTarget — получатель перевода, указанный псевдонимом
+type AliasTarget struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// AliasLookup — сведения о получателе для подтверждения перевода отправителем
+type AliasLookup struct {
+	Type       string `json:"type"`
+	MaskedName string `json:"masked_name"`
+	Currency   string `json:"currency"`
+}
+
+// MaskName оставляет от полного имени то, что показывают при переводе по номеру телефона:
+// «Фамилия Имя Отчество» → «Имя Отчество Ф.», «Имя Фамилия» → «Имя Ф.»
+func MaskName(fullName string) string {
+	parts := strings.Fields(fullName)
+	switch len(parts) {
+	case 0:
+		return ""
+	case 1:
+		return parts[0]
+	case 2:
+		return parts[0] + " " + initial(parts[1])
+	default:
+		return strings.Join(parts[1:], " ") + " " + initial(parts[0])
+	}
+}
+
+// MaskEmail скрывает локальную часть адреса, кроме первого символа
+func MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return maskTail(email)
+	}
+	first, _ := utf8.DecodeRuneInString(email)
+	return string(first) + "***" + email[at:]
+}
+
+// MaskPhone оставляет последние четыре цифры номера
+func MaskPhone(phone string) string {
+	return maskTail(phone)
+}
+
+func initial(word string) string {
+	r, _ := utf8.DecodeRuneInString(word)
+	return string(r) + "."
+}
@@ -0,0 +1,58 @@
+package model
+
+import "time"
+
+// Области доступа машинных клиентов. Токен пользователя ограничен только его правами,
+// токен клиента — еще и выданными клиенту scopes.
+const (
+	ScopeAccountsRead   = "accounts:read"
+	ScopeAccountsWrite  = "accounts:write"
+	ScopeTransfersWrite = "transfers:write"
+	ScopeCardsRead      = "cards:read"
+	ScopeCardsWrite     = "cards:write"
+	ScopeCreditsRead    = "credits:read"
+	ScopeCreditsWrite   = "credits:write"
+)
+
+// ValidScope — известна ли область доступа
+func ValidScope(scope string) bool {
+	switch scope {
+	case ScopeAccountsRead, ScopeAccountsWrite, ScopeTransfersWrite, ScopeCardsRead, ScopeCardsWrite,
+		ScopeCreditsRead, ScopeCreditsWrite:
+		return true
+	}
+	return false
+}
+
+const (
+	APIClientActive  = "active"
+	APIClientRevoked = "revoked"
+)
+
+// APIClient — машинный клиент, действующий от имени пользователя-владельца
+type APIClient struct {
+	ID          string     `json:"client_id"`
+	OwnerUserID string     `json:"owner_user_id"`
+	Name        string     `json:"name"`
+	Scopes      []string   `json:"scopes"`
+	RateLimit   int        `json:"rate_limit"` // запросов в минуту
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIClientCredentials — секрет клиента, показывается только при выпуске.
+// APIKey — тот же секрет в виде "client_id.secret" для заголовка X-API-Key.
+type APIClientCredentials struct {
+	Client       *APIClient `json:"client"`
+	ClientSecret string     `json:"client_secret"`
+	APIKey       string     `json:"api_key"`
+}
+
+// ClientToken — ответ OAuth2 client credentials
+type ClientToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
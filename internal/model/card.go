@@ -4,20 +4,74 @@ import (
 	"time"
 )
 
+const (
+	CardActive  = "active"
+	CardExpired = "expired"
+)
+
 // Card — карта, привязанная к счету (CVV зашифрован)
 type Card struct {
-	ExpiryMonth    int       `json:"expiry_month"`
-	ExpiryYear     int       `json:"expiry_year"`
-	ID             int64     `json:"id"`
-	AccountID      int64     `json:"account_id"`
-	PAN            string    `json:"number"` // При сохранении шифруем
-	CVV            string    `json:"cvv"`    // Не храним
-	CardholderName string    `json:"cardholder_name"`
-	CreatedAt      time.Time `json:"created_at"`
-	IsActive       bool      `json:"is_active"`
+	ExpiryMonth    int        `json:"expiry_month"`
+	ExpiryYear     int        `json:"expiry_year"`
+	ID             int64      `json:"id"`
+	AccountID      int64      `json:"account_id"`
+	PAN            string     `json:"number"` // При сохранении шифруем
+	EncryptedPAN   []byte     `json:"-"`
+	PANFingerprint []byte     `json:"-"`           // HMAC от PAN, по нему ищем карту
+	KeyVersion     int        `json:"-"`           // версия ключа keystore, 0 — PGP
+	CVV            string     `json:"cvv"`         // Не храним
+	DynamicCVV     bool       `json:"dynamic_cvv"` // CVV меняется каждое окно времени
+	CVVExpiresAt   *time.Time `json:"cvv_expires_at,omitempty"`
+	CardholderName string     `json:"cardholder_name"`
+	CreatedAt      time.Time  `json:"created_at"`
+	IsActive       bool       `json:"is_active"`
+	Status         string     `json:"status"`
+	// ReplacedByID — карта, выпущенная взамен этой при перевыпуске с новым номером
+	ReplacedByID *int64 `json:"replaced_by_id,omitempty"`
+}
+
+// CardPIN — хэш PIN карты и счетчик неверных попыток, хранится отдельно от PAN
+type CardPIN struct {
+	CardID         int64     `json:"card_id"`
+	Hash           string    `json:"-"`
+	FailedAttempts int       `json:"failed_attempts"`
+	Locked         bool      `json:"locked"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// CardAuthorization — реквизиты, предъявленные при авторизации операции по карте.
+// Вместо PAN может быть предъявлен токен, тогда RequestorID должен совпадать с владельцем токена.
+type CardAuthorization struct {
+	PAN         string
+	Token       string
+	RequestorID string
+	PIN         string // пусто для операций без PIN
+	CVV         string // пусто для операций с PIN
+	Amount      float64
+}
+
+// Mask скрывает реквизиты карты для ответов без подтверждения личности
+func (c *Card) Mask() {
+	c.PAN = MaskPAN(c.PAN)
+	c.CVV = ""
+	c.CVVExpiresAt = nil
+}
+
+// MaskPAN оставляет видимыми только последние 4 цифры номера
+func MaskPAN(pan string) string {
+	if len(pan) < 4 {
+		return "****"
+	}
+	return "**** " + pan[len(pan)-4:]
+}
+
+// IsExpired — карта действует до конца месяца, указанного в сроке действия
+func (c *Card) IsExpired(now time.Time) bool {
+	expiresAt := time.Date(c.ExpiryYear, time.Month(c.ExpiryMonth)+1, 1, 0, 0, 0, 0, now.Location())
+	return !now.Before(expiresAt)
 }
 
-func (c *Card) GenerateTimeExpiry() {
-	c.ExpiryYear = time.Now().AddDate(4, 0, 0).Year()
-	c.ExpiryMonth = int(time.Now().Month())
+func (c *Card) GenerateTimeExpiry(now time.Time) {
+	c.ExpiryYear = now.AddDate(4, 0, 0).Year()
+	c.ExpiryMonth = int(now.Month())
 }
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+const (
+	TokenRequestorDevice   = "device"   // кошелек на устройстве
+	TokenRequestorMerchant = "merchant" // сохраненная карта у e-commerce мерчанта
+)
+
+const (
+	TokenActive    = "active"
+	TokenSuspended = "suspended"
+	TokenDeleted   = "deleted"
+)
+
+// CardToken — заменитель PAN, выданный конкретному устройству или мерчанту.
+// Сам токен хранится только в виде HMAC-отпечатка и показывается один раз при выпуске.
+type CardToken struct {
+	ID            int64      `json:"id"`
+	CardID        int64      `json:"card_id"`
+	Token         string     `json:"token,omitempty"`
+	Fingerprint   []byte     `json:"-"`
+	LastFour      string     `json:"last_four"`
+	RequestorType string     `json:"requestor_type"`
+	RequestorID   string     `json:"requestor_id"`
+	Status        string     `json:"status"`
+	MaxAmount     float64    `json:"max_amount"` // лимит на операцию, 0 — без лимита
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
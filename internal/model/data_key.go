@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+const (
+	DataKeyActive  = "active"  // шифрует новые данные
+	DataKeyRetired = "retired" // только расшифровка, ждёт перешифрования данных
+)
+
+const (
+	KeyPurposeEncryption  = "encryption"  // DEK для AES-GCM, ротируется
+	KeyPurposeFingerprint = "fingerprint" // ключ HMAC для поискового индекса PAN, не ротируется
+)
+
+// DataKey — версия ключа шифрования данных (DEK), обёрнутого мастер-ключом
+type DataKey struct {
+	Version    int       `json:"version"`
+	Purpose    string    `json:"purpose"`
+	WrappedKey []byte    `json:"-"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+}
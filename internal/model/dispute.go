@@ -0,0 +1,80 @@
+package model
+
+import "time"
+
+// Причины обращения по операции
+const (
+	DisputeUnauthorized    = "unauthorized"
+	DisputeDuplicate       = "duplicate"
+	DisputeIncorrectAmount = "incorrect_amount"
+	DisputeNotReceived     = "not_received"
+	DisputeOther           = "other"
+)
+
+// ValidDisputeReason — входит ли причина в справочник
+func ValidDisputeReason(reason string) bool {
+	switch reason {
+	case DisputeUnauthorized, DisputeDuplicate, DisputeIncorrectAmount, DisputeNotReceived, DisputeOther:
+		return true
+	}
+	return false
+}
+
+// Статусы обращения: open → in_review → resolved или rejected; клиент может отозвать открытое обращение
+const (
+	DisputeOpen      = "open"
+	DisputeInReview  = "in_review"
+	DisputeResolved  = "resolved"
+	DisputeRejected  = "rejected"
+	DisputeWithdrawn = "withdrawn"
+)
+
+// Решения бэк-офиса по обращению
+const (
+	DecisionReverse = "reverse"
+	DecisionReject  = "reject"
+)
+
+// Dispute — обращение клиента по операции
+type Dispute struct {
+	ID                int64      `json:"id"`
+	UserID            string     `json:"user_id"`
+	TransactionID     int64      `json:"transaction_id"`
+	Reason            string     `json:"reason"`
+	Description       string     `json:"description"`
+	Status            string     `json:"status"`
+	ResolutionComment string     `json:"resolution_comment,omitempty"`
+	ReversedAmount    *float64   `json:"reversed_amount,omitempty"`
+	ResolvedBy        string     `json:"resolved_by,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	ResolvedAt        *time.Time `json:"resolved_at,omitempty"`
+
+	Attachments []*DisputeAttachment `json:"attachments,omitempty"`
+}
+
+// Closed — обращение рассмотрено или отозвано
+func (d *Dispute) Closed() bool {
+	return d.Status != DisputeOpen && d.Status != DisputeInReview
+}
+
+// DisputeAttachment — подтверждающий документ; содержимое хранится зашифрованным
+type DisputeAttachment struct {
+	ID          int64     `json:"id"`
+	DisputeID   int64     `json:"dispute_id"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int       `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	Content          []byte `json:"-"`
+	EncryptedContent []byte `json:"-"`
+	KeyVersion       int    `json:"-"`
+}
+
+// DisputeResolution — решение бэк-офиса. Amount — сумма сторно в валюте операции, 0 — весь несторнированный остаток.
+type DisputeResolution struct {
+	Decision string  `json:"decision"`
+	Amount   float64 `json:"amount,omitempty"`
+	AdminReason
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// FXQuote — котировка конвертации, зафиксированная для клиента до ExpiresAt.
+// Rate — единиц валюты To за единицу валюты From с учетом спреда банка.
+type FXQuote struct {
+	ID              string     `json:"id"`
+	UserID          string     `json:"-"`
+	From            string     `json:"from_currency"`
+	To              string     `json:"to_currency"`
+	Amount          float64    `json:"amount"`
+	ConvertedAmount float64    `json:"converted_amount"`
+	Rate            float64    `json:"rate"`
+	MidRate         float64    `json:"mid_rate"`
+	Spread          float64    `json:"spread"`
+	RatesDate       time.Time  `json:"rates_date"`
+	CreatedAt       time.Time  `json:"created_at"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	UsedAt          *time.Time `json:"used_at,omitempty"`
+}
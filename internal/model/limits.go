@@ -0,0 +1,104 @@
+package model
+
+import "time"
+
+// Операции, на которые действуют лимиты списаний; совпадают с типами операций по счету
+const (
+	LimitWithdraw = TransactionWithdraw
+	LimitTransfer = TransactionTransfer
+)
+
+// ValidLimitOperation — есть ли у операции лимиты
+func ValidLimitOperation(operation string) bool {
+	return operation == LimitWithdraw || operation == LimitTransfer
+}
+
+// Коды превышения лимитов, клиент получает их в ответе на отклоненную операцию
+const (
+	LimitPerOperationExceeded  = "per_operation_limit_exceeded"
+	LimitDailyAmountExceeded   = "daily_amount_limit_exceeded"
+	LimitMonthlyAmountExceeded = "monthly_amount_limit_exceeded"
+	LimitDailyCountExceeded    = "daily_count_limit_exceeded"
+	LimitMonthlyCountExceeded  = "monthly_count_limit_exceeded"
+)
+
+// Скользящие окна лимитов: сутки и 30 дней до момента операции
+const (
+	LimitDailyWindow   = 24 * time.Hour
+	LimitMonthlyWindow = 30 * 24 * time.Hour
+)
+
+// Limits — лимиты одной операции в валюте счета, 0 — без ограничения
+type Limits struct {
+	PerOperation float64 `json:"per_operation"`
+	Daily        float64 `json:"daily"`
+	Monthly      float64 `json:"monthly"`
+	DailyCount   int     `json:"daily_count"`
+	MonthlyCount int     `json:"monthly_count"`
+}
+
+// Lower возвращает лимиты, в которых каждое ограничение — строжайшее из двух
+func (l Limits) Lower(other Limits) Limits {
+	return Limits{
+		PerOperation: lowerAmount(l.PerOperation, other.PerOperation),
+		Daily:        lowerAmount(l.Daily, other.Daily),
+		Monthly:      lowerAmount(l.Monthly, other.Monthly),
+		DailyCount:   int(lowerAmount(float64(l.DailyCount), float64(other.DailyCount))),
+		MonthlyCount: int(lowerAmount(float64(l.MonthlyCount), float64(other.MonthlyCount))),
+	}
+}
+
+// Exceeded возвращает код первого лимита, который нарушит списание amount, пустая строка — лимиты соблюдены
+func (l Limits) Exceeded(amount float64, usage LimitUsage) string {
+	switch {
+	case l.PerOperation > 0 && amount > l.PerOperation:
+		return LimitPerOperationExceeded
+	case l.DailyCount > 0 && usage.DailyCount+1 > l.DailyCount:
+		return LimitDailyCountExceeded
+	case l.MonthlyCount > 0 && usage.MonthlyCount+1 > l.MonthlyCount:
+		return LimitMonthlyCountExceeded
+	case l.Daily > 0 && usage.DailyAmount+amount > l.Daily:
+		return LimitDailyAmountExceeded
+	case l.Monthly > 0 && usage.MonthlyAmount+amount > l.Monthly:
+		return LimitMonthlyAmountExceeded
+	}
+	return ""
+}
+
+func lowerAmount(a, b float64) float64 {
+	switch {
+	case a == 0:
+		return b
+	case b == 0:
+		return a
+	case b < a:
+		return b
+	}
+	return a
+}
+
+// LimitUsage — сумма и число списаний за скользящие окна, сторнированная часть не учитывается
+type LimitUsage struct {
+	DailyAmount   float64 `json:"daily_amount"`
+	MonthlyAmount float64 `json:"monthly_amount"`
+	DailyCount    int     `json:"daily_count"`
+	MonthlyCount  int     `json:"monthly_count"`
+}
+
+// LimitOverride — лимиты, временно сниженные владельцем счета
+type LimitOverride struct {
+	AccountID int64     `json:"account_id"`
+	Operation string    `json:"operation"`
+	Limits    Limits    `json:"limits"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AccountLimits — лимиты операции по счету: по тарифу, сниженные клиентом, действующие и использованные
+type AccountLimits struct {
+	Operation string         `json:"operation"`
+	Base      Limits         `json:"base"`
+	Override  *LimitOverride `json:"override,omitempty"`
+	Effective Limits         `json:"effective"`
+	Usage     LimitUsage     `json:"usage"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// Области учета неудачных входов
+const (
+	LockoutScopeAccount = "account"
+	LockoutScopeIP      = "ip"
+	// LockoutScopeAliasLookup — поиск получателей по псевдонимам, ограничивается против перебора телефонов и адресов
+	LockoutScopeAliasLookup = "alias_lookup"
+)
+
+// LoginLockout — счетчик неудачных входов по аккаунту или IP-адресу
+type LoginLockout struct {
+	Scope         string
+	Key           string
+	Failures      int
+	LockedUntil   *time.Time
+	LastFailureAt time.Time
+}
+
+// PasswordResetToken — одноразовый токен сброса пароля, хранится только в виде SHA-256
+type PasswordResetToken struct {
+	UserID    string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// PasswordChange — смена пароля авторизованным пользователем
+type PasswordChange struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
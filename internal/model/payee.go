@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// Типы сохраненных получателей
+const (
+	// PayeeInternal — счет в нашем банке по номеру
+	PayeeInternal = "internal"
+	// PayeeExternal — счет в другом банке: номер и БИК
+	PayeeExternal = "external"
+	// PayeeAlias — клиент по телефону или email
+	PayeeAlias = "alias"
+)
+
+// Payee — сохраненный получатель переводов с названием, которое дал ему клиент
+type Payee struct {
+	ID            int64  `json:"id"`
+	UserID        string `json:"-"`
+	Nickname      string `json:"nickname"`
+	Type          string `json:"type"`
+	AccountNumber string `json:"account_number,omitempty"`
+	BIC           string `json:"bic,omitempty"`
+	RecipientName string `json:"recipient_name,omitempty"`
+	AliasType     string `json:"alias_type,omitempty"`
+	// AliasValue — телефон или email при сохранении; само значение не хранится, как и у псевдонимов
+	AliasValue string `json:"alias_value,omitempty"`
+	// AliasMasked — маскированный телефон или email для показа владельцу
+	AliasMasked string `json:"alias_masked,omitempty"`
+	// AliasFingerprint — отпечаток HMAC, по которому находится счет получателя
+	AliasFingerprint []byte    `json:"-"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// PaymentTemplate — шаблон платежа сохраненному получателю с заполненными счетом списания, суммой и назначением
+type PaymentTemplate struct {
+	ID            int64     `json:"id"`
+	UserID        string    `json:"-"`
+	Name          string    `json:"name"`
+	PayeeID       int64     `json:"payee_id"`
+	FromAccountID int64     `json:"from_account_id"`
+	Amount        float64   `json:"amount"`
+	Description   string    `json:"description,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// DataExport — выгрузка персональных данных клиента по его запросу (152-ФЗ, GDPR)
+type DataExport struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	User        *User            `json:"user"`
+	Profile     *Profile         `json:"profile"`
+	Documents   []*KYCDocument   `json:"documents"`
+	Accounts    []*AccountExport `json:"accounts"`
+	Credits     []*Credit        `json:"credits"`
+	Sessions    []*Session       `json:"sessions"`
+	APIClients  []*APIClient     `json:"api_clients"`
+	// AuditLog — действия сотрудников и самого клиента над его учетной записью
+	AuditLog []*AdminAction `json:"audit_log"`
+}
+
+// AccountExport — счет с операциями и картами; реквизиты карт замаскированы
+type AccountExport struct {
+	*Account
+	Transactions []*Transaction `json:"transactions"`
+	Cards        []*Card        `json:"cards"`
+}
+
+// ErasedUser — обезличенные значения полей пользователя после удаления учетной записи
+type ErasedUser struct {
+	Email    string
+	Username string
+}
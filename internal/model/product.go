@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// Product — сберегательный продукт банка. Ставки — годовые, доли; проценты считаются на ежедневный остаток.
+type Product struct {
+	Code     string  `json:"code"`
+	Type     string  `json:"type"`
+	Name     string  `json:"name"`
+	Currency string  `json:"currency"`
+	Rate     float64 `json:"rate"`
+	// MinAmount — минимальная сумма при открытии
+	MinAmount float64 `json:"min_amount"`
+	// TermDays, EarlyPenalty и Rollover — только для вкладов: срок, доля процентов, теряемая при досрочном
+	// закрытии, и пролонгация по умолчанию
+	TermDays     int     `json:"term_days,omitempty"`
+	EarlyPenalty float64 `json:"early_penalty,omitempty"`
+	Rollover     bool    `json:"rollover,omitempty"`
+}
+
+// OpenProduct — открытие сберегательного счета или вклада с переводом Amount со счета FundingID
+type OpenProduct struct {
+	UserID    string
+	Product   Product
+	Number    string
+	FundingID int64
+	Amount    float64
+	Rollover  bool
+	MaturesOn *time.Time
+}
+
+// DepositSettlement — выплата процентов по окончании срока вклада или при досрочном закрытии.
+// Если Rollover, вклад продлевается до NextMaturity по ставке NextRate, иначе остаток переводится на счет выплаты
+// и вклад закрывается.
+type DepositSettlement struct {
+	AccountID    int64
+	MaturesOn    time.Time
+	Penalty      float64
+	Rollover     bool
+	NextMaturity time.Time
+	NextRate     float64
+	// PayoutID — счет выплаты вместо сохраненного во вкладе, nil — сохраненный
+	PayoutID    *int64
+	Description string
+}
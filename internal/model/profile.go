@@ -0,0 +1,97 @@
+package model
+
+import "time"
+
+// Статусы проверки личности (KYC)
+const (
+	KYCNotStarted = "not_started"
+	KYCPending    = "pending"
+	KYCVerified   = "verified"
+	KYCRejected   = "rejected"
+)
+
+// Типы документов, загружаемых для проверки личности
+const (
+	DocumentPassportMain         = "passport_main"
+	DocumentPassportRegistration = "passport_registration"
+	DocumentINNCertificate       = "inn_certificate"
+	DocumentSelfie               = "selfie"
+)
+
+// ValidDocumentType — известен ли тип документа
+func ValidDocumentType(docType string) bool {
+	switch docType {
+	case DocumentPassportMain, DocumentPassportRegistration, DocumentINNCertificate, DocumentSelfie:
+		return true
+	}
+	return false
+}
+
+// PersonalData — персональные данные клиента; в базе хранятся только в зашифрованном виде.
+// При обновлении пустое поле означает «не менять».
+type PersonalData struct {
+	DateOfBirth string `json:"date_of_birth,omitempty"` // YYYY-MM-DD
+	Phone       string `json:"phone,omitempty"`         // +7XXXXXXXXXX
+	Passport    string `json:"passport,omitempty"`      // серия и номер, 10 цифр
+	INN         string `json:"inn,omitempty"`
+	Address     string `json:"address,omitempty"`
+}
+
+// Profile — анкета клиента и состояние проверки личности
+type Profile struct {
+	UserID string `json:"user_id"`
+	PersonalData
+	KYCStatus string `json:"kyc_status"`
+	// KYCComment — причина отказа, видна клиенту
+	KYCComment     string     `json:"kyc_comment,omitempty"`
+	KYCSubmittedAt *time.Time `json:"kyc_submitted_at,omitempty"`
+	KYCReviewedAt  *time.Time `json:"kyc_reviewed_at,omitempty"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	EncryptedData []byte `json:"-"`
+	KeyVersion    int    `json:"-"` // версия ключа keystore
+	// отпечатки HMAC для проверки уникальности и поиска без расшифровки
+	PhoneFingerprint    []byte `json:"-"`
+	PassportFingerprint []byte `json:"-"`
+	INNFingerprint      []byte `json:"-"`
+}
+
+// Complete — заполнены ли все данные, нужные для проверки личности
+func (p *Profile) Complete() bool {
+	d := p.PersonalData
+	return d.DateOfBirth != "" && d.Phone != "" && d.Passport != "" && d.INN != "" && d.Address != ""
+}
+
+// Mask скрывает документы для сотрудников, которым не нужны полные реквизиты
+func (p *Profile) Mask() {
+	p.Passport = maskTail(p.Passport)
+	p.INN = maskTail(p.INN)
+}
+
+// KYCDocument — загруженный документ; содержимое хранится зашифрованным
+type KYCDocument struct {
+	ID          int64     `json:"id"`
+	UserID      string    `json:"user_id"`
+	Type        string    `json:"type"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int       `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	Content          []byte `json:"-"`
+	EncryptedContent []byte `json:"-"`
+	KeyVersion       int    `json:"-"`
+}
+
+// maskTail оставляет видимыми только последние 4 символа
+func maskTail(s string) string {
+	if len(s) <= 4 {
+		return s
+	}
+	masked := make([]byte, len(s))
+	for i := range masked[:len(s)-4] {
+		masked[i] = '*'
+	}
+	copy(masked[len(s)-4:], s[len(s)-4:])
+	return string(masked)
+}
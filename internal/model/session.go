@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// Session — вход пользователя с конкретного устройства. Сессия живет, пока
+// обновляется refresh-токен, и отзывается при выходе или обнаружении повторного использования токена.
+type Session struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// RefreshToken — одноразовый токен обновления, хранится только в виде SHA-256
+type RefreshToken struct {
+	SessionID string
+	ExpiresAt time.Time
+	UsedAt    *time.Time // не nil — токен уже обменивался, повторное предъявление означает кражу
+}
+
+// TokenPair — выданные при входе или обновлении токены
+type TokenPair struct {
+	AccessToken      string    `json:"token"`
+	AccessExpiresAt  time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	SessionID        string    `json:"session_id"`
+	// MFAToken — частичный токен, выдается вместо пары, пока не подтвержден второй фактор
+	MFAToken    string `json:"mfa_token,omitempty"`
+	MFARequired bool   `json:"mfa_required,omitempty"`
+}
+
+// ClientInfo — сведения о клиенте, с которого выполняется вход
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
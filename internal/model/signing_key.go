@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// Алгоритмы подписи access-токенов
+const (
+	SigningAlgEdDSA = "EdDSA"
+	SigningAlgRS256 = "RS256"
+)
+
+// SigningKey — ключ подписи JWT. Закрытый ключ зашифрован keystore, открытый публикуется в JWKS.
+// Ключ подписывает новые токены, пока не выпущен следующий, и остается в JWKS до ExpiresAt,
+// чтобы выданные им токены проверялись до своего истечения.
+type SigningKey struct {
+	KID        string     `json:"kid"`
+	Algorithm  string     `json:"alg"`
+	PrivateKey []byte     `json:"-"`
+	PublicKey  []byte     `json:"-"` // PKIX DER
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// JWK — открытый ключ в формате RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	KID string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv,omitempty"` // OKP
+	X   string `json:"x,omitempty"`   // OKP
+	N   string `json:"n,omitempty"`   // RSA
+	E   string `json:"e,omitempty"`   // RSA
+}
+
+// JWKSet — содержимое /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
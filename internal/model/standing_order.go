@@ -0,0 +1,74 @@
+package model
+
+import "time"
+
+// Периодичность регулярного перевода
+const (
+	// FrequencyWeekly — каждую неделю в день Weekday
+	FrequencyWeekly = "weekly"
+	// FrequencyMonthly — каждый месяц в день DayOfMonth; в коротких месяцах — в последний день
+	FrequencyMonthly = "monthly"
+	// FrequencyEndOfMonth — в последний день каждого месяца
+	FrequencyEndOfMonth = "end_of_month"
+)
+
+// Перенос платежа, выпавшего на выходной или праздник
+const (
+	BusinessDayNone      = "none"
+	BusinessDayFollowing = "following"
+	BusinessDayPreceding = "preceding"
+)
+
+// Статусы регулярного перевода
+const (
+	StandingOrderActive    = "active"
+	StandingOrderPaused    = "paused"
+	StandingOrderCancelled = "cancelled"
+	StandingOrderCompleted = "completed"
+)
+
+// Результаты исполнения регулярного перевода
+const (
+	ExecutionSuccess  = "success"
+	ExecutionRetrying = "retrying"
+	ExecutionFailed   = "failed"
+)
+
+// StandingOrder — регулярный перевод сохраненному получателю по расписанию.
+// Заканчивается после EndDate или MaxOccurrences успешных платежей, если они заданы.
+type StandingOrder struct {
+	ID             int64      `json:"id"`
+	UserID         string     `json:"-"`
+	FromAccountID  int64      `json:"from_account_id"`
+	PayeeID        int64      `json:"payee_id"`
+	Amount         float64    `json:"amount"`
+	Description    string     `json:"description,omitempty"`
+	Frequency      string     `json:"frequency"`
+	DayOfMonth     int        `json:"day_of_month,omitempty"`
+	Weekday        *int       `json:"weekday,omitempty"` // 0 — воскресенье, как в time.Weekday
+	BusinessDay    string     `json:"business_day"`
+	StartDate      time.Time  `json:"start_date"`
+	EndDate        *time.Time `json:"end_date,omitempty"`
+	MaxOccurrences int        `json:"max_occurrences,omitempty"`
+	Occurrences    int        `json:"occurrences"`
+	Status         string     `json:"status"`
+	ScheduledFor   *time.Time `json:"scheduled_for,omitempty"`
+	NextRunAt      *time.Time `json:"next_run_at,omitempty"`
+	RetryCount     int        `json:"retry_count"`
+	// LockedUntil — резерв исполнителя, под которым поручение взято в работу
+	LockedUntil *time.Time `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// StandingOrderExecution — попытка исполнения регулярного перевода
+type StandingOrderExecution struct {
+	ID           int64     `json:"id"`
+	OrderID      int64     `json:"order_id"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+	Attempt      int       `json:"attempt"`
+	Status       string    `json:"status"`
+	Amount       float64   `json:"amount"`
+	Error        string    `json:"error,omitempty"`
+	ExecutedAt   time.Time `json:"executed_at"`
+}
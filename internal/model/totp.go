@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// TOTP — второй фактор пользователя. Секрет хранится зашифрованным ключами keystore.
+type TOTP struct {
+	UserID          string     `json:"user_id"`
+	EncryptedSecret []byte     `json:"-"`
+	Enabled         bool       `json:"enabled"`
+	LastCounter     int64      `json:"-"` // последний принятый интервал, защита от повтора кода
+	EnrolledAt      *time.Time `json:"enrolled_at,omitempty"`
+}
+
+// TOTPEnrollment — данные для добавления секрета в приложение-аутентификатор
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// StepUp — повторное подтверждение личности для чувствительных операций.
+// При включенном 2FA требуется код TOTP или код восстановления, иначе пароль.
+type StepUp struct {
+	Password     string `json:"password"`
+	OTPCode      string `json:"otp_code"`
+	RecoveryCode string `json:"recovery_code"`
+}
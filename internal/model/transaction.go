@@ -2,15 +2,73 @@ package model
 
 import "time"
 
+// Типы операций
+const (
+	TransactionDeposit    = "deposit"
+	TransactionWithdraw   = "withdraw"
+	TransactionTransfer   = "transfer"
+	TransactionAdjustment = "adjustment"
+	// TransactionReversal — компенсирующая проводка, исходная операция не удаляется и не меняется
+	TransactionReversal = "reversal"
+	// TransactionOverdraftInterest — ежемесячное списание процентов за овердрафт
+	TransactionOverdraftInterest = "overdraft_interest"
+	// TransactionInterest — выплата процентов по сберегательному счету или вкладу
+	TransactionInterest = "interest"
+)
+
+// TransactionSuccess — статус проведенной операции
+const TransactionSuccess = "success"
+
 // Transaction — история операций
 type Transaction struct {
-	ID              int64     `json:"id"`
-	AccountID       int64     `json:"account_id"`
-	Amount          float64   `json:"amount"`
-	Currency        string    `json:"currency"`
-	Type            string    `json:"type"`   // deposit, withdraw, transfer, payment и т.д.
-	Status          string    `json:"status"` // success, pending, failed
-	Description     string    `json:"description"`
-	RelatedEntityID *int64    `json:"related_entity_id,omitempty"` // напр: ID карты, кредита, если нужно
-	CreatedAt       time.Time `json:"created_at"`
+	ID              int64   `json:"id"`
+	AccountID       int64   `json:"account_id"`
+	Amount          float64 `json:"amount"`
+	Currency        string  `json:"currency"`
+	Type            string  `json:"type"`   // deposit, withdraw, transfer, payment и т.д.
+	Status          string  `json:"status"` // success, pending, failed
+	Description     string  `json:"description"`
+	RelatedEntityID *int64  `json:"related_entity_id,omitempty"` // напр: ID карты, кредита, если нужно
+	// FXRate — курс перевода между валютами, записывается на обе ноги перевода
+	FXRate *float64 `json:"fx_rate,omitempty"`
+	// CounterpartID — вторая нога перевода; счет получателя клиенту отправителя не раскрывается
+	CounterpartID *int64 `json:"-"`
+	// ReversalOf — исходная операция, если это сторно
+	ReversalOf *int64 `json:"reversal_of,omitempty"`
+	// ReversedAmount — сколько суммы операции уже сторнировано, всегда неотрицательно
+	ReversedAmount float64   `json:"reversed_amount,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ReversibleAmount — сколько суммы операции еще можно сторнировать
+func (t *Transaction) ReversibleAmount() float64 {
+	amount := t.Amount
+	if amount < 0 {
+		amount = -amount
+	}
+	return RoundAmount(amount-t.ReversedAmount, t.Currency)
+}
+
+// ReversalLeg — компенсирующая проводка по одной ноге исходной операции
+type ReversalLeg struct {
+	TransactionID int64   `json:"transaction_id"`
+	AccountID     int64   `json:"account_id"`
+	Amount        float64 `json:"amount"` // изменение баланса, по знаку противоположное исходной проводке
+	Currency      string  `json:"currency"`
+}
+
+// Transfer — проводка перевода: списание в валюте счета отправителя и зачисление в валюте счета получателя
+type Transfer struct {
+	FromAccountID  int64   `json:"from_account_id"`
+	ToAccountID    int64   `json:"to_account_id,omitempty"`
+	DebitAmount    float64 `json:"debited_amount"`
+	DebitCurrency  string  `json:"debited_currency"`
+	CreditAmount   float64 `json:"credited_amount"`
+	CreditCurrency string  `json:"credited_currency"`
+	// Rate — единиц валюты получателя за единицу валюты отправителя, nil — валюты совпадают
+	Rate        *float64 `json:"rate,omitempty"`
+	QuoteID     string   `json:"quote_id,omitempty"`
+	Description string   `json:"description,omitempty"`
+	// IdempotencyKey — ключ повторяемой операции, например платежа поручения за период; второй перевод с тем же ключом не проводится
+	IdempotencyKey string `json:"-"`
 }
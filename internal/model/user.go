@@ -2,11 +2,45 @@ package model
 
 import "time"
 
+// Статусы пользователя
+const (
+	UserPendingVerification = "pending_verification"
+	UserActive              = "active"
+	// UserErased — учетная запись удалена по запросу клиента, персональные данные обезличены
+	UserErased = "erased"
+)
+
+// Роли пользователей. Все роли, кроме customer, относятся к сотрудникам банка.
+const (
+	RoleCustomer   = "customer"
+	RoleSupport    = "support"
+	RoleBackOffice = "back_office"
+	RoleAdmin      = "admin"
+)
+
+// ValidRole — известна ли роль
+func ValidRole(role string) bool {
+	switch role {
+	case RoleCustomer, RoleSupport, RoleBackOffice, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// IsStaffRole — роль сотрудника банка
+func IsStaffRole(role string) bool {
+	return role == RoleSupport || role == RoleBackOffice || role == RoleAdmin
+}
+
 // User — данные пользователя
 type User struct {
-	UUID         string    `json:"uuid"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"` // bcrypt hash
-	FullName     string    `json:"full_name"`
-	CreatedAt    time.Time `json:"created_at"`
+	UUID         string     `json:"uuid"`
+	Email        string     `json:"email"`
+	Username     string     `json:"username"`
+	PasswordHash string     `json:"-"` // bcrypt hash
+	FullName     string     `json:"full_name"`
+	Status       string     `json:"status"`
+	Role         string     `json:"role"`
+	VerifiedAt   *time.Time `json:"verified_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
 }
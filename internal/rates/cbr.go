@@ -0,0 +1,103 @@
+package rates
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCBRURL — ежедневные официальные курсы ЦБ РФ
+const DefaultCBRURL = "https://www.cbr.ru/scripts/XML_daily.asp"
+
+// CBR получает официальные курсы ЦБ РФ на текущий день
+type CBR struct {
+	url    string
+	client *http.Client
+}
+
+func NewCBR(url string) *CBR {
+	if url == "" {
+		url = DefaultCBRURL
+	}
+	return &CBR{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+type cbrRates struct {
+	Date    string `xml:"Date,attr"`
+	Valutes []struct {
+		CharCode string `xml:"CharCode"`
+		Nominal  string `xml:"Nominal"`
+		Value    string `xml:"Value"`
+	} `xml:"Valute"`
+}
+
+func (c *CBR) Rates(ctx context.Context) (*Table, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cbr rates: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cbr rates: unexpected status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("cbr rates: %w", err)
+	}
+	return parseCBR(body)
+}
+
+func parseCBR(body []byte) (*Table, error) {
+	var doc cbrRates
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = asciiOnly
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("cbr rates: %w", err)
+	}
+	date, err := time.Parse("02.01.2006", doc.Date)
+	if err != nil {
+		return nil, fmt.Errorf("cbr rates date: %w", err)
+	}
+	table := &Table{Date: date, Rates: make(map[string]float64, len(doc.Valutes))}
+	for _, v := range doc.Valutes {
+		nominal, err := strconv.ParseFloat(strings.TrimSpace(v.Nominal), 64)
+		if err != nil || nominal <= 0 {
+			return nil, fmt.Errorf("cbr rates: nominal of %s: %q", v.CharCode, v.Nominal)
+		}
+		// ЦБ публикует значения с запятой в качестве десятичного разделителя
+		value, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(v.Value), ",", "."), 64)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("cbr rates: value of %s: %q", v.CharCode, v.Value)
+		}
+		table.Rates[v.CharCode] = value / nominal
+	}
+	return table, nil
+}
+
+// asciiOnly читает документ в windows-1251: нужны только коды валют и числа,
+// поэтому кириллические названия валют заменяются символом '?'
+func asciiOnly(charset string, input io.Reader) (io.Reader, error) {
+	if !strings.EqualFold(charset, "windows-1251") {
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	for i, b := range data {
+		if b >= 0x80 {
+			data[i] = '?'
+		}
+	}
+	return bytes.NewReader(data), nil
+}
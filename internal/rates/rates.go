@@ -0,0 +1,83 @@
+package rates
+
+import (
+	"BankingApp/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrRateUnavailable = errors.New("rate unavailable")
+
+// Table — курсы валют к базовой валюте на дату публикации: сколько рублей стоит единица валюты
+type Table struct {
+	Date  time.Time
+	Rates map[string]float64
+}
+
+// Mid — биржевой курс без спреда: единиц валюты to за единицу валюты from
+func (t *Table) Mid(from, to string) (float64, error) {
+	fromRate, err := t.rate(from)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := t.rate(to)
+	if err != nil {
+		return 0, err
+	}
+	return fromRate / toRate, nil
+}
+
+func (t *Table) rate(currency string) (float64, error) {
+	if currency == model.BaseCurrency {
+		return 1, nil
+	}
+	rate, ok := t.Rates[currency]
+	if !ok || rate <= 0 {
+		return 0, fmt.Errorf("%w: %s", ErrRateUnavailable, currency)
+	}
+	return rate, nil
+}
+
+// Provider — источник курсов валют
+type Provider interface {
+	Rates(ctx context.Context) (*Table, error)
+}
+
+// Cached запрашивает курсы у источника не чаще раза в refresh. Если источник недоступен,
+// отдаются последние полученные курсы, пока они не старше maxAge.
+type Cached struct {
+	provider Provider
+	refresh  time.Duration
+	maxAge   time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	table     *Table
+	fetchedAt time.Time
+}
+
+func NewCached(provider Provider, refresh, maxAge time.Duration, now func() time.Time) *Cached {
+	return &Cached{provider: provider, refresh: refresh, maxAge: maxAge, now: now}
+}
+
+func (c *Cached) Rates(ctx context.Context) (*Table, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if c.table != nil && now.Sub(c.fetchedAt) < c.refresh {
+		return c.table, nil
+	}
+	table, err := c.provider.Rates(ctx)
+	if err != nil {
+		if c.table != nil && now.Sub(c.fetchedAt) < c.maxAge {
+			return c.table, nil
+		}
+		return nil, err
+	}
+	c.table, c.fetchedAt = table, now
+	return table, nil
+}
@@ -0,0 +1,40 @@
+package rates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Static читает курсы из JSON-файла вида {"date": "2024-01-31", "rates": {"USD": 89.69}}.
+// Используется в тестовых окружениях, где нет доступа к ЦБ.
+type Static struct {
+	path string
+}
+
+func NewStatic(path string) *Static {
+	return &Static{path: path}
+}
+
+type staticFile struct {
+	Date  string             `json:"date"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+func (s *Static) Rates(ctx context.Context) (*Table, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("read rates file: %w", err)
+	}
+	var file staticFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse rates file: %w", err)
+	}
+	date, err := time.Parse(time.DateOnly, file.Date)
+	if err != nil {
+		return nil, fmt.Errorf("parse rates date: %w", err)
+	}
+	return &Table{Date: date, Rates: file.Rates}, nil
+}
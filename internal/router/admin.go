@@ -0,0 +1,356 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"BankingApp/internal/model"
+	adminService "BankingApp/internal/service/admin"
+	bankingService "BankingApp/internal/service/banking"
+	profileService "BankingApp/internal/service/profile"
+	"BankingApp/pkg/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+// --- STAFF ROUTES (JWT Auth + staff role required) ---
+
+func (r *Router) InitAdminRoutes() {
+	authMiddleware := r.authMiddleware()
+	adminRouter := r.muxRouter.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(authMiddleware)
+	adminRouter.Use(middleware.RequireRoles(model.RoleSupport, model.RoleBackOffice, model.RoleAdmin))
+
+	// просмотр доступен всем сотрудникам
+	adminRouter.HandleFunc("/users/{id}", r.adminGetUserHandler).Methods("GET")
+	adminRouter.HandleFunc("/users/{id}/accounts", r.adminGetUserAccountsHandler).Methods("GET")
+	adminRouter.HandleFunc("/users/{id}/credits", r.adminGetUserCreditsHandler).Methods("GET")
+	adminRouter.HandleFunc("/users/{id}/profile", r.adminGetProfileHandler).Methods("GET")
+	adminRouter.HandleFunc("/users/{id}/documents", r.adminGetDocumentsHandler).Methods("GET")
+	adminRouter.HandleFunc("/users/{id}/audit", r.adminAuditHandler(model.AdminTargetUser)).Methods("GET")
+	adminRouter.HandleFunc("/accounts/{id:[0-9]+}", r.adminGetAccountHandler).Methods("GET")
+	adminRouter.HandleFunc("/accounts/{id:[0-9]+}/cards", r.adminGetAccountCardsHandler).Methods("GET")
+	adminRouter.HandleFunc("/accounts/{id:[0-9]+}/audit", r.adminAuditHandler(model.AdminTargetAccount)).Methods("GET")
+	adminRouter.HandleFunc("/transactions/{id:[0-9]+}/audit", r.adminAuditHandler(model.AdminTargetTransaction)).Methods("GET")
+	adminRouter.HandleFunc("/disputes", r.adminListDisputesHandler).Methods("GET")
+	adminRouter.HandleFunc("/disputes/{id:[0-9]+}", r.adminGetDisputeHandler).Methods("GET")
+	adminRouter.HandleFunc("/disputes/{id:[0-9]+}/audit", r.adminAuditHandler(model.AdminTargetDispute)).Methods("GET")
+
+	// изменения — только бэк-офис и администраторы
+	backOffice := adminRouter.NewRoute().Subrouter()
+	backOffice.Use(middleware.RequireRoles(model.RoleBackOffice, model.RoleAdmin))
+	backOffice.HandleFunc("/accounts/{id:[0-9]+}/freeze", r.adminFreezeAccountHandler(true)).Methods("POST")
+	backOffice.HandleFunc("/accounts/{id:[0-9]+}/unfreeze", r.adminFreezeAccountHandler(false)).Methods("POST")
+	backOffice.HandleFunc("/accounts/{id:[0-9]+}/adjust", r.adminAdjustBalanceHandler).Methods("POST")
+	backOffice.HandleFunc("/accounts/{id:[0-9]+}/overdraft", r.adminSetOverdraftHandler).Methods("POST")
+	backOffice.HandleFunc("/documents/{id:[0-9]+}", r.adminDownloadDocumentHandler).Methods("GET")
+	backOffice.HandleFunc("/users/{id}/kyc", r.adminReviewKYCHandler).Methods("POST")
+	backOffice.HandleFunc("/transactions/{id:[0-9]+}/reverse", r.adminReverseTransactionHandler).Methods("POST")
+	backOffice.HandleFunc("/disputes/attachments/{id:[0-9]+}", r.adminDownloadDisputeAttachmentHandler).Methods("GET")
+	backOffice.HandleFunc("/disputes/{id:[0-9]+}/review", r.adminReviewDisputeHandler).Methods("POST")
+	backOffice.HandleFunc("/disputes/{id:[0-9]+}/resolve", r.adminResolveDisputeHandler).Methods("POST")
+
+	adminOnly := adminRouter.NewRoute().Subrouter()
+	adminOnly.Use(middleware.RequireRoles(model.RoleAdmin))
+	adminOnly.HandleFunc("/users/{id}/role", r.adminSetRoleHandler).Methods("POST")
+}
+
+// --------- API struct TYPES -----------
+
+type adjustBalanceRequest struct {
+	Amount float64 `json:"amount"`
+	model.AdminReason
+}
+
+// setOverdraftRequest — лимит овердрафта в валюте счета и годовая ставка долей (0.25 — 25%)
+type setOverdraftRequest struct {
+	Limit float64 `json:"limit"`
+	Rate  float64 `json:"rate"`
+	model.AdminReason
+}
+
+type setRoleRequest struct {
+	Role string `json:"role"`
+	model.AdminReason
+}
+
+// reviewKYCRequest — решение по анкете; при отказе comment показывается клиенту
+type reviewKYCRequest struct {
+	Approve bool `json:"approve"`
+	model.AdminReason
+}
+
+// ----------- HANDLERS ------------
+
+func (r *Router) adminGetUserHandler(w http.ResponseWriter, req *http.Request) {
+	user, err := r.userService.GetByID(req.Context(), mux.Vars(req)["id"])
+	if err != nil {
+		r.logger.WithError(err).Error("failed to get user")
+		http.Error(w, "could not get user", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+func (r *Router) adminGetUserAccountsHandler(w http.ResponseWriter, req *http.Request) {
+	accounts, err := r.bankingService.GetAccountsByUser(req.Context(), mux.Vars(req)["id"])
+	if err != nil {
+		r.logger.WithError(err).Error("failed to get accounts")
+		http.Error(w, "could not get accounts", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(accounts)
+}
+
+func (r *Router) adminGetUserCreditsHandler(w http.ResponseWriter, req *http.Request) {
+	credits, err := r.creditService.GetCreditsByUser(req.Context(), mux.Vars(req)["id"])
+	if err != nil {
+		r.logger.WithError(err).Error("failed to get credits")
+		http.Error(w, "could not get credits", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(credits)
+}
+
+func (r *Router) adminGetAccountHandler(w http.ResponseWriter, req *http.Request) {
+	accountID, err := middleware.ValidateAccount(req)
+	if err != nil {
+		http.Error(w, "Invalid account", http.StatusBadRequest)
+		return
+	}
+	account, err := r.bankingService.GetAccountByID(req.Context(), accountID)
+	if err != nil {
+		r.logger.WithError(err).Warn("failed to get account")
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(account)
+}
+
+func (r *Router) adminGetAccountCardsHandler(w http.ResponseWriter, req *http.Request) {
+	accountID, err := middleware.ValidateAccount(req)
+	if err != nil {
+		http.Error(w, "Invalid account", http.StatusBadRequest)
+		return
+	}
+	cards, err := r.cardService.GetCardsByAccount(req.Context(), accountID)
+	if err != nil {
+		r.logger.WithError(err).Error("failed to get cards for account")
+		http.Error(w, "could not get cards", http.StatusInternalServerError)
+		return
+	}
+	// сотрудникам полные реквизиты карт не показываются
+	for _, card := range cards {
+		card.Mask()
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(cards)
+}
+
+func (r *Router) adminAuditHandler(targetType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		actions, err := r.adminService.GetAuditLog(req.Context(), targetType, mux.Vars(req)["id"])
+		if err != nil {
+			r.logger.WithError(err).Error("failed to get audit log")
+			http.Error(w, "could not get audit log", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(actions)
+	}
+}
+
+func (r *Router) adminFreezeAccountHandler(freeze bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		actorID, accountID, ok := r.adminAccountTarget(w, req)
+		if !ok {
+			return
+		}
+		var reqBody model.AdminReason
+		if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		var err error
+		if freeze {
+			err = r.adminService.FreezeAccount(req.Context(), actorID, accountID, reqBody)
+		} else {
+			err = r.adminService.UnfreezeAccount(req.Context(), actorID, accountID, reqBody)
+		}
+		if err != nil {
+			r.writeAdminError(w, err, "failed to change account freeze state")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]bool{"frozen": freeze})
+	}
+}
+
+func (r *Router) adminAdjustBalanceHandler(w http.ResponseWriter, req *http.Request) {
+	actorID, accountID, ok := r.adminAccountTarget(w, req)
+	if !ok {
+		return
+	}
+	var reqBody adjustBalanceRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := r.adminService.AdjustBalance(req.Context(), actorID, accountID, reqBody.Amount, reqBody.AdminReason); err != nil {
+		r.writeAdminError(w, err, "failed to adjust balance")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+func (r *Router) adminSetOverdraftHandler(w http.ResponseWriter, req *http.Request) {
+	actorID, accountID, ok := r.adminAccountTarget(w, req)
+	if !ok {
+		return
+	}
+	var reqBody setOverdraftRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := r.adminService.SetOverdraft(req.Context(), actorID, accountID, reqBody.Limit, reqBody.Rate, reqBody.AdminReason); err != nil {
+		r.writeAdminError(w, err, "failed to set overdraft")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+func (r *Router) adminSetRoleHandler(w http.ResponseWriter, req *http.Request) {
+	actorID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	var reqBody setRoleRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := r.adminService.SetUserRole(req.Context(), actorID, mux.Vars(req)["id"], reqBody.Role, reqBody.AdminReason); err != nil {
+		r.writeAdminError(w, err, "failed to set user role")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"role": reqBody.Role})
+}
+
+// adminGetProfileHandler показывает анкету; паспорт и ИНН целиком видят только бэк-офис и администраторы
+func (r *Router) adminGetProfileHandler(w http.ResponseWriter, req *http.Request) {
+	profile, err := r.profileService.GetProfile(req.Context(), mux.Vars(req)["id"])
+	if err != nil {
+		r.logger.WithError(err).Error("failed to get profile")
+		http.Error(w, "could not get profile", http.StatusInternalServerError)
+		return
+	}
+	if role, _ := middleware.UserRole(req); role == model.RoleSupport {
+		profile.Mask()
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(profile)
+}
+
+func (r *Router) adminGetDocumentsHandler(w http.ResponseWriter, req *http.Request) {
+	docs, err := r.profileService.GetDocuments(req.Context(), mux.Vars(req)["id"])
+	if err != nil {
+		r.logger.WithError(err).Error("failed to get documents")
+		http.Error(w, "could not get documents", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(docs)
+}
+
+func (r *Router) adminDownloadDocumentHandler(w http.ResponseWriter, req *http.Request) {
+	docID, err := strconv.ParseInt(mux.Vars(req)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid document", http.StatusBadRequest)
+		return
+	}
+	doc, err := r.profileService.GetDocument(req.Context(), docID)
+	if errors.Is(err, profileService.ErrDocumentNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		r.logger.WithError(err).Error("failed to get document")
+		http.Error(w, "could not get document", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", doc.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": doc.FileName}))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	w.Write(doc.Content)
+}
+
+func (r *Router) adminReviewKYCHandler(w http.ResponseWriter, req *http.Request) {
+	actorID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	var reqBody reviewKYCRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	userID := mux.Vars(req)["id"]
+	if err := r.adminService.ReviewKYC(req.Context(), actorID, userID, reqBody.Approve, reqBody.AdminReason); err != nil {
+		r.writeAdminError(w, err, "failed to review kyc")
+		return
+	}
+	status := model.KYCRejected
+	if reqBody.Approve {
+		status = model.KYCVerified
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"kyc_status": status})
+}
+
+func (r *Router) adminAccountTarget(w http.ResponseWriter, req *http.Request) (string, int64, bool) {
+	actorID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return "", 0, false
+	}
+	accountID, err := strconv.ParseInt(mux.Vars(req)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid account", http.StatusBadRequest)
+		return "", 0, false
+	}
+	return actorID, accountID, true
+}
+
+// writeAdminError отличает ошибки валидации запроса от ошибок выполнения
+func (r *Router) writeAdminError(w http.ResponseWriter, err error, msg string) {
+	r.logger.WithError(err).Warn(msg)
+	switch {
+	case errors.Is(err, adminService.ErrInvalidReasonCode), errors.Is(err, adminService.ErrCommentRequired),
+		errors.Is(err, adminService.ErrInvalidRole), errors.Is(err, adminService.ErrInvalidAmount),
+		errors.Is(err, adminService.ErrSelfRoleChange), errors.Is(err, adminService.ErrSelfReview),
+		errors.Is(err, adminService.ErrSelfAccount),
+		errors.Is(err, bankingService.ErrInvalidOverdraft), errors.Is(err, bankingService.ErrOverdraftNotAllowed):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, msg+": "+err.Error(), http.StatusUnprocessableEntity)
+	}
+}
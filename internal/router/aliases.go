@@ -0,0 +1,121 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"BankingApp/internal/model"
+	aliasService "BankingApp/internal/service/aliases"
+	profileService "BankingApp/internal/service/profile"
+	"BankingApp/pkg/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+type registerAliasRequest struct {
+	Type      string `json:"type"`
+	AccountID int64  `json:"account_id"`
+}
+
+func (r *Router) getAliasesHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	aliases, err := r.aliasService.GetAliases(req.Context(), userID)
+	if err != nil {
+		r.writeAliasError(w, err, "could not get aliases")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(aliases)
+}
+
+func (r *Router) registerAliasHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	var reqBody registerAliasRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	alias, err := r.aliasService.Register(req.Context(), userID, reqBody.Type, reqBody.AccountID)
+	if err != nil {
+		r.writeAliasError(w, err, "could not register alias")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(alias)
+}
+
+func (r *Router) deleteAliasHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	if err := r.aliasService.Delete(req.Context(), userID, mux.Vars(req)["type"]); err != nil {
+		r.writeAliasError(w, err, "could not delete alias")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *Router) lookupAliasHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	var target model.AliasTarget
+	if err := json.NewDecoder(req.Body).Decode(&target); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	lookup, err := r.aliasService.Lookup(req.Context(), userID, target)
+	if err != nil {
+		r.writeAliasError(w, err, "could not look up alias")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(lookup)
+}
+
+// resolveAliasTarget подставляет счет получателя по псевдониму, если он указан; при ошибке сам отвечает клиенту
+func (r *Router) resolveAliasTarget(w http.ResponseWriter, req *http.Request, userID string, target *model.AliasTarget, accountID *int64) bool {
+	if target == nil {
+		return true
+	}
+	resolved, err := r.aliasService.Resolve(req.Context(), userID, *target)
+	if err != nil {
+		r.writeAliasError(w, err, "could not resolve alias")
+		return false
+	}
+	*accountID = resolved
+	return true
+}
+
+func (r *Router) writeAliasError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, aliasService.ErrInvalidAliasType), errors.Is(err, aliasService.ErrInvalidAliasValue):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, aliasService.ErrAliasNotFound), errors.Is(err, aliasService.ErrAccountNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, aliasService.ErrAliasTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, aliasService.ErrEmailNotVerified), errors.Is(err, profileService.ErrKYCNotVerified):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, aliasService.ErrPhoneMissing):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, aliasService.ErrLookupLimit):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	default:
+		r.logger.WithError(err).Error(msg)
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
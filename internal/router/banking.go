@@ -7,7 +7,9 @@ import (
 	"net/http"
 	"strconv"
 
-	"BankingApp/internal/config"
+	"BankingApp/internal/model"
+	bankingService "BankingApp/internal/service/banking"
+	fxService "BankingApp/internal/service/fx"
 	"BankingApp/pkg/middleware"
 
 	"github.com/gorilla/mux"
@@ -16,13 +18,48 @@ import (
 // --- PROTECTED ROUTES (JWT Auth Required) ---
 
 func (r *Router) InitBankingRoutes() {
-	authMiddleware := middleware.NewAuthMiddleware(config.GetJWTSecretKey())
+	authMiddleware := r.authMiddleware()
 	bankingRouter := r.muxRouter.PathPrefix("/banking").Subrouter()
 	bankingRouter.Use(authMiddleware)
-	bankingRouter.HandleFunc("/account", r.createAccountHandler).Methods("POST")
-	bankingRouter.HandleFunc("/account/{id:[0-9]+}/deposit", r.depositHandler).Methods("POST")
-	bankingRouter.HandleFunc("/account/{id:[0-9]+}/withdraw", r.withdrawHandler).Methods("POST")
-	bankingRouter.HandleFunc("/account/transfer", r.transferHandler).Methods("POST")
+	bankingRouter.Handle("/account", withScope(model.ScopeAccountsWrite, r.createAccountHandler)).Methods("POST")
+	bankingRouter.Handle("/accounts", withScope(model.ScopeAccountsRead, r.getAccountsByUserHandler)).Methods("GET")
+	bankingRouter.Handle("/account/{id:[0-9]+}", withScope(model.ScopeAccountsRead, r.getAccountByIDHandler)).Methods("GET")
+	bankingRouter.Handle("/account/{id:[0-9]+}/transactions", withScope(model.ScopeAccountsRead, r.getAccountTransactionsHandler)).Methods("GET")
+	bankingRouter.Handle("/account/{id:[0-9]+}/limits", withScope(model.ScopeAccountsRead, r.getLimitsHandler)).Methods("GET")
+	bankingRouter.Handle("/account/{id:[0-9]+}/limits/{operation:withdraw|transfer}", withScope(model.ScopeAccountsWrite, r.lowerLimitsHandler)).Methods("PUT")
+	bankingRouter.Handle("/account/{id:[0-9]+}/limits/{operation:withdraw|transfer}", withScope(model.ScopeAccountsWrite, r.resetLimitsHandler)).Methods("DELETE")
+	bankingRouter.Handle("/account/{id:[0-9]+}/close", withScope(model.ScopeAccountsWrite, r.closeAccountHandler)).Methods("POST")
+	bankingRouter.Handle("/account/{id:[0-9]+}/rollover", withScope(model.ScopeAccountsWrite, r.setRolloverHandler)).Methods("PUT")
+	bankingRouter.Handle("/account/{id:[0-9]+}/freeze", withScope(model.ScopeAccountsWrite, r.accountFreezeHandler(true))).Methods("POST")
+	bankingRouter.Handle("/account/{id:[0-9]+}/unfreeze", withScope(model.ScopeAccountsWrite, r.accountFreezeHandler(false))).Methods("POST")
+	bankingRouter.Handle("/account/{id:[0-9]+}/deposit", withScope(model.ScopeTransfersWrite, r.depositHandler)).Methods("POST")
+	bankingRouter.Handle("/account/{id:[0-9]+}/withdraw", withScope(model.ScopeTransfersWrite, r.withdrawHandler)).Methods("POST")
+	bankingRouter.Handle("/account/transfer", withScope(model.ScopeTransfersWrite, r.transferHandler)).Methods("POST")
+	bankingRouter.Handle("/products", withScope(model.ScopeAccountsRead, r.getProductsHandler)).Methods("GET")
+	bankingRouter.Handle("/products/{code}/open", withScope(model.ScopeTransfersWrite, r.openProductHandler)).Methods("POST")
+	bankingRouter.Handle("/fx/quote", withScope(model.ScopeTransfersWrite, r.fxQuoteHandler)).Methods("POST")
+	bankingRouter.Handle("/aliases", withScope(model.ScopeAccountsRead, r.getAliasesHandler)).Methods("GET")
+	bankingRouter.Handle("/aliases", withScope(model.ScopeAccountsWrite, r.registerAliasHandler)).Methods("POST")
+	bankingRouter.Handle("/aliases/{type:phone|email}", withScope(model.ScopeAccountsWrite, r.deleteAliasHandler)).Methods("DELETE")
+	bankingRouter.Handle("/aliases/lookup", withScope(model.ScopeTransfersWrite, r.lookupAliasHandler)).Methods("POST")
+	bankingRouter.Handle("/payees", withScope(model.ScopeAccountsRead, r.getPayeesHandler)).Methods("GET")
+	bankingRouter.Handle("/payees", withScope(model.ScopeTransfersWrite, r.createPayeeHandler)).Methods("POST")
+	bankingRouter.Handle("/payees/{id:[0-9]+}", withScope(model.ScopeTransfersWrite, r.deletePayeeHandler)).Methods("DELETE")
+	bankingRouter.Handle("/templates", withScope(model.ScopeAccountsRead, r.getTemplatesHandler)).Methods("GET")
+	bankingRouter.Handle("/templates", withScope(model.ScopeTransfersWrite, r.createTemplateHandler)).Methods("POST")
+	bankingRouter.Handle("/templates/{id:[0-9]+}", withScope(model.ScopeTransfersWrite, r.deleteTemplateHandler)).Methods("DELETE")
+	bankingRouter.Handle("/templates/{id:[0-9]+}/execute", withScope(model.ScopeTransfersWrite, r.executeTemplateHandler)).Methods("POST")
+	bankingRouter.Handle("/standing-orders", withScope(model.ScopeAccountsRead, r.getStandingOrdersHandler)).Methods("GET")
+	bankingRouter.Handle("/standing-orders", withScope(model.ScopeTransfersWrite, r.createStandingOrderHandler)).Methods("POST")
+	bankingRouter.Handle("/standing-orders/{id:[0-9]+}/executions", withScope(model.ScopeAccountsRead, r.getStandingOrderExecutionsHandler)).Methods("GET")
+	bankingRouter.Handle("/standing-orders/{id:[0-9]+}/pause", withScope(model.ScopeTransfersWrite, r.standingOrderActionHandler(r.standingOrders.Pause))).Methods("POST")
+	bankingRouter.Handle("/standing-orders/{id:[0-9]+}/resume", withScope(model.ScopeTransfersWrite, r.standingOrderActionHandler(r.standingOrders.Resume))).Methods("POST")
+	bankingRouter.Handle("/standing-orders/{id:[0-9]+}/cancel", withScope(model.ScopeTransfersWrite, r.standingOrderActionHandler(r.standingOrders.Cancel))).Methods("POST")
+	bankingRouter.Handle("/transactions/{id:[0-9]+}/dispute", withScope(model.ScopeAccountsWrite, r.openDisputeHandler)).Methods("POST")
+	bankingRouter.Handle("/disputes", withScope(model.ScopeAccountsRead, r.getDisputesHandler)).Methods("GET")
+	bankingRouter.Handle("/disputes/{id:[0-9]+}", withScope(model.ScopeAccountsRead, r.getDisputeHandler)).Methods("GET")
+	bankingRouter.Handle("/disputes/{id:[0-9]+}/attachments", withScope(model.ScopeAccountsWrite, r.addDisputeAttachmentHandler)).Methods("POST")
+	bankingRouter.Handle("/disputes/{id:[0-9]+}/withdraw", withScope(model.ScopeAccountsWrite, r.withdrawDisputeHandler)).Methods("POST")
 }
 
 // --------- API struct TYPES -----------
@@ -36,9 +73,29 @@ type depositWithdrawRequest struct {
 }
 
 type transferRequest struct {
-	FromAccountID int64   `json:"from_account_id"`
-	ToAccountID   int64   `json:"to_account_id"`
-	Amount        float64 `json:"amount"`
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	// счета можно указать 20-значными номерами вместо внутренних ID
+	FromAccountNumber string `json:"from_account_number,omitempty"`
+	ToAccountNumber   string `json:"to_account_number,omitempty"`
+	// ToAlias — получатель по телефону или email, счет получателя в ответе не раскрывается
+	ToAlias *model.AliasTarget `json:"to_alias,omitempty"`
+	// PayeeID — сохраненный получатель из /banking/payees
+	PayeeID int64   `json:"payee_id,omitempty"`
+	Amount  float64 `json:"amount"`
+	// QuoteID — котировка из /banking/fx/quote для перевода между валютами, без нее используется текущий курс
+	QuoteID     string `json:"quote_id,omitempty"`
+	Description string `json:"description,omitempty"`
+	// подтверждение нужно для переводов от banking.step_up_threshold
+	model.StepUp
+	// hideRecipient — счет получателя найден по псевдониму и в ответе не раскрывается
+	hideRecipient bool
+}
+
+type fxQuoteRequest struct {
+	From   string  `json:"from_currency"`
+	To     string  `json:"to_currency"`
+	Amount float64 `json:"amount"`
 }
 
 // ----------- HANDLERS ------------
@@ -50,6 +107,9 @@ func (r *Router) createAccountHandler(w http.ResponseWriter, req *http.Request)
 		http.Error(w, "Invalid user", http.StatusUnauthorized)
 		return
 	}
+	if !r.requireVerified(w, req, userID) || !r.requireKYC(w, req, userID) {
+		return
+	}
 	var reqBody createAccountRequest
 	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
@@ -121,6 +181,9 @@ func (r *Router) withdrawHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 	if err := r.bankingService.Withdraw(req.Context(), accountID, reqBody.Amount); err != nil {
+		if writeLimitExceeded(w, err) {
+			return
+		}
 		r.logger.WithError(err).Error("failed to withdraw")
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -141,6 +204,20 @@ func (r *Router) transferHandler(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
+	r.performTransfer(w, req, userID, reqBody)
+}
+
+// performTransfer проверяет владельца счета и подтверждение крупной суммы и проводит перевод;
+// получатель может быть указан ID, номером счета, псевдонимом или сохраненным получателем
+func (r *Router) performTransfer(w http.ResponseWriter, req *http.Request, userID string, reqBody transferRequest) {
+	if !r.resolvePayee(w, req, userID, &reqBody) {
+		return
+	}
+	if !r.resolveAccountNumber(w, req, userID, reqBody.FromAccountNumber, &reqBody.FromAccountID) ||
+		!r.resolveAccountNumber(w, req, userID, reqBody.ToAccountNumber, &reqBody.ToAccountID) ||
+		!r.resolveAliasTarget(w, req, userID, reqBody.ToAlias, &reqBody.ToAccountID) {
+		return
+	}
 	if reqBody.FromAccountID == reqBody.ToAccountID || reqBody.Amount <= 0 {
 		http.Error(w, "Invalid transfer parameters", http.StatusBadRequest)
 		return
@@ -151,51 +228,205 @@ func (r *Router) transferHandler(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, "Could not get account", http.StatusBadRequest)
 		return
 	}
-	if err := r.bankingService.Transfer(req.Context(), reqBody.FromAccountID, reqBody.ToAccountID, reqBody.Amount); err != nil {
-		r.logger.WithError(err).Error("Transfer failed")
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if threshold := r.cfg.Banking.StepUpThreshold; threshold > 0 && reqBody.Amount >= threshold {
+		if !r.verifyStepUp(w, req, userID, reqBody.StepUp) {
+			return
+		}
+	}
+	transfer, err := r.bankingService.Transfer(req.Context(), reqBody.FromAccountID, reqBody.ToAccountID, reqBody.Amount, reqBody.QuoteID, reqBody.Description)
+	if err != nil {
+		r.writeFXError(w, err, "Transfer failed")
 		return
 	}
+	if reqBody.ToAlias != nil || reqBody.hideRecipient {
+		transfer.ToAccountID = 0
+	}
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	json.NewEncoder(w).Encode(transfer)
 }
 
-// func (r *Router)  getAccountByIDHandler(w http.ResponseWriter, req *http.Request) {
-// 	accountID, err := parseIDFromVars(r, "id")
-// 	if err != nil {
-// 		http.Error(w, "Invalid account id", http.StatusBadRequest)
-// 		return
-// 	}
-// 	account, err := r.bankingService.GetAccountByID(r.Context(), accountID)
-// 	if err != nil {
-// 		http.Error(w, err.Error(), http.StatusNotFound)
-// 		return
-// 	}
-// 	w.WriteHeader(http.StatusOK)
-// 	json.NewEncoder(w).Encode(account)
-// }
-
-// func (r *Router)  getAccountsByUserHandler(w http.ResponseWriter, req *http.Request) {
-// 	var (
-// 		userID string
-// 		ok     bool
-// 	)
-// 	if userID, ok = r.Context().Value(middleware.UserIDKey).(string); !ok {
-// 		http.Error(w, "Invalid user", http.StatusUnauthorized)
-// 		return
-// 	}
-// 	accounts, err := r.bankingService.GetAccountsByUser(r.Context(), userID)
-// 	if err != nil {
-// 		http.Error(w, err.Error(), http.StatusBadRequest)
-// 		r.logger.WithError(err).Error("getAccountsByUser failed")
-// 		return
-// 	}
-// 	w.WriteHeader(http.StatusOK)
-// 	json.NewEncoder(w).Encode(accounts)
-// }
+func (r *Router) fxQuoteHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		r.logger.WithError(err).Error("failed to authenticate user")
+		http.Error(w, "Invalid request", http.StatusUnauthorized)
+		return
+	}
+	var reqBody fxQuoteRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	quote, err := r.fxService.Quote(req.Context(), userID, reqBody.From, reqBody.To, reqBody.Amount)
+	if err != nil {
+		r.writeFXError(w, err, "could not quote exchange rate")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(quote)
+}
+
+func (r *Router) getAccountsByUserHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		r.logger.WithError(err).Error("failed to authenticate user")
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	accounts, err := r.bankingService.GetAccountsByUser(req.Context(), userID)
+	if err != nil {
+		r.logger.WithError(err).Error("getAccountsByUser failed")
+		http.Error(w, "could not get accounts", http.StatusInternalServerError)
+		return
+	}
+	if accounts == nil {
+		accounts = []*model.Account{}
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(accounts)
+}
+
+func (r *Router) getAccountByIDHandler(w http.ResponseWriter, req *http.Request) {
+	account, ok := r.ownedAccount(w, req)
+	if !ok {
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(account)
+}
+
+func (r *Router) getAccountTransactionsHandler(w http.ResponseWriter, req *http.Request) {
+	account, ok := r.ownedAccount(w, req)
+	if !ok {
+		return
+	}
+	transactions, err := r.bankingService.GetTransactions(req.Context(), account.ID)
+	if err != nil {
+		r.writeAccountError(w, err, "failed to get transactions")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transactions)
+}
+
+func (r *Router) closeAccountHandler(w http.ResponseWriter, req *http.Request) {
+	account, ok := r.ownedAccount(w, req)
+	if !ok {
+		return
+	}
+	if err := r.bankingService.CloseAccount(req.Context(), account.ID); err != nil {
+		r.writeAccountError(w, err, "failed to close account")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "closed"})
+}
+
+func (r *Router) accountFreezeHandler(freeze bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		account, ok := r.ownedAccount(w, req)
+		if !ok {
+			return
+		}
+		if err := r.bankingService.SetAccountFrozen(req.Context(), account.ID, freeze); err != nil {
+			r.writeAccountError(w, err, "failed to change account freeze state")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]bool{"owner_frozen": freeze})
+	}
+}
 
 // ----------- HELPERS --------------
 
+// ownedAccount возвращает счет из пути запроса, если он принадлежит пользователю; иначе сам отвечает клиенту
+func (r *Router) ownedAccount(w http.ResponseWriter, req *http.Request) (*model.Account, bool) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		r.logger.WithError(err).Error("failed to authenticate user")
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return nil, false
+	}
+	accountID, err := middleware.ValidateAccount(req)
+	if err != nil {
+		http.Error(w, "Invalid account", http.StatusBadRequest)
+		return nil, false
+	}
+	account, err := r.bankingService.GetAccountByID(req.Context(), accountID)
+	if err != nil || account.UserID != userID {
+		http.Error(w, "account not found", http.StatusNotFound)
+		return nil, false
+	}
+	return account, true
+}
+
+// resolveAccountNumber подставляет ID счета по номеру, если номер указан; при ошибке сам отвечает клиенту.
+// Ответ показывает, существует ли счет, поэтому поиск чужого счета учитывается в лимите поиска получателей.
+func (r *Router) resolveAccountNumber(w http.ResponseWriter, req *http.Request, userID, number string, accountID *int64) bool {
+	if number == "" {
+		return true
+	}
+	account, err := r.bankingService.GetAccountByNumber(req.Context(), number)
+	if account == nil || account.UserID != userID {
+		if err := r.aliasService.CountLookup(req.Context(), userID); err != nil {
+			r.writeAliasError(w, err, "could not count account lookup")
+			return false
+		}
+	}
+	switch {
+	case errors.Is(err, bankingService.ErrInvalidAccountNumber):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	case errors.Is(err, bankingService.ErrAccountNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return false
+	case err != nil:
+		r.logger.WithError(err).Error("failed to find account by number")
+		http.Error(w, "could not get account", http.StatusInternalServerError)
+		return false
+	}
+	*accountID = account.ID
+	return true
+}
+
+func (r *Router) writeAccountError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, bankingService.ErrAccountClosed), errors.Is(err, bankingService.ErrBalanceNotZero),
+		errors.Is(err, bankingService.ErrDepositChanged), errors.Is(err, bankingService.ErrNoPayoutAccount):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		r.logger.WithError(err).Error(msg)
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
+
+// writeFXError отвечает на ошибки конвертации и переводов; прочие ошибки перевода, как и раньше, — 400
+func (r *Router) writeFXError(w http.ResponseWriter, err error, msg string) {
+	if writeLimitExceeded(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, fxService.ErrInvalidCurrency), errors.Is(err, fxService.ErrSameCurrency),
+		errors.Is(err, fxService.ErrInvalidAmount), errors.Is(err, fxService.ErrQuoteMismatch),
+		errors.Is(err, bankingService.ErrQuoteNotApplicable):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, fxService.ErrQuoteNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, fxService.ErrQuoteExpired):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, bankingService.ErrInsufficientFunds), errors.Is(err, bankingService.ErrAccountFrozen):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, bankingService.ErrAccountClosed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, fxService.ErrRateUnavailable):
+		r.logger.WithError(err).Error(msg)
+		http.Error(w, fxService.ErrRateUnavailable.Error(), http.StatusServiceUnavailable)
+	default:
+		r.logger.WithError(err).Error(msg)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
 func parseIDFromVars(req *http.Request, varName string) (int64, error) {
 	vars := mux.Vars(req)
 	raw, ok := vars[varName]
@@ -1,10 +1,13 @@
 package router
 
 import (
-	"BankingApp/internal/config"
+	"BankingApp/internal/model"
+	bankingService "BankingApp/internal/service/banking"
+	cardService "BankingApp/internal/service/cards"
 	"BankingApp/pkg/middleware"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 )
@@ -13,23 +16,57 @@ import (
 
 func (r *Router) InitCardRoutes() {
 
-	authMiddleware := middleware.NewAuthMiddleware(config.GetJWTSecretKey())
+	authMiddleware := r.authMiddleware()
 	cardRouter := r.muxRouter.PathPrefix("/card").Subrouter()
 	cardRouter.Use(authMiddleware)
-	cardRouter.HandleFunc("/issue", r.issueCardHandler).Methods("POST")
-	cardRouter.HandleFunc("/show", r.showCardHandler).Methods("GET")
+	cardRouter.Handle("/issue", withScope(model.ScopeCardsWrite, r.issueCardHandler)).Methods("POST")
+	cardRouter.Handle("/show", withScope(model.ScopeCardsRead, r.showCardHandler)).Methods("GET")
+	cardRouter.Handle("/authorize", withScope(model.ScopeCardsWrite, r.authorizeCardHandler)).Methods("POST")
+	cardRouter.Handle("/{id:[0-9]+}/reveal", withScope(model.ScopeCardsWrite, r.revealCardHandler)).Methods("POST")
+	cardRouter.Handle("/{id:[0-9]+}/pin", withScope(model.ScopeCardsWrite, r.setPINHandler)).Methods("POST")
+	cardRouter.Handle("/{id:[0-9]+}/pin/unlock", withScope(model.ScopeCardsWrite, r.unlockPINHandler)).Methods("POST")
+	cardRouter.Handle("/{id:[0-9]+}/tokens", withScope(model.ScopeCardsWrite, r.issueTokenHandler)).Methods("POST")
+	cardRouter.Handle("/{id:[0-9]+}/tokens", withScope(model.ScopeCardsRead, r.listTokensHandler)).Methods("GET")
+	cardRouter.Handle("/{id:[0-9]+}/tokens/{token_id:[0-9]+}/status", withScope(model.ScopeCardsWrite, r.tokenStatusHandler)).Methods("POST")
 }
 
 // --------- API struct TYPES -----------
 
 type issueCardRequest struct {
-	AccountId int64 `json:"account_id"`
+	AccountId  int64 `json:"account_id"`
+	DynamicCVV bool  `json:"dynamic_cvv"`
 }
 
 type showCardsRequest struct {
 	AccountId int64 `json:"account_id"`
 }
 
+// authorizeCardRequest — реквизиты операции: PAN с PIN или CVV либо токен с идентификатором устройства/мерчанта
+type authorizeCardRequest struct {
+	PAN         string  `json:"pan,omitempty"`
+	Token       string  `json:"token,omitempty"`
+	RequestorID string  `json:"requestor_id,omitempty"`
+	PIN         string  `json:"pin,omitempty"`
+	CVV         string  `json:"cvv,omitempty"`
+	Amount      float64 `json:"amount"`
+}
+
+type setPINRequest struct {
+	model.StepUp
+	PIN string `json:"pin"`
+}
+
+type issueTokenRequest struct {
+	RequestorType string     `json:"requestor_type"`
+	RequestorID   string     `json:"requestor_id"`
+	MaxAmount     float64    `json:"max_amount"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+}
+
+type tokenStatusRequest struct {
+	Status string `json:"status"`
+}
+
 // ----------- HANDLERS ------------
 
 func (r *Router) issueCardHandler(w http.ResponseWriter, req *http.Request) {
@@ -39,6 +76,9 @@ func (r *Router) issueCardHandler(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, "Invalid user", http.StatusUnauthorized)
 		return
 	}
+	if !r.requireVerified(w, req, UUID) {
+		return
+	}
 	var reqBody issueCardRequest
 	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
 		r.logger.Println(err)
@@ -59,8 +99,12 @@ func (r *Router) issueCardHandler(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
+	if !account.IsActive {
+		http.Error(w, bankingService.ErrAccountClosed.Error(), http.StatusConflict)
+		return
+	}
 
-	card, err := r.cardService.GenerateVirtualCard(ctx, reqBody.AccountId, user.FullName)
+	card, err := r.cardService.GenerateVirtualCard(ctx, reqBody.AccountId, user.FullName, reqBody.DynamicCVV)
 	if err != nil {
 		r.logger.WithError(err).Error("failed to generate card")
 		http.Error(w, "card generation error", http.StatusInternalServerError)
@@ -91,11 +135,225 @@ func (r *Router) showCardHandler(w http.ResponseWriter, req *http.Request) {
 	}
 	cards, err := r.cardService.GetCardsByAccount(req.Context(), reqBody.AccountId)
 	if err != nil || account.UserID != UUID {
-		r.logger.WithError(err).Errorf("failed to get cards for account: %w", err)
+		r.logger.WithError(err).Error("failed to get cards for account")
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
+	// полные реквизиты отдаются только через /card/{id}/reveal с подтверждением
+	for _, card := range cards {
+		card.Mask()
+	}
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(cards)
 
 }
+
+func (r *Router) revealCardHandler(w http.ResponseWriter, req *http.Request) {
+	var reqBody model.StepUp
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	cardID, ok := r.authorizeCardStepUp(w, req, reqBody)
+	if !ok {
+		return
+	}
+	UUID, _ := middleware.ValidateUser(req)
+	card, err := r.cardService.GetCardByIDForOwner(req.Context(), cardID, UUID)
+	if err != nil {
+		r.logger.WithError(err).Error("failed to get card")
+		http.Error(w, "card not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(card)
+}
+
+func (r *Router) authorizeCardHandler(w http.ResponseWriter, req *http.Request) {
+	UUID, err := middleware.ValidateUser(req)
+	if err != nil {
+		r.logger.WithError(err).Error("failed to authenticate user")
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	var reqBody authorizeCardRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if (reqBody.PAN == "") == (reqBody.Token == "") || reqBody.Amount < 0 {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	card, err := r.cardService.Authorize(req.Context(), UUID, model.CardAuthorization(reqBody))
+	if err != nil {
+		r.writeCardAuthError(w, err)
+		return
+	}
+	card.Mask()
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(card)
+}
+
+func (r *Router) writeCardAuthError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, cardService.ErrCardNotFound), errors.Is(err, cardService.ErrTokenNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, cardService.ErrNoCardholder):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, cardService.ErrCardInactive), errors.Is(err, cardService.ErrCardExpired),
+		errors.Is(err, cardService.ErrTokenInactive), errors.Is(err, cardService.ErrPINNotSet):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, cardService.ErrWrongPIN), errors.Is(err, cardService.ErrPINLocked),
+		errors.Is(err, cardService.ErrWrongCVV), errors.Is(err, cardService.ErrTokenRequestor),
+		errors.Is(err, cardService.ErrTokenLimit):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		r.logger.WithError(err).Error("failed to authorize card")
+		http.Error(w, "could not authorize card", http.StatusInternalServerError)
+	}
+}
+
+func (r *Router) setPINHandler(w http.ResponseWriter, req *http.Request) {
+	var reqBody setPINRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	cardID, ok := r.authorizeCardStepUp(w, req, reqBody.StepUp)
+	if !ok {
+		return
+	}
+	if err := r.cardService.SetPIN(req.Context(), cardID, reqBody.PIN); err != nil {
+		r.logger.WithError(err).Error("failed to set pin")
+		if errors.Is(err, cardService.ErrInvalidPIN) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "could not set pin", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+func (r *Router) unlockPINHandler(w http.ResponseWriter, req *http.Request) {
+	var reqBody model.StepUp
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	cardID, ok := r.authorizeCardStepUp(w, req, reqBody)
+	if !ok {
+		return
+	}
+	if err := r.cardService.UnlockPIN(req.Context(), cardID); err != nil {
+		r.logger.WithError(err).Error("failed to unlock pin")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+func (r *Router) issueTokenHandler(w http.ResponseWriter, req *http.Request) {
+	var reqBody issueTokenRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	_, cardID, ok := r.authorizeCardOwner(w, req)
+	if !ok {
+		return
+	}
+	token, err := r.cardService.IssueToken(req.Context(), cardID, model.CardToken{
+		RequestorType: reqBody.RequestorType,
+		RequestorID:   reqBody.RequestorID,
+		MaxAmount:     reqBody.MaxAmount,
+		ExpiresAt:     reqBody.ExpiresAt,
+	})
+	if err != nil {
+		r.logger.WithError(err).Error("failed to issue token")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(token)
+}
+
+func (r *Router) listTokensHandler(w http.ResponseWriter, req *http.Request) {
+	_, cardID, ok := r.authorizeCardOwner(w, req)
+	if !ok {
+		return
+	}
+	tokens, err := r.cardService.GetTokens(req.Context(), cardID)
+	if err != nil {
+		r.logger.WithError(err).Error("failed to get tokens")
+		http.Error(w, "could not get tokens", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+func (r *Router) tokenStatusHandler(w http.ResponseWriter, req *http.Request) {
+	var reqBody tokenStatusRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	_, cardID, ok := r.authorizeCardOwner(w, req)
+	if !ok {
+		return
+	}
+	tokenID, err := parseIDFromVars(req, "token_id")
+	if err != nil {
+		http.Error(w, "Invalid token id", http.StatusBadRequest)
+		return
+	}
+	if err := r.cardService.SetTokenStatus(req.Context(), cardID, tokenID, reqBody.Status); err != nil {
+		r.logger.WithError(err).Error("failed to update token status")
+		if errors.Is(err, cardService.ErrTokenDeleted) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// authorizeCardOwner проверяет, что карта из пути принадлежит пользователю.
+// При ошибке пишет ответ сам и возвращает false.
+func (r *Router) authorizeCardOwner(w http.ResponseWriter, req *http.Request) (string, int64, bool) {
+	UUID, err := middleware.ValidateUser(req)
+	if err != nil {
+		r.logger.WithError(err).Error("failed to authenticate user")
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return "", 0, false
+	}
+	cardID, err := parseIDFromVars(req, "id")
+	if err != nil {
+		http.Error(w, "Invalid card id", http.StatusBadRequest)
+		return "", 0, false
+	}
+	if _, err := r.cardService.GetCardByIDForOwner(req.Context(), cardID, UUID); err != nil {
+		r.logger.WithError(err).Error("failed to get card")
+		http.Error(w, "card not found", http.StatusNotFound)
+		return "", 0, false
+	}
+	return UUID, cardID, true
+}
+
+// authorizeCardStepUp дополнительно к authorizeCardOwner требует повторного подтверждения личности
+func (r *Router) authorizeCardStepUp(w http.ResponseWriter, req *http.Request, stepUp model.StepUp) (int64, bool) {
+	UUID, cardID, ok := r.authorizeCardOwner(w, req)
+	if !ok {
+		return 0, false
+	}
+	if !r.verifyStepUp(w, req, UUID, stepUp) {
+		return 0, false
+	}
+	return cardID, true
+}
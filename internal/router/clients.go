@@ -0,0 +1,175 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"BankingApp/internal/model"
+	userService "BankingApp/internal/service/users"
+	"BankingApp/pkg/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+// InitOAuthRoutes — выдача токенов машинным клиентам (OAuth2 client credentials, RFC 6749 §4.4)
+func (r *Router) InitOAuthRoutes() {
+	r.muxRouter.HandleFunc("/oauth/token", r.clientTokenHandler).Methods("POST")
+}
+
+// --------- API struct TYPES -----------
+
+type createAPIClientRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	RateLimit int      `json:"rate_limit"` // 0 — лимит по умолчанию
+	model.StepUp
+}
+
+// ----------- HANDLERS ------------
+
+// clientTokenHandler принимает form-urlencoded запрос; учетные данные — в Basic-авторизации
+// или в полях client_id и client_secret
+func (r *Router) clientTokenHandler(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+	if req.PostForm.Get("grant_type") != "client_credentials" {
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+	clientID, clientSecret, ok := req.BasicAuth()
+	if !ok {
+		clientID, clientSecret = req.PostForm.Get("client_id"), req.PostForm.Get("client_secret")
+	}
+	scopes := strings.Fields(req.PostForm.Get("scope"))
+
+	token, err := r.userService.IssueClientToken(req.Context(), clientID, clientSecret, scopes)
+	if err != nil {
+		switch {
+		case errors.Is(err, userService.ErrInvalidClient):
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+			writeOAuthError(w, http.StatusUnauthorized, "invalid_client")
+		case errors.Is(err, userService.ErrInvalidScope):
+			writeOAuthError(w, http.StatusBadRequest, "invalid_scope")
+		default:
+			r.logger.WithError(err).Error("failed to issue client token")
+			writeOAuthError(w, http.StatusInternalServerError, "server_error")
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(token)
+}
+
+func (r *Router) createAPIClientHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	if !r.requireVerified(w, req, userID) {
+		return
+	}
+	var reqBody createAPIClientRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !r.verifyStepUp(w, req, userID, reqBody.StepUp) {
+		return
+	}
+	creds, err := r.userService.CreateAPIClient(req.Context(), userID, reqBody.Name, reqBody.Scopes, reqBody.RateLimit)
+	if err != nil {
+		r.writeAPIClientError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(creds)
+}
+
+func (r *Router) listAPIClientsHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	clients, err := r.userService.GetAPIClients(req.Context(), userID)
+	if err != nil {
+		r.logger.WithError(err).Error("failed to get api clients")
+		http.Error(w, "could not get api clients", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(clients)
+}
+
+func (r *Router) rotateAPIClientHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	var reqBody model.StepUp
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !r.verifyStepUp(w, req, userID, reqBody) {
+		return
+	}
+	creds, err := r.userService.RotateAPIClientSecret(req.Context(), userID, mux.Vars(req)["id"])
+	if err != nil {
+		r.writeAPIClientError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(creds)
+}
+
+func (r *Router) revokeAPIClientHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	if err := r.userService.RevokeAPIClient(req.Context(), userID, mux.Vars(req)["id"]); err != nil {
+		r.writeAPIClientError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+func (r *Router) writeAPIClientError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, userService.ErrInvalidClientName), errors.Is(err, userService.ErrInvalidScope),
+		errors.Is(err, userService.ErrInvalidRateLimit):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, userService.ErrAPIClientNotAllowed):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, userService.ErrAPIClientNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		r.logger.WithError(err).Error("api client operation failed")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+// withScope ограничивает маршрут для машинных клиентов областью доступа scope
+func withScope(scope string, handler http.HandlerFunc) http.Handler {
+	return middleware.RequireScope(scope)(handler)
+}
@@ -1,7 +1,6 @@
 package router
 
 import (
-	"BankingApp/internal/config"
 	"BankingApp/internal/model"
 	"BankingApp/pkg/middleware"
 	"context"
@@ -22,13 +21,13 @@ type issueCreditRequest struct {
 // ----------- HANDLERS ------------
 
 func (r *Router) InitCreditRoutes() {
-	authMiddleware := middleware.NewAuthMiddleware(config.GetJWTSecretKey())
+	authMiddleware := r.authMiddleware()
 	creditRouter := r.muxRouter.PathPrefix("/credit").Subrouter()
 	creditRouter.Use(authMiddleware)
 
-	creditRouter.HandleFunc("/issue", r.issueCreditHandler).Methods("POST")
-	creditRouter.HandleFunc("/schedule", r.showScheduleHandler).Methods("POST")
-	creditRouter.HandleFunc("/payment-graph", r.showPaymentsHandler).Methods("POST")
+	creditRouter.Handle("/issue", withScope(model.ScopeCreditsWrite, r.issueCreditHandler)).Methods("POST")
+	creditRouter.Handle("/schedule", withScope(model.ScopeCreditsRead, r.showScheduleHandler)).Methods("POST")
+	creditRouter.Handle("/payment-graph", withScope(model.ScopeCreditsRead, r.showPaymentsHandler)).Methods("POST")
 }
 
 func (r *Router) issueCreditHandler(w http.ResponseWriter, req *http.Request) {
@@ -38,6 +37,9 @@ func (r *Router) issueCreditHandler(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, "Invalid user", http.StatusUnauthorized)
 		return
 	}
+	if !r.requireVerified(w, req, userID) || !r.requireKYC(w, req, userID) {
+		return
+	}
 	var reqBody issueCreditRequest
 	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
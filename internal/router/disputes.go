@@ -0,0 +1,280 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"BankingApp/internal/model"
+	adminService "BankingApp/internal/service/admin"
+	bankingService "BankingApp/internal/service/banking"
+	disputeService "BankingApp/internal/service/disputes"
+	"BankingApp/pkg/middleware"
+)
+
+type openDisputeRequest struct {
+	Reason      string `json:"reason"`
+	Description string `json:"description"`
+}
+
+// reverseTransactionRequest — сторно бэк-офисом; amount в валюте операции, 0 — весь несторнированный остаток
+type reverseTransactionRequest struct {
+	Amount float64 `json:"amount,omitempty"`
+	model.AdminReason
+}
+
+func (r *Router) openDisputeHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	transactionID, err := parseIDFromVars(req, "id")
+	if err != nil {
+		http.Error(w, "Invalid transaction id", http.StatusBadRequest)
+		return
+	}
+	var reqBody openDisputeRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	dispute, err := r.disputeService.Open(req.Context(), userID, transactionID, reqBody.Reason, reqBody.Description)
+	if err != nil {
+		r.writeDisputeError(w, err, "could not open dispute")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dispute)
+}
+
+func (r *Router) getDisputesHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	disputes, err := r.disputeService.GetDisputes(req.Context(), userID)
+	if err != nil {
+		r.writeDisputeError(w, err, "could not get disputes")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(disputes)
+}
+
+func (r *Router) getDisputeHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	disputeID, err := parseIDFromVars(req, "id")
+	if err != nil {
+		http.Error(w, "Invalid dispute id", http.StatusBadRequest)
+		return
+	}
+	dispute, err := r.disputeService.GetDispute(req.Context(), userID, disputeID)
+	if err != nil {
+		r.writeDisputeError(w, err, "could not get dispute")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dispute)
+}
+
+// addDisputeAttachmentHandler принимает multipart/form-data с полем file
+func (r *Router) addDisputeAttachmentHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	disputeID, err := parseIDFromVars(req, "id")
+	if err != nil {
+		http.Error(w, "Invalid dispute id", http.StatusBadRequest)
+		return
+	}
+	// запас на заголовки multipart
+	req.Body = http.MaxBytesReader(w, req.Body, disputeService.MaxAttachmentSize+64<<10)
+	if err := req.ParseMultipartForm(disputeService.MaxAttachmentSize); err != nil {
+		http.Error(w, "Invalid multipart form or file too large", http.StatusBadRequest)
+		return
+	}
+	file, header, err := req.FormFile("file")
+	if err != nil {
+		http.Error(w, "file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "could not read file", http.StatusBadRequest)
+		return
+	}
+	attachment, err := r.disputeService.AddAttachment(req.Context(), userID, model.DisputeAttachment{
+		DisputeID: disputeID,
+		FileName:  header.Filename,
+		Content:   content,
+	})
+	if err != nil {
+		r.writeDisputeError(w, err, "failed to add attachment")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(attachment)
+}
+
+func (r *Router) withdrawDisputeHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	disputeID, err := parseIDFromVars(req, "id")
+	if err != nil {
+		http.Error(w, "Invalid dispute id", http.StatusBadRequest)
+		return
+	}
+	if err := r.disputeService.Withdraw(req.Context(), userID, disputeID); err != nil {
+		r.writeDisputeError(w, err, "could not withdraw dispute")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": model.DisputeWithdrawn})
+}
+
+// ----------- BACK OFFICE ------------
+
+func (r *Router) adminListDisputesHandler(w http.ResponseWriter, req *http.Request) {
+	disputes, err := r.disputeService.ListDisputes(req.Context(), req.URL.Query().Get("status"))
+	if err != nil {
+		r.writeDisputeError(w, err, "could not get disputes")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(disputes)
+}
+
+func (r *Router) adminGetDisputeHandler(w http.ResponseWriter, req *http.Request) {
+	disputeID, err := parseIDFromVars(req, "id")
+	if err != nil {
+		http.Error(w, "Invalid dispute id", http.StatusBadRequest)
+		return
+	}
+	dispute, err := r.disputeService.GetDisputeByID(req.Context(), disputeID)
+	if err != nil {
+		r.writeDisputeError(w, err, "could not get dispute")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dispute)
+}
+
+func (r *Router) adminDownloadDisputeAttachmentHandler(w http.ResponseWriter, req *http.Request) {
+	attachmentID, err := parseIDFromVars(req, "id")
+	if err != nil {
+		http.Error(w, "Invalid attachment", http.StatusBadRequest)
+		return
+	}
+	attachment, err := r.disputeService.GetAttachment(req.Context(), attachmentID)
+	if err != nil {
+		r.writeDisputeError(w, err, "could not get attachment")
+		return
+	}
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	w.Write(attachment.Content)
+}
+
+func (r *Router) adminReviewDisputeHandler(w http.ResponseWriter, req *http.Request) {
+	disputeID, err := parseIDFromVars(req, "id")
+	if err != nil {
+		http.Error(w, "Invalid dispute id", http.StatusBadRequest)
+		return
+	}
+	if err := r.disputeService.StartReview(req.Context(), disputeID); err != nil {
+		r.writeDisputeError(w, err, "could not start dispute review")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": model.DisputeInReview})
+}
+
+func (r *Router) adminResolveDisputeHandler(w http.ResponseWriter, req *http.Request) {
+	actorID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	disputeID, err := parseIDFromVars(req, "id")
+	if err != nil {
+		http.Error(w, "Invalid dispute id", http.StatusBadRequest)
+		return
+	}
+	var reqBody model.DisputeResolution
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	dispute, err := r.disputeService.Resolve(req.Context(), actorID, disputeID, reqBody)
+	if err != nil {
+		r.writeDisputeError(w, err, "failed to resolve dispute")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dispute)
+}
+
+func (r *Router) adminReverseTransactionHandler(w http.ResponseWriter, req *http.Request) {
+	actorID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	transactionID, err := parseIDFromVars(req, "id")
+	if err != nil {
+		http.Error(w, "Invalid transaction id", http.StatusBadRequest)
+		return
+	}
+	var reqBody reverseTransactionRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	reversals, err := r.adminService.ReverseTransaction(req.Context(), actorID, transactionID, reqBody.Amount, reqBody.AdminReason)
+	if err != nil {
+		r.writeDisputeError(w, err, "failed to reverse transaction")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reversals)
+}
+
+func (r *Router) writeDisputeError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, disputeService.ErrInvalidReason), errors.Is(err, disputeService.ErrDescriptionTooLong),
+		errors.Is(err, disputeService.ErrInvalidAttachment), errors.Is(err, disputeService.ErrAttachmentTooLarge),
+		errors.Is(err, disputeService.ErrInvalidDecision), errors.Is(err, disputeService.ErrInvalidStatus),
+		errors.Is(err, bankingService.ErrInvalidReversal), errors.Is(err, adminService.ErrInvalidReasonCode),
+		errors.Is(err, adminService.ErrCommentRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, disputeService.ErrTransactionNotFound), errors.Is(err, disputeService.ErrDisputeNotFound),
+		errors.Is(err, disputeService.ErrAttachmentNotFound), errors.Is(err, bankingService.ErrTransactionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, disputeService.ErrDisputeExists), errors.Is(err, disputeService.ErrDisputeClosed),
+		errors.Is(err, disputeService.ErrTooManyAttachments):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, bankingService.ErrNotReversible), errors.Is(err, bankingService.ErrInsufficientFunds),
+		errors.Is(err, bankingService.ErrAccountClosed):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		r.logger.WithError(err).Error(msg)
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
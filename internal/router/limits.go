@@ -0,0 +1,104 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"BankingApp/internal/model"
+	bankingService "BankingApp/internal/service/banking"
+	"BankingApp/pkg/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+// lowerLimitsRequest — новые лимиты операции; незаполненные поля остаются тарифными
+type lowerLimitsRequest struct {
+	model.Limits
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (r *Router) getLimitsHandler(w http.ResponseWriter, req *http.Request) {
+	account, ok := r.ownedAccount(w, req)
+	if !ok {
+		return
+	}
+	limits, err := r.bankingService.GetLimits(req.Context(), account.ID)
+	if err != nil {
+		r.writeLimitsError(w, err, "failed to get limits")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(limits)
+}
+
+func (r *Router) lowerLimitsHandler(w http.ResponseWriter, req *http.Request) {
+	account, ok := r.ownedAccount(w, req)
+	if !ok {
+		return
+	}
+	var reqBody lowerLimitsRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	override, err := r.bankingService.LowerLimits(req.Context(), account.ID, mux.Vars(req)["operation"], reqBody.Limits, reqBody.ExpiresAt)
+	if err != nil {
+		r.writeLimitsError(w, err, "failed to lower limits")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(override)
+}
+
+// resetLimitsHandler возвращает тарифные лимиты; снижение могло быть защитой от мошенников, поэтому нужно подтверждение
+func (r *Router) resetLimitsHandler(w http.ResponseWriter, req *http.Request) {
+	account, ok := r.ownedAccount(w, req)
+	if !ok {
+		return
+	}
+	var reqBody model.StepUp
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	userID, _ := middleware.ValidateUser(req)
+	if !r.verifyStepUp(w, req, userID, reqBody) {
+		return
+	}
+	if err := r.bankingService.ResetLimits(req.Context(), account.ID, mux.Vars(req)["operation"]); err != nil {
+		r.writeLimitsError(w, err, "failed to reset limits")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+func (r *Router) writeLimitsError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, bankingService.ErrInvalidLimitOperation), errors.Is(err, bankingService.ErrInvalidLimits),
+		errors.Is(err, bankingService.ErrInvalidLimitExpiry):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, bankingService.ErrLimitOverrideNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		r.writeAccountError(w, err, msg)
+	}
+}
+
+// writeLimitExceeded отвечает кодом нарушенного лимита, если операция отклонена лимитом; иначе ничего не пишет
+func writeLimitExceeded(w http.ResponseWriter, err error) bool {
+	var limitErr *bankingService.LimitError
+	if !errors.As(err, &limitErr) {
+		return false
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":     limitErr.Code,
+		"operation": limitErr.Operation,
+		"message":   limitErr.Error(),
+	})
+	return true
+}
@@ -0,0 +1,216 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"BankingApp/internal/model"
+	aliasService "BankingApp/internal/service/aliases"
+	bankingService "BankingApp/internal/service/banking"
+	payeeService "BankingApp/internal/service/payees"
+	"BankingApp/pkg/middleware"
+)
+
+// executeTemplateRequest — необязательные поправки к шаблону при платеже
+type executeTemplateRequest struct {
+	Amount      float64 `json:"amount,omitempty"`
+	Description string  `json:"description,omitempty"`
+	QuoteID     string  `json:"quote_id,omitempty"`
+	model.StepUp
+}
+
+func (r *Router) getPayeesHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	payees, err := r.payeeService.GetPayees(req.Context(), userID)
+	if err != nil {
+		r.writePayeeError(w, err, "could not get payees")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(payees)
+}
+
+func (r *Router) createPayeeHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	var reqBody model.Payee
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	payee, err := r.payeeService.CreatePayee(req.Context(), userID, reqBody)
+	if err != nil {
+		r.writePayeeError(w, err, "could not create payee")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(payee)
+}
+
+func (r *Router) deletePayeeHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	payeeID, err := parseIDFromVars(req, "id")
+	if err != nil {
+		http.Error(w, "Invalid payee id", http.StatusBadRequest)
+		return
+	}
+	if err := r.payeeService.DeletePayee(req.Context(), userID, payeeID); err != nil {
+		r.writePayeeError(w, err, "could not delete payee")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *Router) getTemplatesHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	templates, err := r.payeeService.GetTemplates(req.Context(), userID)
+	if err != nil {
+		r.writePayeeError(w, err, "could not get templates")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(templates)
+}
+
+func (r *Router) createTemplateHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	var reqBody model.PaymentTemplate
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	template, err := r.payeeService.CreateTemplate(req.Context(), userID, reqBody)
+	if err != nil {
+		r.writePayeeError(w, err, "could not create template")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(template)
+}
+
+func (r *Router) deleteTemplateHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	templateID, err := parseIDFromVars(req, "id")
+	if err != nil {
+		http.Error(w, "Invalid template id", http.StatusBadRequest)
+		return
+	}
+	if err := r.payeeService.DeleteTemplate(req.Context(), userID, templateID); err != nil {
+		r.writePayeeError(w, err, "could not delete template")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// executeTemplateHandler проводит платеж по шаблону тем же путем, что и обычный перевод
+func (r *Router) executeTemplateHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	templateID, err := parseIDFromVars(req, "id")
+	if err != nil {
+		http.Error(w, "Invalid template id", http.StatusBadRequest)
+		return
+	}
+	var reqBody executeTemplateRequest
+	// тело необязательно: без него платеж проводится ровно по шаблону
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	template, err := r.payeeService.GetTemplate(req.Context(), userID, templateID)
+	if err != nil {
+		r.writePayeeError(w, err, "could not get template")
+		return
+	}
+	transfer := transferRequest{
+		FromAccountID: template.FromAccountID,
+		PayeeID:       template.PayeeID,
+		Amount:        template.Amount,
+		Description:   template.Description,
+		QuoteID:       reqBody.QuoteID,
+		StepUp:        reqBody.StepUp,
+	}
+	if reqBody.Amount > 0 {
+		transfer.Amount = reqBody.Amount
+	}
+	if reqBody.Description != "" {
+		transfer.Description = reqBody.Description
+	}
+	r.performTransfer(w, req, userID, transfer)
+}
+
+// resolvePayee подставляет реквизиты сохраненного получателя, если он указан; при ошибке сам отвечает клиенту
+func (r *Router) resolvePayee(w http.ResponseWriter, req *http.Request, userID string, transfer *transferRequest) bool {
+	if transfer.PayeeID == 0 {
+		return true
+	}
+	payee, err := r.payeeService.GetPayee(req.Context(), userID, transfer.PayeeID)
+	if err != nil {
+		r.writePayeeError(w, err, "could not get payee")
+		return false
+	}
+	switch payee.Type {
+	case model.PayeeInternal:
+		transfer.ToAccountNumber = payee.AccountNumber
+	case model.PayeeAlias:
+		accountID, err := r.aliasService.ResolveSealed(req.Context(), userID, payee.AliasFingerprint)
+		if err != nil {
+			r.writeAliasError(w, err, "could not resolve payee")
+			return false
+		}
+		transfer.ToAccountID = accountID
+		transfer.hideRecipient = true
+	default:
+		http.Error(w, payeeService.ErrExternalPayee.Error(), http.StatusUnprocessableEntity)
+		return false
+	}
+	return true
+}
+
+func (r *Router) writePayeeError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, payeeService.ErrInvalidName), errors.Is(err, payeeService.ErrInvalidPayee),
+		errors.Is(err, payeeService.ErrInvalidBIC), errors.Is(err, payeeService.ErrInvalidAmount),
+		errors.Is(err, payeeService.ErrDescriptionTooLong), errors.Is(err, bankingService.ErrInvalidAccountNumber),
+		errors.Is(err, aliasService.ErrInvalidAliasType), errors.Is(err, aliasService.ErrInvalidAliasValue):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, payeeService.ErrPayeeNotFound), errors.Is(err, payeeService.ErrTemplateNotFound),
+		errors.Is(err, payeeService.ErrAccountNotFound), errors.Is(err, bankingService.ErrAccountNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, payeeService.ErrNameTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, aliasService.ErrLookupLimit):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	default:
+		r.logger.WithError(err).Error(msg)
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
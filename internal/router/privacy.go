@@ -0,0 +1,69 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"BankingApp/internal/model"
+	privacyService "BankingApp/internal/service/privacy"
+	"BankingApp/pkg/middleware"
+)
+
+// ----------- HANDLERS ------------
+
+// exportDataHandler отдает выгрузку данных клиента файлом JSON
+func (r *Router) exportDataHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	export, err := r.privacyService.Export(req.Context(), userID)
+	if err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Error("failed to export user data")
+		http.Error(w, "could not export data", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="bankingapp-export.json"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(export)
+}
+
+// eraseAccountHandler удаляет учетную запись после повторного подтверждения личности
+func (r *Router) eraseAccountHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	var reqBody model.StepUp
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !r.verifyStepUp(w, req, userID, reqBody) {
+		return
+	}
+	err = r.privacyService.Erase(req.Context(), userID)
+	switch {
+	case err == nil:
+	case errors.Is(err, privacyService.ErrNonZeroBalance), errors.Is(err, privacyService.ErrOpenCredits),
+		errors.Is(err, privacyService.ErrStaffAccount):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, privacyService.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	default:
+		r.logger.WithError(err).WithField("user_id", userID).Error("failed to erase user")
+		http.Error(w, "could not erase account", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": model.UserErased})
+}
@@ -0,0 +1,93 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	bankingService "BankingApp/internal/service/banking"
+	"BankingApp/pkg/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+// openProductRequest — пополнение при открытии со счета клиента; rollover без значения — по умолчанию продукта
+type openProductRequest struct {
+	FundingAccountID int64 `json:"funding_account_id"`
+	// счет можно указать 20-значным номером вместо внутреннего ID
+	FundingAccountNumber string  `json:"funding_account_number,omitempty"`
+	Amount               float64 `json:"amount"`
+	Rollover             *bool   `json:"rollover,omitempty"`
+}
+
+type rolloverRequest struct {
+	Rollover bool `json:"rollover"`
+}
+
+func (r *Router) getProductsHandler(w http.ResponseWriter, req *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(r.bankingService.GetProducts())
+}
+
+func (r *Router) openProductHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		r.logger.WithError(err).Error("failed to authenticate user")
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	if !r.requireVerified(w, req, userID) || !r.requireKYC(w, req, userID) {
+		return
+	}
+	var reqBody openProductRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if !r.resolveAccountNumber(w, req, userID, reqBody.FundingAccountNumber, &reqBody.FundingAccountID) {
+		return
+	}
+	account, err := r.bankingService.OpenProduct(req.Context(), userID, mux.Vars(req)["code"],
+		reqBody.FundingAccountID, reqBody.Amount, reqBody.Rollover)
+	if err != nil {
+		if writeLimitExceeded(w, err) {
+			return
+		}
+		r.writeProductError(w, err, "failed to open product")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(account)
+}
+
+func (r *Router) setRolloverHandler(w http.ResponseWriter, req *http.Request) {
+	account, ok := r.ownedAccount(w, req)
+	if !ok {
+		return
+	}
+	var reqBody rolloverRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := r.bankingService.SetDepositRollover(req.Context(), account.ID, reqBody.Rollover); err != nil {
+		r.writeProductError(w, err, "failed to change rollover")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]bool{"rollover": reqBody.Rollover})
+}
+
+func (r *Router) writeProductError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, bankingService.ErrBelowMinAmount), errors.Is(err, bankingService.ErrProductCurrency),
+		errors.Is(err, bankingService.ErrNotTermDeposit), errors.Is(err, bankingService.ErrTermDeposit):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, bankingService.ErrProductNotFound), errors.Is(err, bankingService.ErrAccountNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, bankingService.ErrInsufficientFunds), errors.Is(err, bankingService.ErrAccountFrozen):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		r.writeAccountError(w, err, msg)
+	}
+}
@@ -0,0 +1,166 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"BankingApp/internal/model"
+	profileService "BankingApp/internal/service/profile"
+	"BankingApp/pkg/middleware"
+)
+
+// ----------- HANDLERS ------------
+
+func (r *Router) getProfileHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	profile, err := r.profileService.GetProfile(req.Context(), userID)
+	if err != nil {
+		r.logger.WithError(err).Error("failed to get profile")
+		http.Error(w, "could not get profile", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(profile)
+}
+
+// updateProfileHandler меняет только переданные поля анкеты
+func (r *Router) updateProfileHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	var reqBody model.PersonalData
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	profile, err := r.profileService.UpdateProfile(req.Context(), userID, reqBody)
+	if err != nil {
+		r.writeProfileError(w, err, "failed to update profile")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(profile)
+}
+
+func (r *Router) listDocumentsHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	docs, err := r.profileService.GetDocuments(req.Context(), userID)
+	if err != nil {
+		r.logger.WithError(err).Error("failed to get documents")
+		http.Error(w, "could not get documents", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(docs)
+}
+
+// uploadDocumentHandler принимает multipart/form-data с полями type и file
+func (r *Router) uploadDocumentHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	maxSize := r.cfg.KYC.MaxDocumentSize
+	if maxSize <= 0 {
+		maxSize = 5 << 20
+	}
+	// запас на заголовки multipart и поле type
+	req.Body = http.MaxBytesReader(w, req.Body, maxSize+64<<10)
+	if err := req.ParseMultipartForm(maxSize); err != nil {
+		http.Error(w, "Invalid multipart form or file too large", http.StatusBadRequest)
+		return
+	}
+	file, header, err := req.FormFile("file")
+	if err != nil {
+		http.Error(w, "file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "could not read file", http.StatusBadRequest)
+		return
+	}
+	doc, err := r.profileService.UploadDocument(req.Context(), model.KYCDocument{
+		UserID:   userID,
+		Type:     req.FormValue("type"),
+		FileName: header.Filename,
+		Content:  content,
+	})
+	if err != nil {
+		r.writeProfileError(w, err, "failed to upload document")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(doc)
+}
+
+func (r *Router) submitKYCHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	if !r.requireVerified(w, req, userID) {
+		return
+	}
+	profile, err := r.profileService.SubmitKYC(req.Context(), userID)
+	if err != nil {
+		r.writeProfileError(w, err, "failed to submit kyc")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(profile)
+}
+
+// requireKYC пропускает операцию, только если личность клиента подтверждена.
+// При ошибке пишет ответ сам и возвращает false.
+func (r *Router) requireKYC(w http.ResponseWriter, req *http.Request, userID string) bool {
+	err := r.profileService.RequireKYC(req.Context(), userID)
+	if errors.Is(err, profileService.ErrKYCNotVerified) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return false
+	}
+	if err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Error("failed to check kyc status")
+		http.Error(w, "could not check kyc status", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+func (r *Router) writeProfileError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, profileService.ErrInvalidPhone), errors.Is(err, profileService.ErrInvalidPassport),
+		errors.Is(err, profileService.ErrInvalidINN), errors.Is(err, profileService.ErrInvalidDateOfBirth),
+		errors.Is(err, profileService.ErrTooYoung), errors.Is(err, profileService.ErrInvalidAddress),
+		errors.Is(err, profileService.ErrInvalidDocumentType), errors.Is(err, profileService.ErrInvalidDocument),
+		errors.Is(err, profileService.ErrDocumentTooLarge):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, profileService.ErrKYCInProgress), errors.Is(err, profileService.ErrKYCAlreadyVerified),
+		errors.Is(err, profileService.ErrIdentityLocked), errors.Is(err, profileService.ErrDuplicateIdentity):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, profileService.ErrProfileIncomplete), errors.Is(err, profileService.ErrPassportScanMissing),
+		errors.Is(err, profileService.ErrTooManyDocuments):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		r.logger.WithError(err).Error(msg)
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
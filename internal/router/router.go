@@ -4,6 +4,7 @@ import (
 	"BankingApp/internal/config"
 	"BankingApp/pkg/middleware"
 	"context"
+	"encoding/json"
 	"net/http"
 	"time"
 
@@ -16,19 +17,36 @@ import (
 // Router основной роутер приложения
 type Router struct {
 	logger         *logrus.Logger
+	cfg            *config.Config
+	rootRouter     *mux.Router
 	muxRouter      *mux.Router
 	userService    service.UserService
 	bankingService service.BankingService
+	fxService      service.FXService
+	aliasService   service.AliasService
+	payeeService   service.PayeeService
+	standingOrders service.StandingOrderService
 	cardService    service.CardService
 	creditService  service.CreditService
-	srv            *http.Server
+	adminService   service.AdminService
+	disputeService service.DisputeService
+	profileService service.ProfileService
+	privacyService service.PrivacyService
+	// clientLimiter — общий счетчик запросов машинных клиентов для всех подроутеров
+	clientLimiter *middleware.RateLimiter
+	srv           *http.Server
 }
 
 // NewRouter — конструктор роутера
 func NewRouter(logger *logrus.Logger, cfg *config.Config) *Router {
+	root := mux.NewRouter()
 	r := &Router{
-		muxRouter: mux.NewRouter().PathPrefix("/api/v1").Subrouter(),
-		logger:    logger,
+		rootRouter: root,
+		muxRouter:  root.PathPrefix("/api/v1").Subrouter(),
+		logger:     logger,
+		cfg:        cfg,
+
+		clientLimiter: middleware.NewRateLimiter(time.Minute),
 	}
 	r.srv = &http.Server{
 		Handler:      r.Handler(),
@@ -37,7 +55,7 @@ func NewRouter(logger *logrus.Logger, cfg *config.Config) *Router {
 		ReadTimeout:  15 * time.Second,
 	}
 	loggingMiddleware := middleware.NewLoggingMiddleware(logger)
-	r.muxRouter.Use(loggingMiddleware)
+	r.rootRouter.Use(loggingMiddleware)
 	return r
 }
 func (r *Router) Start() error {
@@ -49,18 +67,72 @@ func (r *Router) Stop(ctx context.Context) error {
 }
 
 // InitRoutes регистрирует эндпоинты
-func (r *Router) InitRoutes(userService service.UserService, bankingService service.BankingService, cardService service.CardService, creditService service.CreditService) {
+func (r *Router) InitRoutes(userService service.UserService, bankingService service.BankingService, fxService service.FXService, aliasService service.AliasService,
+	payeeService service.PayeeService, standingOrderService service.StandingOrderService, cardService service.CardService, creditService service.CreditService,
+	adminService service.AdminService, disputeService service.DisputeService, profileService service.ProfileService, privacyService service.PrivacyService) {
 	r.userService = userService
 	r.bankingService = bankingService
+	r.fxService = fxService
+	r.aliasService = aliasService
+	r.payeeService = payeeService
+	r.standingOrders = standingOrderService
 	r.cardService = cardService
 	r.creditService = creditService
+	r.adminService = adminService
+	r.disputeService = disputeService
+	r.profileService = profileService
+	r.privacyService = privacyService
 	r.InitUserRoutes()
 	r.InitCardRoutes()
 	r.InitBankingRoutes()
 	r.InitCreditRoutes()
+	r.InitAdminRoutes()
+	r.InitOAuthRoutes()
+	r.InitWellKnownRoutes()
+}
+
+// InitWellKnownRoutes — публичные метаданные вне /api/v1
+func (r *Router) InitWellKnownRoutes() {
+	r.rootRouter.HandleFunc("/.well-known/jwks.json", r.jwksHandler).Methods("GET")
+}
+
+// authMiddleware проверяет access-токен или API-ключ защищенных маршрутов и ограничивает
+// частоту запросов машинных клиентов. Пока режим подписи не asymmetric, принимаются и токены HS256,
+// подписанные JWT_SECRET_KEY.
+func (r *Router) authMiddleware() func(http.Handler) http.Handler {
+	jwtCfg := r.cfg.Auth.JWT
+	validator := &middleware.TokenValidator{
+		Keys:     r.userService,
+		Issuer:   jwtCfg.Issuer,
+		Audience: jwtCfg.Audience,
+		APIKeys:  r.authenticateAPIKey,
+	}
+	if jwtCfg.Mode != config.JWTModeAsymmetric {
+		validator.LegacySecret = []byte(config.GetJWTSecretKey())
+	}
+	auth := middleware.NewAuthMiddleware(validator, r.userService)
+	rateLimit := middleware.NewClientRateLimit(r.clientLimiter, r.userService)
+	return func(next http.Handler) http.Handler {
+		return auth(rateLimit(next))
+	}
+}
+
+func (r *Router) authenticateAPIKey(ctx context.Context, apiKey string) (string, string, []string, error) {
+	client, err := r.userService.AuthenticateAPIKey(ctx, apiKey)
+	if err != nil {
+		return "", "", nil, err
+	}
+	return client.OwnerUserID, client.ID, client.Scopes, nil
+}
+
+func (r *Router) jwksHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(r.userService.JWKS())
 }
 
 // Handler возвращает основной http.Handler
 func (r *Router) Handler() http.Handler {
-	return r.muxRouter
+	return r.rootRouter
 }
@@ -0,0 +1,159 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"BankingApp/internal/model"
+	payeeService "BankingApp/internal/service/payees"
+	standingOrderService "BankingApp/internal/service/standingorders"
+	"BankingApp/pkg/middleware"
+)
+
+// standingOrderRequest — новое поручение; даты в формате 2006-01-02
+type standingOrderRequest struct {
+	FromAccountID  int64   `json:"from_account_id"`
+	PayeeID        int64   `json:"payee_id"`
+	Amount         float64 `json:"amount"`
+	Description    string  `json:"description,omitempty"`
+	Frequency      string  `json:"frequency"`
+	DayOfMonth     int     `json:"day_of_month,omitempty"`
+	Weekday        *int    `json:"weekday,omitempty"`
+	BusinessDay    string  `json:"business_day,omitempty"`
+	StartDate      string  `json:"start_date,omitempty"`
+	EndDate        string  `json:"end_date,omitempty"`
+	MaxOccurrences int     `json:"max_occurrences,omitempty"`
+	model.StepUp
+}
+
+func (r *Router) getStandingOrdersHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	orders, err := r.standingOrders.GetStandingOrders(req.Context(), userID)
+	if err != nil {
+		r.writeStandingOrderError(w, err, "could not get standing orders")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(orders)
+}
+
+// createStandingOrderHandler — крупная сумма подтверждается один раз при создании поручения
+func (r *Router) createStandingOrderHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	var reqBody standingOrderRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	order := model.StandingOrder{
+		FromAccountID:  reqBody.FromAccountID,
+		PayeeID:        reqBody.PayeeID,
+		Amount:         reqBody.Amount,
+		Description:    reqBody.Description,
+		Frequency:      reqBody.Frequency,
+		DayOfMonth:     reqBody.DayOfMonth,
+		Weekday:        reqBody.Weekday,
+		BusinessDay:    reqBody.BusinessDay,
+		MaxOccurrences: reqBody.MaxOccurrences,
+	}
+	if reqBody.StartDate != "" {
+		if order.StartDate, err = time.Parse(time.DateOnly, reqBody.StartDate); err != nil {
+			http.Error(w, "Invalid start_date", http.StatusBadRequest)
+			return
+		}
+	}
+	if reqBody.EndDate != "" {
+		end, err := time.Parse(time.DateOnly, reqBody.EndDate)
+		if err != nil {
+			http.Error(w, "Invalid end_date", http.StatusBadRequest)
+			return
+		}
+		order.EndDate = &end
+	}
+	if threshold := r.cfg.Banking.StepUpThreshold; threshold > 0 && reqBody.Amount >= threshold {
+		if !r.verifyStepUp(w, req, userID, reqBody.StepUp) {
+			return
+		}
+	}
+	created, err := r.standingOrders.Create(req.Context(), userID, order)
+	if err != nil {
+		r.writeStandingOrderError(w, err, "could not create standing order")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (r *Router) getStandingOrderExecutionsHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	orderID, err := parseIDFromVars(req, "id")
+	if err != nil {
+		http.Error(w, "Invalid standing order id", http.StatusBadRequest)
+		return
+	}
+	executions, err := r.standingOrders.GetExecutions(req.Context(), userID, orderID)
+	if err != nil {
+		r.writeStandingOrderError(w, err, "could not get standing order executions")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(executions)
+}
+
+// standingOrderActionHandler — пауза, возобновление и отмена поручения
+func (r *Router) standingOrderActionHandler(action func(ctx context.Context, userID string, orderID int64) (*model.StandingOrder, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		userID, err := middleware.ValidateUser(req)
+		if err != nil {
+			http.Error(w, "Invalid user", http.StatusUnauthorized)
+			return
+		}
+		orderID, err := parseIDFromVars(req, "id")
+		if err != nil {
+			http.Error(w, "Invalid standing order id", http.StatusBadRequest)
+			return
+		}
+		order, err := action(req.Context(), userID, orderID)
+		if err != nil {
+			r.writeStandingOrderError(w, err, "could not update standing order")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(order)
+	}
+}
+
+func (r *Router) writeStandingOrderError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, standingOrderService.ErrInvalidAmount), errors.Is(err, standingOrderService.ErrDescriptionTooLong),
+		errors.Is(err, standingOrderService.ErrInvalidFrequency), errors.Is(err, standingOrderService.ErrInvalidDay),
+		errors.Is(err, standingOrderService.ErrInvalidBusinessDay), errors.Is(err, standingOrderService.ErrInvalidPeriod),
+		errors.Is(err, standingOrderService.ErrInvalidOccurrences):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, standingOrderService.ErrOrderNotFound), errors.Is(err, standingOrderService.ErrAccountNotFound),
+		errors.Is(err, payeeService.ErrPayeeNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, standingOrderService.ErrInvalidStatus):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, payeeService.ErrExternalPayee):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		r.logger.WithError(err).Error(msg)
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
@@ -2,7 +2,14 @@ package router
 
 import (
 	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
+	"strconv"
+
+	"BankingApp/internal/model"
+	userService "BankingApp/internal/service/users"
+	"BankingApp/pkg/middleware"
 
 	"github.com/gorilla/mux"
 )
@@ -11,7 +18,40 @@ func (r *Router) InitUserRoutes() {
 	userRouter := r.muxRouter.PathPrefix("/user").Subrouter()
 	userRouter.HandleFunc("/register", r.registerUserHandler).Methods("POST")
 	userRouter.HandleFunc("/login", r.loginHandler).Methods("POST")
-	userRouter.HandleFunc("/{id:[0-9]+}", r.getUserByIDHandler).Methods("GET")
+	userRouter.HandleFunc("/login/2fa", r.loginMFAHandler).Methods("POST")
+	userRouter.HandleFunc("/refresh", r.refreshHandler).Methods("POST")
+	userRouter.HandleFunc("/password/forgot", r.forgotPasswordHandler).Methods("POST")
+	userRouter.HandleFunc("/password/reset", r.resetPasswordHandler).Methods("POST")
+	userRouter.HandleFunc("/verify", r.verifyEmailHandler).Methods("GET")
+
+	// --- PROTECTED ROUTES (JWT Auth Required) ---
+	authMiddleware := r.authMiddleware()
+	sessionRouter := userRouter.NewRoute().Subrouter()
+	sessionRouter.Use(authMiddleware, middleware.RequireUserToken)
+	sessionRouter.HandleFunc("/logout", r.logoutHandler).Methods("POST")
+	sessionRouter.HandleFunc("/password/change", r.changePasswordHandler).Methods("POST")
+	sessionRouter.HandleFunc("/verify/resend", r.resendVerificationHandler).Methods("POST")
+	// данные пользователя доступны ему самому и сотрудникам банка
+	sessionRouter.HandleFunc("/{id:[0-9a-fA-F-]{36}}", r.getUserByIDHandler).Methods("GET")
+	sessionRouter.HandleFunc("/sessions", r.listSessionsHandler).Methods("GET")
+	sessionRouter.HandleFunc("/sessions/{id}", r.revokeSessionHandler).Methods("DELETE")
+	sessionRouter.HandleFunc("/2fa/enroll", r.enrollTOTPHandler).Methods("POST")
+	sessionRouter.HandleFunc("/2fa/confirm", r.confirmTOTPHandler).Methods("POST")
+	sessionRouter.HandleFunc("/2fa/disable", r.disableTOTPHandler).Methods("POST")
+	// анкета и проверка личности
+	sessionRouter.HandleFunc("/profile", r.getProfileHandler).Methods("GET")
+	sessionRouter.HandleFunc("/profile", r.updateProfileHandler).Methods("PUT")
+	sessionRouter.HandleFunc("/profile/documents", r.listDocumentsHandler).Methods("GET")
+	sessionRouter.HandleFunc("/profile/documents", r.uploadDocumentHandler).Methods("POST")
+	sessionRouter.HandleFunc("/profile/kyc/submit", r.submitKYCHandler).Methods("POST")
+	// выгрузка и удаление персональных данных
+	sessionRouter.HandleFunc("/me/export", r.exportDataHandler).Methods("GET")
+	sessionRouter.HandleFunc("/me/erase", r.eraseAccountHandler).Methods("POST")
+	// машинные клиенты пользователя
+	sessionRouter.HandleFunc("/clients", r.createAPIClientHandler).Methods("POST")
+	sessionRouter.HandleFunc("/clients", r.listAPIClientsHandler).Methods("GET")
+	sessionRouter.HandleFunc("/clients/{id}/rotate", r.rotateAPIClientHandler).Methods("POST")
+	sessionRouter.HandleFunc("/clients/{id}", r.revokeAPIClientHandler).Methods("DELETE")
 }
 
 type registerRequest struct {
@@ -21,11 +61,35 @@ type registerRequest struct {
 	FullName string `json:"full_name"`
 }
 
+// loginRequest — в login можно передать email или username, поле email оставлено для старых клиентов
 type loginRequest struct {
+	Login    string `json:"login"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type loginMFARequest struct {
+	MFAToken string `json:"mfa_token"`
+	model.StepUp
+}
+
+type confirmTOTPRequest struct {
+	Code string `json:"code"`
+}
+
+type forgotPasswordRequest struct {
+	Login string `json:"login"`
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
 func (r *Router) registerUserHandler(w http.ResponseWriter, req *http.Request) {
 	var reqBody registerRequest
 	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
@@ -49,18 +113,95 @@ func (r *Router) loginHandler(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	login := reqBody.Login
+	if login == "" {
+		login = reqBody.Email
+	}
 	ctx := req.Context()
-	jwtToken, expiresAt, err := r.userService.Authenticate(ctx, reqBody.Email, reqBody.Password)
+	client := clientInfo(req)
+	tokens, err := r.userService.Authenticate(ctx, login, reqBody.Password, client)
 	if err != nil {
-		r.logger.WithError(err).Warn("failed to authenticate user")
-		http.Error(w, "Authentication failed: "+err.Error(), http.StatusUnauthorized)
+		r.logger.WithError(err).WithField("ip", client.IP).Warn("failed to authenticate user")
+		switch {
+		case errors.Is(err, userService.ErrTooManyAttempts):
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+		case errors.Is(err, userService.ErrInvalidCredentials):
+			http.Error(w, "Authentication failed: "+err.Error(), http.StatusUnauthorized)
+		default:
+			http.Error(w, "Authentication failed", http.StatusUnauthorized)
+		}
 		return
 	}
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"token":      jwtToken,
-		"expires_at": expiresAt,
-	})
+	json.NewEncoder(w).Encode(tokens)
+}
+
+func (r *Router) refreshHandler(w http.ResponseWriter, req *http.Request) {
+	var reqBody refreshRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil || reqBody.RefreshToken == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	tokens, err := r.userService.Refresh(req.Context(), reqBody.RefreshToken)
+	if err != nil {
+		r.logger.WithError(err).Warn("failed to refresh token")
+		http.Error(w, "Refresh failed: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+func (r *Router) logoutHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	token, err := middleware.ValidateToken(req)
+	if err != nil {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+	if err := r.userService.Logout(req.Context(), userID, token.SessionID, token.ID, token.ExpiresAt); err != nil {
+		r.logger.WithError(err).Error("failed to logout")
+		http.Error(w, "could not logout", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+func (r *Router) listSessionsHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	sessions, err := r.userService.GetSessions(req.Context(), userID)
+	if err != nil {
+		r.logger.WithError(err).Error("failed to get sessions")
+		http.Error(w, "could not get sessions", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sessions)
+}
+
+func (r *Router) revokeSessionHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	sessionID := mux.Vars(req)["id"]
+	if err := r.userService.RevokeSession(req.Context(), userID, sessionID); err != nil {
+		r.logger.WithError(err).Warn("failed to revoke session")
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
 func (r *Router) getUserByIDHandler(w http.ResponseWriter, req *http.Request) {
@@ -70,6 +211,16 @@ func (r *Router) getUserByIDHandler(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, "User ID not specified", http.StatusBadRequest)
 		return
 	}
+	callerID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	role, _ := middleware.UserRole(req)
+	if callerID != userID && !model.IsStaffRole(role) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
 
 	ctx := req.Context()
 	user, err := r.userService.GetByID(ctx, userID)
@@ -78,6 +229,225 @@ func (r *Router) getUserByIDHandler(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, "User not found: "+err.Error(), http.StatusNotFound)
 		return
 	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(user)
 }
+
+func (r *Router) loginMFAHandler(w http.ResponseWriter, req *http.Request) {
+	var reqBody loginMFARequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil || reqBody.MFAToken == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	client := clientInfo(req)
+	tokens, err := r.userService.CompleteMFA(req.Context(), reqBody.MFAToken, reqBody.StepUp, client)
+	if err != nil {
+		r.logger.WithError(err).WithField("ip", client.IP).Warn("failed to verify second factor")
+		if errors.Is(err, userService.ErrTooManyAttempts) {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		http.Error(w, "Authentication failed: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+func (r *Router) enrollTOTPHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	enrollment, err := r.userService.EnrollTOTP(req.Context(), userID)
+	if err != nil {
+		r.logger.WithError(err).Warn("failed to enroll totp")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+func (r *Router) confirmTOTPHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	var reqBody confirmTOTPRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	codes, err := r.userService.ConfirmTOTP(req.Context(), userID, reqBody.Code)
+	if err != nil {
+		r.logger.WithError(err).Warn("failed to confirm totp")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"recovery_codes": codes,
+	})
+}
+
+func (r *Router) disableTOTPHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	var reqBody model.StepUp
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := r.userService.DisableTOTP(req.Context(), userID, reqBody); err != nil {
+		r.logger.WithError(err).Warn("failed to disable totp")
+		http.Error(w, "step-up authentication failed", http.StatusForbidden)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// verifyStepUp требует повторного подтверждения личности для чувствительной операции.
+// При ошибке пишет ответ сам и возвращает false.
+func (r *Router) verifyStepUp(w http.ResponseWriter, req *http.Request, userID string, stepUp model.StepUp) bool {
+	if err := r.userService.StepUp(req.Context(), userID, stepUp); err != nil {
+		r.logger.WithError(err).Warn("step-up authentication failed")
+		status := http.StatusForbidden
+		if errors.Is(err, userService.ErrTooManyAttempts) {
+			status = http.StatusTooManyRequests
+		}
+		http.Error(w, "step-up authentication failed: "+err.Error(), status)
+		return false
+	}
+	return true
+}
+
+func (r *Router) forgotPasswordHandler(w http.ResponseWriter, req *http.Request) {
+	var reqBody forgotPasswordRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil || reqBody.Login == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	// ответ одинаков для существующих и несуществующих пользователей
+	if err := r.userService.ForgotPassword(req.Context(), reqBody.Login); err != nil {
+		r.logger.WithError(err).Error("failed to send password reset token")
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "if the account exists, a reset token has been sent"})
+}
+
+func (r *Router) resetPasswordHandler(w http.ResponseWriter, req *http.Request) {
+	var reqBody resetPasswordRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil || reqBody.Token == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := r.userService.ResetPassword(req.Context(), reqBody.Token, reqBody.NewPassword); err != nil {
+		r.logger.WithError(err).Warn("failed to reset password")
+		http.Error(w, "Password reset failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+func (r *Router) changePasswordHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	token, err := middleware.ValidateToken(req)
+	if err != nil {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+	var reqBody model.PasswordChange
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := r.userService.ChangePassword(req.Context(), userID, token.SessionID, reqBody); err != nil {
+		r.logger.WithError(err).Warn("failed to change password")
+		switch {
+		case errors.Is(err, userService.ErrTooManyAttempts):
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+		case errors.Is(err, userService.ErrInvalidCredentials):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, "Password change failed: "+err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+func (r *Router) verifyEmailHandler(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	expires, err := strconv.ParseInt(query.Get("exp"), 10, 64)
+	if err != nil || query.Get("uid") == "" || query.Get("sig") == "" {
+		http.Error(w, "Invalid verification link", http.StatusBadRequest)
+		return
+	}
+	err = r.userService.VerifyEmail(req.Context(), query.Get("uid"), expires, query.Get("sig"))
+	if err != nil && !errors.Is(err, userService.ErrEmailAlreadyVerified) {
+		r.logger.WithError(err).Warn("failed to verify email")
+		http.Error(w, "Verification failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": model.UserActive})
+}
+
+func (r *Router) resendVerificationHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := middleware.ValidateUser(req)
+	if err != nil {
+		http.Error(w, "Invalid user", http.StatusUnauthorized)
+		return
+	}
+	if err := r.userService.ResendVerification(req.Context(), userID); err != nil {
+		r.logger.WithError(err).Warn("failed to resend verification email")
+		switch {
+		case errors.Is(err, userService.ErrVerificationResendTooSoon):
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+		case errors.Is(err, userService.ErrEmailAlreadyVerified):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "could not send verification email", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "sent"})
+}
+
+// requireVerified запрещает операцию пользователю, не подтвердившему email.
+// При отказе пишет ответ сам и возвращает false.
+func (r *Router) requireVerified(w http.ResponseWriter, req *http.Request, userID string) bool {
+	if err := r.userService.RequireVerified(req.Context(), userID); err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Warn("operation requires verified email")
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// clientInfo — устройство и адрес клиента без порта, чтобы блокировка по IP не обходилась сменой порта
+func clientInfo(req *http.Request) model.ClientInfo {
+	ip, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		ip = req.RemoteAddr
+	}
+	return model.ClientInfo{UserAgent: req.UserAgent(), IP: ip}
+}
@@ -0,0 +1,189 @@
+package admin
+
+import (
+	"BankingApp/internal/model"
+	"BankingApp/internal/service"
+	bankingService "BankingApp/internal/service/banking"
+	"BankingApp/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+var _ service.AdminService = (*Service)(nil)
+
+var (
+	ErrInvalidReasonCode = errors.New("неизвестный код причины")
+	ErrCommentRequired   = errors.New("для этого кода причины нужен комментарий")
+	ErrInvalidRole       = errors.New("неизвестная роль")
+	ErrInvalidAmount     = errors.New("сумма корректировки не может быть нулевой")
+	ErrSelfRoleChange    = errors.New("нельзя изменить собственную роль")
+	ErrSelfReview        = errors.New("нельзя проверять собственную анкету")
+	ErrSelfAccount       = errors.New("нельзя проводить операции по собственному счету")
+)
+
+type Service struct {
+	storage storage.AdminStorage
+	banking service.BankingService
+}
+
+func NewAdminService(storage storage.AdminStorage, banking service.BankingService) *Service {
+	return &Service{storage: storage, banking: banking}
+}
+
+func (s *Service) SetUserRole(ctx context.Context, actorID, userID, role string, reason model.AdminReason) error {
+	if !model.ValidRole(role) {
+		return ErrInvalidRole
+	}
+	if actorID == userID {
+		return ErrSelfRoleChange
+	}
+	if err := ValidateReason(reason); err != nil {
+		return err
+	}
+	action := newAction(actorID, model.AdminActionSetRole, model.AdminTargetUser, userID, reason)
+	return s.storage.SetUserRole(ctx, userID, role, action)
+}
+
+func (s *Service) FreezeAccount(ctx context.Context, actorID string, accountID int64, reason model.AdminReason) error {
+	return s.setFrozen(ctx, actorID, accountID, true, reason)
+}
+
+func (s *Service) UnfreezeAccount(ctx context.Context, actorID string, accountID int64, reason model.AdminReason) error {
+	return s.setFrozen(ctx, actorID, accountID, false, reason)
+}
+
+// AdjustBalance зачисляет (amount > 0) или списывает (amount < 0) средства вне обычных операций
+func (s *Service) AdjustBalance(ctx context.Context, actorID string, accountID int64, amount float64, reason model.AdminReason) error {
+	if amount == 0 {
+		return ErrInvalidAmount
+	}
+	if err := ValidateReason(reason); err != nil {
+		return err
+	}
+	if err := s.checkNotOwner(ctx, actorID, accountID); err != nil {
+		return err
+	}
+	action := newAction(actorID, model.AdminActionAdjustBalance, model.AdminTargetAccount, strconv.FormatInt(accountID, 10), reason)
+	action.Amount = &amount
+	return s.storage.AdjustBalance(ctx, accountID, amount, action)
+}
+
+// SetOverdraft устанавливает лимит овердрафта и годовую ставку; ставка попадает в комментарий журнала
+func (s *Service) SetOverdraft(ctx context.Context, actorID string, accountID int64, limit, rate float64, reason model.AdminReason) error {
+	if err := ValidateReason(reason); err != nil {
+		return err
+	}
+	if err := s.checkNotOwner(ctx, actorID, accountID); err != nil {
+		return err
+	}
+	limit, err := s.banking.PlanOverdraft(ctx, accountID, limit, rate)
+	if err != nil {
+		return err
+	}
+	action := newAction(actorID, model.AdminActionSetOverdraft, model.AdminTargetAccount, strconv.FormatInt(accountID, 10), reason)
+	action.Amount = &limit
+	note := "ставка " + strconv.FormatFloat(rate, 'f', -1, 64)
+	if action.Comment != "" {
+		note += "; " + action.Comment
+	}
+	action.Comment = note
+	return bankingService.OverdraftError(s.storage.SetOverdraft(ctx, accountID, limit, rate, action))
+}
+
+// ReverseTransaction сторнирует операцию полностью (amount = 0) или частично компенсирующими проводками.
+// Исходная операция не удаляется, сторно ссылается на нее.
+func (s *Service) ReverseTransaction(ctx context.Context, actorID string, transactionID int64, amount float64, reason model.AdminReason) ([]*model.Transaction, error) {
+	if err := ValidateReason(reason); err != nil {
+		return nil, err
+	}
+	legs, err := s.banking.PlanReversal(ctx, transactionID, amount)
+	if err != nil {
+		return nil, err
+	}
+	for _, leg := range legs {
+		if err := s.checkNotOwner(ctx, actorID, leg.AccountID); err != nil {
+			return nil, err
+		}
+	}
+	reversed := math.Abs(legs[0].Amount)
+	action := newAction(actorID, model.AdminActionReverse, model.AdminTargetTransaction, strconv.FormatInt(transactionID, 10), reason)
+	action.Amount = &reversed
+	reversals, err := s.storage.ReverseTransaction(ctx, legs, fmt.Sprintf("Сторно операции №%d", transactionID), action)
+	if err != nil {
+		return nil, bankingService.ReversalError(err)
+	}
+	return reversals, nil
+}
+
+// ReviewKYC подтверждает или отклоняет анкету на проверке. Комментарий отказа показывается клиенту,
+// поэтому при отклонении он обязателен.
+func (s *Service) ReviewKYC(ctx context.Context, actorID, userID string, approve bool, reason model.AdminReason) error {
+	if actorID == userID {
+		return ErrSelfReview
+	}
+	if err := ValidateReason(reason); err != nil {
+		return err
+	}
+	name, status, comment := model.AdminActionApproveKYC, model.KYCVerified, ""
+	if !approve {
+		if reason.Comment == "" {
+			return ErrCommentRequired
+		}
+		name, status, comment = model.AdminActionRejectKYC, model.KYCRejected, reason.Comment
+	}
+	action := newAction(actorID, name, model.AdminTargetUser, userID, reason)
+	return s.storage.SetKYCStatus(ctx, userID, status, comment, action)
+}
+
+func (s *Service) GetAuditLog(ctx context.Context, targetType, targetID string) ([]*model.AdminAction, error) {
+	return s.storage.GetAdminActions(ctx, targetType, targetID)
+}
+
+// checkNotOwner запрещает сотруднику менять деньги и условия на собственном счете
+func (s *Service) checkNotOwner(ctx context.Context, actorID string, accountID int64) error {
+	account, err := s.banking.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	if account.UserID == actorID {
+		return ErrSelfAccount
+	}
+	return nil
+}
+
+func (s *Service) setFrozen(ctx context.Context, actorID string, accountID int64, frozen bool, reason model.AdminReason) error {
+	if err := ValidateReason(reason); err != nil {
+		return err
+	}
+	name := model.AdminActionUnfreezeAccount
+	if frozen {
+		name = model.AdminActionFreezeAccount
+	}
+	action := newAction(actorID, name, model.AdminTargetAccount, strconv.FormatInt(accountID, 10), reason)
+	return s.storage.SetAccountFrozen(ctx, accountID, frozen, action)
+}
+
+// ValidateReason проверяет код по справочнику; исправление ошибок и жесты доброй воли
+// не выводятся из кода однозначно, поэтому для них нужен комментарий
+func ValidateReason(reason model.AdminReason) error {
+	if !model.ValidReasonCode(reason.ReasonCode) {
+		return ErrInvalidReasonCode
+	}
+	if (reason.ReasonCode == model.ReasonErrorCorrection || reason.ReasonCode == model.ReasonGoodwill) && reason.Comment == "" {
+		return ErrCommentRequired
+	}
+	return nil
+}
+
+func newAction(actorID, name, targetType, targetID string, reason model.AdminReason) *model.AdminAction {
+	return &model.AdminAction{
+		ActorID:     actorID,
+		Action:      name,
+		TargetType:  targetType,
+		TargetID:    targetID,
+		AdminReason: reason,
+	}
+}
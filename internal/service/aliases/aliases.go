@@ -0,0 +1,287 @@
+package aliases
+
+import (
+	"BankingApp/internal/config"
+	"BankingApp/internal/keystore"
+	"BankingApp/internal/model"
+	"BankingApp/internal/service"
+	profileService "BankingApp/internal/service/profile"
+	"BankingApp/internal/storage"
+	"BankingApp/pkg/clock"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const defaultLookupWindow = time.Hour
+
+var (
+	ErrInvalidAliasType  = errors.New("неизвестный тип псевдонима, ожидается phone или email")
+	ErrInvalidAliasValue = errors.New("неверный номер телефона или адрес email")
+	ErrAliasNotFound     = errors.New("получатель не найден")
+	ErrAliasTaken        = errors.New("этот телефон или email уже привязан другим клиентом")
+	ErrAccountNotFound   = errors.New("счет не найден или закрыт")
+	ErrEmailNotVerified  = errors.New("сначала подтвердите email")
+	ErrPhoneMissing      = errors.New("укажите телефон в анкете")
+	ErrLookupLimit       = errors.New("слишком много запросов поиска получателя, повторите позже")
+)
+
+var _ service.AliasService = (*Service)(nil)
+
+// Service привязывает телефон и email клиента к счету по умолчанию и находит по ним получателей переводов.
+// Привязать можно только собственные подтвержденные контакты: email после подтверждения, телефон после KYC.
+type Service struct {
+	storage  storage.AliasStorage
+	lockouts storage.LockoutStorage
+	users    service.UserService
+	profiles service.ProfileService
+	banking  service.BankingService
+	keys     *keystore.KeyStore
+	clock    clock.Clock
+	limit    int
+	window   time.Duration
+}
+
+func NewAliasService(storage storage.AliasStorage, lockouts storage.LockoutStorage, users service.UserService, profiles service.ProfileService,
+	banking service.BankingService, keys *keystore.KeyStore, clk clock.Clock, cfg *config.Config) *Service {
+	window := time.Duration(cfg.Banking.AliasLookupWindow)
+	if window <= 0 {
+		window = defaultLookupWindow
+	}
+	return &Service{
+		storage:  storage,
+		lockouts: lockouts,
+		users:    users,
+		profiles: profiles,
+		banking:  banking,
+		keys:     keys,
+		clock:    clk,
+		limit:    cfg.Banking.AliasLookupLimit,
+		window:   window,
+	}
+}
+
+// Register привязывает телефон из анкеты или email клиента к счету accountID.
+// Повторная регистрация того же типа меняет счет и подхватывает изменившийся контакт.
+func (s *Service) Register(ctx context.Context, userID, aliasType string, accountID int64) (*model.Alias, error) {
+	if !model.ValidAliasType(aliasType) {
+		return nil, ErrInvalidAliasType
+	}
+	account, err := s.banking.GetAccountByID(ctx, accountID)
+	if err != nil || account.UserID != userID || !account.IsActive {
+		return nil, ErrAccountNotFound
+	}
+
+	var value, masked string
+	switch aliasType {
+	case model.AliasEmail:
+		user, err := s.users.GetByID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if user.VerifiedAt == nil {
+			return nil, ErrEmailNotVerified
+		}
+		value = normalizeEmail(user.Email)
+		masked = model.MaskEmail(value)
+	case model.AliasPhone:
+		if err := s.profiles.RequireKYC(ctx, userID); err != nil {
+			return nil, err
+		}
+		profile, err := s.profiles.GetProfile(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if profile == nil || profile.Phone == "" {
+			return nil, ErrPhoneMissing
+		}
+		value = profile.Phone
+		masked = model.MaskPhone(value)
+	}
+
+	alias, err := s.storage.SaveAlias(ctx, &model.Alias{
+		UserID:      userID,
+		Type:        aliasType,
+		MaskedValue: masked,
+		AccountID:   account.ID,
+		Fingerprint: s.fingerprint(aliasType, value),
+		UpdatedAt:   s.clock.Now(),
+	})
+	if errors.Is(err, storage.ErrAlreadyExists) {
+		return nil, ErrAliasTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return alias, nil
+}
+
+func (s *Service) GetAliases(ctx context.Context, userID string) ([]*model.Alias, error) {
+	return s.storage.GetAliasesByUser(ctx, userID)
+}
+
+func (s *Service) Delete(ctx context.Context, userID, aliasType string) error {
+	deleted, err := s.storage.DeleteAlias(ctx, userID, aliasType)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAliasNotFound
+	}
+	return nil
+}
+
+// Lookup возвращает маскированное имя получателя, чтобы отправитель убедился, что переводит нужному человеку
+func (s *Service) Lookup(ctx context.Context, userID string, target model.AliasTarget) (*model.AliasLookup, error) {
+	alias, account, err := s.find(ctx, userID, target)
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := s.users.GetByID(ctx, alias.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.AliasLookup{
+		Type:       alias.Type,
+		MaskedName: model.MaskName(recipient.FullName),
+		Currency:   account.Currency,
+	}, nil
+}
+
+// Resolve возвращает счет получателя для перевода по псевдониму
+func (s *Service) Resolve(ctx context.Context, userID string, target model.AliasTarget) (int64, error) {
+	_, account, err := s.find(ctx, userID, target)
+	if err != nil {
+		return 0, err
+	}
+	return account.ID, nil
+}
+
+// ResolveSealed возвращает счет сохраненного получателя, у которого хранится только отпечаток псевдонима
+func (s *Service) ResolveSealed(ctx context.Context, userID string, fingerprint []byte) (int64, error) {
+	_, account, err := s.findFingerprint(ctx, userID, fingerprint)
+	if err != nil {
+		return 0, err
+	}
+	return account.ID, nil
+}
+
+// ResolveScheduled возвращает счет получателя регулярного перевода. Получателя клиент сохранил сам,
+// а платежи идут по расписанию, поэтому поиск не расходует лимит интерактивных запросов клиента.
+func (s *Service) ResolveScheduled(ctx context.Context, fingerprint []byte) (int64, error) {
+	_, account, err := s.lookup(ctx, fingerprint)
+	if err != nil {
+		return 0, err
+	}
+	return account.ID, nil
+}
+
+// Seal возвращает отпечаток и маскированную форму телефона или email, чтобы сохранять получателя
+// без открытого значения, как и сами псевдонимы
+func (s *Service) Seal(target model.AliasTarget) ([]byte, string, error) {
+	target, err := NormalizeTarget(target)
+	if err != nil {
+		return nil, "", err
+	}
+	masked := model.MaskEmail(target.Value)
+	if target.Type == model.AliasPhone {
+		masked = model.MaskPhone(target.Value)
+	}
+	return s.fingerprint(target.Type, target.Value), masked, nil
+}
+
+// CountLookup учитывает поиск получателя по номеру счета: ответ тоже показывает, существует ли счет
+func (s *Service) CountLookup(ctx context.Context, userID string) error {
+	return s.countLookup(ctx, userID)
+}
+
+// find ищет действующий счет по псевдониму
+func (s *Service) find(ctx context.Context, userID string, target model.AliasTarget) (*model.Alias, *model.Account, error) {
+	target, err := NormalizeTarget(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.findFingerprint(ctx, userID, s.fingerprint(target.Type, target.Value))
+}
+
+// findFingerprint ищет действующий счет по отпечатку псевдонима. Каждый поиск учитывается в лимите отправителя,
+// чтобы по ответам нельзя было перебрать телефоны и адреса клиентов банка.
+func (s *Service) findFingerprint(ctx context.Context, userID string, fingerprint []byte) (*model.Alias, *model.Account, error) {
+	if err := s.countLookup(ctx, userID); err != nil {
+		return nil, nil, err
+	}
+	return s.lookup(ctx, fingerprint)
+}
+
+// lookup ищет действующий счет по отпечатку псевдонима без учета в лимите
+func (s *Service) lookup(ctx context.Context, fingerprint []byte) (*model.Alias, *model.Account, error) {
+	alias, err := s.storage.FindAlias(ctx, fingerprint)
+	if err != nil {
+		return nil, nil, err
+	}
+	if alias == nil {
+		return nil, nil, ErrAliasNotFound
+	}
+	account, err := s.banking.GetAccountByID(ctx, alias.AccountID)
+	if err != nil || !account.IsActive {
+		return nil, nil, ErrAliasNotFound
+	}
+	return alias, account, nil
+}
+
+// countLookup учитывает поиск и блокирует поиск на окно, когда клиент исчерпал лимит
+func (s *Service) countLookup(ctx context.Context, userID string) error {
+	if s.limit <= 0 {
+		return nil
+	}
+	now := s.clock.Now()
+	lockout, err := s.lockouts.GetLockout(ctx, model.LockoutScopeAliasLookup, userID)
+	if err != nil {
+		return fmt.Errorf("countLookup: %w", err)
+	}
+	if lockout != nil && lockout.LockedUntil != nil && now.Before(*lockout.LockedUntil) {
+		return ErrLookupLimit
+	}
+	lockout, err = s.lockouts.RegisterLoginFailure(ctx, model.LockoutScopeAliasLookup, userID, now, s.window)
+	if err != nil {
+		return err
+	}
+	if lockout.Failures <= s.limit {
+		return nil
+	}
+	if err := s.lockouts.LockLogin(ctx, model.LockoutScopeAliasLookup, userID, now.Add(s.window)); err != nil {
+		return err
+	}
+	return ErrLookupLimit
+}
+
+func (s *Service) fingerprint(aliasType, value string) []byte {
+	return s.keys.Fingerprint([]byte("alias:" + aliasType + "|" + value))
+}
+
+// NormalizeTarget проверяет тип псевдонима и приводит телефон или email к виду, в котором он привязывается
+func NormalizeTarget(target model.AliasTarget) (model.AliasTarget, error) {
+	switch target.Type {
+	case model.AliasPhone:
+		phone, err := profileService.NormalizePhone(target.Value)
+		if err != nil {
+			return target, ErrInvalidAliasValue
+		}
+		target.Value = phone
+	case model.AliasEmail:
+		email := normalizeEmail(target.Value)
+		if at := strings.LastIndex(email, "@"); at <= 0 || at == len(email)-1 {
+			return target, ErrInvalidAliasValue
+		}
+		target.Value = email
+	default:
+		return target, ErrInvalidAliasType
+	}
+	return target, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
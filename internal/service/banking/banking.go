@@ -1,30 +1,134 @@
 package banking
 
 import (
+	"BankingApp/internal/config"
 	"BankingApp/internal/model"
+	"BankingApp/internal/service"
+	fxService "BankingApp/internal/service/fx"
 	"BankingApp/internal/storage"
+	"BankingApp/pkg/clock"
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxDescriptionLength — предельная длина назначения платежа в символах
+const MaxDescriptionLength = 210
+
+var (
+	ErrAccountFrozen       = errors.New("account is frozen")
+	ErrAccountClosed       = errors.New("account is closed")
+	ErrBalanceNotZero      = errors.New("account balance must be zero to close it")
+	ErrAccountNotFound     = errors.New("account not found")
+	ErrDescriptionTooLong  = fmt.Errorf("description must be at most %d characters", MaxDescriptionLength)
+	ErrInvalidCurrency     = errors.New("unsupported currency code")
+	ErrInsufficientFunds   = errors.New("insufficient balance")
+	ErrQuoteNotApplicable  = errors.New("fx quote is not applicable to a same-currency transfer")
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrNotReversible       = errors.New("transaction cannot be reversed")
+	ErrInvalidReversal     = errors.New("reversal amount must be positive and not exceed the unreversed amount")
+	// ErrAlreadyTransferred — перевод с этим ключом уже проведен
+	ErrAlreadyTransferred = errors.New("transfer already completed")
 )
 
 type BankingService struct {
-	storage storage.BankingStorage
+	storage   storage.BankingStorage
+	fx        service.FXService
+	notifier  service.NotificationService
+	clock     clock.Clock
+	limits    config.Limits
+	overdraft config.Overdraft
+	// products — сберегательные продукты из конфигурации, productBatch — размер пачки фоновой задачи
+	products     []model.Product
+	productBatch int
+	// реквизиты для номеров счетов
+	bic            string
+	branch         string
+	balanceAccount string
 }
 
-func NewBankingService(storage storage.BankingStorage) *BankingService {
-	return &BankingService{storage: storage}
+func NewBankingService(storage storage.BankingStorage, fx service.FXService, notifier service.NotificationService,
+	clk clock.Clock, cfg *config.Config) (*BankingService, error) {
+	bankingCfg := cfg.Banking
+	if len(bankingCfg.BIC) != 9 || !isDigits(bankingCfg.BIC) {
+		return nil, fmt.Errorf("banking.bic must be 9 digits, got %q", bankingCfg.BIC)
+	}
+	if len(bankingCfg.Branch) != 4 || !isDigits(bankingCfg.Branch) {
+		return nil, fmt.Errorf("banking.branch must be 4 digits, got %q", bankingCfg.Branch)
+	}
+	if len(bankingCfg.BalanceAccount) != 5 || !isDigits(bankingCfg.BalanceAccount) {
+		return nil, fmt.Errorf("banking.balance_account must be 5 digits, got %q", bankingCfg.BalanceAccount)
+	}
+	if err := validateLimitRules(cfg.Limits.Rules); err != nil {
+		return nil, err
+	}
+	overdraft, err := validateOverdraft(cfg.Overdraft)
+	if err != nil {
+		return nil, err
+	}
+	products, err := validateProducts(cfg.Products)
+	if err != nil {
+		return nil, err
+	}
+	return &BankingService{
+		storage:        storage,
+		fx:             fx,
+		notifier:       notifier,
+		clock:          clk,
+		limits:         cfg.Limits,
+		overdraft:      overdraft,
+		products:       products,
+		productBatch:   cfg.Products.Batch,
+		bic:            bankingCfg.BIC,
+		branch:         bankingCfg.Branch,
+		balanceAccount: bankingCfg.BalanceAccount,
+	}, nil
 }
 
 func (s *BankingService) CreateAccount(ctx context.Context, userID string, currency string) (*model.Account, error) {
-	return s.storage.CreateAccount(ctx, userID, currency)
+	currency, ok := model.NormalizeCurrency(currency)
+	if !ok {
+		return nil, ErrInvalidCurrency
+	}
+	var account *model.Account
+	err := s.withUniqueNumber(currency, func(number string) error {
+		var err error
+		account, err = s.storage.CreateAccount(ctx, userID, currency, number)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
 }
 
 func (s *BankingService) Deposit(ctx context.Context, accountID int64, amount float64) error {
 	if amount <= 0 {
 		return errors.New("amount must be positive")
 	}
-	return s.storage.UpdateAccountBalance(ctx, accountID, amount)
+	account, err := s.storage.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	if !account.IsActive {
+		return ErrAccountClosed
+	}
+	if account.Type == model.AccountTermDeposit {
+		return ErrTermDeposit
+	}
+	amount = model.RoundAmount(amount, account.Currency)
+	if amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	if err := s.storage.UpdateAccountBalance(ctx, accountID, amount); err != nil {
+		return err
+	}
+	if account.Balance < 0 {
+		s.warnOverdraftUsage(ctx, accountID)
+	}
+	return nil
 }
 
 func (s *BankingService) Withdraw(ctx context.Context, accountID int64, amount float64) error {
@@ -35,51 +139,193 @@ func (s *BankingService) Withdraw(ctx context.Context, accountID int64, amount f
 	if err != nil {
 		return err
 	}
-	if account.Balance < amount {
-		return errors.New("insufficient balance")
+	if !account.IsActive {
+		return ErrAccountClosed
+	}
+	if account.DebitBlocked() {
+		return ErrAccountFrozen
+	}
+	if account.Type == model.AccountTermDeposit {
+		return ErrTermDeposit
+	}
+	amount = model.RoundAmount(amount, account.Currency)
+	if amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	if account.Available() < amount {
+		return ErrInsufficientFunds
+	}
+	limits, err := s.effectiveLimits(ctx, account, model.LimitWithdraw)
+	if err != nil {
+		return err
+	}
+	err = s.storage.WithdrawFunds(ctx, accountID, amount, limits, s.clock.Now())
+	switch {
+	case errors.Is(err, storage.ErrInsufficientFunds):
+		return ErrInsufficientFunds
+	case errors.Is(err, storage.ErrAccountClosed):
+		return ErrAccountClosed
+	case err != nil:
+		return limitError(err, model.LimitWithdraw)
+	}
+	s.warnOverdraftUsage(ctx, accountID)
+	return nil
+}
+
+// Transfer списывает amount в валюте счета отправителя. Если валюты счетов различаются, сумма зачисления
+// считается по котировке quoteID, а без нее — по текущему курсу со спредом.
+func (s *BankingService) Transfer(ctx context.Context, fromAccountID, toAccountID int64, amount float64, quoteID, description string) (*model.Transfer, error) {
+	return s.transfer(ctx, "", fromAccountID, toAccountID, amount, quoteID, description)
+}
+
+// TransferOnce проводит перевод по текущему курсу не больше одного раза на ключ idempotencyKey;
+// повтор, в том числе после сбоя между переводом и его учетом у вызывающего, дает ErrAlreadyTransferred.
+func (s *BankingService) TransferOnce(ctx context.Context, idempotencyKey string, fromAccountID, toAccountID int64, amount float64, description string) (*model.Transfer, error) {
+	// ключ проверяется до остатка: уже проведенный платеж не должен превращаться в отказ из-за нехватки средств
+	done, err := s.storage.HasTransfer(ctx, idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	if done {
+		return nil, ErrAlreadyTransferred
 	}
-	return s.storage.UpdateAccountBalance(ctx, accountID, -amount)
+	return s.transfer(ctx, idempotencyKey, fromAccountID, toAccountID, amount, "", description)
 }
 
-func (s *BankingService) Transfer(ctx context.Context, fromAccountID, toAccountID int64, amount float64) error {
+func (s *BankingService) transfer(ctx context.Context, idempotencyKey string, fromAccountID, toAccountID int64, amount float64, quoteID, description string) (*model.Transfer, error) {
 	if fromAccountID == toAccountID {
-		return errors.New("cannot transfer to the same account")
+		return nil, errors.New("cannot transfer to the same account")
 	}
 	if amount <= 0 {
-		return errors.New("amount must be positive")
+		return nil, errors.New("amount must be positive")
+	}
+	description = strings.TrimSpace(description)
+	if utf8.RuneCountInString(description) > MaxDescriptionLength {
+		return nil, ErrDescriptionTooLong
 	}
 	from, err := s.storage.GetAccountByID(ctx, fromAccountID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if !from.IsActive {
+		return nil, ErrAccountClosed
 	}
-	_, err = s.storage.GetAccountByID(ctx, toAccountID)
+	if from.DebitBlocked() {
+		return nil, ErrAccountFrozen
+	}
+	to, err := s.storage.GetAccountByID(ctx, toAccountID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if !to.IsActive {
+		return nil, ErrAccountClosed
+	}
+	// средства вклада возвращаются только по окончании срока или при закрытии
+	if from.Type == model.AccountTermDeposit || to.Type == model.AccountTermDeposit {
+		return nil, ErrTermDeposit
 	}
-	if from.Balance < amount {
-		return errors.New("insufficient balance")
+	amount = model.RoundAmount(amount, from.Currency)
+	if amount <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+	if from.Available() < amount {
+		return nil, ErrInsufficientFunds
+	}
+	transfer := &model.Transfer{
+		FromAccountID:  from.ID,
+		ToAccountID:    to.ID,
+		DebitAmount:    amount,
+		DebitCurrency:  from.Currency,
+		CreditAmount:   amount,
+		CreditCurrency: to.Currency,
+		Description:    description,
+		IdempotencyKey: idempotencyKey,
 	}
-	tx, err := s.storage.BeginTransaction(ctx)
+	if from.Currency != to.Currency {
+		var quote *model.FXQuote
+		if quoteID != "" {
+			quote, err = s.fx.QuoteForTransfer(ctx, quoteID, from.UserID, from.Currency, to.Currency, amount)
+		} else {
+			quote, err = s.fx.Convert(ctx, from.Currency, to.Currency, amount)
+		}
+		if err != nil {
+			return nil, err
+		}
+		transfer.CreditAmount = quote.ConvertedAmount
+		transfer.Rate = &quote.Rate
+		transfer.QuoteID = quote.ID
+	} else if quoteID != "" {
+		return nil, ErrQuoteNotApplicable
+	}
+	limits, err := s.effectiveLimits(ctx, from, model.LimitTransfer)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer tx.Rollback(ctx)
-	if err := s.storage.UpdateAccountBalance(ctx, fromAccountID, -amount); err != nil {
-		return err
+	err = s.storage.TransferFunds(ctx, transfer, limits, s.clock.Now())
+	switch {
+	case errors.Is(err, storage.ErrInsufficientFunds):
+		return nil, ErrInsufficientFunds
+	case errors.Is(err, storage.ErrAccountClosed):
+		return nil, ErrAccountClosed
+	case errors.Is(err, storage.ErrQuoteUnavailable):
+		return nil, fxService.ErrQuoteExpired
+	case errors.Is(err, storage.ErrAlreadyExists):
+		return nil, ErrAlreadyTransferred
+	case err != nil:
+		return nil, limitError(err, model.LimitTransfer)
+	}
+	s.warnOverdraftUsage(ctx, from.ID)
+	if to.Balance < 0 {
+		s.warnOverdraftUsage(ctx, to.ID)
 	}
-	if err := s.storage.UpdateAccountBalance(ctx, toAccountID, amount); err != nil {
+	return transfer, nil
+}
+
+// CloseAccount закрывает счет с нулевым остатком, карты счета закрываются вместе с ним.
+// Вклад закрывается с возвратом средств на счет выплаты, до окончания срока — с потерей части процентов.
+func (s *BankingService) CloseAccount(ctx context.Context, accountID int64) error {
+	account, err := s.storage.GetAccountByID(ctx, accountID)
+	if err != nil {
 		return err
 	}
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("commit error: %w", err)
+	switch {
+	case account.Type == model.AccountTermDeposit:
+		return s.closeDeposit(ctx, account)
+	case account.Type == model.AccountSavings && account.IsActive && account.Balance == 0 && account.Accrued > 0:
+		// начисленные проценты выплачиваются, чтобы клиент не потерял их при закрытии; их придется вывести
+		_, err := s.storage.CapitalizeInterest(ctx, accountID, dateOf(s.clock.Now()).AddDate(0, 0, 1), "Проценты на остаток при закрытии счета", s.clock.Now())
+		if err != nil {
+			return err
+		}
 	}
-	return nil
+	err = s.storage.CloseAccount(ctx, accountID, s.clock.Now())
+	switch {
+	case errors.Is(err, storage.ErrBalanceNotZero):
+		return ErrBalanceNotZero
+	case errors.Is(err, storage.ErrAccountClosed):
+		return ErrAccountClosed
+	}
+	return err
+}
+
+// SetAccountFrozen замораживает счет по просьбе владельца или снимает такую заморозку.
+// Заморозку сотрудником банка владелец снять не может.
+func (s *BankingService) SetAccountFrozen(ctx context.Context, accountID int64, frozen bool) error {
+	err := s.storage.SetAccountOwnerFrozen(ctx, accountID, frozen)
+	if errors.Is(err, storage.ErrAccountClosed) {
+		return ErrAccountClosed
+	}
+	return err
 }
 
 func (s *BankingService) GetAccountsByUser(ctx context.Context, userID string) ([]*model.Account, error) {
 	return s.storage.GetAccountsByUser(ctx, userID)
 }
 
+func (s *BankingService) GetTransactions(ctx context.Context, accountID int64) ([]*model.Transaction, error) {
+	return s.storage.GetTransactionsByAccount(ctx, accountID)
+}
+
 func (s *BankingService) GetAccountByID(ctx context.Context, accountID int64) (*model.Account, error) {
 	return s.storage.GetAccountByID(ctx, accountID)
 }
@@ -0,0 +1,184 @@
+package banking
+
+import (
+	"BankingApp/internal/config"
+	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrLimitExceeded         = errors.New("operation limit exceeded")
+	ErrInvalidLimitOperation = errors.New("limits apply to withdraw and transfer operations only")
+	ErrInvalidLimits         = errors.New("limits can only be lowered: each value must be positive and not above the account limit")
+	ErrInvalidLimitExpiry    = errors.New("limits can be lowered only temporarily: expires_at must be in the future and within the allowed period")
+	ErrLimitOverrideNotFound = errors.New("limits are not lowered")
+)
+
+// LimitError — операция отклонена лимитом счета, Code — машиночитаемый код из model.Limit*Exceeded
+type LimitError struct {
+	Operation string
+	Code      string
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s limit exceeded: %s", e.Operation, e.Code)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// GetLimits возвращает лимиты снятий и переводов по счету вместе с использованной частью
+func (s *BankingService) GetLimits(ctx context.Context, accountID int64) ([]*model.AccountLimits, error) {
+	account, err := s.storage.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	base, err := s.baseLimits(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	result := make([]*model.AccountLimits, 0, len(base))
+	for _, operation := range []string{model.LimitWithdraw, model.LimitTransfer} {
+		limits := &model.AccountLimits{Operation: operation, Base: base[operation], Effective: base[operation]}
+		limits.Override, err = s.storage.GetLimitOverride(ctx, account.ID, operation, now)
+		if err != nil {
+			return nil, err
+		}
+		if limits.Override != nil {
+			limits.Effective = limits.Base.Lower(limits.Override.Limits)
+		}
+		limits.Usage, err = s.storage.GetLimitUsage(ctx, account.ID, operation, now)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, limits)
+	}
+	return result, nil
+}
+
+// LowerLimits временно снижает лимиты операции по счету до expiresAt. Нулевое значение оставляет
+// ограничение по тарифу, поднять лимит выше тарифа нельзя. Новое снижение заменяет предыдущее.
+func (s *BankingService) LowerLimits(ctx context.Context, accountID int64, operation string, limits model.Limits, expiresAt time.Time) (*model.LimitOverride, error) {
+	if !model.ValidLimitOperation(operation) {
+		return nil, ErrInvalidLimitOperation
+	}
+	now := s.clock.Now()
+	if !expiresAt.After(now) || (s.limits.MaxOverridePeriod > 0 && expiresAt.After(now.Add(time.Duration(s.limits.MaxOverridePeriod)))) {
+		return nil, ErrInvalidLimitExpiry
+	}
+	account, err := s.storage.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if !account.IsActive {
+		return nil, ErrAccountClosed
+	}
+	base, err := s.baseLimits(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	limits.PerOperation = model.RoundAmount(limits.PerOperation, account.Currency)
+	limits.Daily = model.RoundAmount(limits.Daily, account.Currency)
+	limits.Monthly = model.RoundAmount(limits.Monthly, account.Currency)
+	if limits == (model.Limits{}) || !lowersAmount(limits.PerOperation, base[operation].PerOperation) ||
+		!lowersAmount(limits.Daily, base[operation].Daily) || !lowersAmount(limits.Monthly, base[operation].Monthly) ||
+		!lowersAmount(float64(limits.DailyCount), float64(base[operation].DailyCount)) ||
+		!lowersAmount(float64(limits.MonthlyCount), float64(base[operation].MonthlyCount)) {
+		return nil, ErrInvalidLimits
+	}
+	override := &model.LimitOverride{
+		AccountID: account.ID,
+		Operation: operation,
+		Limits:    limits,
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	}
+	if err := s.storage.SaveLimitOverride(ctx, override); err != nil {
+		return nil, err
+	}
+	return override, nil
+}
+
+// ResetLimits досрочно возвращает лимиты операции по счету к тарифным
+func (s *BankingService) ResetLimits(ctx context.Context, accountID int64, operation string) error {
+	if !model.ValidLimitOperation(operation) {
+		return ErrInvalidLimitOperation
+	}
+	deleted, err := s.storage.DeleteLimitOverride(ctx, accountID, operation, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrLimitOverrideNotFound
+	}
+	return nil
+}
+
+// effectiveLimits — лимиты операции по тарифу счета с учетом снижения клиентом
+func (s *BankingService) effectiveLimits(ctx context.Context, account *model.Account, operation string) (model.Limits, error) {
+	base, err := s.baseLimits(ctx, account)
+	if err != nil {
+		return model.Limits{}, err
+	}
+	override, err := s.storage.GetLimitOverride(ctx, account.ID, operation, s.clock.Now())
+	if err != nil {
+		return model.Limits{}, err
+	}
+	if override == nil {
+		return base[operation], nil
+	}
+	return base[operation].Lower(override.Limits), nil
+}
+
+// baseLimits — лимиты по первому правилу конфигурации, подходящему к типу счета, статусу KYC владельца и валюте
+func (s *BankingService) baseLimits(ctx context.Context, account *model.Account) (map[string]model.Limits, error) {
+	kycStatus, err := s.storage.GetKYCStatus(ctx, account.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range s.limits.Rules {
+		if (rule.AccountType == "" || rule.AccountType == account.Type) &&
+			(rule.KYCStatus == "" || rule.KYCStatus == kycStatus) &&
+			(rule.Currency == "" || rule.Currency == account.Currency) {
+			return map[string]model.Limits{
+				model.LimitWithdraw: model.Limits(rule.Withdraw),
+				model.LimitTransfer: model.Limits(rule.Transfer),
+			}, nil
+		}
+	}
+	return map[string]model.Limits{}, nil
+}
+
+// lowersAmount — значение не задано или не превышает тарифное ограничение (0 — без ограничения)
+func lowersAmount(value, base float64) bool {
+	if value < 0 {
+		return false
+	}
+	return value == 0 || base == 0 || value <= base
+}
+
+// limitError переводит отказ хранилища по лимиту в ошибку сервиса с кодом для клиента
+func limitError(err error, operation string) error {
+	var limitErr *storage.LimitError
+	if errors.As(err, &limitErr) {
+		return &LimitError{Operation: operation, Code: limitErr.Code}
+	}
+	return err
+}
+
+func validateLimitRules(rules []config.LimitRule) error {
+	for i, rule := range rules {
+		for _, limits := range []config.OperationLimits{rule.Withdraw, rule.Transfer} {
+			if limits.PerOperation < 0 || limits.Daily < 0 || limits.Monthly < 0 || limits.DailyCount < 0 || limits.MonthlyCount < 0 {
+				return fmt.Errorf("limits.rules[%d]: limits must not be negative", i)
+			}
+		}
+	}
+	return nil
+}
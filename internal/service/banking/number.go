@@ -0,0 +1,143 @@
+package banking
+
+import (
+	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Номер счета по схеме Банка России: балансовый счет второго порядка (5 цифр), код валюты (3),
+// защитный ключ (1), код подразделения банка (4) и порядковый номер счета (7)
+const (
+	accountNumberLength = 20
+	controlKeyIndex     = 8
+	serialLength        = 7
+	// numberAttempts — сколько раз подбираем порядковый номер при совпадении с существующим счетом
+	numberAttempts = 5
+)
+
+// rubAccountCode — в номерах рублевых счетов указывается код 810, а не 643 из ISO 4217
+const rubAccountCode = "810"
+
+var ErrInvalidAccountNumber = errors.New("invalid account number")
+
+// controlWeights — весовые коэффициенты расчета защитного ключа, повторяются по всей длине
+var controlWeights = [3]int{7, 1, 3}
+
+// NormalizeAccountNumber убирает пробелы и проверяет длину и защитный ключ номера счета в банке с этим БИК
+func NormalizeAccountNumber(number, bic string) (string, error) {
+	number = strings.ReplaceAll(strings.TrimSpace(number), " ", "")
+	if len(number) != accountNumberLength || !isDigits(number) || len(bic) != 9 || !isDigits(bic) {
+		return "", ErrInvalidAccountNumber
+	}
+	if controlSum(bic, number)%10 != 0 {
+		return "", ErrInvalidAccountNumber
+	}
+	return number, nil
+}
+
+// controlSum — сумма младших разрядов произведений цифр последних трех цифр БИК и номера счета на веса.
+// У номера с верным защитным ключом она кратна 10.
+func controlSum(bic, number string) int {
+	digits := bic[len(bic)-3:] + number
+	sum := 0
+	for i := range len(digits) {
+		sum += int(digits[i]-'0') * controlWeights[i%len(controlWeights)] % 10
+	}
+	return sum
+}
+
+// withControlKey рассчитывает защитный ключ и подставляет его в номер
+func withControlKey(bic, number string) string {
+	raw := []byte(number)
+	raw[controlKeyIndex] = '0'
+	key := controlSum(bic, string(raw)) % 10 * 3 % 10
+	raw[controlKeyIndex] = byte('0' + key)
+	return string(raw)
+}
+
+func accountCurrencyCode(currency string) string {
+	if currency == model.BaseCurrency {
+		return rubAccountCode
+	}
+	return model.CurrencyNumericCode(currency)
+}
+
+// generateAccountNumber выпускает номер счета в валюте со случайным порядковым номером
+func (s *BankingService) generateAccountNumber(currency string) (string, error) {
+	code := accountCurrencyCode(currency)
+	if code == "" {
+		return "", ErrInvalidCurrency
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(10_000_000))
+	if err != nil {
+		return "", fmt.Errorf("generate account number: %w", err)
+	}
+	number := fmt.Sprintf("%s%s0%s%0*d", s.balanceAccount, code, s.branch, serialLength, serial.Int64())
+	return withControlKey(s.bic, number), nil
+}
+
+// GetAccountByNumber ищет счет банка по номеру; номер с неверным ключом не ищется
+func (s *BankingService) GetAccountByNumber(ctx context.Context, number string) (*model.Account, error) {
+	number, err := NormalizeAccountNumber(number, s.bic)
+	if err != nil {
+		return nil, err
+	}
+	account, err := s.storage.GetAccountByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, ErrAccountNotFound
+	}
+	return account, nil
+}
+
+// AssignAccountNumbers выдает номера счетам, открытым до появления номеров, не больше batch за вызов
+func (s *BankingService) AssignAccountNumbers(ctx context.Context, batch int) (int, error) {
+	accounts, err := s.storage.GetAccountsWithoutNumber(ctx, batch)
+	if err != nil {
+		return 0, err
+	}
+	assigned := 0
+	for _, account := range accounts {
+		err := s.withUniqueNumber(account.Currency, func(number string) error {
+			return s.storage.SetAccountNumber(ctx, account.ID, number)
+		})
+		if err != nil {
+			return assigned, err
+		}
+		assigned++
+	}
+	return assigned, nil
+}
+
+// withUniqueNumber подбирает номер, пока save не перестанет возвращать storage.ErrAlreadyExists
+func (s *BankingService) withUniqueNumber(currency string, save func(number string) error) error {
+	for range numberAttempts {
+		number, err := s.generateAccountNumber(currency)
+		if err != nil {
+			return err
+		}
+		err = save(number)
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			continue
+		}
+		return err
+	}
+	return errors.New("could not pick a unique account number")
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,172 @@
+package banking
+
+import (
+	"BankingApp/internal/config"
+	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+var (
+	ErrOverdraftNotAllowed = errors.New("overdraft is only available on current accounts")
+	ErrInvalidOverdraft    = errors.New("overdraft limit must not be negative and rate must be within the allowed range")
+	ErrOverdraftInUse      = errors.New("overdraft limit cannot be lower than the overdraft already used")
+)
+
+// PlanOverdraft проверяет новые условия овердрафта и возвращает лимит, округленный до валюты счета.
+// Сами условия меняет сотрудник банка через AdminService.
+func (s *BankingService) PlanOverdraft(ctx context.Context, accountID int64, limit, rate float64) (float64, error) {
+	if limit < 0 || rate < 0 || rate > s.overdraft.MaxRate {
+		return 0, ErrInvalidOverdraft
+	}
+	account, err := s.storage.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return 0, err
+	}
+	if !account.IsActive {
+		return 0, ErrAccountClosed
+	}
+	if account.Type != model.AccountCurrent && limit > 0 {
+		return 0, ErrOverdraftNotAllowed
+	}
+	limit = model.RoundAmount(limit, account.Currency)
+	if account.Balance+limit < 0 {
+		return 0, ErrOverdraftInUse
+	}
+	return limit, nil
+}
+
+// OverdraftError переводит отказ хранилища при смене условий овердрафта в ошибку сервиса
+func OverdraftError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrInsufficientFunds):
+		return ErrOverdraftInUse
+	case errors.Is(err, storage.ErrAccountClosed):
+		return ErrAccountClosed
+	}
+	return err
+}
+
+// ProcessOverdrafts — ежедневное обслуживание овердрафтов: начисляет проценты по вчерашний день на остаток,
+// сложившийся к запуску, а с началом месяца списывает проценты, начисленные за прошлые месяцы.
+// Дни, пропущенные из-за простоя задачи, начисляются по порядку на тот же остаток.
+// Повторный запуск в тот же день ничего не начисляет повторно.
+func (s *BankingService) ProcessOverdrafts(ctx context.Context) (accrued, posted int, err error) {
+	now := s.clock.Now()
+	today := dateOf(now)
+	yesterday := today.AddDate(0, 0, -1)
+	start, err := s.storage.GetOverdraftAccrualStart(ctx, yesterday)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start != nil {
+		for day := dateOf(*start); !day.After(yesterday); day = day.AddDate(0, 0, 1) {
+			n, err := s.accrueOverdraftDay(ctx, day)
+			accrued += n
+			if err != nil {
+				return accrued, posted, err
+			}
+		}
+	}
+
+	periodStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	description := fmt.Sprintf("Проценты за овердрафт по %s", periodStart.AddDate(0, 0, -1).Format("02.01.2006"))
+	for {
+		accounts, err := s.storage.GetAccountsWithInterestDue(ctx, periodStart, s.overdraft.Batch)
+		if err != nil {
+			return accrued, posted, err
+		}
+		for _, account := range accounts {
+			charge, err := s.storage.PostOverdraftInterest(ctx, account.ID, periodStart, description, now)
+			if err != nil {
+				return accrued, posted, err
+			}
+			if charge == nil {
+				continue
+			}
+			posted++
+			// ошибка уведомления не отменяет списания
+			_ = s.notifier.Notify(ctx, account.UserID, "Списаны проценты за овердрафт",
+				fmt.Sprintf("По счету %s списаны проценты за овердрафт: %.2f %s.", account.Number, -charge.Amount, charge.Currency))
+			s.warnOverdraftUsage(ctx, account.ID)
+		}
+		if len(accounts) < s.overdraft.Batch {
+			break
+		}
+	}
+	return accrued, posted, nil
+}
+
+// accrueOverdraftDay начисляет проценты за день day пачками по overdraft.batch счетов
+func (s *BankingService) accrueOverdraftDay(ctx context.Context, day time.Time) (int, error) {
+	accrued := 0
+	for {
+		n, err := s.storage.AccrueOverdraftInterest(ctx, day, daysInYear(day.Year()), s.overdraft.Batch)
+		accrued += n
+		if err != nil || n < s.overdraft.Batch {
+			return accrued, err
+		}
+	}
+}
+
+// warnOverdraftUsage предупреждает клиента, когда использование овердрафта пересекает очередной порог.
+// Когда долг уменьшается, счетчик порогов снижается без уведомления, чтобы следующее пересечение снова
+// вызвало предупреждение. Ошибки не возвращаются: предупреждение не должно отменять уже проведенную операцию.
+func (s *BankingService) warnOverdraftUsage(ctx context.Context, accountID int64) {
+	if len(s.overdraft.WarningThresholds) == 0 {
+		return
+	}
+	account, err := s.storage.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return
+	}
+	usage := account.OverdraftUsage()
+	crossed := 0
+	for _, threshold := range s.overdraft.WarningThresholds {
+		if usage >= threshold {
+			crossed++
+		}
+	}
+	if crossed == account.OverdraftWarned {
+		return
+	}
+	updated, err := s.storage.SetOverdraftWarned(ctx, account.ID, account.OverdraftWarned, crossed)
+	if err != nil || !updated || crossed < account.OverdraftWarned {
+		return
+	}
+	body := fmt.Sprintf("По счету %s использовано %.0f%% овердрафта: остаток %.2f %s при лимите %.2f %s.",
+		account.Number, math.Floor(usage*100), account.Balance, account.Currency, account.OverdraftLimit, account.Currency)
+	_ = s.notifier.Notify(ctx, account.UserID, "Использование овердрафта", body)
+}
+
+func validateOverdraft(cfg config.Overdraft) (config.Overdraft, error) {
+	if cfg.Batch <= 0 {
+		return cfg, fmt.Errorf("overdraft.batch must be positive")
+	}
+	if cfg.MaxRate < 0 {
+		return cfg, fmt.Errorf("overdraft.max_rate must not be negative")
+	}
+	for _, threshold := range cfg.WarningThresholds {
+		if threshold <= 0 || threshold > 1 {
+			return cfg, fmt.Errorf("overdraft.warning_thresholds must be in (0, 1], got %v", threshold)
+		}
+	}
+	cfg.WarningThresholds = slices.Clone(cfg.WarningThresholds)
+	slices.Sort(cfg.WarningThresholds)
+	return cfg, nil
+}
+
+// dateOf — календарная дата момента t в полночь UTC
+func dateOf(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func daysInYear(year int) int {
+	return time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+}
@@ -0,0 +1,333 @@
+package banking
+
+import (
+	"BankingApp/internal/config"
+	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrProductNotFound = errors.New("product not found")
+	ErrBelowMinAmount  = errors.New("amount is below the product minimum")
+	ErrProductCurrency = errors.New("funding account currency does not match the product")
+	ErrTermDeposit     = errors.New("term deposit does not allow top-ups or withdrawals before maturity")
+	ErrNotTermDeposit  = errors.New("account is not a term deposit")
+	ErrDepositChanged  = errors.New("deposit term has just changed, try again")
+	ErrNoPayoutAccount = errors.New("no open account in the deposit currency to pay the deposit out to")
+)
+
+// GetProducts — сберегательные продукты, которые можно открыть
+func (s *BankingService) GetProducts() []model.Product {
+	return s.products
+}
+
+// OpenProduct открывает сберегательный счет или вклад и переводит на него amount со счета fundingAccountID клиента.
+// Без rollover вклад продлевается по умолчанию продукта.
+func (s *BankingService) OpenProduct(ctx context.Context, userID, code string, fundingAccountID int64, amount float64, rollover *bool) (*model.Account, error) {
+	product, ok := s.product(code)
+	if !ok {
+		return nil, ErrProductNotFound
+	}
+	amount = model.RoundAmount(amount, product.Currency)
+	if amount < 0 || amount < product.MinAmount || (product.Type == model.AccountTermDeposit && amount == 0) {
+		return nil, ErrBelowMinAmount
+	}
+	open := &model.OpenProduct{
+		UserID:    userID,
+		Product:   product,
+		FundingID: fundingAccountID,
+		Amount:    amount,
+	}
+	var limits model.Limits
+	if amount > 0 {
+		funding, err := s.storage.GetAccountByID(ctx, fundingAccountID)
+		if err != nil || funding.UserID != userID {
+			return nil, ErrAccountNotFound
+		}
+		switch {
+		case !funding.IsActive:
+			return nil, ErrAccountClosed
+		case funding.DebitBlocked():
+			return nil, ErrAccountFrozen
+		case funding.Type == model.AccountTermDeposit:
+			return nil, ErrTermDeposit
+		case funding.Currency != product.Currency:
+			return nil, ErrProductCurrency
+		case funding.Balance < amount:
+			return nil, ErrInsufficientFunds
+		}
+		// пополнение продукта — перевод со счета клиента и расходует тот же лимит
+		limits, err = s.effectiveLimits(ctx, funding, model.LimitTransfer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if product.Type == model.AccountTermDeposit {
+		open.Rollover = product.Rollover
+		if rollover != nil {
+			open.Rollover = *rollover
+		}
+		maturesOn := dateOf(s.clock.Now()).AddDate(0, 0, product.TermDays)
+		open.MaturesOn = &maturesOn
+	}
+
+	var account *model.Account
+	err := s.withUniqueNumber(product.Currency, func(number string) error {
+		open.Number = number
+		var err error
+		account, err = s.storage.OpenProductAccount(ctx, open, limits, s.clock.Now())
+		return err
+	})
+	if errors.Is(err, storage.ErrInsufficientFunds) {
+		return nil, ErrInsufficientFunds
+	}
+	if err != nil {
+		return nil, limitError(err, model.LimitTransfer)
+	}
+	return account, nil
+}
+
+// SetDepositRollover включает или отключает продление вклада по окончании срока
+func (s *BankingService) SetDepositRollover(ctx context.Context, accountID int64, rollover bool) error {
+	account, err := s.storage.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	if account.Type != model.AccountTermDeposit {
+		return ErrNotTermDeposit
+	}
+	err = s.storage.SetDepositRollover(ctx, accountID, rollover)
+	if errors.Is(err, storage.ErrAccountClosed) {
+		return ErrAccountClosed
+	}
+	return err
+}
+
+// ProcessInterest — ежедневное обслуживание сберегательных продуктов: начисляет проценты по вчерашний день,
+// с началом месяца капитализирует проценты сберегательных счетов и закрывает или продлевает вклады,
+// срок которых закончился. Дни, пропущенные из-за простоя задачи, начисляются по порядку на тот же остаток.
+// Повторный запуск в тот же день ничего не начисляет повторно.
+func (s *BankingService) ProcessInterest(ctx context.Context) (accrued, paid int, err error) {
+	now := s.clock.Now()
+	today := dateOf(now)
+	yesterday := today.AddDate(0, 0, -1)
+	for _, product := range s.products {
+		if product.Type != model.AccountSavings {
+			continue
+		}
+		if _, err := s.storage.SetProductRate(ctx, product.Code, product.Rate); err != nil {
+			return accrued, paid, err
+		}
+	}
+	start, err := s.storage.GetInterestAccrualStart(ctx, yesterday)
+	if err != nil {
+		return accrued, paid, err
+	}
+	if start != nil {
+		for day := dateOf(*start); !day.After(yesterday); day = day.AddDate(0, 0, 1) {
+			n, err := s.accrueInterestDay(ctx, day)
+			accrued += n
+			if err != nil {
+				return accrued, paid, err
+			}
+		}
+	}
+
+	periodStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	description := fmt.Sprintf("Проценты на остаток по %s", periodStart.AddDate(0, 0, -1).Format("02.01.2006"))
+	for {
+		accounts, err := s.storage.GetSavingsInterestDue(ctx, periodStart, s.productBatch)
+		if err != nil {
+			return accrued, paid, err
+		}
+		for _, account := range accounts {
+			credited, err := s.storage.CapitalizeInterest(ctx, account.ID, periodStart, description, now)
+			if err != nil {
+				return accrued, paid, err
+			}
+			if credited != nil {
+				paid++
+			}
+		}
+		if len(accounts) < s.productBatch {
+			break
+		}
+	}
+
+	for {
+		deposits, err := s.storage.GetMaturedDeposits(ctx, today, s.productBatch)
+		if err != nil {
+			return accrued, paid, err
+		}
+		for _, deposit := range deposits {
+			settled, err := s.settleMatured(ctx, deposit, now)
+			if err != nil {
+				return accrued, paid, err
+			}
+			if settled {
+				paid++
+			}
+		}
+		if len(deposits) < s.productBatch {
+			break
+		}
+	}
+	return accrued, paid, nil
+}
+
+// accrueInterestDay начисляет проценты за день day пачками по products.batch счетов
+func (s *BankingService) accrueInterestDay(ctx context.Context, day time.Time) (int, error) {
+	accrued := 0
+	for {
+		n, err := s.storage.AccrueInterest(ctx, day, daysInYear(day.Year()), s.productBatch)
+		accrued += n
+		if err != nil || n < s.productBatch {
+			return accrued, err
+		}
+	}
+}
+
+// settleMatured выплачивает проценты за срок вклада и продлевает его или возвращает средства на счет выплаты.
+// Если счет выплаты уже закрыт, вклад продлевается, чтобы средства продолжали приносить доход.
+func (s *BankingService) settleMatured(ctx context.Context, deposit *model.Account, now time.Time) (bool, error) {
+	rollover := deposit.Rollover || deposit.PayoutID == nil
+	if !rollover {
+		payout, err := s.storage.GetAccountByID(ctx, *deposit.PayoutID)
+		rollover = err != nil || !payout.IsActive
+	}
+	settlement := &model.DepositSettlement{
+		AccountID:    deposit.ID,
+		MaturesOn:    *deposit.MaturesOn,
+		Rollover:     rollover,
+		NextMaturity: deposit.MaturesOn.AddDate(0, 0, deposit.TermDays),
+		NextRate:     deposit.InterestRate,
+		Description:  fmt.Sprintf("Проценты по вкладу за срок по %s", deposit.MaturesOn.AddDate(0, 0, -1).Format("02.01.2006")),
+	}
+	// продленный вклад получает текущую ставку продукта
+	if product, ok := s.product(deposit.Product); ok {
+		settlement.NextRate = product.Rate
+	}
+	settled, err := s.storage.SettleDeposit(ctx, settlement, now)
+	if err != nil || !settled {
+		return false, err
+	}
+	// ошибка уведомления не отменяет выплаты
+	var body string
+	if rollover {
+		body = fmt.Sprintf("Вклад %s продлен до %s по ставке %.2f%% годовых.",
+			deposit.Number, settlement.NextMaturity.Format("02.01.2006"), settlement.NextRate*100)
+	} else {
+		body = fmt.Sprintf("Срок вклада %s закончился, средства с процентами переведены на счет выплаты.", deposit.Number)
+	}
+	_ = s.notifier.Notify(ctx, deposit.UserID, "Окончание срока вклада", body)
+	return true, nil
+}
+
+// closeDeposit закрывает вклад по просьбе клиента. До окончания срока часть процентов теряется.
+// Если счет выплаты уже закрыт, средства возвращаются на другой открытый счет клиента в валюте вклада.
+func (s *BankingService) closeDeposit(ctx context.Context, deposit *model.Account) error {
+	if !deposit.IsActive {
+		return ErrAccountClosed
+	}
+	payoutID, err := s.depositPayout(ctx, deposit)
+	if err != nil {
+		return err
+	}
+	now := s.clock.Now()
+	settlement := &model.DepositSettlement{
+		AccountID:   deposit.ID,
+		MaturesOn:   *deposit.MaturesOn,
+		Penalty:     deposit.EarlyPenalty,
+		PayoutID:    &payoutID,
+		Description: "Проценты по вкладу при досрочном закрытии",
+	}
+	// срок уже закончился, а фоновая задача до вклада еще не дошла
+	if !dateOf(now).Before(*deposit.MaturesOn) {
+		settlement.Penalty = 0
+		settlement.Description = fmt.Sprintf("Проценты по вкладу за срок по %s", deposit.MaturesOn.AddDate(0, 0, -1).Format("02.01.2006"))
+	}
+	settled, err := s.storage.SettleDeposit(ctx, settlement, now)
+	switch {
+	case errors.Is(err, storage.ErrAccountClosed):
+		return ErrAccountClosed
+	case err != nil:
+		return err
+	case !settled:
+		return ErrDepositChanged
+	}
+	return nil
+}
+
+// depositPayout возвращает счет выплаты вклада: сохраненный, пока он открыт, иначе открытый текущий
+// или сберегательный счет клиента в валюте вклада. ErrNoPayoutAccount — такого счета нет.
+func (s *BankingService) depositPayout(ctx context.Context, deposit *model.Account) (int64, error) {
+	if deposit.PayoutID != nil {
+		payout, err := s.storage.GetAccountByID(ctx, *deposit.PayoutID)
+		if err == nil && payout.IsActive && payout.Currency == deposit.Currency {
+			return payout.ID, nil
+		}
+	}
+	accounts, err := s.storage.GetAccountsByUser(ctx, deposit.UserID)
+	if err != nil {
+		return 0, err
+	}
+	for _, account := range accounts {
+		if account.IsActive && account.Type != model.AccountTermDeposit && account.Currency == deposit.Currency {
+			return account.ID, nil
+		}
+	}
+	return 0, ErrNoPayoutAccount
+}
+
+func (s *BankingService) product(code string) (model.Product, bool) {
+	for _, product := range s.products {
+		if product.Code == code {
+			return product, true
+		}
+	}
+	return model.Product{}, false
+}
+
+func validateProducts(cfg config.Products) ([]model.Product, error) {
+	if cfg.Batch <= 0 {
+		return nil, fmt.Errorf("products.batch must be positive")
+	}
+	products := make([]model.Product, 0, len(cfg.Items))
+	seen := make(map[string]bool, len(cfg.Items))
+	for i, item := range cfg.Items {
+		product := model.Product(item)
+		if product.Code == "" || seen[product.Code] {
+			return nil, fmt.Errorf("products.items[%d]: code must be unique and not empty", i)
+		}
+		seen[product.Code] = true
+		currency, ok := model.NormalizeCurrency(product.Currency)
+		if !ok {
+			return nil, fmt.Errorf("products.items[%d]: unsupported currency %q", i, product.Currency)
+		}
+		product.Currency = currency
+		if product.Rate < 0 || product.MinAmount < 0 {
+			return nil, fmt.Errorf("products.items[%d]: rate and min_amount must not be negative", i)
+		}
+		switch product.Type {
+		case model.AccountSavings:
+			if product.TermDays != 0 || product.EarlyPenalty != 0 || product.Rollover {
+				return nil, fmt.Errorf("products.items[%d]: savings product must not have term settings", i)
+			}
+		case model.AccountTermDeposit:
+			if product.TermDays <= 0 {
+				return nil, fmt.Errorf("products.items[%d]: term_days must be positive", i)
+			}
+			if product.EarlyPenalty < 0 || product.EarlyPenalty > 1 {
+				return nil, fmt.Errorf("products.items[%d]: early_penalty must be in [0, 1]", i)
+			}
+		default:
+			return nil, fmt.Errorf("products.items[%d]: unknown type %q", i, product.Type)
+		}
+		products = append(products, product)
+	}
+	return products, nil
+}
@@ -0,0 +1,93 @@
+package banking
+
+import (
+	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
+	"context"
+	"errors"
+	"math"
+)
+
+func (s *BankingService) GetTransaction(ctx context.Context, transactionID int64) (*model.Transaction, error) {
+	transaction, err := s.storage.GetTransaction(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if transaction == nil {
+		return nil, ErrTransactionNotFound
+	}
+	return transaction, nil
+}
+
+// PlanReversal рассчитывает компенсирующие проводки; сами проводки делает хранилище вместе с записью в журнал.
+// Перевод сторнируется на обеих ногах: вторая нога возвращается в той же доле,
+// а при возврате всего остатка — ровно на свой остаток, чтобы округление не накапливалось.
+func (s *BankingService) PlanReversal(ctx context.Context, transactionID int64, amount float64) ([]model.ReversalLeg, error) {
+	original, err := s.GetTransaction(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	switch original.Type {
+	case model.TransactionDeposit, model.TransactionWithdraw, model.TransactionAdjustment, model.TransactionTransfer,
+		model.TransactionOverdraftInterest, model.TransactionInterest:
+	default:
+		return nil, ErrNotReversible
+	}
+	if original.Status != model.TransactionSuccess || original.Amount == 0 {
+		return nil, ErrNotReversible
+	}
+	remaining := original.ReversibleAmount()
+	if amount == 0 {
+		amount = remaining
+	}
+	amount = model.RoundAmount(amount, original.Currency)
+	if amount <= 0 || amount > remaining {
+		return nil, ErrInvalidReversal
+	}
+	legs := []model.ReversalLeg{reversalLeg(original, amount)}
+	if original.Type != model.TransactionTransfer {
+		return legs, nil
+	}
+	// у переводов, проведенных до связывания ног, вторую ногу не найти
+	if original.CounterpartID == nil {
+		return nil, ErrNotReversible
+	}
+	counterpart, err := s.GetTransaction(ctx, *original.CounterpartID)
+	if err != nil {
+		return nil, err
+	}
+	part := counterpart.ReversibleAmount()
+	if amount < remaining {
+		part = math.Min(part, model.RoundAmount(amount*math.Abs(counterpart.Amount)/math.Abs(original.Amount), counterpart.Currency))
+	}
+	if part > 0 {
+		legs = append(legs, reversalLeg(counterpart, part))
+	}
+	return legs, nil
+}
+
+// reversalLeg — проводка, обратная по знаку исходной
+func reversalLeg(original *model.Transaction, amount float64) model.ReversalLeg {
+	if original.Amount > 0 {
+		amount = -amount
+	}
+	return model.ReversalLeg{
+		TransactionID: original.ID,
+		AccountID:     original.AccountID,
+		Amount:        amount,
+		Currency:      original.Currency,
+	}
+}
+
+// ReversalError переводит отказы хранилища при проведении сторно в ошибки сервиса
+func ReversalError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrReversalExceeded):
+		return ErrInvalidReversal
+	case errors.Is(err, storage.ErrInsufficientFunds):
+		return ErrInsufficientFunds
+	case errors.Is(err, storage.ErrAccountClosed):
+		return ErrAccountClosed
+	}
+	return err
+}
@@ -2,66 +2,119 @@ package cards
 
 import (
 	"BankingApp/internal/config"
+	"BankingApp/internal/keystore"
 	"BankingApp/internal/model"
+	"BankingApp/internal/service"
 	"BankingApp/internal/storage"
+	"BankingApp/pkg/clock"
 	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/sha256"
 	"encoding/binary"
+	"errors"
 	"fmt"
-	"log"
 	"math/big"
 	"time"
 
 	"crypto/rand"
 )
 
+// panAttempts — сколько раз перевыпускаем номер при совпадении отпечатка с существующей картой
+const panAttempts = 5
+
+var (
+	ErrCardNotFound = errors.New("карта не найдена")
+	ErrCardInactive = errors.New("карта неактивна")
+	ErrCardExpired  = errors.New("срок действия карты истек")
+	ErrWrongCVV     = errors.New("неверный CVV")
+	ErrNoCardholder = errors.New("требуется PIN или CVV")
+)
+
 type CardService struct {
-	storage storage.CardStorage
-	gcm     cipher.AEAD
+	storage        storage.CardStorage
+	keys           *keystore.KeyStore
+	notifier       service.NotificationService
+	clock          clock.Clock
+	pinMaxAttempts int
+	cvvWindow      time.Duration
+	renewal        config.Cards
+	// reencryptAfter — последняя карта, обработанная текущим проходом перешифрования (только для фоновой задачи)
+	reencryptAfter int64
 }
 
-func NewCardService(storage storage.CardStorage) *CardService {
-	service := &CardService{storage: storage}
-	// generate a new aes cipher using our 32 byte long key
-	c, err := aes.NewCipher([]byte(config.GetCardSecretKey()))
-	if err != nil {
-		log.Fatalf("%v", err)
+func NewCardService(storage storage.CardStorage, keys *keystore.KeyStore, notifier service.NotificationService, clk clock.Clock, cfg *config.Config) (*CardService, error) {
+	// без порога неверных попыток PIN блокировался бы с первой ошибки или не блокировался вовсе
+	if cfg.Cards.PINMaxAttempts <= 0 {
+		return nil, fmt.Errorf("cards.pin_max_attempts must be positive")
 	}
-	// gcm or Galois/Counter Mode, is a mode of operation
-	gcm, err := cipher.NewGCM(c)
-	if err != nil {
-		log.Fatalf("%v", err)
+	cs := &CardService{
+		storage:        storage,
+		keys:           keys,
+		notifier:       notifier,
+		clock:          clk,
+		pinMaxAttempts: cfg.Cards.PINMaxAttempts,
+		cvvWindow:      time.Duration(cfg.Cards.DynamicCVVWindow),
+		renewal:        cfg.Cards,
 	}
-	service.gcm = gcm
-	return service
+	if cs.cvvWindow <= 0 {
+		cs.cvvWindow = defaultCVVWindow
+	}
+	return cs, nil
 }
 
-func (cs *CardService) GenerateVirtualCard(ctx context.Context, accountID int64, cardholderName string) (*model.Card, error) {
-	pan, err := cs.generatePAN()
-	if err != nil {
-		return nil, err
-	}
+func (cs *CardService) GenerateVirtualCard(ctx context.Context, accountID int64, cardholderName string, dynamicCVV bool) (*model.Card, error) {
+	now := cs.clock.Now()
 	card := &model.Card{
 		AccountID:      accountID,
 		CardholderName: cardholderName,
-		PAN:            pan,
+		DynamicCVV:     dynamicCVV,
 		IsActive:       true,
-		CreatedAt:      time.Now(),
+		Status:         model.CardActive,
+		CreatedAt:      now,
 	}
-	card.GenerateTimeExpiry()
-	cvv, err := cs.generateCVV(card)
-	if err != nil {
+	card.GenerateTimeExpiry(now)
+	if err := cs.issueCard(ctx, card, cs.storage.CreateVirtualCard); err != nil {
 		return nil, err
 	}
-	card.CVV = cvv
+	var err error
+	if card.CVV, card.CVVExpiresAt, err = cs.currentCVV(card, now); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
 
-	id, err := cs.storage.CreateVirtualCard(ctx, card)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при создании карты: %w", err)
+// issueCard подбирает карте уникальный номер и сохраняет ее через save
+func (cs *CardService) issueCard(ctx context.Context, card *model.Card, save func(context.Context, *model.Card) (int64, error)) error {
+	for range panAttempts {
+		pan, err := cs.generatePAN()
+		if err != nil {
+			return err
+		}
+		card.PAN = pan
+		if err := cs.encryptPAN(card); err != nil {
+			return err
+		}
+		id, err := save(ctx, card)
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка при создании карты: %w", err)
+		}
+		card.ID = id
+		return nil
+	}
+	return errors.New("ошибка при создании карты: не удалось подобрать уникальный номер")
+}
+
+// FindByPAN ищет карту по номеру без перебора и расшифровки всех карт, nil — карта не найдена
+func (cs *CardService) FindByPAN(ctx context.Context, pan string) (*model.Card, error) {
+	card, err := cs.storage.FindByPAN(ctx, cs.keys.Fingerprint([]byte(pan)))
+	if err != nil || card == nil {
+		return nil, err
 	}
-	card.ID = id
+	card.PAN = pan
 	return card, nil
 }
 func (cs *CardService) GetCardsByAccount(ctx context.Context, accountID int64) ([]*model.Card, error) {
@@ -70,16 +123,154 @@ func (cs *CardService) GetCardsByAccount(ctx context.Context, accountID int64) (
 		return nil, fmt.Errorf("ошибка получения карт: %w", err)
 	}
 	for _, card := range cards {
-		cvv, err := cs.generateCVV(card)
+		pan, err := cs.keys.Decrypt(ctx, card.EncryptedPAN)
 		if err != nil {
+			return nil, fmt.Errorf("ошибка расшифровки карты: %w", err)
+		}
+		card.PAN = string(pan)
+		if card.CVV, card.CVVExpiresAt, err = cs.currentCVV(card, cs.clock.Now()); err != nil {
 			return nil, err
 		}
-		card.CVV = cvv
 	}
 	return cards, nil
 }
-func (cs *CardService) GetCardByIDForOwner(ctx context.Context, cardID, ownerUserID int64) (*model.Card, error) {
-	panic("implement")
+func (cs *CardService) GetCardByIDForOwner(ctx context.Context, cardID int64, ownerUserID string) (*model.Card, error) {
+	card, err := cs.storage.GetCardByID(ctx, cardID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения карты: %w", err)
+	}
+	if card == nil {
+		return nil, ErrCardNotFound
+	}
+	account, err := cs.storage.GetAccountByID(ctx, card.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения счета: %w", err)
+	}
+	if account.UserID != ownerUserID {
+		// не раскрываем существование чужих карт
+		return nil, ErrCardNotFound
+	}
+	pan, err := cs.keys.Decrypt(ctx, card.EncryptedPAN)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка расшифровки карты: %w", err)
+	}
+	card.PAN = string(pan)
+	if card.CVV, card.CVVExpiresAt, err = cs.currentCVV(card, cs.clock.Now()); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+// Authorize проверяет реквизиты карты или токена при операции, которую проводит клиент-владелец
+// или его машинный клиент. Чужая карта не отличается от несуществующей, и попытки ввода PIN по ней не учитываются.
+func (cs *CardService) Authorize(ctx context.Context, ownerUserID string, auth model.CardAuthorization) (*model.Card, error) {
+	now := cs.clock.Now()
+	var (
+		card *model.Card
+		err  error
+	)
+	if auth.Token != "" {
+		card, err = cs.resolveToken(ctx, auth, now)
+	} else {
+		card, err = cs.FindByPAN(ctx, auth.PAN)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if card == nil {
+		return nil, ErrCardNotFound
+	}
+	account, err := cs.storage.GetAccountByID(ctx, card.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения счета: %w", err)
+	}
+	if account.UserID != ownerUserID {
+		return nil, ErrCardNotFound
+	}
+	if !card.IsActive {
+		return nil, ErrCardInactive
+	}
+	if card.IsExpired(now) {
+		return nil, ErrCardExpired
+	}
+	switch {
+	case auth.PIN != "":
+		if err := cs.VerifyPIN(ctx, card.ID, auth.PIN); err != nil {
+			return nil, err
+		}
+	case auth.CVV != "":
+		if card.PAN == "" {
+			pan, err := cs.keys.Decrypt(ctx, card.EncryptedPAN)
+			if err != nil {
+				return nil, fmt.Errorf("ошибка расшифровки карты: %w", err)
+			}
+			card.PAN = string(pan)
+		}
+		ok, err := cs.verifyCVV(card, auth.CVV, now)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrWrongCVV
+		}
+	case auth.Token == "":
+		// операция по токену подтверждается привязкой к устройству/мерчанту
+		return nil, ErrNoCardholder
+	}
+	return card, nil
+}
+
+// ReencryptPANs перешифровывает активной версией ключа до batch карт,
+// зашифрованных устаревшими версиями. Возвращает число перешифрованных карт.
+// Карта, которую не удалось перешифровать, пропускается до следующего прохода, чтобы не останавливать ротацию;
+// ошибки по таким картам возвращаются вместе.
+func (cs *CardService) ReencryptPANs(ctx context.Context, batch int) (int, error) {
+	active := cs.keys.ActiveVersion()
+	cards, err := cs.storage.GetCardsWithStaleKey(ctx, active, cs.reencryptAfter, batch)
+	if err != nil {
+		return 0, err
+	}
+	if len(cards) < batch {
+		// проход закончен, следующий начнется сначала и повторит пропущенные карты
+		cs.reencryptAfter = 0
+	} else {
+		cs.reencryptAfter = cards[len(cards)-1].ID
+	}
+	var (
+		n    int
+		errs []error
+	)
+	for _, card := range cards {
+		if err := cs.reencryptPAN(ctx, card); err != nil {
+			errs = append(errs, fmt.Errorf("card %d: %w", card.ID, err))
+			continue
+		}
+		n++
+	}
+	return n, errors.Join(errs...)
+}
+
+func (cs *CardService) reencryptPAN(ctx context.Context, card *model.Card) error {
+	pan, err := cs.keys.Decrypt(ctx, card.EncryptedPAN)
+	if err != nil {
+		return err
+	}
+	card.PAN = string(pan)
+	if err := cs.encryptPAN(card); err != nil {
+		return err
+	}
+	return cs.storage.UpdateEncryptedPAN(ctx, card)
+}
+
+// encryptPAN заполняет шифртекст и поисковый отпечаток номера карты
+func (cs *CardService) encryptPAN(card *model.Card) error {
+	var err error
+	card.EncryptedPAN, card.KeyVersion, err = cs.keys.Encrypt([]byte(card.PAN))
+	if err != nil {
+		return fmt.Errorf("ошибка шифрования карты: %w", err)
+	}
+	card.PANFingerprint = cs.keys.Fingerprint([]byte(card.PAN))
+	return nil
 }
 
 func (cs *CardService) generateCVV(card *model.Card) (string, error) {
@@ -0,0 +1,50 @@
+package cards
+
+import (
+	"BankingApp/internal/model"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+const defaultCVVWindow = 5 * time.Minute
+
+// currentCVV возвращает CVV, действующий в момент now, и время его истечения
+// (nil для статического CVV)
+func (cs *CardService) currentCVV(card *model.Card, now time.Time) (string, *time.Time, error) {
+	if !card.DynamicCVV {
+		cvv, err := cs.generateCVV(card)
+		return cvv, nil, err
+	}
+	counter := now.UnixNano() / int64(cs.cvvWindow)
+	expiresAt := time.Unix(0, (counter+1)*int64(cs.cvvWindow))
+	return cs.dynamicCVV(card, counter), &expiresAt, nil
+}
+
+// verifyCVV сверяет CVV. Для динамического CVV принимается и предыдущее окно,
+// чтобы код, показанный перед самой сменой окна, успел дойти до мерчанта.
+func (cs *CardService) verifyCVV(card *model.Card, cvv string, now time.Time) (bool, error) {
+	if !card.DynamicCVV {
+		expected, err := cs.generateCVV(card)
+		if err != nil {
+			return false, err
+		}
+		return subtle.ConstantTimeCompare([]byte(expected), []byte(cvv)) == 1, nil
+	}
+	counter := now.UnixNano() / int64(cs.cvvWindow)
+	for _, c := range []int64{counter, counter - 1} {
+		if subtle.ConstantTimeCompare([]byte(cs.dynamicCVV(card, c)), []byte(cvv)) == 1 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// dynamicCVV — HOTP (RFC 4226) от реквизитов карты и номера временного окна, усеченный до 3 цифр
+func (cs *CardService) dynamicCVV(card *model.Card, counter int64) string {
+	mac := cs.keys.Fingerprint([]byte(fmt.Sprintf("cvv|%s|%02d/%d|%d", card.PAN, card.ExpiryMonth, card.ExpiryYear, counter)))
+	offset := mac[len(mac)-1] & 0x0f
+	code := binary.BigEndian.Uint32(mac[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%03d", code%1000)
+}
@@ -0,0 +1,100 @@
+package cards
+
+import (
+	"BankingApp/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// renewalBatch — сколько карт перевыпускается за один запуск задачи
+const renewalBatch = 500
+
+// ProcessExpiry переводит просроченные карты в expired и перевыпускает карты активных
+// счетов, срок которых истекает в ближайшие RenewalLeadDays дней. Время берется из clock,
+// ошибки уведомлений не прерывают обработку остальных карт.
+func (cs *CardService) ProcessExpiry(ctx context.Context) (expired int, renewed int, err error) {
+	now := cs.clock.Now()
+	expiredCards, err := cs.storage.ExpireCards(ctx, now.Year(), int(now.Month()))
+	if err != nil {
+		return 0, 0, err
+	}
+	var errs []error
+	for _, card := range expiredCards {
+		errs = append(errs, cs.notifyCardOwner(ctx, card, "Срок действия карты истек",
+			fmt.Sprintf("Срок действия карты %s истек, карта заблокирована.", model.MaskPAN(cs.cardPAN(ctx, card)))))
+	}
+
+	due := now.AddDate(0, 0, cs.renewal.RenewalLeadDays)
+	dueCards, err := cs.storage.GetCardsDueForRenewal(ctx, due.Year(), int(due.Month()), renewalBatch)
+	if err != nil {
+		return len(expiredCards), 0, err
+	}
+	for _, card := range dueCards {
+		ok, err := cs.renewCard(ctx, card, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("renew card %d: %w", card.ID, err))
+			continue
+		}
+		if ok {
+			renewed++
+		}
+	}
+	return len(expiredCards), renewed, errors.Join(errs...)
+}
+
+// renewCard продлевает карту на 4 года от текущего срока. В зависимости от политики
+// продукта номер сохраняется или выпускается новая карта взамен старой. false — карту
+// уже перевыпустил параллельный запуск.
+func (cs *CardService) renewCard(ctx context.Context, card *model.Card, now time.Time) (bool, error) {
+	month, year := card.ExpiryMonth, card.ExpiryYear+4
+	oldPAN := cs.cardPAN(ctx, card)
+	if cs.renewal.RenewalKeepPAN {
+		if err := cs.storage.UpdateCardExpiry(ctx, card.ID, month, year); err != nil {
+			return false, err
+		}
+		return true, cs.notifyCardOwner(ctx, card, "Карта перевыпущена",
+			fmt.Sprintf("Карта %s продлена до %02d/%d, номер не изменился.", model.MaskPAN(oldPAN), month, year))
+	}
+	renewal := &model.Card{
+		AccountID:      card.AccountID,
+		CardholderName: card.CardholderName,
+		DynamicCVV:     card.DynamicCVV,
+		ExpiryMonth:    month,
+		ExpiryYear:     year,
+		IsActive:       true,
+		Status:         model.CardActive,
+		CreatedAt:      now,
+	}
+	// новая карта и ссылка на нее сохраняются одной транзакцией, чтобы сбой между ними не привел к повторному перевыпуску
+	save := func(ctx context.Context, c *model.Card) (int64, error) {
+		return cs.storage.CreateReplacementCard(ctx, card.ID, c)
+	}
+	if err := cs.issueCard(ctx, renewal, save); err != nil {
+		return false, err
+	}
+	if renewal.ID == 0 {
+		return false, nil
+	}
+	return true, cs.notifyCardOwner(ctx, card, "Карта перевыпущена",
+		fmt.Sprintf("Взамен карты %s выпущена карта %s со сроком действия до %02d/%d.",
+			model.MaskPAN(oldPAN), model.MaskPAN(renewal.PAN), month, year))
+}
+
+func (cs *CardService) notifyCardOwner(ctx context.Context, card *model.Card, subject, body string) error {
+	account, err := cs.storage.GetAccountByID(ctx, card.AccountID)
+	if err != nil {
+		return err
+	}
+	return cs.notifier.Notify(ctx, account.UserID, subject, body)
+}
+
+// cardPAN расшифровывает номер для уведомления; при ошибке номер в тексте не показывается
+func (cs *CardService) cardPAN(ctx context.Context, card *model.Card) string {
+	pan, err := cs.keys.Decrypt(ctx, card.EncryptedPAN)
+	if err != nil {
+		return ""
+	}
+	return string(pan)
+}
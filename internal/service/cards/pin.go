@@ -0,0 +1,92 @@
+package cards
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidPIN = errors.New("PIN должен состоять из 4-6 цифр и не быть тривиальным")
+	ErrPINNotSet  = errors.New("PIN не установлен")
+	ErrWrongPIN   = errors.New("неверный PIN")
+	ErrPINLocked  = errors.New("PIN заблокирован после неверных попыток ввода")
+)
+
+// SetPIN устанавливает или меняет PIN карты. Смена PIN снимает блокировку.
+func (cs *CardService) SetPIN(ctx context.Context, cardID int64, pin string) error {
+	if err := validatePIN(pin); err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword(cs.pepperPIN(cardID, pin), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := cs.storage.SetCardPIN(ctx, cardID, string(hash)); err != nil {
+		return fmt.Errorf("ошибка сохранения PIN: %w", err)
+	}
+	return nil
+}
+
+// VerifyPIN сверяет PIN и блокирует его после pinMaxAttempts неверных попыток подряд.
+// Блокировка проверяется еще раз при учете попытки: параллельные запросы могли заблокировать PIN
+// после чтения, и верный PIN в этом случае тоже отклоняется.
+func (cs *CardService) VerifyPIN(ctx context.Context, cardID int64, pin string) error {
+	stored, err := cs.storage.GetCardPIN(ctx, cardID)
+	if err != nil {
+		return fmt.Errorf("ошибка получения PIN: %w", err)
+	}
+	if stored == nil {
+		return ErrPINNotSet
+	}
+	if stored.Locked {
+		return ErrPINLocked
+	}
+	ok := bcrypt.CompareHashAndPassword([]byte(stored.Hash), cs.pepperPIN(cardID, pin)) == nil
+	locked, err := cs.storage.RegisterPINAttempt(ctx, cardID, ok, cs.pinMaxAttempts)
+	if err != nil {
+		return err
+	}
+	switch {
+	case locked:
+		return ErrPINLocked
+	case !ok:
+		return ErrWrongPIN
+	}
+	return nil
+}
+
+func (cs *CardService) UnlockPIN(ctx context.Context, cardID int64) error {
+	return cs.storage.UnlockCardPIN(ctx, cardID)
+}
+
+// pepperPIN привязывает PIN к карте и секрету keystore: пространство PIN мало,
+// и без перца bcrypt-хэши из утекшей базы перебираются за минуты
+func (cs *CardService) pepperPIN(cardID int64, pin string) []byte {
+	return cs.keys.Fingerprint([]byte("pin|" + strconv.FormatInt(cardID, 10) + "|" + pin))
+}
+
+func validatePIN(pin string) error {
+	if len(pin) < 4 || len(pin) > 6 {
+		return ErrInvalidPIN
+	}
+	same, ascending, descending := true, true, true
+	for i := range len(pin) {
+		if pin[i] < '0' || pin[i] > '9' {
+			return ErrInvalidPIN
+		}
+		if i == 0 {
+			continue
+		}
+		same = same && pin[i] == pin[i-1]
+		ascending = ascending && pin[i] == pin[i-1]+1
+		descending = descending && pin[i] == pin[i-1]-1
+	}
+	if same || ascending || descending {
+		return ErrInvalidPIN
+	}
+	return nil
+}
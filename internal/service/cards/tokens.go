@@ -0,0 +1,136 @@
+package cards
+
+import (
+	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// tokenPrefix отличает токены от настоящих PAN, которые начинаются с 4
+const tokenPrefix = "9"
+
+var (
+	ErrTokenNotFound   = errors.New("токен не найден")
+	ErrTokenInactive   = errors.New("токен неактивен")
+	ErrTokenRequestor  = errors.New("токен выпущен для другого устройства или мерчанта")
+	ErrTokenLimit      = errors.New("сумма превышает лимит токена")
+	ErrInvalidTokenReq = errors.New("некорректные параметры токена")
+	ErrTokenDeleted    = errors.New("токен удален, его статус больше не меняется")
+)
+
+// IssueToken выпускает токен карты для устройства или мерчанта. Значение токена
+// возвращается только в ответе на выпуск, в базе остается HMAC-отпечаток.
+func (cs *CardService) IssueToken(ctx context.Context, cardID int64, req model.CardToken) (*model.CardToken, error) {
+	if req.RequestorType != model.TokenRequestorDevice && req.RequestorType != model.TokenRequestorMerchant {
+		return nil, ErrInvalidTokenReq
+	}
+	if req.RequestorID == "" || req.MaxAmount < 0 {
+		return nil, ErrInvalidTokenReq
+	}
+	token := &model.CardToken{
+		CardID:        cardID,
+		RequestorType: req.RequestorType,
+		RequestorID:   req.RequestorID,
+		Status:        model.TokenActive,
+		MaxAmount:     req.MaxAmount,
+		ExpiresAt:     req.ExpiresAt,
+		CreatedAt:     cs.clock.Now(),
+	}
+	for range panAttempts {
+		value, err := luhnNumber(tokenPrefix, 16)
+		if err != nil {
+			return nil, err
+		}
+		token.Token = value
+		token.LastFour = value[len(value)-4:]
+		token.Fingerprint = cs.keys.Fingerprint([]byte(value))
+		id, err := cs.storage.CreateCardToken(ctx, token)
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка выпуска токена: %w", err)
+		}
+		token.ID = id
+		return token, nil
+	}
+	return nil, errors.New("ошибка выпуска токена: не удалось подобрать уникальный номер")
+}
+
+func (cs *CardService) GetTokens(ctx context.Context, cardID int64) ([]*model.CardToken, error) {
+	return cs.storage.GetCardTokens(ctx, cardID)
+}
+
+// SetTokenStatus приостанавливает, возобновляет или удаляет токен. Удаление окончательно:
+// удаленный токен мог быть отозван из-за компрометации устройства.
+func (cs *CardService) SetTokenStatus(ctx context.Context, cardID, tokenID int64, status string) error {
+	switch status {
+	case model.TokenActive, model.TokenSuspended, model.TokenDeleted:
+	default:
+		return ErrInvalidTokenReq
+	}
+	err := cs.storage.UpdateCardTokenStatus(ctx, cardID, tokenID, status)
+	if errors.Is(err, storage.ErrTokenDeleted) {
+		return ErrTokenDeleted
+	}
+	return err
+}
+
+// resolveToken находит карту по токену и проверяет статус, привязку и лимит токена
+func (cs *CardService) resolveToken(ctx context.Context, auth model.CardAuthorization, now time.Time) (*model.Card, error) {
+	token, err := cs.storage.FindCardToken(ctx, cs.keys.Fingerprint([]byte(auth.Token)))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, ErrTokenNotFound
+	}
+	if token.Status != model.TokenActive || (token.ExpiresAt != nil && !now.Before(*token.ExpiresAt)) {
+		return nil, ErrTokenInactive
+	}
+	if token.RequestorID != auth.RequestorID {
+		return nil, ErrTokenRequestor
+	}
+	if token.MaxAmount > 0 && auth.Amount > token.MaxAmount {
+		return nil, ErrTokenLimit
+	}
+	card, err := cs.storage.GetCardByID(ctx, token.CardID)
+	if err != nil {
+		return nil, err
+	}
+	if card == nil {
+		return nil, ErrCardNotFound
+	}
+	return card, nil
+}
+
+// luhnNumber генерирует случайный номер заданной длины с префиксом и контрольной цифрой по Луну
+func luhnNumber(prefix string, length int) (string, error) {
+	digits := make([]byte, length)
+	copy(digits, prefix)
+	for i := len(prefix); i < length-1; i++ {
+		d, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		digits[i] = byte('0' + d.Int64())
+	}
+	sum := 0
+	for i := length - 2; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (length-2-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	digits[length-1] = byte('0' + (10-sum%10)%10)
+	return string(digits), nil
+}
@@ -50,6 +50,10 @@ func (cs *CreditService) IssueCredit(ctx context.Context, credit model.Credit) (
 	return payments, nil
 }
 
+func (cs *CreditService) GetCreditsByUser(ctx context.Context, userID string) ([]*model.Credit, error) {
+	return cs.storage.GetCreditsByUser(ctx, userID)
+}
+
 func calculatePayment(sum, rate float64, months int) float64 {
 	p := math.Pow(rate+1, float64(months))
 	return sum * rate * p / (p - 1)
@@ -0,0 +1,361 @@
+package disputes
+
+import (
+	"BankingApp/internal/keystore"
+	"BankingApp/internal/model"
+	"BankingApp/internal/service"
+	adminService "BankingApp/internal/service/admin"
+	bankingService "BankingApp/internal/service/banking"
+	profileService "BankingApp/internal/service/profile"
+	"BankingApp/internal/storage"
+	"BankingApp/pkg/clock"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MaxAttachmentSize — предельный размер вложения в байтах
+	MaxAttachmentSize = 5 << 20
+
+	maxAttachments       = 10
+	maxDescriptionLength = 2000
+	// backOfficeListLimit — сколько обращений отдается в одном списке бэк-офиса
+	backOfficeListLimit = 200
+)
+
+var (
+	ErrTransactionNotFound = errors.New("операция не найдена")
+	ErrDisputeNotFound     = errors.New("обращение не найдено")
+	ErrAttachmentNotFound  = errors.New("вложение не найдено")
+	ErrInvalidReason       = errors.New("неизвестная причина обращения")
+	ErrDescriptionTooLong  = errors.New("описание обращения слишком длинное")
+	ErrDisputeExists       = errors.New("по этой операции уже есть открытое обращение")
+	ErrDisputeClosed       = errors.New("обращение уже рассмотрено или отозвано")
+	ErrInvalidAttachment   = errors.New("вложение должно быть в формате JPEG, PNG или PDF")
+	ErrAttachmentTooLarge  = errors.New("вложение слишком большое")
+	ErrTooManyAttachments  = errors.New("к обращению приложено слишком много файлов")
+	ErrInvalidDecision     = errors.New("решение должно быть reverse или reject")
+	ErrInvalidStatus       = errors.New("неизвестный статус обращения")
+)
+
+var _ service.DisputeService = (*Service)(nil)
+
+// Service ведет обращения клиентов по операциям. Бэк-офис закрывает обращение сторно операции
+// через банковский сервис или отказом; решение записывается в журнал действий сотрудников.
+type Service struct {
+	storage  storage.DisputeStorage
+	banking  service.BankingService
+	keys     *keystore.KeyStore
+	notifier service.NotificationService
+	clock    clock.Clock
+	// reencryptAfter — последнее вложение, обработанное текущим проходом перешифрования (только для фоновой задачи)
+	reencryptAfter int64
+}
+
+func NewDisputeService(storage storage.DisputeStorage, banking service.BankingService, keys *keystore.KeyStore,
+	notifier service.NotificationService, clk clock.Clock) *Service {
+	return &Service{storage: storage, banking: banking, keys: keys, notifier: notifier, clock: clk}
+}
+
+// Open открывает обращение по операции на счете клиента
+func (s *Service) Open(ctx context.Context, userID string, transactionID int64, reason, description string) (*model.Dispute, error) {
+	if !model.ValidDisputeReason(reason) {
+		return nil, ErrInvalidReason
+	}
+	description = strings.TrimSpace(description)
+	if utf8.RuneCountInString(description) > maxDescriptionLength {
+		return nil, ErrDescriptionTooLong
+	}
+	transaction, err := s.banking.GetTransaction(ctx, transactionID)
+	if errors.Is(err, bankingService.ErrTransactionNotFound) {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	account, err := s.banking.GetAccountByID(ctx, transaction.AccountID)
+	if err != nil || account.UserID != userID {
+		return nil, ErrTransactionNotFound
+	}
+	dispute, err := s.storage.CreateDispute(ctx, &model.Dispute{
+		UserID:        userID,
+		TransactionID: transactionID,
+		Reason:        reason,
+		Description:   description,
+		Status:        model.DisputeOpen,
+		CreatedAt:     s.clock.Now(),
+	})
+	if errors.Is(err, storage.ErrAlreadyExists) {
+		return nil, ErrDisputeExists
+	}
+	return dispute, err
+}
+
+func (s *Service) GetDisputes(ctx context.Context, userID string) ([]*model.Dispute, error) {
+	return s.storage.GetDisputesByUser(ctx, userID)
+}
+
+// GetDispute возвращает обращение клиента вместе со списком вложений
+func (s *Service) GetDispute(ctx context.Context, userID string, disputeID int64) (*model.Dispute, error) {
+	dispute, err := s.owned(ctx, userID, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	return s.withAttachments(ctx, dispute)
+}
+
+// AddAttachment шифрует и сохраняет подтверждающий документ к открытому обращению.
+// Формат определяется по содержимому, а не по заявленному клиентом типу.
+func (s *Service) AddAttachment(ctx context.Context, userID string, attachment model.DisputeAttachment) (*model.DisputeAttachment, error) {
+	dispute, err := s.owned(ctx, userID, attachment.DisputeID)
+	if err != nil {
+		return nil, err
+	}
+	if dispute.Closed() {
+		return nil, ErrDisputeClosed
+	}
+	if len(attachment.Content) > MaxAttachmentSize {
+		return nil, ErrAttachmentTooLarge
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(attachment.Content), ";")
+	if len(attachment.Content) == 0 || !slices.Contains(profileService.AllowedContentTypes, contentType) {
+		return nil, ErrInvalidAttachment
+	}
+	attachments, err := s.storage.GetDisputeAttachments(ctx, dispute.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) >= maxAttachments {
+		return nil, ErrTooManyAttachments
+	}
+
+	attachment.ContentType = contentType
+	attachment.Size = len(attachment.Content)
+	attachment.FileName = profileService.SanitizeFileName(attachment.FileName)
+	attachment.EncryptedContent, attachment.KeyVersion, err = s.keys.Encrypt(attachment.Content)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка шифрования вложения: %w", err)
+	}
+	if err := s.storage.AddDisputeAttachment(ctx, &attachment); err != nil {
+		return nil, err
+	}
+	attachment.Content = nil
+	return &attachment, nil
+}
+
+// Withdraw — клиент отзывает обращение, пока по нему нет решения
+func (s *Service) Withdraw(ctx context.Context, userID string, disputeID int64) error {
+	if _, err := s.owned(ctx, userID, disputeID); err != nil {
+		return err
+	}
+	return s.setStatus(ctx, disputeID, model.DisputeWithdrawn)
+}
+
+// ListDisputes — обращения для бэк-офиса, пустой статус — все
+func (s *Service) ListDisputes(ctx context.Context, status string) ([]*model.Dispute, error) {
+	switch status {
+	case "", model.DisputeOpen, model.DisputeInReview, model.DisputeResolved, model.DisputeRejected, model.DisputeWithdrawn:
+	default:
+		return nil, ErrInvalidStatus
+	}
+	return s.storage.GetDisputes(ctx, status, backOfficeListLimit)
+}
+
+func (s *Service) GetDisputeByID(ctx context.Context, disputeID int64) (*model.Dispute, error) {
+	dispute, err := s.get(ctx, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	return s.withAttachments(ctx, dispute)
+}
+
+// GetAttachment возвращает вложение с расшифрованным содержимым для сотрудника
+func (s *Service) GetAttachment(ctx context.Context, attachmentID int64) (*model.DisputeAttachment, error) {
+	attachment, err := s.storage.GetDisputeAttachment(ctx, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	if attachment == nil {
+		return nil, ErrAttachmentNotFound
+	}
+	attachment.Content, err = s.keys.Decrypt(ctx, attachment.EncryptedContent)
+	if err != nil {
+		return nil, fmt.Errorf("attachment %d: %w", attachment.ID, err)
+	}
+	return attachment, nil
+}
+
+// StartReview берет открытое обращение в работу
+func (s *Service) StartReview(ctx context.Context, disputeID int64) error {
+	if _, err := s.get(ctx, disputeID); err != nil {
+		return err
+	}
+	return s.setStatus(ctx, disputeID, model.DisputeInReview)
+}
+
+// Resolve закрывает обращение сторно операции (полным или частичным) или отказом.
+// Комментарий отказа показывается клиенту, поэтому при отказе он обязателен.
+func (s *Service) Resolve(ctx context.Context, actorID string, disputeID int64, resolution model.DisputeResolution) (*model.Dispute, error) {
+	if err := adminService.ValidateReason(resolution.AdminReason); err != nil {
+		return nil, err
+	}
+	dispute, err := s.get(ctx, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	if dispute.Closed() {
+		return nil, ErrDisputeClosed
+	}
+	now := s.clock.Now()
+	dispute.ResolutionComment = resolution.Comment
+	dispute.ResolvedBy = actorID
+	dispute.ResolvedAt = &now
+
+	var (
+		legs   []model.ReversalLeg
+		action *model.AdminAction
+	)
+	switch resolution.Decision {
+	case model.DecisionReverse:
+		legs, err = s.banking.PlanReversal(ctx, dispute.TransactionID, resolution.Amount)
+		if err != nil {
+			return nil, err
+		}
+		reversed := math.Abs(legs[0].Amount)
+		dispute.Status = model.DisputeResolved
+		dispute.ReversedAmount = &reversed
+		action = newAction(actorID, model.AdminActionResolveDispute, disputeID, resolution.AdminReason)
+		action.Amount = &reversed
+	case model.DecisionReject:
+		if resolution.Comment == "" {
+			return nil, adminService.ErrCommentRequired
+		}
+		dispute.Status = model.DisputeRejected
+		action = newAction(actorID, model.AdminActionRejectDispute, disputeID, resolution.AdminReason)
+	default:
+		return nil, ErrInvalidDecision
+	}
+
+	err = s.storage.ResolveDispute(ctx, dispute, legs, action)
+	if errors.Is(err, storage.ErrDisputeClosed) {
+		return nil, ErrDisputeClosed
+	}
+	if err != nil {
+		return nil, bankingService.ReversalError(err)
+	}
+	s.notifyResolution(ctx, dispute)
+	return dispute, nil
+}
+
+// ReencryptAttachments перешифровывает активной версией ключа до batch вложений,
+// зашифрованных устаревшими версиями. Возвращает число перешифрованных вложений.
+// Вложение, которое не удалось перешифровать, пропускается до следующего прохода; ошибки возвращаются вместе.
+func (s *Service) ReencryptAttachments(ctx context.Context, batch int) (int, error) {
+	attachments, err := s.storage.GetDisputeAttachmentsWithStaleKey(ctx, s.keys.ActiveVersion(), s.reencryptAfter, batch)
+	if err != nil {
+		return 0, err
+	}
+	if len(attachments) < batch {
+		// проход закончен, следующий начнется сначала и повторит пропущенные вложения
+		s.reencryptAfter = 0
+	} else {
+		s.reencryptAfter = attachments[len(attachments)-1].ID
+	}
+	var (
+		n    int
+		errs []error
+	)
+	for _, attachment := range attachments {
+		if err := s.reencryptAttachment(ctx, attachment); err != nil {
+			errs = append(errs, fmt.Errorf("attachment %d: %w", attachment.ID, err))
+			continue
+		}
+		n++
+	}
+	return n, errors.Join(errs...)
+}
+
+func (s *Service) reencryptAttachment(ctx context.Context, attachment *model.DisputeAttachment) error {
+	content, err := s.keys.Decrypt(ctx, attachment.EncryptedContent)
+	if err != nil {
+		return err
+	}
+	attachment.EncryptedContent, attachment.KeyVersion, err = s.keys.Encrypt(content)
+	if err != nil {
+		return err
+	}
+	return s.storage.UpdateDisputeAttachmentEncryption(ctx, attachment)
+}
+
+func (s *Service) notifyResolution(ctx context.Context, dispute *model.Dispute) {
+	body := fmt.Sprintf("По обращению №%d принято решение: в возврате отказано. %s", dispute.ID, dispute.ResolutionComment)
+	if dispute.Status == model.DisputeResolved {
+		body = fmt.Sprintf("По обращению №%d операция сторнирована на сумму %.2f.", dispute.ID, *dispute.ReversedAmount)
+	}
+	// ошибка уведомления не отменяет принятого решения
+	_ = s.notifier.Notify(ctx, dispute.UserID, "Решение по обращению", body)
+}
+
+// setStatus переводит обращение из open или in_review; закрытое обращение не меняется
+func (s *Service) setStatus(ctx context.Context, disputeID int64, status string) error {
+	from := []string{model.DisputeOpen, model.DisputeInReview}
+	if status == model.DisputeInReview {
+		from = []string{model.DisputeOpen}
+	}
+	updated, err := s.storage.SetDisputeStatus(ctx, disputeID, from, status, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrDisputeClosed
+	}
+	return nil
+}
+
+func (s *Service) owned(ctx context.Context, userID string, disputeID int64) (*model.Dispute, error) {
+	dispute, err := s.get(ctx, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	if dispute.UserID != userID {
+		return nil, ErrDisputeNotFound
+	}
+	return dispute, nil
+}
+
+func (s *Service) get(ctx context.Context, disputeID int64) (*model.Dispute, error) {
+	dispute, err := s.storage.GetDispute(ctx, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	if dispute == nil {
+		return nil, ErrDisputeNotFound
+	}
+	return dispute, nil
+}
+
+func (s *Service) withAttachments(ctx context.Context, dispute *model.Dispute) (*model.Dispute, error) {
+	attachments, err := s.storage.GetDisputeAttachments(ctx, dispute.ID)
+	if err != nil {
+		return nil, err
+	}
+	dispute.Attachments = attachments
+	return dispute, nil
+}
+
+func newAction(actorID, name string, disputeID int64, reason model.AdminReason) *model.AdminAction {
+	return &model.AdminAction{
+		ActorID:     actorID,
+		Action:      name,
+		TargetType:  model.AdminTargetDispute,
+		TargetID:    strconv.FormatInt(disputeID, 10),
+		AdminReason: reason,
+	}
+}
@@ -0,0 +1,136 @@
+package fx
+
+import (
+	"BankingApp/internal/config"
+	"BankingApp/internal/model"
+	"BankingApp/internal/rates"
+	"BankingApp/internal/service"
+	"BankingApp/internal/storage"
+	"BankingApp/pkg/clock"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidCurrency = errors.New("неизвестный код валюты")
+	ErrSameCurrency    = errors.New("валюты конвертации совпадают")
+	ErrInvalidAmount   = errors.New("сумма должна быть положительной")
+	ErrRateUnavailable = errors.New("курс валюты недоступен")
+	ErrQuoteNotFound   = errors.New("котировка не найдена")
+	ErrQuoteExpired    = errors.New("котировка истекла или уже использована")
+	ErrQuoteMismatch   = errors.New("котировка выдана на другие валюты или сумму")
+)
+
+var _ service.FXService = (*Service)(nil)
+
+// Service конвертирует суммы по курсам источника со спредом банка и выдает котировки,
+// фиксирующие курс для клиента на время QuoteTTL
+type Service struct {
+	provider rates.Provider
+	storage  storage.FXStorage
+	clock    clock.Clock
+	spread   float64
+	quoteTTL time.Duration
+}
+
+func NewFXService(provider rates.Provider, storage storage.FXStorage, clk clock.Clock, cfg *config.Config) *Service {
+	return &Service{
+		provider: provider,
+		storage:  storage,
+		clock:    clk,
+		spread:   cfg.FX.Spread,
+		quoteTTL: time.Duration(cfg.FX.QuoteTTL),
+	}
+}
+
+// Convert считает конвертацию по текущему курсу без сохранения котировки
+func (s *Service) Convert(ctx context.Context, from, to string, amount float64) (*model.FXQuote, error) {
+	from, to, err := validatePair(from, to)
+	if err != nil {
+		return nil, err
+	}
+	amount = model.RoundAmount(amount, from)
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	table, err := s.provider.Rates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRateUnavailable, err)
+	}
+	mid, err := table.Mid(from, to)
+	if errors.Is(err, rates.ErrRateUnavailable) {
+		return nil, fmt.Errorf("%w: %w", ErrRateUnavailable, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// клиент продает валюту from, поэтому спред уменьшает курс
+	rate := mid * (1 - s.spread)
+	if rate <= 0 {
+		return nil, ErrRateUnavailable
+	}
+	now := s.clock.Now()
+	return &model.FXQuote{
+		From:            from,
+		To:              to,
+		Amount:          amount,
+		ConvertedAmount: model.RoundAmount(amount*rate, to),
+		Rate:            rate,
+		MidRate:         mid,
+		Spread:          s.spread,
+		RatesDate:       table.Date,
+		CreatedAt:       now,
+	}, nil
+}
+
+// Quote фиксирует курс конвертации для клиента на время quoteTTL
+func (s *Service) Quote(ctx context.Context, userID, from, to string, amount float64) (*model.FXQuote, error) {
+	quote, err := s.Convert(ctx, from, to, amount)
+	if err != nil {
+		return nil, err
+	}
+	quote.ID = uuid.New().String()
+	quote.UserID = userID
+	quote.ExpiresAt = quote.CreatedAt.Add(s.quoteTTL)
+	if err := s.storage.CreateFXQuote(ctx, quote); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// QuoteForTransfer возвращает действующую котировку клиента, выданную на эти валюты и сумму
+func (s *Service) QuoteForTransfer(ctx context.Context, quoteID, userID, from, to string, amount float64) (*model.FXQuote, error) {
+	quote, err := s.storage.GetFXQuote(ctx, quoteID)
+	if err != nil {
+		return nil, err
+	}
+	if quote == nil || quote.UserID != userID {
+		return nil, ErrQuoteNotFound
+	}
+	if quote.UsedAt != nil || !s.clock.Now().Before(quote.ExpiresAt) {
+		return nil, ErrQuoteExpired
+	}
+	if quote.From != from || quote.To != to || quote.Amount != model.RoundAmount(amount, from) {
+		return nil, ErrQuoteMismatch
+	}
+	return quote, nil
+}
+
+func validatePair(from, to string) (string, string, error) {
+	from, ok := model.NormalizeCurrency(from)
+	if !ok {
+		return "", "", ErrInvalidCurrency
+	}
+	to, ok = model.NormalizeCurrency(to)
+	if !ok {
+		return "", "", ErrInvalidCurrency
+	}
+	if from == to {
+		return "", "", ErrSameCurrency
+	}
+	return from, to, nil
+}
@@ -0,0 +1,71 @@
+package notification
+
+import (
+	"BankingApp/internal/config"
+	"BankingApp/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"net/smtp"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Sender доставляет сообщение на адрес электронной почты
+type Sender interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+type Service struct {
+	users  storage.UserStorage
+	sender Sender
+}
+
+// NewNotificationService отправляет письма через SMTP, если он настроен, иначе пишет их в лог
+func NewNotificationService(users storage.UserStorage, cfg *config.Config, logger *logrus.Logger) *Service {
+	var sender Sender = &logSender{logger: logger}
+	if cfg.SMTP.Host != "" {
+		sender = &smtpSender{cfg: cfg.SMTP}
+	}
+	return &Service{users: users, sender: sender}
+}
+
+// Notify отправляет уведомление пользователю на его email
+func (s *Service) Notify(ctx context.Context, userID, subject, body string) error {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+	if user == nil {
+		return errors.New("notify: user not found")
+	}
+	return s.sender.Send(ctx, user.Email, subject, body)
+}
+
+// SendEmail отправляет письмо на произвольный адрес, например для его подтверждения
+func (s *Service) SendEmail(ctx context.Context, to, subject, body string) error {
+	return s.sender.Send(ctx, to, subject, body)
+}
+
+type smtpSender struct {
+	cfg config.SMTP
+}
+
+func (s *smtpSender) Send(_ context.Context, to, subject, body string) error {
+	auth := smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		s.cfg.From, to, subject, body)
+	return smtp.SendMail(s.cfg.Host+":"+s.cfg.Port, auth, s.cfg.From, []string{to}, []byte(msg))
+}
+
+type logSender struct {
+	logger *logrus.Logger
+}
+
+func (s *logSender) Send(_ context.Context, to, subject, body string) error {
+	s.logger.WithFields(map[string]interface{}{
+		"to":      to,
+		"subject": subject,
+	}).Info(body)
+	return nil
+}
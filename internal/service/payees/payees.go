@@ -0,0 +1,242 @@
+package payees
+
+import (
+	"BankingApp/internal/config"
+	"BankingApp/internal/model"
+	"BankingApp/internal/service"
+	bankingService "BankingApp/internal/service/banking"
+	"BankingApp/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxNameLength = 64
+
+var (
+	ErrInvalidName        = errors.New("название должно быть от 1 до 64 символов")
+	ErrInvalidPayee       = errors.New("укажите получателя: номер счета или телефон/email, но не оба")
+	ErrInvalidBIC         = errors.New("БИК должен состоять из 9 цифр")
+	ErrPayeeNotFound      = errors.New("получатель не найден")
+	ErrTemplateNotFound   = errors.New("шаблон не найден")
+	ErrNameTaken          = errors.New("название уже используется")
+	ErrAccountNotFound    = errors.New("счет не найден или закрыт")
+	ErrInvalidAmount      = errors.New("сумма должна быть положительной")
+	ErrDescriptionTooLong = errors.New("назначение платежа слишком длинное")
+	// ErrExternalPayee — переводы в другие банки не поддерживаются, такой получатель пока только хранится
+	ErrExternalPayee = errors.New("переводы в другие банки пока недоступны")
+)
+
+var _ service.PayeeService = (*Service)(nil)
+
+// Service хранит получателей и шаблоны платежей клиента. Сами платежи проводятся BankingService.Transfer.
+type Service struct {
+	storage storage.PayeeStorage
+	banking service.BankingService
+	aliases service.AliasService
+	bic     string
+}
+
+func NewPayeeService(storage storage.PayeeStorage, banking service.BankingService, aliases service.AliasService, cfg *config.Config) *Service {
+	return &Service{storage: storage, banking: banking, aliases: aliases, bic: cfg.Banking.BIC}
+}
+
+// CreatePayee сохраняет получателя. Тип определяется по реквизитам: номер счета с БИК нашего банка
+// или без БИК — internal, с БИК другого банка — external, телефон или email — alias.
+// Телефон и email хранятся отпечатком и маской, проверка номера чужого счета учитывается в лимите поиска получателей.
+func (s *Service) CreatePayee(ctx context.Context, userID string, payee model.Payee) (*model.Payee, error) {
+	nickname, err := validateName(payee.Nickname)
+	if err != nil {
+		return nil, err
+	}
+	hasAccount := payee.AccountNumber != ""
+	hasAlias := payee.AliasType != "" || payee.AliasValue != ""
+	if hasAccount == hasAlias {
+		return nil, ErrInvalidPayee
+	}
+	created := &model.Payee{UserID: userID, Nickname: nickname}
+	if hasAlias {
+		fingerprint, masked, err := s.aliases.Seal(model.AliasTarget{Type: payee.AliasType, Value: payee.AliasValue})
+		if err != nil {
+			return nil, err
+		}
+		created.Type = model.PayeeAlias
+		created.AliasType = payee.AliasType
+		created.AliasFingerprint = fingerprint
+		created.AliasMasked = masked
+	} else {
+		bic := strings.TrimSpace(payee.BIC)
+		if bic == "" {
+			bic = s.bic
+		}
+		if len(bic) != 9 || strings.Trim(bic, "0123456789") != "" {
+			return nil, ErrInvalidBIC
+		}
+		number, err := bankingService.NormalizeAccountNumber(payee.AccountNumber, bic)
+		if err != nil {
+			return nil, err
+		}
+		created.AccountNumber = number
+		created.BIC = bic
+		created.RecipientName = strings.TrimSpace(payee.RecipientName)
+		created.Type = model.PayeeExternal
+		if bic == s.bic {
+			account, err := s.banking.GetAccountByNumber(ctx, number)
+			if account == nil || account.UserID != userID {
+				if err := s.aliases.CountLookup(ctx, userID); err != nil {
+					return nil, err
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+			created.Type = model.PayeeInternal
+		}
+	}
+	created, err = s.storage.CreatePayee(ctx, created)
+	if errors.Is(err, storage.ErrAlreadyExists) {
+		return nil, ErrNameTaken
+	}
+	return created, err
+}
+
+func (s *Service) GetPayees(ctx context.Context, userID string) ([]*model.Payee, error) {
+	payees, err := s.storage.GetPayees(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, payee := range payees {
+		if err := s.sealAlias(ctx, payee); err != nil {
+			return nil, err
+		}
+	}
+	return payees, nil
+}
+
+func (s *Service) GetPayee(ctx context.Context, userID string, payeeID int64) (*model.Payee, error) {
+	payee, err := s.storage.GetPayee(ctx, userID, payeeID)
+	if err != nil {
+		return nil, err
+	}
+	if payee == nil {
+		return nil, ErrPayeeNotFound
+	}
+	if err := s.sealAlias(ctx, payee); err != nil {
+		return nil, err
+	}
+	return payee, nil
+}
+
+// SealAliases заменяет отпечатками телефоны и email получателей, сохраненных в открытом виде (для фоновой задачи)
+func (s *Service) SealAliases(ctx context.Context, batch int) (int, error) {
+	payees, err := s.storage.GetPayeesWithPlainAlias(ctx, batch)
+	if err != nil {
+		return 0, err
+	}
+	for i, payee := range payees {
+		if err := s.sealAlias(ctx, payee); err != nil {
+			return i, err
+		}
+	}
+	return len(payees), nil
+}
+
+// sealAlias заменяет открытое значение получателя, сохраненного до отпечатков, отпечатком и маской
+func (s *Service) sealAlias(ctx context.Context, payee *model.Payee) error {
+	if payee.AliasValue == "" {
+		return nil
+	}
+	fingerprint, masked, err := s.aliases.Seal(model.AliasTarget{Type: payee.AliasType, Value: payee.AliasValue})
+	if err != nil {
+		return fmt.Errorf("seal payee %d alias: %w", payee.ID, err)
+	}
+	if err := s.storage.SealPayeeAlias(ctx, payee.ID, fingerprint, masked); err != nil {
+		return err
+	}
+	payee.AliasValue = ""
+	payee.AliasFingerprint = fingerprint
+	payee.AliasMasked = masked
+	return nil
+}
+
+// DeletePayee удаляет получателя вместе с шаблонами платежей ему
+func (s *Service) DeletePayee(ctx context.Context, userID string, payeeID int64) error {
+	deleted, err := s.storage.DeletePayee(ctx, userID, payeeID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrPayeeNotFound
+	}
+	return nil
+}
+
+func (s *Service) CreateTemplate(ctx context.Context, userID string, template model.PaymentTemplate) (*model.PaymentTemplate, error) {
+	name, err := validateName(template.Name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.GetPayee(ctx, userID, template.PayeeID); err != nil {
+		return nil, err
+	}
+	account, err := s.banking.GetAccountByID(ctx, template.FromAccountID)
+	if err != nil || account.UserID != userID || !account.IsActive {
+		return nil, ErrAccountNotFound
+	}
+	amount := model.RoundAmount(template.Amount, account.Currency)
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	description := strings.TrimSpace(template.Description)
+	if utf8.RuneCountInString(description) > bankingService.MaxDescriptionLength {
+		return nil, ErrDescriptionTooLong
+	}
+	created, err := s.storage.CreateTemplate(ctx, &model.PaymentTemplate{
+		UserID:        userID,
+		Name:          name,
+		PayeeID:       template.PayeeID,
+		FromAccountID: account.ID,
+		Amount:        amount,
+		Description:   description,
+	})
+	if errors.Is(err, storage.ErrAlreadyExists) {
+		return nil, ErrNameTaken
+	}
+	return created, err
+}
+
+func (s *Service) GetTemplates(ctx context.Context, userID string) ([]*model.PaymentTemplate, error) {
+	return s.storage.GetTemplates(ctx, userID)
+}
+
+func (s *Service) GetTemplate(ctx context.Context, userID string, templateID int64) (*model.PaymentTemplate, error) {
+	template, err := s.storage.GetTemplate(ctx, userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return nil, ErrTemplateNotFound
+	}
+	return template, nil
+}
+
+func (s *Service) DeleteTemplate(ctx context.Context, userID string, templateID int64) error {
+	deleted, err := s.storage.DeleteTemplate(ctx, userID, templateID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+func validateName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxNameLength {
+		return "", ErrInvalidName
+	}
+	return name, nil
+}
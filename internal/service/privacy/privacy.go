@@ -0,0 +1,170 @@
+package privacy
+
+import (
+	"BankingApp/internal/model"
+	"BankingApp/internal/service"
+	"BankingApp/internal/storage"
+	"BankingApp/pkg/clock"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var _ service.PrivacyService = (*Service)(nil)
+
+// creditClosed — статус погашенного кредита; остальные статусы означают действующий долг
+const creditClosed = "closed"
+
+var (
+	ErrUserNotFound   = errors.New("пользователь не найден")
+	ErrNonZeroBalance = errors.New("перед удалением учетной записи выведите остаток со всех счетов")
+	ErrOpenCredits    = errors.New("учетную запись нельзя удалить, пока не погашены кредиты")
+	ErrStaffAccount   = errors.New("учетную запись сотрудника удаляет администратор")
+)
+
+// Service выгружает и удаляет данные клиента. Выгрузка собирается через сервисы,
+// чтобы зашифрованные данные расшифровывались и маскировались так же, как в остальном API.
+type Service struct {
+	storage  storage.PrivacyStorage
+	users    service.UserService
+	banking  service.BankingService
+	cards    service.CardService
+	credits  service.CreditService
+	profiles service.ProfileService
+	admin    service.AdminService
+	notifier service.NotificationService
+	clock    clock.Clock
+}
+
+func NewPrivacyService(storage storage.PrivacyStorage, users service.UserService, banking service.BankingService, cards service.CardService,
+	credits service.CreditService, profiles service.ProfileService, admin service.AdminService, notifier service.NotificationService, clk clock.Clock) *Service {
+	return &Service{
+		storage:  storage,
+		users:    users,
+		banking:  banking,
+		cards:    cards,
+		credits:  credits,
+		profiles: profiles,
+		admin:    admin,
+		notifier: notifier,
+		clock:    clk,
+	}
+}
+
+func (s *Service) Export(ctx context.Context, userID string) (*model.DataExport, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	export := &model.DataExport{GeneratedAt: s.clock.Now(), User: user}
+
+	if export.Profile, err = s.profiles.GetProfile(ctx, userID); err != nil {
+		return nil, fmt.Errorf("export profile: %w", err)
+	}
+	if export.Documents, err = s.profiles.GetDocuments(ctx, userID); err != nil {
+		return nil, fmt.Errorf("export documents: %w", err)
+	}
+	accounts, err := s.banking.GetAccountsByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("export accounts: %w", err)
+	}
+	export.Accounts = make([]*model.AccountExport, 0, len(accounts))
+	for _, account := range accounts {
+		item := &model.AccountExport{Account: account}
+		if item.Transactions, err = s.banking.GetTransactions(ctx, account.ID); err != nil {
+			return nil, fmt.Errorf("export transactions of account %d: %w", account.ID, err)
+		}
+		if item.Cards, err = s.cards.GetCardsByAccount(ctx, account.ID); err != nil {
+			return nil, fmt.Errorf("export cards of account %d: %w", account.ID, err)
+		}
+		for _, card := range item.Cards {
+			card.Mask()
+		}
+		export.Accounts = append(export.Accounts, item)
+	}
+	if export.Credits, err = s.credits.GetCreditsByUser(ctx, userID); err != nil {
+		return nil, fmt.Errorf("export credits: %w", err)
+	}
+	if export.Sessions, err = s.users.GetSessions(ctx, userID); err != nil {
+		return nil, fmt.Errorf("export sessions: %w", err)
+	}
+	if export.APIClients, err = s.users.GetAPIClients(ctx, userID); err != nil {
+		return nil, fmt.Errorf("export api clients: %w", err)
+	}
+	if export.AuditLog, err = s.admin.GetAuditLog(ctx, model.AdminTargetUser, userID); err != nil {
+		return nil, fmt.Errorf("export audit log: %w", err)
+	}
+	return export, nil
+}
+
+// Erase удаляет учетную запись по запросу клиента. Счета, операции и кредиты остаются в базе
+// под обезличенным идентификатором: их хранения требует закон, но связать их с человеком
+// после удаления анкеты, email и имени уже нельзя.
+func (s *Service) Erase(ctx context.Context, userID string) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.Status == model.UserErased {
+		return ErrUserNotFound
+	}
+	if model.IsStaffRole(user.Role) {
+		return ErrStaffAccount
+	}
+	accounts, err := s.banking.GetAccountsByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		if account.Balance != 0 {
+			return ErrNonZeroBalance
+		}
+	}
+	credits, err := s.credits.GetCreditsByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, credit := range credits {
+		if credit.Status != creditClosed {
+			return ErrOpenCredits
+		}
+	}
+
+	action := &model.AdminAction{
+		ActorID:     userID,
+		Action:      model.AdminActionEraseUser,
+		TargetType:  model.AdminTargetUser,
+		TargetID:    userID,
+		AdminReason: model.AdminReason{ReasonCode: model.ReasonCustomerRequest},
+	}
+	err = s.storage.EraseUser(ctx, userID, erasedValues(userID), action)
+	if errors.Is(err, storage.ErrBalanceNotZero) {
+		return ErrNonZeroBalance
+	}
+	if err != nil {
+		return err
+	}
+	// адрес уже удален из базы, поэтому письмо отправляется на сохраненное значение
+	body := "Ваша учетная запись удалена, персональные данные обезличены. " +
+		"Сведения об операциях хранятся в течение срока, установленного законом."
+	_ = s.notifier.SendEmail(ctx, user.Email, "Учетная запись удалена", body)
+	return nil
+}
+
+// erasedValues — уникальные обезличенные email и username. Username начинается с символа,
+// недопустимого при регистрации, поэтому его нельзя занять или использовать для входа.
+func erasedValues(userID string) model.ErasedUser {
+	id := strings.ReplaceAll(userID, "-", "")
+	username := "~" + id
+	if len(username) > 32 {
+		username = username[:32]
+	}
+	return model.ErasedUser{
+		Email:    "erased+" + id + "@erased.invalid",
+		Username: username,
+	}
+}
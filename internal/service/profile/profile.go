@@ -0,0 +1,410 @@
+package profile
+
+import (
+	"BankingApp/internal/config"
+	"BankingApp/internal/keystore"
+	"BankingApp/internal/model"
+	"BankingApp/internal/service"
+	"BankingApp/internal/storage"
+	"BankingApp/pkg/clock"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var _ service.ProfileService = (*Service)(nil)
+
+const (
+	defaultMinAge          = 14
+	defaultMaxDocumentSize = 5 << 20
+
+	// maxDocuments — сколько документов клиент может загрузить, чтобы загрузки не занимали базу без предела
+	maxDocuments = 20
+)
+
+// AllowedContentTypes — форматы сканов, которые принимает проверка личности и обращения по операциям
+var AllowedContentTypes = []string{"image/jpeg", "image/png", "application/pdf"}
+
+var (
+	ErrKYCInProgress       = errors.New("анкета на проверке и не может быть изменена")
+	ErrKYCAlreadyVerified  = errors.New("личность уже подтверждена")
+	ErrIdentityLocked      = errors.New("после подтверждения личности дату рождения, паспорт и ИНН можно изменить только через банк")
+	ErrProfileIncomplete   = errors.New("заполните дату рождения, телефон, паспорт, ИНН и адрес")
+	ErrPassportScanMissing = errors.New("загрузите скан основного разворота паспорта")
+	ErrDuplicateIdentity   = errors.New("телефон, паспорт или ИНН уже указаны в анкете другого клиента")
+	ErrInvalidDocumentType = errors.New("неизвестный тип документа")
+	ErrInvalidDocument     = errors.New("документ должен быть в формате JPEG, PNG или PDF")
+	ErrDocumentTooLarge    = errors.New("документ слишком большой")
+	ErrTooManyDocuments    = errors.New("загружено слишком много документов")
+	ErrDocumentNotFound    = errors.New("документ не найден")
+	ErrKYCNotVerified      = errors.New("операция доступна после подтверждения личности")
+)
+
+type Service struct {
+	storage storage.ProfileStorage
+	keys    *keystore.KeyStore
+	clock   clock.Clock
+
+	minAge          int
+	maxDocumentSize int64
+
+	// последние анкета и документ, обработанные текущим проходом перешифрования (только для фоновой задачи)
+	reencryptProfileAfter  string
+	reencryptDocumentAfter int64
+}
+
+func NewProfileService(storage storage.ProfileStorage, keys *keystore.KeyStore, clk clock.Clock, cfg *config.Config) *Service {
+	s := &Service{
+		storage:         storage,
+		keys:            keys,
+		clock:           clk,
+		minAge:          cfg.KYC.MinAge,
+		maxDocumentSize: cfg.KYC.MaxDocumentSize,
+	}
+	if s.minAge <= 0 {
+		s.minAge = defaultMinAge
+	}
+	if s.maxDocumentSize <= 0 {
+		s.maxDocumentSize = defaultMaxDocumentSize
+	}
+	return s
+}
+
+// GetProfile возвращает расшифрованную анкету; пустую, если клиент ее еще не заполнял
+func (s *Service) GetProfile(ctx context.Context, userID string) (*model.Profile, error) {
+	profile, err := s.storage.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return &model.Profile{UserID: userID, KYCStatus: model.KYCNotStarted}, nil
+	}
+	if err := s.decryptProfile(ctx, profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// UpdateProfile меняет заполненные поля анкеты. После подтверждения личности
+// клиент может менять только телефон и адрес.
+func (s *Service) UpdateProfile(ctx context.Context, userID string, data model.PersonalData) (*model.Profile, error) {
+	data, err := s.normalize(data)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	switch profile.KYCStatus {
+	case model.KYCPending:
+		return nil, ErrKYCInProgress
+	case model.KYCVerified:
+		if changed(profile.DateOfBirth, data.DateOfBirth) || changed(profile.Passport, data.Passport) || changed(profile.INN, data.INN) {
+			return nil, ErrIdentityLocked
+		}
+	}
+	merge(&profile.PersonalData, data)
+
+	if err := s.encryptProfile(profile); err != nil {
+		return nil, err
+	}
+	saved, err := s.storage.SaveProfile(ctx, profile)
+	if errors.Is(err, storage.ErrAlreadyExists) {
+		return nil, ErrDuplicateIdentity
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, ErrKYCInProgress
+	}
+	return profile, nil
+}
+
+// UploadDocument шифрует и сохраняет скан документа. Формат определяется по содержимому,
+// а не по заявленному клиентом типу.
+func (s *Service) UploadDocument(ctx context.Context, doc model.KYCDocument) (*model.KYCDocument, error) {
+	if !model.ValidDocumentType(doc.Type) {
+		return nil, ErrInvalidDocumentType
+	}
+	if int64(len(doc.Content)) > s.maxDocumentSize {
+		return nil, ErrDocumentTooLarge
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(doc.Content), ";")
+	if len(doc.Content) == 0 || !slices.Contains(AllowedContentTypes, contentType) {
+		return nil, ErrInvalidDocument
+	}
+	profile, err := s.storage.GetProfile(ctx, doc.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if profile != nil && profile.KYCStatus == model.KYCPending {
+		return nil, ErrKYCInProgress
+	}
+	if profile != nil && profile.KYCStatus == model.KYCVerified {
+		return nil, ErrKYCAlreadyVerified
+	}
+	docs, err := s.storage.GetKYCDocuments(ctx, doc.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) >= maxDocuments {
+		return nil, ErrTooManyDocuments
+	}
+
+	doc.ContentType = contentType
+	doc.Size = len(doc.Content)
+	doc.FileName = SanitizeFileName(doc.FileName)
+	doc.EncryptedContent, doc.KeyVersion, err = s.keys.Encrypt(doc.Content)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка шифрования документа: %w", err)
+	}
+	if err := s.storage.AddKYCDocument(ctx, &doc); err != nil {
+		return nil, err
+	}
+	doc.Content = nil
+	return &doc, nil
+}
+
+func (s *Service) GetDocuments(ctx context.Context, userID string) ([]*model.KYCDocument, error) {
+	return s.storage.GetKYCDocuments(ctx, userID)
+}
+
+// GetDocument возвращает документ с расшифрованным содержимым для проверки сотрудником
+func (s *Service) GetDocument(ctx context.Context, docID int64) (*model.KYCDocument, error) {
+	doc, err := s.storage.GetKYCDocument(ctx, docID)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, ErrDocumentNotFound
+	}
+	doc.Content, err = s.keys.Decrypt(ctx, doc.EncryptedContent)
+	if err != nil {
+		return nil, fmt.Errorf("document %d: %w", doc.ID, err)
+	}
+	return doc, nil
+}
+
+// SubmitKYC отправляет заполненную анкету со сканом паспорта на проверку
+func (s *Service) SubmitKYC(ctx context.Context, userID string) (*model.Profile, error) {
+	profile, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	switch profile.KYCStatus {
+	case model.KYCPending:
+		return nil, ErrKYCInProgress
+	case model.KYCVerified:
+		return nil, ErrKYCAlreadyVerified
+	}
+	if !profile.Complete() {
+		return nil, ErrProfileIncomplete
+	}
+	docs, err := s.storage.GetKYCDocuments(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(docs, func(d *model.KYCDocument) bool { return d.Type == model.DocumentPassportMain }) {
+		return nil, ErrPassportScanMissing
+	}
+	now := s.clock.Now()
+	submitted, err := s.storage.SubmitKYC(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !submitted {
+		return nil, ErrKYCInProgress
+	}
+	profile.KYCStatus = model.KYCPending
+	profile.KYCComment = ""
+	profile.KYCSubmittedAt = &now
+	return profile, nil
+}
+
+// RequireKYC — ошибка, если личность клиента не подтверждена
+func (s *Service) RequireKYC(ctx context.Context, userID string) error {
+	profile, err := s.storage.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if profile == nil || profile.KYCStatus != model.KYCVerified {
+		return ErrKYCNotVerified
+	}
+	return nil
+}
+
+// ReencryptPII перешифровывает активной версией ключа до batch анкет и документов,
+// зашифрованных устаревшими версиями. Запись, которую не удалось перешифровать, пропускается до следующего прохода,
+// чтобы не останавливать ротацию; ошибки возвращаются вместе. Возвращает число перешифрованных записей.
+func (s *Service) ReencryptPII(ctx context.Context, batch int) (int, error) {
+	active := s.keys.ActiveVersion()
+	profiles, err := s.storage.GetProfilesWithStaleKey(ctx, active, s.reencryptProfileAfter, batch)
+	if err != nil {
+		return 0, err
+	}
+	if len(profiles) < batch {
+		// проход закончен, следующий начнется сначала и повторит пропущенные анкеты
+		s.reencryptProfileAfter = ""
+	} else {
+		s.reencryptProfileAfter = profiles[len(profiles)-1].UserID
+	}
+	var (
+		n    int
+		errs []error
+	)
+	for _, profile := range profiles {
+		if err := s.reencryptProfile(ctx, profile); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		n++
+	}
+	if len(profiles) >= batch {
+		return n, errors.Join(errs...)
+	}
+
+	docs, err := s.storage.GetKYCDocumentsWithStaleKey(ctx, active, s.reencryptDocumentAfter, batch-len(profiles))
+	if err != nil {
+		return n, errors.Join(append(errs, err)...)
+	}
+	if len(docs) < batch-len(profiles) {
+		s.reencryptDocumentAfter = 0
+	} else {
+		s.reencryptDocumentAfter = docs[len(docs)-1].ID
+	}
+	for _, doc := range docs {
+		if err := s.reencryptDocument(ctx, doc); err != nil {
+			errs = append(errs, fmt.Errorf("document %d: %w", doc.ID, err))
+			continue
+		}
+		n++
+	}
+	return n, errors.Join(errs...)
+}
+
+func (s *Service) reencryptProfile(ctx context.Context, profile *model.Profile) error {
+	// ошибка расшифровки уже содержит user_id анкеты
+	if err := s.decryptProfile(ctx, profile); err != nil {
+		return err
+	}
+	if err := s.encryptProfile(profile); err != nil {
+		return fmt.Errorf("profile %s: %w", profile.UserID, err)
+	}
+	if err := s.storage.UpdateProfileEncryption(ctx, profile); err != nil {
+		return fmt.Errorf("profile %s: %w", profile.UserID, err)
+	}
+	return nil
+}
+
+func (s *Service) reencryptDocument(ctx context.Context, doc *model.KYCDocument) error {
+	content, err := s.keys.Decrypt(ctx, doc.EncryptedContent)
+	if err != nil {
+		return err
+	}
+	doc.EncryptedContent, doc.KeyVersion, err = s.keys.Encrypt(content)
+	if err != nil {
+		return err
+	}
+	return s.storage.UpdateKYCDocumentEncryption(ctx, doc)
+}
+
+// normalize проверяет и приводит к каноническому виду заполненные поля
+func (s *Service) normalize(data model.PersonalData) (model.PersonalData, error) {
+	var err error
+	if data.Phone != "" {
+		if data.Phone, err = NormalizePhone(data.Phone); err != nil {
+			return data, err
+		}
+	}
+	if data.Passport != "" {
+		if data.Passport, err = normalizePassport(data.Passport); err != nil {
+			return data, err
+		}
+	}
+	if data.INN != "" {
+		data.INN = strings.TrimSpace(data.INN)
+		if err := validateINN(data.INN); err != nil {
+			return data, err
+		}
+	}
+	if data.DateOfBirth != "" {
+		if err := validateDateOfBirth(data.DateOfBirth, s.minAge, s.clock.Now()); err != nil {
+			return data, err
+		}
+	}
+	data.Address = strings.TrimSpace(data.Address)
+	if len([]rune(data.Address)) > maxAddressLength {
+		return data, ErrInvalidAddress
+	}
+	return data, nil
+}
+
+// encryptProfile шифрует персональные данные и пересчитывает отпечатки для проверки уникальности
+func (s *Service) encryptProfile(profile *model.Profile) error {
+	plaintext, err := json.Marshal(profile.PersonalData)
+	if err != nil {
+		return err
+	}
+	profile.EncryptedData, profile.KeyVersion, err = s.keys.Encrypt(plaintext)
+	if err != nil {
+		return fmt.Errorf("ошибка шифрования анкеты: %w", err)
+	}
+	profile.PhoneFingerprint = s.fingerprint("phone", profile.Phone)
+	profile.PassportFingerprint = s.fingerprint("passport", profile.Passport)
+	profile.INNFingerprint = s.fingerprint("inn", profile.INN)
+	return nil
+}
+
+func (s *Service) decryptProfile(ctx context.Context, profile *model.Profile) error {
+	plaintext, err := s.keys.Decrypt(ctx, profile.EncryptedData)
+	if err != nil {
+		return fmt.Errorf("profile %s: %w", profile.UserID, err)
+	}
+	return json.Unmarshal(plaintext, &profile.PersonalData)
+}
+
+// fingerprint разделяет отпечатки разных полей, чтобы совпадающие строки не давали одинаковый HMAC
+func (s *Service) fingerprint(field, value string) []byte {
+	if value == "" {
+		return nil
+	}
+	return s.keys.Fingerprint([]byte(field + "|" + value))
+}
+
+func merge(dst *model.PersonalData, src model.PersonalData) {
+	for _, f := range []struct{ dst, src *string }{
+		{&dst.DateOfBirth, &src.DateOfBirth},
+		{&dst.Phone, &src.Phone},
+		{&dst.Passport, &src.Passport},
+		{&dst.INN, &src.INN},
+		{&dst.Address, &src.Address},
+	} {
+		if *f.src != "" {
+			*f.dst = *f.src
+		}
+	}
+}
+
+// changed — клиент передал новое значение поля, отличное от сохраненного
+func changed(current, update string) bool {
+	return update != "" && update != current
+}
+
+// SanitizeFileName оставляет только имя файла без пути
+func SanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		return "document"
+	}
+	if len(name) > 255 {
+		name = name[len(name)-255:]
+	}
+	return name
+}
@@ -0,0 +1,100 @@
+package profile
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const maxAddressLength = 500
+
+var (
+	ErrInvalidPhone       = errors.New("неверный номер телефона, ожидается российский номер +7XXXXXXXXXX")
+	ErrInvalidPassport    = errors.New("неверные серия и номер паспорта, ожидается 10 цифр")
+	ErrInvalidINN         = errors.New("неверный ИНН")
+	ErrInvalidDateOfBirth = errors.New("неверная дата рождения")
+	ErrTooYoung           = errors.New("клиент не достиг минимального возраста")
+	ErrInvalidAddress     = errors.New("адрес не длиннее 500 символов")
+)
+
+// NormalizePhone приводит российский номер к виду +7XXXXXXXXXX.
+// Допускаются пробелы, дефисы и скобки, а также префиксы 8 и 7 без плюса.
+func NormalizePhone(phone string) (string, error) {
+	digits := make([]byte, 0, 11)
+	for i, r := range phone {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = append(digits, byte(r))
+		case r == '+' && i == 0, r == ' ', r == '-', r == '(', r == ')':
+		default:
+			return "", ErrInvalidPhone
+		}
+	}
+	if len(digits) != 11 || (digits[0] != '7' && digits[0] != '8') {
+		return "", ErrInvalidPhone
+	}
+	if digits[0] == '8' && strings.HasPrefix(phone, "+") {
+		return "", ErrInvalidPhone
+	}
+	return "+7" + string(digits[1:]), nil
+}
+
+// normalizePassport оставляет только цифры серии и номера
+func normalizePassport(passport string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, passport)
+	if len(digits) != 10 || !isDigits(digits) {
+		return "", ErrInvalidPassport
+	}
+	return digits, nil
+}
+
+var (
+	innWeights11 = []int{7, 2, 4, 10, 3, 5, 9, 4, 6, 8}
+	innWeights12 = []int{3, 7, 2, 4, 10, 3, 5, 9, 4, 6, 8}
+)
+
+// validateINN проверяет контрольные цифры ИНН физического лица (12 цифр)
+func validateINN(inn string) error {
+	if len(inn) != 12 || !isDigits(inn) {
+		return ErrInvalidINN
+	}
+	if innControl(inn, innWeights11) != int(inn[10]-'0') || innControl(inn, innWeights12) != int(inn[11]-'0') {
+		return ErrInvalidINN
+	}
+	return nil
+}
+
+func innControl(inn string, weights []int) int {
+	sum := 0
+	for i, w := range weights {
+		sum += int(inn[i]-'0') * w
+	}
+	return sum % 11 % 10
+}
+
+// validateDateOfBirth проверяет формат YYYY-MM-DD и возраст клиента на дату now
+func validateDateOfBirth(dob string, minAge int, now time.Time) error {
+	born, err := time.Parse(time.DateOnly, dob)
+	if err != nil || born.After(now) || born.Year() < now.Year()-120 {
+		return ErrInvalidDateOfBirth
+	}
+	if now.Before(born.AddDate(minAge, 0, 0)) {
+		return ErrTooYoung
+	}
+	return nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"crypto"
 	"time"
 
 	"BankingApp/internal/model"
@@ -9,23 +10,176 @@ import (
 
 type UserService interface {
 	Register(ctx context.Context, email, username, password, fullName string) (*model.User, error)
-	Authenticate(ctx context.Context, email, password string) (jwtToken string, expiresAt time.Time, err error)
+	Authenticate(ctx context.Context, login, password string, client model.ClientInfo) (*model.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*model.TokenPair, error)
+	Logout(ctx context.Context, userID, sessionID, jti string, expiresAt time.Time) error
+	GetSessions(ctx context.Context, userID string) ([]*model.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	IsTokenRevoked(ctx context.Context, jti, sessionID string) (bool, error)
+	PurgeExpiredTokens(ctx context.Context) (int64, error) // для фоновой очистки
 	GetByID(ctx context.Context, userID string) (*model.User, error)
+	EnrollTOTP(ctx context.Context, userID string) (*model.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userID, code string) (recoveryCodes []string, err error)
+	DisableTOTP(ctx context.Context, userID string, stepUp model.StepUp) error
+	// CompleteMFA обменивает частичный токен и второй фактор на пару токенов
+	CompleteMFA(ctx context.Context, mfaToken string, stepUp model.StepUp, client model.ClientInfo) (*model.TokenPair, error)
+	// StepUp — повторное подтверждение личности для чувствительных операций
+	StepUp(ctx context.Context, userID string, stepUp model.StepUp) error
+	// ForgotPassword отправляет токен сброса пароля; ответ не раскрывает, существует ли пользователь
+	ForgotPassword(ctx context.Context, login string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	ChangePassword(ctx context.Context, userID, sessionID string, change model.PasswordChange) error
+	VerifyEmail(ctx context.Context, userID string, expires int64, signature string) error
+	ResendVerification(ctx context.Context, userID string) error
+	// RequireVerified — ошибка, если пользователь не активен, в том числе еще не подтвердил email
+	RequireVerified(ctx context.Context, userID string) error
+	// VerificationKey — открытый ключ подписи access-токенов по kid
+	VerificationKey(ctx context.Context, kid, alg string) (crypto.PublicKey, error)
+	JWKS() *model.JWKSet
+
+	// Машинные клиенты владельца
+	CreateAPIClient(ctx context.Context, ownerID, name string, scopes []string, rateLimit int) (*model.APIClientCredentials, error)
+	GetAPIClients(ctx context.Context, ownerID string) ([]*model.APIClient, error)
+	// RotateAPIClientSecret выпускает новый секрет; старый действует еще период ротации
+	RotateAPIClientSecret(ctx context.Context, ownerID, clientID string) (*model.APIClientCredentials, error)
+	RevokeAPIClient(ctx context.Context, ownerID, clientID string) error
+	// IssueClientToken — OAuth2 client credentials: access-токен с запрошенными scopes (пусто — все выданные клиенту)
+	IssueClientToken(ctx context.Context, clientID, clientSecret string, scopes []string) (*model.ClientToken, error)
+	// AuthenticateAPIKey проверяет ключ из заголовка X-API-Key
+	AuthenticateAPIKey(ctx context.Context, apiKey string) (*model.APIClient, error)
+	// ClientRateLimit — лимит запросов в минуту; 0 — клиент отозван или не найден
+	ClientRateLimit(ctx context.Context, clientID string) (int, error)
 }
 
 type BankingService interface {
 	CreateAccount(ctx context.Context, userID string, currency string) (*model.Account, error)
 	Deposit(ctx context.Context, accountID int64, amount float64) error
 	Withdraw(ctx context.Context, accountID int64, amount float64) error
-	Transfer(ctx context.Context, fromAccountID, toAccountID int64, amount float64) error
+	// Transfer переводит amount в валюте счета отправителя; между валютами — по котировке quoteID или текущему курсу.
+	// description — назначение платежа, записывается на обе ноги перевода.
+	Transfer(ctx context.Context, fromAccountID, toAccountID int64, amount float64, quoteID, description string) (*model.Transfer, error)
+	// TransferOnce проводит перевод не больше одного раза на ключ, повтор дает ErrAlreadyTransferred
+	TransferOnce(ctx context.Context, idempotencyKey string, fromAccountID, toAccountID int64, amount float64, description string) (*model.Transfer, error)
 	GetAccountsByUser(ctx context.Context, userID string) ([]*model.Account, error)
 	GetAccountByID(ctx context.Context, accountID int64) (*model.Account, error)
+	// GetAccountByNumber ищет счет по 20-значному номеру с проверкой защитного ключа
+	GetAccountByNumber(ctx context.Context, number string) (*model.Account, error)
+	// AssignAccountNumbers выдает номера счетам, открытым до их появления (для фоновой задачи)
+	AssignAccountNumbers(ctx context.Context, batch int) (int, error)
+	// ProcessOverdrafts начисляет проценты по овердрафтам за прошедший день и списывает их раз в месяц (для фоновой задачи)
+	ProcessOverdrafts(ctx context.Context) (accrued, posted int, err error)
+	// GetProducts — сберегательные счета и вклады, которые клиент может открыть
+	GetProducts() []model.Product
+	// OpenProduct открывает сберегательный счет или вклад и пополняет его со счета fundingAccountID клиента
+	OpenProduct(ctx context.Context, userID, code string, fundingAccountID int64, amount float64, rollover *bool) (*model.Account, error)
+	SetDepositRollover(ctx context.Context, accountID int64, rollover bool) error
+	// ProcessInterest начисляет проценты по сберегательным продуктам, капитализирует их и обслуживает окончание вкладов (для фоновой задачи)
+	ProcessInterest(ctx context.Context) (accrued, paid int, err error)
+	// PlanOverdraft проверяет условия овердрафта для AdminService и возвращает округленный лимит
+	PlanOverdraft(ctx context.Context, accountID int64, limit, rate float64) (float64, error)
+	GetTransactions(ctx context.Context, accountID int64) ([]*model.Transaction, error)
+	GetTransaction(ctx context.Context, transactionID int64) (*model.Transaction, error)
+	// PlanReversal рассчитывает компенсирующие проводки для сторно amount в валюте операции, 0 — весь остаток
+	PlanReversal(ctx context.Context, transactionID int64, amount float64) ([]model.ReversalLeg, error)
+	// CloseAccount закрывает счет с нулевым остатком вместе с его картами
+	CloseAccount(ctx context.Context, accountID int64) error
+	// SetAccountFrozen — заморозка счета владельцем, блокирует списания
+	SetAccountFrozen(ctx context.Context, accountID int64, frozen bool) error
+	// GetLimits — лимиты снятий и переводов по счету и их использованная часть
+	GetLimits(ctx context.Context, accountID int64) ([]*model.AccountLimits, error)
+	// LowerLimits временно снижает лимиты операции по счету до expiresAt
+	LowerLimits(ctx context.Context, accountID int64, operation string, limits model.Limits, expiresAt time.Time) (*model.LimitOverride, error)
+	// ResetLimits досрочно возвращает тарифные лимиты операции
+	ResetLimits(ctx context.Context, accountID int64, operation string) error
+}
+
+// AliasService — переводы по телефону и email, как в СБП
+type AliasService interface {
+	// Register привязывает собственный подтвержденный телефон или email клиента к его счету
+	Register(ctx context.Context, userID, aliasType string, accountID int64) (*model.Alias, error)
+	GetAliases(ctx context.Context, userID string) ([]*model.Alias, error)
+	Delete(ctx context.Context, userID, aliasType string) error
+	// Lookup возвращает маскированное имя получателя; поиски ограничены лимитом против перебора
+	Lookup(ctx context.Context, userID string, target model.AliasTarget) (*model.AliasLookup, error)
+	// Resolve возвращает счет получателя для перевода, учитывается в том же лимите
+	Resolve(ctx context.Context, userID string, target model.AliasTarget) (int64, error)
+	// ResolveSealed — Resolve по отпечатку сохраненного получателя, учитывается в том же лимите
+	ResolveSealed(ctx context.Context, userID string, fingerprint []byte) (int64, error)
+	// ResolveScheduled — ResolveSealed для регулярных переводов, в лимите поиска не учитывается
+	ResolveScheduled(ctx context.Context, fingerprint []byte) (int64, error)
+	// Seal нормализует телефон или email и возвращает отпечаток для поиска и маскированную форму
+	Seal(target model.AliasTarget) (fingerprint []byte, masked string, err error)
+	// CountLookup учитывает в том же лимите поиск получателя по номеру счета
+	CountLookup(ctx context.Context, userID string) error
+}
+
+// PayeeService — сохраненные получатели и шаблоны платежей
+type PayeeService interface {
+	CreatePayee(ctx context.Context, userID string, payee model.Payee) (*model.Payee, error)
+	GetPayees(ctx context.Context, userID string) ([]*model.Payee, error)
+	GetPayee(ctx context.Context, userID string, payeeID int64) (*model.Payee, error)
+	DeletePayee(ctx context.Context, userID string, payeeID int64) error
+	// SealAliases заменяет отпечатками телефоны и email получателей, сохраненных в открытом виде (для фоновой задачи)
+	SealAliases(ctx context.Context, batch int) (int, error)
+	CreateTemplate(ctx context.Context, userID string, template model.PaymentTemplate) (*model.PaymentTemplate, error)
+	GetTemplates(ctx context.Context, userID string) ([]*model.PaymentTemplate, error)
+	GetTemplate(ctx context.Context, userID string, templateID int64) (*model.PaymentTemplate, error)
+	DeleteTemplate(ctx context.Context, userID string, templateID int64) error
+}
+
+// StandingOrderService — регулярные переводы сохраненным получателям по расписанию
+type StandingOrderService interface {
+	Create(ctx context.Context, userID string, order model.StandingOrder) (*model.StandingOrder, error)
+	GetStandingOrders(ctx context.Context, userID string) ([]*model.StandingOrder, error)
+	GetExecutions(ctx context.Context, userID string, orderID int64) ([]*model.StandingOrderExecution, error)
+	Pause(ctx context.Context, userID string, orderID int64) (*model.StandingOrder, error)
+	Resume(ctx context.Context, userID string, orderID int64) (*model.StandingOrder, error)
+	Cancel(ctx context.Context, userID string, orderID int64) (*model.StandingOrder, error)
+	// ExecuteDue проводит платежи, срок которых наступил (для фоновой задачи)
+	ExecuteDue(ctx context.Context) (int, error)
+}
+
+// DisputeService — обращения клиентов по операциям и их рассмотрение бэк-офисом
+type DisputeService interface {
+	Open(ctx context.Context, userID string, transactionID int64, reason, description string) (*model.Dispute, error)
+	GetDisputes(ctx context.Context, userID string) ([]*model.Dispute, error)
+	GetDispute(ctx context.Context, userID string, disputeID int64) (*model.Dispute, error)
+	AddAttachment(ctx context.Context, userID string, attachment model.DisputeAttachment) (*model.DisputeAttachment, error)
+	Withdraw(ctx context.Context, userID string, disputeID int64) error
+
+	// для бэк-офиса
+	ListDisputes(ctx context.Context, status string) ([]*model.Dispute, error)
+	GetDisputeByID(ctx context.Context, disputeID int64) (*model.Dispute, error)
+	GetAttachment(ctx context.Context, attachmentID int64) (*model.DisputeAttachment, error) // с расшифровкой
+	StartReview(ctx context.Context, disputeID int64) error
+	// Resolve закрывает обращение сторно операции или отказом с записью в журнал
+	Resolve(ctx context.Context, actorID string, disputeID int64, resolution model.DisputeResolution) (*model.Dispute, error)
+	ReencryptAttachments(ctx context.Context, batch int) (int, error) // для фоновой ротации ключей
+}
+
+type FXService interface {
+	// Convert считает конвертацию по текущему курсу со спредом без сохранения котировки
+	Convert(ctx context.Context, from, to string, amount float64) (*model.FXQuote, error)
+	// Quote фиксирует курс для клиента на ограниченное время
+	Quote(ctx context.Context, userID, from, to string, amount float64) (*model.FXQuote, error)
+	// QuoteForTransfer возвращает действующую котировку клиента на эти валюты и сумму
+	QuoteForTransfer(ctx context.Context, quoteID, userID, from, to string, amount float64) (*model.FXQuote, error)
 }
 
 type CardService interface {
-	GenerateVirtualCard(ctx context.Context, accountID int64, cardholderName string) (*model.Card, error)
+	GenerateVirtualCard(ctx context.Context, accountID int64, cardholderName string, dynamicCVV bool) (*model.Card, error)
 	GetCardsByAccount(ctx context.Context, accountID int64) ([]*model.Card, error)
-	GetCardByIDForOwner(ctx context.Context, cardID, ownerUserID int64) (*model.Card, error) // с расшифровкой
+	GetCardByIDForOwner(ctx context.Context, cardID int64, ownerUserID string) (*model.Card, error) // с расшифровкой
+	FindByPAN(ctx context.Context, pan string) (*model.Card, error)
+	SetPIN(ctx context.Context, cardID int64, pin string) error
+	UnlockPIN(ctx context.Context, cardID int64) error
+	IssueToken(ctx context.Context, cardID int64, req model.CardToken) (*model.CardToken, error)
+	GetTokens(ctx context.Context, cardID int64) ([]*model.CardToken, error)
+	SetTokenStatus(ctx context.Context, cardID, tokenID int64, status string) error
+	// Authorize проверяет реквизиты карты или токена клиента ownerUserID при проведении операции
+	Authorize(ctx context.Context, ownerUserID string, auth model.CardAuthorization) (*model.Card, error)
+	ReencryptPANs(ctx context.Context, batch int) (int, error)               // для фоновой ротации ключей
+	ProcessExpiry(ctx context.Context) (expired int, renewed int, err error) // для фонового истечения и перевыпуска
 }
 
 type CreditService interface {
@@ -33,10 +187,16 @@ type CreditService interface {
 	// GeneratePaymentSchedule(ctx context.Context, creditID int64) ([]*model.PaymentSchedule, error)
 	// AutoWithdrawCreditPayments(ctx context.Context, now time.Time) error // для background-шейдулера
 	// ProcessFine(ctx context.Context, creditID int64, overdueAmount float64) error
-	// GetCreditsByUser(ctx context.Context, userID int64) ([]*model.Credit, error)
+	GetCreditsByUser(ctx context.Context, userID string) ([]*model.Credit, error)
 	// GetPaymentSchedule(ctx context.Context, creditID int64) ([]*model.PaymentSchedule, error)
 }
 
+type NotificationService interface {
+	// Notify отправляет уведомление пользователю по его контактам
+	Notify(ctx context.Context, userID, subject, body string) error
+	SendEmail(ctx context.Context, to, subject, body string) error
+}
+
 type AnalyticService interface {
 	// GetMonthlyReport(ctx context.Context, userID int64, month time.Month, year int) (*model.Analytics, error)
 	// GetCreditLoadAnalytics(ctx context.Context, userID int64) (*model.CreditLoadAnalytics, error)
@@ -47,3 +207,38 @@ type IntegrationService interface {
 	GetCentralBankKeyRate(ctx context.Context, date time.Time) (float64, error)       // SOAP запрос и парсинг XML
 	SendPaymentNotificationEmail(ctx context.Context, to, subject, body string) error // SMTP/SIMPLE
 }
+
+// ProfileService — анкета клиента, документы и проверка личности (KYC)
+type ProfileService interface {
+	GetProfile(ctx context.Context, userID string) (*model.Profile, error) // с расшифровкой
+	UpdateProfile(ctx context.Context, userID string, data model.PersonalData) (*model.Profile, error)
+	UploadDocument(ctx context.Context, doc model.KYCDocument) (*model.KYCDocument, error)
+	GetDocuments(ctx context.Context, userID string) ([]*model.KYCDocument, error)
+	GetDocument(ctx context.Context, docID int64) (*model.KYCDocument, error) // с расшифровкой, для сотрудников
+	SubmitKYC(ctx context.Context, userID string) (*model.Profile, error)
+	// RequireKYC — ошибка, если личность клиента не подтверждена
+	RequireKYC(ctx context.Context, userID string) error
+	ReencryptPII(ctx context.Context, batch int) (int, error) // для фоновой ротации ключей
+}
+
+type PrivacyService interface {
+	// Export собирает машиночитаемую выгрузку данных клиента
+	Export(ctx context.Context, userID string) (*model.DataExport, error)
+	// Erase закрывает счета с нулевым остатком и обезличивает клиента, сохраняя финансовые записи
+	Erase(ctx context.Context, userID string) error
+}
+
+// AdminService — действия сотрудников банка. Каждое действие требует кода причины и попадает в журнал.
+type AdminService interface {
+	SetUserRole(ctx context.Context, actorID, userID, role string, reason model.AdminReason) error
+	FreezeAccount(ctx context.Context, actorID string, accountID int64, reason model.AdminReason) error
+	UnfreezeAccount(ctx context.Context, actorID string, accountID int64, reason model.AdminReason) error
+	AdjustBalance(ctx context.Context, actorID string, accountID int64, amount float64, reason model.AdminReason) error
+	// SetOverdraft меняет лимит овердрафта и годовую ставку по нему, 0 — отключает овердрафт
+	SetOverdraft(ctx context.Context, actorID string, accountID int64, limit, rate float64, reason model.AdminReason) error
+	// ReverseTransaction сторнирует операцию на amount в ее валюте, 0 — на весь несторнированный остаток
+	ReverseTransaction(ctx context.Context, actorID string, transactionID int64, amount float64, reason model.AdminReason) ([]*model.Transaction, error)
+	// ReviewKYC завершает проверку личности: подтверждает анкету или отклоняет ее с комментарием для клиента
+	ReviewKYC(ctx context.Context, actorID, userID string, approve bool, reason model.AdminReason) error
+	GetAuditLog(ctx context.Context, targetType, targetID string) ([]*model.AdminAction, error)
+}
@@ -0,0 +1,86 @@
+package standingorders
+
+import (
+	"BankingApp/internal/model"
+	"time"
+)
+
+// calendar — рабочие дни банка: все, кроме суббот, воскресений и праздников из конфигурации
+type calendar struct {
+	holidays map[string]struct{}
+}
+
+func newCalendar(holidays []string) (*calendar, error) {
+	c := &calendar{holidays: make(map[string]struct{}, len(holidays))}
+	for _, day := range holidays {
+		date, err := time.Parse(time.DateOnly, day)
+		if err != nil {
+			return nil, err
+		}
+		c.holidays[date.Format(time.DateOnly)] = struct{}{}
+	}
+	return c, nil
+}
+
+func (c *calendar) isBusinessDay(date time.Time) bool {
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return false
+	}
+	_, holiday := c.holidays[date.Format(time.DateOnly)]
+	return !holiday
+}
+
+// adjust переносит дату на ближайший рабочий день по правилу поручения
+func (c *calendar) adjust(date time.Time, rule string) time.Time {
+	step := 0
+	switch rule {
+	case model.BusinessDayFollowing:
+		step = 1
+	case model.BusinessDayPreceding:
+		step = -1
+	default:
+		return date
+	}
+	for !c.isBusinessDay(date) {
+		date = date.AddDate(0, 0, step)
+	}
+	return date
+}
+
+// nominalDate — первая дата по расписанию поручения не раньше from, без переноса на рабочий день
+func nominalDate(order *model.StandingOrder, from time.Time) time.Time {
+	from = dateOf(from)
+	switch order.Frequency {
+	case model.FrequencyWeekly:
+		days := (*order.Weekday - int(from.Weekday()) + 7) % 7
+		return from.AddDate(0, 0, days)
+	case model.FrequencyEndOfMonth:
+		return lastDayOfMonth(from.Year(), from.Month())
+	default:
+		date := monthDay(from.Year(), from.Month(), order.DayOfMonth)
+		if date.Before(from) {
+			next := from.AddDate(0, 0, 1-from.Day()).AddDate(0, 1, 0)
+			date = monthDay(next.Year(), next.Month(), order.DayOfMonth)
+		}
+		return date
+	}
+}
+
+// monthDay — день месяца; в коротких месяцах — последний день
+func monthDay(year int, month time.Month, day int) time.Time {
+	last := lastDayOfMonth(year, month)
+	if day > last.Day() {
+		return last
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func lastDayOfMonth(year int, month time.Month) time.Time {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+}
+
+// dateOf — календарная дата момента t в полночь UTC
+func dateOf(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,368 @@
+package standingorders
+
+import (
+	"BankingApp/internal/config"
+	"BankingApp/internal/model"
+	"BankingApp/internal/service"
+	aliasService "BankingApp/internal/service/aliases"
+	bankingService "BankingApp/internal/service/banking"
+	payeeService "BankingApp/internal/service/payees"
+	"BankingApp/internal/storage"
+	"BankingApp/pkg/clock"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// claimLease — на сколько исполнитель резервирует поручение; если экземпляр упал во время платежа,
+// поручение вернется в очередь после истечения резерва
+const claimLease = 15 * time.Minute
+
+var (
+	ErrOrderNotFound      = errors.New("регулярный перевод не найден")
+	ErrAccountNotFound    = errors.New("счет не найден или закрыт")
+	ErrInvalidAmount      = errors.New("сумма должна быть положительной")
+	ErrDescriptionTooLong = errors.New("назначение платежа слишком длинное")
+	ErrInvalidFrequency   = errors.New("периодичность должна быть weekly, monthly или end_of_month")
+	ErrInvalidDay         = errors.New("укажите день месяца от 1 до 31 или день недели от 0 до 6")
+	ErrInvalidBusinessDay = errors.New("перенос с выходных должен быть none, following или preceding")
+	ErrInvalidPeriod      = errors.New("дата начала не может быть в прошлом, дата окончания — раньше начала")
+	ErrInvalidOccurrences = errors.New("число платежей не может быть отрицательным")
+	// ErrInvalidStatus — пауза возможна для активного поручения, возобновление — для приостановленного,
+	// отмена — для активного или приостановленного
+	ErrInvalidStatus = errors.New("действие недоступно в текущем статусе перевода")
+)
+
+var _ service.StandingOrderService = (*Service)(nil)
+
+// Service ведет регулярные переводы сохраненным получателям и исполняет их по расписанию.
+// Платежи проводятся BankingService.Transfer, получатель определяется в момент исполнения.
+type Service struct {
+	storage  storage.StandingOrderStorage
+	banking  service.BankingService
+	payees   service.PayeeService
+	aliases  service.AliasService
+	notifier service.NotificationService
+	clock    clock.Clock
+	calendar *calendar
+	cfg      config.StandingOrders
+}
+
+func NewStandingOrderService(storage storage.StandingOrderStorage, banking service.BankingService, payees service.PayeeService,
+	aliases service.AliasService, notifier service.NotificationService, clk clock.Clock, cfg *config.Config) (*Service, error) {
+	ordersCfg := cfg.StandingOrders
+	if ordersCfg.MaxRetries < 0 || ordersCfg.MaxRetries > 0 && ordersCfg.RetryInterval <= 0 {
+		return nil, fmt.Errorf("standing_orders: max_retries must be non-negative and retry_interval positive")
+	}
+	cal, err := newCalendar(ordersCfg.Holidays)
+	if err != nil {
+		return nil, fmt.Errorf("standing_orders.holidays: %w", err)
+	}
+	return &Service{
+		storage:  storage,
+		banking:  banking,
+		payees:   payees,
+		aliases:  aliases,
+		notifier: notifier,
+		clock:    clk,
+		calendar: cal,
+		cfg:      ordersCfg,
+	}, nil
+}
+
+// Create проверяет поручение и планирует первый платеж не раньше даты начала
+func (s *Service) Create(ctx context.Context, userID string, order model.StandingOrder) (*model.StandingOrder, error) {
+	account, err := s.banking.GetAccountByID(ctx, order.FromAccountID)
+	if err != nil || account.UserID != userID || !account.IsActive {
+		return nil, ErrAccountNotFound
+	}
+	payee, err := s.payees.GetPayee(ctx, userID, order.PayeeID)
+	if err != nil {
+		return nil, err
+	}
+	if payee.Type == model.PayeeExternal {
+		return nil, payeeService.ErrExternalPayee
+	}
+	order.Amount = model.RoundAmount(order.Amount, account.Currency)
+	if order.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	order.Description = strings.TrimSpace(order.Description)
+	if utf8.RuneCountInString(order.Description) > bankingService.MaxDescriptionLength {
+		return nil, ErrDescriptionTooLong
+	}
+	if err := validateSchedule(&order); err != nil {
+		return nil, err
+	}
+
+	today := dateOf(s.clock.Now())
+	if order.StartDate.IsZero() {
+		order.StartDate = today
+	}
+	order.StartDate = dateOf(order.StartDate)
+	if order.StartDate.Before(today) {
+		return nil, ErrInvalidPeriod
+	}
+	if order.EndDate != nil {
+		end := dateOf(*order.EndDate)
+		if end.Before(order.StartDate) {
+			return nil, ErrInvalidPeriod
+		}
+		order.EndDate = &end
+	}
+	if order.MaxOccurrences < 0 {
+		return nil, ErrInvalidOccurrences
+	}
+
+	order.UserID = userID
+	order.Occurrences = 0
+	order.RetryCount = 0
+	order.Status = model.StandingOrderActive
+	s.schedule(&order, nominalDate(&order, order.StartDate))
+	return s.storage.CreateStandingOrder(ctx, &order)
+}
+
+func (s *Service) GetStandingOrders(ctx context.Context, userID string) ([]*model.StandingOrder, error) {
+	return s.storage.GetStandingOrders(ctx, userID)
+}
+
+// GetExecutions возвращает историю исполнения поручения клиента
+func (s *Service) GetExecutions(ctx context.Context, userID string, orderID int64) ([]*model.StandingOrderExecution, error) {
+	if _, err := s.get(ctx, userID, orderID); err != nil {
+		return nil, err
+	}
+	return s.storage.GetStandingOrderExecutions(ctx, orderID)
+}
+
+// Pause останавливает исполнение; платежи за время паузы не проводятся и потом не догоняются
+func (s *Service) Pause(ctx context.Context, userID string, orderID int64) (*model.StandingOrder, error) {
+	return s.transition(ctx, userID, orderID, func(order *model.StandingOrder) bool {
+		if order.Status != model.StandingOrderActive {
+			return false
+		}
+		order.Status = model.StandingOrderPaused
+		return true
+	})
+}
+
+// Resume возобновляет поручение со следующей даты по расписанию, начиная с сегодняшней
+func (s *Service) Resume(ctx context.Context, userID string, orderID int64) (*model.StandingOrder, error) {
+	return s.transition(ctx, userID, orderID, func(order *model.StandingOrder) bool {
+		if order.Status != model.StandingOrderPaused {
+			return false
+		}
+		from := dateOf(s.clock.Now())
+		if from.Before(order.StartDate) {
+			from = order.StartDate
+		}
+		order.Status = model.StandingOrderActive
+		s.schedule(order, nominalDate(order, from))
+		return true
+	})
+}
+
+func (s *Service) Cancel(ctx context.Context, userID string, orderID int64) (*model.StandingOrder, error) {
+	return s.transition(ctx, userID, orderID, func(order *model.StandingOrder) bool {
+		if order.Status != model.StandingOrderActive && order.Status != model.StandingOrderPaused {
+			return false
+		}
+		order.Status = model.StandingOrderCancelled
+		order.ScheduledFor = nil
+		order.NextRunAt = nil
+		return true
+	})
+}
+
+// transition меняет статус поручения, если apply разрешает переход из текущего статуса.
+// Обновление условно по прежнему статусу: параллельное изменение дает ErrInvalidStatus.
+func (s *Service) transition(ctx context.Context, userID string, orderID int64, apply func(*model.StandingOrder) bool) (*model.StandingOrder, error) {
+	order, err := s.get(ctx, userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	fromStatus := order.Status
+	if !apply(order) {
+		return nil, ErrInvalidStatus
+	}
+	order.RetryCount = 0
+	order.UpdatedAt = s.clock.Now()
+	updated, err := s.storage.UpdateStandingOrderSchedule(ctx, order, fromStatus)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrInvalidStatus
+	}
+	return order, nil
+}
+
+func (s *Service) get(ctx context.Context, userID string, orderID int64) (*model.StandingOrder, error) {
+	order, err := s.storage.GetStandingOrder(ctx, userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, ErrOrderNotFound
+	}
+	return order, nil
+}
+
+// ExecuteDue исполняет поручения, срок которых наступил. При нехватке средств платеж повторяется
+// через RetryInterval до MaxRetries раз, затем, как и при других отказах, платеж периода
+// пропускается, клиент получает уведомление, а поручение переходит к следующей дате.
+// Неожиданные ошибки не записываются в историю: поручение вернется в очередь после истечения резерва.
+func (s *Service) ExecuteDue(ctx context.Context) (int, error) {
+	now := s.clock.Now()
+	orders, err := s.storage.ClaimDueStandingOrders(ctx, now, claimLease, s.cfg.Batch)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		executed int
+		errs     []error
+	)
+	for _, order := range orders {
+		if err := s.execute(ctx, order, now); err != nil {
+			errs = append(errs, fmt.Errorf("standing order %d: %w", order.ID, err))
+			continue
+		}
+		executed++
+	}
+	return executed, errors.Join(errs...)
+}
+
+func (s *Service) execute(ctx context.Context, order *model.StandingOrder, now time.Time) error {
+	execution := &model.StandingOrderExecution{
+		OrderID:      order.ID,
+		ScheduledFor: *order.ScheduledFor,
+		Attempt:      order.RetryCount + 1,
+		Amount:       order.Amount,
+		ExecutedAt:   now,
+	}
+	err := s.transfer(ctx, order)
+	switch {
+	case err == nil, errors.Is(err, bankingService.ErrAlreadyTransferred):
+		execution.Status = model.ExecutionSuccess
+		order.Occurrences++
+		order.RetryCount = 0
+		s.schedule(order, order.ScheduledFor.AddDate(0, 0, 1))
+	case errors.Is(err, bankingService.ErrInsufficientFunds) && order.RetryCount < s.cfg.MaxRetries:
+		execution.Status = model.ExecutionRetrying
+		execution.Error = err.Error()
+		order.RetryCount++
+		retryAt := now.Add(time.Duration(s.cfg.RetryInterval))
+		order.NextRunAt = &retryAt
+	case isPaymentFailure(err):
+		execution.Status = model.ExecutionFailed
+		execution.Error = err.Error()
+		order.RetryCount = 0
+		s.schedule(order, order.ScheduledFor.AddDate(0, 0, 1))
+	default:
+		return err
+	}
+	order.UpdatedAt = now
+	recorded, err := s.storage.RecordStandingOrderExecution(ctx, order, execution)
+	if err != nil {
+		return err
+	}
+	if !recorded {
+		// платеж, если он прошел, защищен ключом и повторно не проведется; попытку учтет следующий исполнитель
+		return errors.New("lease expired before the execution was recorded")
+	}
+	if execution.Status == model.ExecutionFailed {
+		_ = s.notifier.Notify(ctx, order.UserID, "Регулярный перевод не выполнен",
+			fmt.Sprintf("Перевод %.2f по поручению №%d за %s не выполнен: %s.",
+				order.Amount, order.ID, execution.ScheduledFor.Format(time.DateOnly), execution.Error))
+	}
+	return nil
+}
+
+// transfer определяет счет получателя и проводит платеж. Ключ платежа — поручение и плановая дата,
+// поэтому сбой или истекший резерв после перевода не приводят ко второму списанию за тот же период.
+func (s *Service) transfer(ctx context.Context, order *model.StandingOrder) error {
+	payee, err := s.payees.GetPayee(ctx, order.UserID, order.PayeeID)
+	if err != nil {
+		return err
+	}
+	var toAccountID int64
+	switch payee.Type {
+	case model.PayeeInternal:
+		account, err := s.banking.GetAccountByNumber(ctx, payee.AccountNumber)
+		if err != nil {
+			return err
+		}
+		toAccountID = account.ID
+	case model.PayeeAlias:
+		toAccountID, err = s.aliases.ResolveScheduled(ctx, payee.AliasFingerprint)
+		if err != nil {
+			return err
+		}
+	default:
+		return payeeService.ErrExternalPayee
+	}
+	key := fmt.Sprintf("standing_order:%d:%s", order.ID, order.ScheduledFor.Format(time.DateOnly))
+	_, err = s.banking.TransferOnce(ctx, key, order.FromAccountID, toAccountID, order.Amount, order.Description)
+	return err
+}
+
+// isPaymentFailure — отказ в платеже, который не исправится повтором в ближайшее время
+func isPaymentFailure(err error) bool {
+	for _, target := range []error{
+		bankingService.ErrInsufficientFunds, bankingService.ErrAccountClosed, bankingService.ErrAccountFrozen,
+		bankingService.ErrAccountNotFound, bankingService.ErrInvalidAccountNumber, payeeService.ErrPayeeNotFound,
+		payeeService.ErrExternalPayee, aliasService.ErrAliasNotFound, aliasService.ErrAccountNotFound,
+		bankingService.ErrLimitExceeded,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// schedule назначает ближайший платеж не раньше from или завершает поручение,
+// если срок действия или число платежей исчерпаны
+func (s *Service) schedule(order *model.StandingOrder, from time.Time) {
+	next := nominalDate(order, from)
+	if order.EndDate != nil && next.After(*order.EndDate) ||
+		order.MaxOccurrences > 0 && order.Occurrences >= order.MaxOccurrences {
+		order.Status = model.StandingOrderCompleted
+		order.ScheduledFor = nil
+		order.NextRunAt = nil
+		return
+	}
+	runAt := s.calendar.adjust(next, order.BusinessDay)
+	order.ScheduledFor = &next
+	order.NextRunAt = &runAt
+}
+
+func validateSchedule(order *model.StandingOrder) error {
+	switch order.Frequency {
+	case model.FrequencyMonthly:
+		if order.DayOfMonth < 1 || order.DayOfMonth > 31 {
+			return ErrInvalidDay
+		}
+		order.Weekday = nil
+	case model.FrequencyWeekly:
+		if order.Weekday == nil || *order.Weekday < 0 || *order.Weekday > 6 {
+			return ErrInvalidDay
+		}
+		order.DayOfMonth = 0
+	case model.FrequencyEndOfMonth:
+		order.DayOfMonth = 0
+		order.Weekday = nil
+	default:
+		return ErrInvalidFrequency
+	}
+	switch order.BusinessDay {
+	case "":
+		order.BusinessDay = model.BusinessDayNone
+	case model.BusinessDayNone, model.BusinessDayFollowing, model.BusinessDayPreceding:
+	default:
+		return ErrInvalidBusinessDay
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package users
+
+import (
+	"BankingApp/internal/model"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultClientTokenTTL      = 15 * time.Minute
+	defaultClientRateLimit     = 60
+	defaultClientMaxRateLimit  = 600
+	defaultSecretRotationGrace = 24 * time.Hour
+
+	clientIDPrefix = "cl_"
+	maxClientName  = 255
+)
+
+var (
+	ErrInvalidClient       = errors.New("неверные учетные данные клиента")
+	ErrInvalidScope        = errors.New("недопустимая область доступа")
+	ErrInvalidClientName   = errors.New("имя клиента обязательно и не длиннее 255 символов")
+	ErrInvalidRateLimit    = errors.New("недопустимый лимит запросов")
+	ErrAPIClientNotFound   = errors.New("клиент не найден")
+	ErrAPIClientNotAllowed = errors.New("машинные клиенты доступны только клиентам банка")
+)
+
+// CreateAPIClient регистрирует машинного клиента. Секрет возвращается один раз,
+// в базе хранится только его хеш.
+func (s *Service) CreateAPIClient(ctx context.Context, ownerID, name string, scopes []string, rateLimit int) (*model.APIClientCredentials, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxClientName {
+		return nil, ErrInvalidClientName
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, err
+	}
+	if len(scopes) == 0 {
+		return nil, ErrInvalidScope
+	}
+	if rateLimit == 0 {
+		rateLimit = s.clients.DefaultRateLimit
+	}
+	if rateLimit < 0 || rateLimit > s.clients.MaxRateLimit {
+		return nil, ErrInvalidRateLimit
+	}
+	owner, err := s.repo.FindByID(ctx, ownerID)
+	if err != nil || owner == nil {
+		return nil, errors.New("пользователь не найден")
+	}
+	if owner.Role != model.RoleCustomer {
+		// токен клиента не несет роли, поэтому сотрудникам он бесполезен и только расширяет поверхность атаки
+		return nil, ErrAPIClientNotAllowed
+	}
+
+	id, err := newClientID()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := newClientSecret()
+	if err != nil {
+		return nil, err
+	}
+	client := &model.APIClient{
+		ID:          id,
+		OwnerUserID: ownerID,
+		Name:        name,
+		Scopes:      scopes,
+		RateLimit:   rateLimit,
+		Status:      model.APIClientActive,
+	}
+	if err := s.apiClients.CreateAPIClient(ctx, client, hashToken(secret)); err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	return credentials(client, secret), nil
+}
+
+func (s *Service) GetAPIClients(ctx context.Context, ownerID string) ([]*model.APIClient, error) {
+	return s.apiClients.GetAPIClientsByOwner(ctx, ownerID)
+}
+
+// RotateAPIClientSecret выпускает новый секрет. Прежние секреты продолжают действовать
+// SecretRotationGrace, чтобы интеграция успела переключиться без простоя.
+func (s *Service) RotateAPIClientSecret(ctx context.Context, ownerID, clientID string) (*model.APIClientCredentials, error) {
+	client, err := s.ownedClient(ctx, ownerID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := newClientSecret()
+	if err != nil {
+		return nil, err
+	}
+	expireAt := time.Now().Add(time.Duration(s.clients.SecretRotationGrace))
+	if err := s.apiClients.AddAPIClientSecret(ctx, client.ID, hashToken(secret), expireAt); err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	return credentials(client, secret), nil
+}
+
+// RevokeAPIClient отзывает клиента; выданные ему токены перестают приниматься сразу
+func (s *Service) RevokeAPIClient(ctx context.Context, ownerID, clientID string) error {
+	if _, err := s.ownedClient(ctx, ownerID, clientID); err != nil {
+		return err
+	}
+	return s.apiClients.RevokeAPIClient(ctx, clientID, ownerID)
+}
+
+func (s *Service) IssueClientToken(ctx context.Context, clientID, clientSecret string, scopes []string) (*model.ClientToken, error) {
+	client, err := s.authenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	scopes, err = normalizeScopes(scopes)
+	if err != nil {
+		return nil, err
+	}
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(client.Scopes, scope) {
+			return nil, ErrInvalidScope
+		}
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(s.clients.TokenTTL))
+	claims := accessClaims{
+		ClientID:         client.ID,
+		Scope:            strings.Join(scopes, " "),
+		RegisteredClaims: s.registeredClaims(client.OwnerUserID, now, expiresAt),
+	}
+	claims.ID = uuid.New().String()
+	token, err := s.signToken(claims)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ClientToken{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(time.Duration(s.clients.TokenTTL).Seconds()),
+		Scope:       claims.Scope,
+	}, nil
+}
+
+// AuthenticateAPIKey проверяет ключ вида "client_id.secret"
+func (s *Service) AuthenticateAPIKey(ctx context.Context, apiKey string) (*model.APIClient, error) {
+	clientID, secret, ok := strings.Cut(apiKey, ".")
+	if !ok {
+		return nil, ErrInvalidClient
+	}
+	return s.authenticateClient(ctx, clientID, secret)
+}
+
+func (s *Service) ClientRateLimit(ctx context.Context, clientID string) (int, error) {
+	client, err := s.apiClients.GetAPIClient(ctx, clientID)
+	if err != nil {
+		return 0, err
+	}
+	if client == nil || client.Status != model.APIClientActive {
+		return 0, nil
+	}
+	return client.RateLimit, nil
+}
+
+// authenticateClient проверяет секрет и то, что владелец клиента по-прежнему может работать с API
+func (s *Service) authenticateClient(ctx context.Context, clientID, secret string) (*model.APIClient, error) {
+	if !strings.HasPrefix(clientID, clientIDPrefix) || secret == "" {
+		return nil, ErrInvalidClient
+	}
+	client, err := s.apiClients.FindAPIClientBySecret(ctx, clientID, hashToken(secret), time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	if client == nil {
+		return nil, ErrInvalidClient
+	}
+	if err := s.RequireVerified(ctx, client.OwnerUserID); err != nil {
+		return nil, ErrInvalidClient
+	}
+	return client, nil
+}
+
+func (s *Service) ownedClient(ctx context.Context, ownerID, clientID string) (*model.APIClient, error) {
+	client, err := s.apiClients.GetAPIClient(ctx, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	if client == nil || client.OwnerUserID != ownerID || client.Status != model.APIClientActive {
+		return nil, ErrAPIClientNotFound
+	}
+	return client, nil
+}
+
+// normalizeScopes проверяет области доступа и убирает повторы
+func normalizeScopes(scopes []string) ([]string, error) {
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !model.ValidScope(scope) {
+			return nil, ErrInvalidScope
+		}
+		if !slices.Contains(result, scope) {
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
+
+func credentials(client *model.APIClient, secret string) *model.APIClientCredentials {
+	return &model.APIClientCredentials{
+		Client:       client,
+		ClientSecret: secret,
+		APIKey:       client.ID + "." + secret,
+	}
+}
+
+func newClientID() (string, error) {
+	raw := make([]byte, 12)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return clientIDPrefix + hex.EncodeToString(raw), nil
+}
+
+func newClientSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
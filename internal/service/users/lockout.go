@@ -0,0 +1,62 @@
+package users
+
+import (
+	"BankingApp/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultAccountLockoutThreshold = 5
+	defaultIPLockoutThreshold      = 20
+	defaultLockoutBaseDelay        = time.Minute
+	defaultLockoutMaxDelay         = time.Hour
+	defaultLockoutWindow           = 24 * time.Hour
+)
+
+var ErrTooManyAttempts = errors.New("слишком много неудачных попыток входа, повторите позже")
+
+// checkLockout возвращает ErrTooManyAttempts, пока действует блокировка по ключу
+func (s *Service) checkLockout(ctx context.Context, scope, key string, now time.Time) error {
+	if key == "" {
+		return nil
+	}
+	lockout, err := s.lockouts.GetLockout(ctx, scope, key)
+	if err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+	if lockout != nil && lockout.LockedUntil != nil && now.Before(*lockout.LockedUntil) {
+		return ErrTooManyAttempts
+	}
+	return nil
+}
+
+// registerFailure учитывает неудачу и, начиная с порога, блокирует вход
+// на срок, удваивающийся с каждой следующей неудачей
+func (s *Service) registerFailure(ctx context.Context, scope, key string, now time.Time) error {
+	if key == "" {
+		return nil
+	}
+	lockout, err := s.lockouts.RegisterLoginFailure(ctx, scope, key, now, time.Duration(s.lockout.FailureWindow))
+	if err != nil {
+		return err
+	}
+	threshold := s.lockout.AccountThreshold
+	if scope == model.LockoutScopeIP {
+		threshold = s.lockout.IPThreshold
+	}
+	if lockout.Failures < threshold {
+		return nil
+	}
+	delay := time.Duration(s.lockout.BaseDelay)
+	maxDelay := time.Duration(s.lockout.MaxDelay)
+	for i := threshold; i < lockout.Failures && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return s.lockouts.LockLogin(ctx, scope, key, now.Add(delay))
+}
@@ -0,0 +1,266 @@
+package users
+
+import (
+	"BankingApp/internal/model"
+	"BankingApp/pkg/totp"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	totpIssuer        = "BankingApp"
+	mfaTokenTTL       = 5 * time.Minute
+	recoveryCodeCount = 10
+	// mfaMaxAttempts — сколько кодов можно предъявить по одному частичному токену, после этого он отзывается
+	mfaMaxAttempts = 3
+	// mfaPending — значение claim "mfa" частичного токена; middleware такие токены не принимает
+	mfaPending = "pending"
+)
+
+var (
+	ErrTOTPAlreadyEnabled = errors.New("двухфакторная аутентификация уже включена")
+	ErrTOTPNotEnrolled    = errors.New("двухфакторная аутентификация не подключена")
+	ErrInvalidOTP         = errors.New("неверный код подтверждения")
+	ErrInvalidMFAToken    = errors.New("недействительный токен второго фактора")
+	ErrStepUpRequired     = errors.New("требуется подтверждение операции")
+)
+
+type mfaClaims struct {
+	MFA string `json:"mfa"`
+	jwt.RegisteredClaims
+}
+
+// EnrollTOTP выпускает новый секрет. Второй фактор включается только после ConfirmTOTP.
+func (s *Service) EnrollTOTP(ctx context.Context, userID string) (*model.TOTPEnrollment, error) {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, errors.New("пользователь не найден")
+	}
+	current, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	if current != nil && current.Enabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, _, err := s.keys.Encrypt([]byte(secret))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetTOTPSecret(ctx, userID, encrypted); err != nil {
+		return nil, err
+	}
+	return &model.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP включает второй фактор по первому коду из приложения и возвращает
+// коды восстановления. Коды показываются один раз, в базе хранятся только их HMAC.
+func (s *Service) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	t, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	if t == nil {
+		return nil, ErrTOTPNotEnrolled
+	}
+	if t.Enabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	secret, err := s.keys.Decrypt(ctx, t.EncryptedSecret)
+	if err != nil {
+		return nil, err
+	}
+	counter, ok := totp.Validate(string(secret), code, time.Now())
+	if !ok {
+		return nil, ErrInvalidOTP
+	}
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([][]byte, recoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = generateRecoveryCode(); err != nil {
+			return nil, err
+		}
+		hashes[i] = s.recoveryCodeHash(userID, codes[i])
+	}
+	if err := s.repo.EnableTOTP(ctx, userID, counter, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *Service) DisableTOTP(ctx context.Context, userID string, stepUp model.StepUp) error {
+	if err := s.StepUp(ctx, userID, stepUp); err != nil {
+		return err
+	}
+	return s.repo.DisableTOTP(ctx, userID)
+}
+
+// CompleteMFA завершает вход по частичному токену и второму фактору. Токен одноразовый и отзывается
+// после mfaMaxAttempts неверных кодов; неудачи учитываются в тех же блокировках, что и неверный пароль.
+func (s *Service) CompleteMFA(ctx context.Context, mfaToken string, stepUp model.StepUp, client model.ClientInfo) (*model.TokenPair, error) {
+	var claims mfaClaims
+	err := s.parseToken(ctx, mfaToken, &claims)
+	if err != nil || claims.MFA != mfaPending || claims.Subject == "" || claims.ID == "" {
+		return nil, ErrInvalidMFAToken
+	}
+	now := time.Now()
+	if err := s.checkLockout(ctx, model.LockoutScopeIP, client.IP, now); err != nil {
+		return nil, err
+	}
+	accepted, err := s.repo.UseMFAChallenge(ctx, claims.ID, claims.Subject, mfaMaxAttempts, now)
+	if err != nil {
+		return nil, err
+	}
+	if !accepted {
+		return nil, ErrInvalidMFAToken
+	}
+	t, err := s.repo.GetTOTP(ctx, claims.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("CompleteMFA: %w", err)
+	}
+	if t == nil || !t.Enabled {
+		return nil, ErrInvalidMFAToken
+	}
+	if err := s.verifySecondFactor(ctx, t, stepUp); err != nil {
+		if errors.Is(err, ErrInvalidOTP) {
+			if err := s.registerFailure(ctx, model.LockoutScopeIP, client.IP, now); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+	// параллельный запрос с тем же токеном мог успеть войти первым
+	deleted, err := s.repo.DeleteMFAChallenge(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !deleted {
+		return nil, ErrInvalidMFAToken
+	}
+	if err := s.lockouts.ResetLoginFailures(ctx, model.LockoutScopeAccount, claims.Subject); err != nil {
+		return nil, err
+	}
+	return s.startSession(ctx, claims.Subject, client)
+}
+
+// StepUp подтверждает чувствительную операцию: вторым фактором, если он включен, иначе паролем
+func (s *Service) StepUp(ctx context.Context, userID string, stepUp model.StepUp) error {
+	t, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+	if t != nil && t.Enabled {
+		return s.verifySecondFactor(ctx, t, stepUp)
+	}
+	if stepUp.Password == "" {
+		return ErrStepUpRequired
+	}
+	return s.VerifyPassword(ctx, userID, stepUp.Password)
+}
+
+// mfaRequired возвращает частичный токен, если у пользователя включен второй фактор
+func (s *Service) mfaRequired(ctx context.Context, userID string) (*model.TokenPair, error) {
+	t, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	if t == nil || !t.Enabled {
+		return nil, nil
+	}
+	now := time.Now()
+	claims := mfaClaims{
+		MFA:              mfaPending,
+		RegisteredClaims: s.registeredClaims(userID, now, now.Add(mfaTokenTTL)),
+	}
+	claims.ID = uuid.New().String()
+	if err := s.repo.CreateMFAChallenge(ctx, claims.ID, userID, claims.ExpiresAt.Time); err != nil {
+		return nil, err
+	}
+	token, err := s.signToken(claims)
+	if err != nil {
+		return nil, err
+	}
+	return &model.TokenPair{MFARequired: true, MFAToken: token}, nil
+}
+
+// verifySecondFactor проверяет код и учитывает неверные коды в блокировке учетной записи,
+// чтобы второй фактор нельзя было подобрать ни при входе, ни при подтверждении операций
+func (s *Service) verifySecondFactor(ctx context.Context, t *model.TOTP, stepUp model.StepUp) error {
+	now := time.Now()
+	if err := s.checkLockout(ctx, model.LockoutScopeAccount, t.UserID, now); err != nil {
+		return err
+	}
+	err := s.checkSecondFactor(ctx, t, stepUp)
+	if errors.Is(err, ErrInvalidOTP) {
+		if err := s.registerFailure(ctx, model.LockoutScopeAccount, t.UserID, now); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func (s *Service) checkSecondFactor(ctx context.Context, t *model.TOTP, stepUp model.StepUp) error {
+	switch {
+	case stepUp.OTPCode != "":
+		secret, err := s.keys.Decrypt(ctx, t.EncryptedSecret)
+		if err != nil {
+			return err
+		}
+		counter, ok := totp.Validate(string(secret), stepUp.OTPCode, time.Now())
+		if !ok {
+			return ErrInvalidOTP
+		}
+		// один и тот же код нельзя предъявить дважды
+		accepted, err := s.repo.AdvanceTOTPCounter(ctx, t.UserID, counter)
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			return ErrInvalidOTP
+		}
+		return nil
+	case stepUp.RecoveryCode != "":
+		used, err := s.repo.UseRecoveryCode(ctx, t.UserID, s.recoveryCodeHash(t.UserID, stepUp.RecoveryCode))
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidOTP
+		}
+		return nil
+	default:
+		return ErrStepUpRequired
+	}
+}
+
+func (s *Service) recoveryCodeHash(userID, code string) []byte {
+	normalized := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return s.keys.Fingerprint([]byte("recovery|" + userID + "|" + normalized))
+}
+
+// generateRecoveryCode возвращает код вида XXXXX-XXXXX без похожих символов
+func generateRecoveryCode() (string, error) {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	raw := make([]byte, 10)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	for i := range raw {
+		raw[i] = alphabet[int(raw[i])%len(alphabet)]
+	}
+	return string(raw[:5]) + "-" + string(raw[5:]), nil
+}
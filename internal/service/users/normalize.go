@@ -0,0 +1,44 @@
+package users
+
+import (
+	"errors"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrInvalidEmail    = errors.New("некорректный email")
+	ErrInvalidUsername = errors.New("username должен содержать от 3 до 32 символов: латинские буквы, цифры, '.', '_' или '-', и начинаться с буквы или цифры")
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{2,31}$`)
+
+// NormalizeEmail приводит email к каноническому виду: без пробелов по краям и в нижнем регистре.
+// Допускается только «голый» адрес, без отображаемого имени и угловых скобок.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return "", ErrInvalidEmail
+	}
+	if at := strings.LastIndexByte(email, '@'); !strings.Contains(email[at+1:], ".") {
+		return "", ErrInvalidEmail
+	}
+	return email, nil
+}
+
+// NormalizeUsername приводит username к нижнему регистру и проверяет допустимые символы
+func NormalizeUsername(username string) (string, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	if !usernamePattern.MatchString(username) {
+		return "", ErrInvalidUsername
+	}
+	return username, nil
+}
+
+// isEmailLogin — логин, содержащий '@', считается email, иначе username.
+// '@' не входит в допустимые символы username, поэтому пересечений нет.
+func isEmailLogin(login string) bool {
+	return strings.Contains(login, "@")
+}
@@ -0,0 +1,211 @@
+package users
+
+import (
+	"BankingApp/internal/config"
+	"BankingApp/internal/model"
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultMinPasswordLength = 8
+	defaultResetTokenTTL     = 30 * time.Minute
+	// bcrypt учитывает только первые 72 байта пароля
+	maxPasswordBytes = 72
+)
+
+var (
+	ErrWeakPassword       = errors.New("пароль не соответствует требованиям")
+	ErrBreachedPassword   = errors.New("пароль встречается в утечках, выберите другой")
+	ErrInvalidResetToken  = errors.New("недействительный или истекший токен сброса пароля")
+	ErrSamePassword       = errors.New("новый пароль совпадает с текущим")
+	ErrInvalidCredentials = errors.New("неверный логин или пароль")
+)
+
+// passwordPolicy проверяет пароль на соответствие настройкам и по списку утекших паролей
+type passwordPolicy struct {
+	cfg      config.Password
+	breached map[string]struct{}
+}
+
+func newPasswordPolicy(cfg config.Password) (*passwordPolicy, error) {
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = defaultMinPasswordLength
+	}
+	p := &passwordPolicy{cfg: cfg, breached: map[string]struct{}{}}
+	if cfg.BreachedListFile == "" {
+		return p, nil
+	}
+	f, err := os.Open(cfg.BreachedListFile)
+	if err != nil {
+		return nil, fmt.Errorf("open breached password list: %w", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			p.breached[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read breached password list: %w", err)
+	}
+	return p, nil
+}
+
+// validate проверяет пароль; identities — email и username владельца, которые не должны входить в пароль
+func (p *passwordPolicy) validate(password string, identities ...string) error {
+	if len([]rune(password)) < p.cfg.MinLength {
+		return fmt.Errorf("%w: не менее %d символов", ErrWeakPassword, p.cfg.MinLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("%w: не более %d байт", ErrWeakPassword, maxPasswordBytes)
+	}
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+	switch {
+	case p.cfg.RequireUpper && !upper:
+		return fmt.Errorf("%w: нужна заглавная буква", ErrWeakPassword)
+	case p.cfg.RequireLower && !lower:
+		return fmt.Errorf("%w: нужна строчная буква", ErrWeakPassword)
+	case p.cfg.RequireDigit && !digit:
+		return fmt.Errorf("%w: нужна цифра", ErrWeakPassword)
+	case p.cfg.RequireSymbol && !symbol:
+		return fmt.Errorf("%w: нужен спецсимвол", ErrWeakPassword)
+	}
+	lowered := strings.ToLower(password)
+	for _, identity := range identities {
+		if at := strings.IndexByte(identity, '@'); at >= 0 {
+			identity = identity[:at]
+		}
+		if len(identity) >= 3 && strings.Contains(lowered, strings.ToLower(identity)) {
+			return fmt.Errorf("%w: пароль не должен содержать email или username", ErrWeakPassword)
+		}
+	}
+	if _, ok := p.breached[lowered]; ok {
+		return ErrBreachedPassword
+	}
+	return nil
+}
+
+// ForgotPassword отправляет одноразовый токен сброса пароля через канал уведомлений.
+// Ответ не зависит от того, существует ли пользователь.
+func (s *Service) ForgotPassword(ctx context.Context, login string) error {
+	user, err := s.findByLogin(ctx, login)
+	if err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+	if user == nil {
+		return nil
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	expiresAt := time.Now().Add(s.resetTTL)
+	if err := s.repo.CreatePasswordResetToken(ctx, user.UUID, hashToken(token), expiresAt); err != nil {
+		return err
+	}
+	body := fmt.Sprintf("Для сброса пароля используйте токен: %s\nТокен действует до %s. Если вы не запрашивали сброс, проигнорируйте это письмо.",
+		token, expiresAt.Format(time.RFC1123))
+	return s.notifier.Notify(ctx, user.UUID, "Сброс пароля", body)
+}
+
+// ResetPassword устанавливает новый пароль по токену сброса и завершает все сессии пользователя
+func (s *Service) ResetPassword(ctx context.Context, token, newPassword string) error {
+	hash := hashToken(token)
+	resetToken, err := s.repo.GetPasswordResetToken(ctx, hash)
+	if err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+	now := time.Now()
+	if resetToken == nil || resetToken.UsedAt != nil || !now.Before(resetToken.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+	user, err := s.repo.FindByID(ctx, resetToken.UserID)
+	if err != nil || user == nil {
+		return ErrInvalidResetToken
+	}
+	// пароль проверяется до погашения токена, чтобы слабый пароль не сжигал токен
+	if err := s.policy.validate(newPassword, user.Email, user.Username); err != nil {
+		return err
+	}
+	used, err := s.repo.UsePasswordResetToken(ctx, hash, now)
+	if err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+	if !used {
+		return ErrInvalidResetToken
+	}
+	if err := s.setPassword(ctx, user.UUID, newPassword, ""); err != nil {
+		return err
+	}
+	if err := s.lockouts.ResetLoginFailures(ctx, model.LockoutScopeAccount, user.UUID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ChangePassword меняет пароль по текущему и завершает остальные сессии пользователя
+func (s *Service) ChangePassword(ctx context.Context, userID, sessionID string, change model.PasswordChange) error {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return errors.New("пользователь не найден")
+	}
+	if err := s.checkLockout(ctx, model.LockoutScopeAccount, user.UUID, time.Now()); err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(change.CurrentPassword)); err != nil {
+		if err := s.registerFailure(ctx, model.LockoutScopeAccount, user.UUID, time.Now()); err != nil {
+			return err
+		}
+		return ErrInvalidCredentials
+	}
+	if change.NewPassword == change.CurrentPassword {
+		return ErrSamePassword
+	}
+	if err := s.policy.validate(change.NewPassword, user.Email, user.Username); err != nil {
+		return err
+	}
+	return s.setPassword(ctx, user.UUID, change.NewPassword, sessionID)
+}
+
+// setPassword сохраняет новый пароль, отзывает сессии, кроме keepSessionID, и уведомляет владельца
+func (s *Service) setPassword(ctx context.Context, userID, password, keepSessionID string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePassword(ctx, userID, string(hash)); err != nil {
+		return err
+	}
+	if err := s.sessions.RevokeUserSessions(ctx, userID, keepSessionID); err != nil {
+		return err
+	}
+	// уведомление не должно откатывать уже измененный пароль
+	_ = s.notifier.Notify(ctx, userID, "Пароль изменен",
+		"Пароль от вашей учетной записи был изменен. Если это были не вы, немедленно обратитесь в банк.")
+	return nil
+}
@@ -0,0 +1,156 @@
+package users
+
+import (
+	"BankingApp/internal/model"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("недействительный refresh-токен")
+	ErrRefreshTokenReused  = errors.New("refresh-токен использован повторно, сессия отозвана")
+)
+
+// accessClaims — claims access-токена: jti для точечного отзыва, sid для отзыва всей сессии.
+// Роль читается из базы при каждой выдаче, поэтому ее изменение вступает в силу с очередным обновлением токена.
+// Токен машинного клиента не имеет sid и роли, вместо них — client_id и scope.
+type accessClaims struct {
+	SessionID string `json:"sid,omitempty"`
+	Role      string `json:"role,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Refresh обменивает refresh-токен на новую пару. Каждый refresh-токен одноразовый:
+// повторное предъявление уже обменянного токена означает его утечку, и вся сессия отзывается.
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (*model.TokenPair, error) {
+	token, err := s.sessions.UseRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	if token == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if token.UsedAt != nil {
+		if err := s.sessions.RevokeSession(ctx, token.SessionID, ""); err != nil {
+			return nil, fmt.Errorf("revoke session: %w", err)
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	now := time.Now()
+	if !now.Before(token.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+	userID, err := s.sessionOwner(ctx, token.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessions.TouchSession(ctx, token.SessionID, now); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(ctx, userID, token.SessionID, now)
+}
+
+// Logout отзывает сессию и текущий access-токен
+func (s *Service) Logout(ctx context.Context, userID, sessionID, jti string, expiresAt time.Time) error {
+	if err := s.sessions.RevokeToken(ctx, jti, expiresAt); err != nil {
+		return err
+	}
+	if sessionID == "" {
+		return nil
+	}
+	return s.sessions.RevokeSession(ctx, sessionID, userID)
+}
+
+func (s *Service) GetSessions(ctx context.Context, userID string) ([]*model.Session, error) {
+	return s.sessions.GetSessionsByUser(ctx, userID)
+}
+
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	return s.sessions.RevokeSession(ctx, sessionID, userID)
+}
+
+func (s *Service) IsTokenRevoked(ctx context.Context, jti, sessionID string) (bool, error) {
+	return s.sessions.IsTokenRevoked(ctx, jti, sessionID)
+}
+
+// PurgeExpiredTokens удаляет истекшие refresh-токены и записи об отзыве
+func (s *Service) PurgeExpiredTokens(ctx context.Context) (int64, error) {
+	return s.sessions.PurgeExpiredTokens(ctx, time.Now())
+}
+
+// startSession создает сессию и выдает первую пару токенов
+func (s *Service) startSession(ctx context.Context, userID string, client model.ClientInfo) (*model.TokenPair, error) {
+	now := time.Now()
+	session := &model.Session{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		CreatedAt:  now,
+		LastUsedAt: now,
+	}
+	if err := s.sessions.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(ctx, userID, session.ID, now)
+}
+
+func (s *Service) issueTokens(ctx context.Context, userID, sessionID string, now time.Time) (*model.TokenPair, error) {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("пользователь не найден")
+	}
+	pair := &model.TokenPair{
+		SessionID:        sessionID,
+		AccessExpiresAt:  now.Add(s.accessTTL),
+		RefreshExpiresAt: now.Add(s.refreshTTL),
+	}
+	claims := accessClaims{
+		SessionID:        sessionID,
+		Role:             user.Role,
+		RegisteredClaims: s.registeredClaims(userID, now, pair.AccessExpiresAt),
+	}
+	claims.ID = uuid.New().String()
+	pair.AccessToken, err = s.signToken(claims)
+	if err != nil {
+		return nil, err
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	pair.RefreshToken = base64.RawURLEncoding.EncodeToString(raw)
+	if err := s.sessions.AddRefreshToken(ctx, sessionID, hashToken(pair.RefreshToken), pair.RefreshExpiresAt); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+func (s *Service) sessionOwner(ctx context.Context, sessionID string) (string, error) {
+	session, err := s.sessions.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return "", err
+	}
+	if session == nil || session.RevokedAt != nil {
+		return "", ErrInvalidRefreshToken
+	}
+	return session.UserID, nil
+}
+
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
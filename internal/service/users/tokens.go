@@ -0,0 +1,81 @@
+package users
+
+import (
+	"BankingApp/internal/config"
+	"BankingApp/internal/model"
+	"context"
+	"crypto"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// registeredClaims — стандартные claims всех выдаваемых токенов
+func (s *Service) registeredClaims(subject string, now, expiresAt time.Time) jwt.RegisteredClaims {
+	claims := jwt.RegisteredClaims{
+		Issuer:    s.jwt.Issuer,
+		Subject:   subject,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+	if s.jwt.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.jwt.Audience}
+	}
+	return claims
+}
+
+// signToken подписывает токен асимметричным ключом, а в режиме hs256 — общим секретом
+func (s *Service) signToken(claims jwt.Claims) (string, error) {
+	if s.jwt.Mode == config.JWTModeHS256 {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.jwtSecret)
+	}
+	return s.signer.Sign(claims)
+}
+
+// parseToken проверяет подпись и стандартные claims токена, выданного этим сервисом;
+// iss, aud и iat обязательны только для токенов с асимметричной подписью
+func (s *Service) parseToken(ctx context.Context, tokenStr string, claims jwt.Claims) error {
+	methods := []string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}
+	if s.jwtSecret != nil {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+			return s.jwtSecret, nil
+		}
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("kid missing in token")
+		}
+		return s.signer.VerificationKey(ctx, kid, token.Method.Alg())
+	},
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return err
+	}
+	// токены HS256, выданные до появления iss, aud и iat, принимаются по прежним правилам до закрытия окна совместимости
+	if _, legacy := token.Method.(*jwt.SigningMethodHMAC); legacy {
+		return nil
+	}
+	err = jwt.NewValidator(jwt.WithIssuer(s.jwt.Issuer), jwt.WithAudience(s.jwt.Audience), jwt.WithIssuedAt()).Validate(claims)
+	if err != nil {
+		return err
+	}
+	if iat, err := claims.GetIssuedAt(); err != nil || iat == nil {
+		return errors.New("iat missing in token")
+	}
+	return nil
+}
+
+// VerificationKey — открытый ключ для проверки access-токенов в middleware
+func (s *Service) VerificationKey(ctx context.Context, kid, alg string) (crypto.PublicKey, error) {
+	return s.signer.VerificationKey(ctx, kid, alg)
+}
+
+// JWKS — открытые ключи подписи для /.well-known/jwks.json
+func (s *Service) JWKS() *model.JWKSet {
+	return s.signer.JWKS()
+}
@@ -2,31 +2,145 @@ package users
 
 import (
 	"BankingApp/internal/config"
+	"BankingApp/internal/jwtkeys"
+	"BankingApp/internal/keystore"
 	"BankingApp/internal/model"
 	"BankingApp/internal/service"
 	"BankingApp/internal/storage"
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 var _ service.UserService = (*Service)(nil)
 
+const (
+	defaultAccessTTL  = 15 * time.Minute
+	defaultRefreshTTL = 30 * 24 * time.Hour
+)
+
+// dummyHash сравнивается с паролем, когда пользователь не найден,
+// чтобы время ответа не выдавало существование логина
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
 type Service struct {
-	repo      storage.UserStorage
-	jwtSecret []byte
+	repo     storage.UserStorage
+	sessions storage.SessionStorage
+	lockouts storage.LockoutStorage
+	// apiClients — машинные клиенты пользователей
+	apiClients storage.APIClientStorage
+	keys       *keystore.KeyStore
+	signer     *jwtkeys.KeyRing
+	notifier   service.NotificationService
+	policy     *passwordPolicy
+	lockout    config.Lockout
+	clients    config.Clients
+
+	jwt        config.JWT
+	jwtSecret  []byte // nil в режиме asymmetric
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	resetTTL   time.Duration
+
+	// параметры ссылки подтверждения email
+	verifyBaseURL  string
+	verifyLinkTTL  time.Duration
+	resendInterval time.Duration
 }
 
-func NewUserService(repo storage.UserStorage, cfg *config.Config) *Service {
-	return &Service{repo: repo, jwtSecret: []byte(config.GetJWTSecretKey())}
+func NewUserService(repo storage.UserStorage, sessions storage.SessionStorage, lockouts storage.LockoutStorage,
+	apiClients storage.APIClientStorage, keys *keystore.KeyStore,
+	signer *jwtkeys.KeyRing, notifier service.NotificationService, cfg *config.Config) (*Service, error) {
+	policy, err := newPasswordPolicy(cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+	s := &Service{
+		repo:       repo,
+		sessions:   sessions,
+		lockouts:   lockouts,
+		apiClients: apiClients,
+		keys:       keys,
+		signer:     signer,
+		notifier:   notifier,
+		policy:     policy,
+		lockout:    cfg.Auth.Lockout,
+		clients:    cfg.Auth.Clients,
+		jwt:        cfg.Auth.JWT,
+		accessTTL:  time.Duration(cfg.Auth.AccessTokenTTL),
+		refreshTTL: time.Duration(cfg.Auth.RefreshTokenTTL),
+		resetTTL:   time.Duration(cfg.Password.ResetTokenTTL),
+
+		verifyBaseURL:  strings.TrimRight(cfg.Auth.Verification.LinkBaseURL, "/"),
+		verifyLinkTTL:  time.Duration(cfg.Auth.Verification.LinkTTL),
+		resendInterval: time.Duration(cfg.Auth.Verification.ResendInterval),
+	}
+	if s.jwt.Mode != config.JWTModeAsymmetric {
+		s.jwtSecret = []byte(config.GetJWTSecretKey())
+	}
+	if s.accessTTL <= 0 {
+		s.accessTTL = defaultAccessTTL
+	}
+	if s.refreshTTL <= 0 {
+		s.refreshTTL = defaultRefreshTTL
+	}
+	if s.verifyLinkTTL <= 0 {
+		s.verifyLinkTTL = defaultVerificationLinkTTL
+	}
+	if s.resendInterval <= 0 {
+		s.resendInterval = defaultResendInterval
+	}
+	if s.resetTTL <= 0 {
+		s.resetTTL = defaultResetTokenTTL
+	}
+	if s.clients.TokenTTL <= 0 {
+		s.clients.TokenTTL = config.Duration(defaultClientTokenTTL)
+	}
+	if s.clients.DefaultRateLimit <= 0 {
+		s.clients.DefaultRateLimit = defaultClientRateLimit
+	}
+	if s.clients.MaxRateLimit < s.clients.DefaultRateLimit {
+		s.clients.MaxRateLimit = max(defaultClientMaxRateLimit, s.clients.DefaultRateLimit)
+	}
+	if s.clients.SecretRotationGrace <= 0 {
+		s.clients.SecretRotationGrace = config.Duration(defaultSecretRotationGrace)
+	}
+	if s.lockout.AccountThreshold <= 0 {
+		s.lockout.AccountThreshold = defaultAccountLockoutThreshold
+	}
+	if s.lockout.IPThreshold <= 0 {
+		s.lockout.IPThreshold = defaultIPLockoutThreshold
+	}
+	if s.lockout.BaseDelay <= 0 {
+		s.lockout.BaseDelay = config.Duration(defaultLockoutBaseDelay)
+	}
+	if s.lockout.MaxDelay <= 0 {
+		s.lockout.MaxDelay = config.Duration(defaultLockoutMaxDelay)
+	}
+	if s.lockout.FailureWindow <= 0 {
+		s.lockout.FailureWindow = config.Duration(defaultLockoutWindow)
+	}
+	return s, nil
 }
 
 func (s *Service) Register(ctx context.Context, email, username, password, fullName string) (*model.User, error) {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	username, err = NormalizeUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.validate(password, email, username); err != nil {
+		return nil, err
+	}
+
 	// Проверим уникальность email и username
 	if user, err := s.repo.FindByEmail(ctx, email); user != nil {
 		return nil, errors.New("email уже зарегистрирован")
@@ -47,38 +161,105 @@ func (s *Service) Register(ctx context.Context, email, username, password, fullN
 	user := &model.User{
 		UUID:         uuid.New().String(),
 		Email:        email,
+		Username:     username,
 		PasswordHash: string(hash),
 		FullName:     fullName,
+		Status:       model.UserPendingVerification,
+		Role:         model.RoleCustomer,
 		CreatedAt:    time.Now(),
 	}
 	err = s.repo.CreateUser(ctx, user)
+	if errors.Is(err, storage.ErrAlreadyExists) {
+		// параллельная регистрация успела занять email или username
+		return nil, errors.New("email или username уже заняты")
+	}
 	if err != nil {
 		return nil, err
 	}
+	// письмо можно запросить повторно, поэтому ошибка отправки не отменяет регистрацию
+	_ = s.sendVerification(ctx, user)
 	return user, nil
 }
 
-func (s *Service) Authenticate(ctx context.Context, email, password string) (string, time.Time, error) {
-	user, err := s.repo.FindByEmail(ctx, email)
-	if err != nil || user == nil {
-		return "", time.Time{}, errors.New("пользователя с таким email не существует")
+// Authenticate принимает в качестве логина email или username. Неизвестный логин и неверный пароль
+// дают одну и ту же ошибку, неудачи учитываются для блокировки по аккаунту и по IP-адресу.
+func (s *Service) Authenticate(ctx context.Context, login, password string, client model.ClientInfo) (*model.TokenPair, error) {
+	now := time.Now()
+	if err := s.checkLockout(ctx, model.LockoutScopeIP, client.IP, now); err != nil {
+		return nil, err
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return "", time.Time{}, errors.New("неверный пароль")
+	user, err := s.findByLogin(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	// для несуществующего логина счетчик ведется по самому логину, чтобы поведение не отличалось
+	accountKey := strings.ToLower(strings.TrimSpace(login))
+	hash := dummyHash
+	if user != nil {
+		accountKey = user.UUID
+		hash = []byte(user.PasswordHash)
 	}
+	if err := s.checkLockout(ctx, model.LockoutScopeAccount, accountKey, now); err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || user == nil {
+		if err := s.registerFailure(ctx, model.LockoutScopeAccount, accountKey, now); err != nil {
+			return nil, err
+		}
+		if err := s.registerFailure(ctx, model.LockoutScopeIP, client.IP, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+	// при включенном втором факторе счетчик сбрасывается только после верного кода,
+	// иначе верный пароль обнулял бы неудачные попытки подбора кода
+	partial, err := s.mfaRequired(ctx, user.UUID)
+	if err != nil || partial != nil {
+		return partial, err
+	}
+	if err := s.lockouts.ResetLoginFailures(ctx, model.LockoutScopeAccount, accountKey); err != nil {
+		return nil, err
+	}
+	return s.startSession(ctx, user.UUID, client)
+}
 
-	// Генерируем JWT
-	exp := time.Now().Add(24 * time.Hour)
-	claims := jwt.RegisteredClaims{
-		Subject:   user.UUID,
-		ExpiresAt: jwt.NewNumericDate(exp),
+// findByLogin ищет пользователя по email или username; некорректный логин равносилен ненайденному
+func (s *Service) findByLogin(ctx context.Context, login string) (*model.User, error) {
+	if isEmailLogin(login) {
+		email, err := NormalizeEmail(login)
+		if err != nil {
+			return nil, nil
+		}
+		return s.repo.FindByEmail(ctx, email)
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenStr, err := token.SignedString(s.jwtSecret)
+	username, err := NormalizeUsername(login)
 	if err != nil {
-		return "", time.Time{}, err
+		return nil, nil
+	}
+	return s.repo.FindByUsername(ctx, username)
+}
+
+// VerifyPassword проверяет пароль пользователя. Неверные пароли учитываются в блокировке учетной записи,
+// как и при смене пароля, чтобы подтверждение операций нельзя было использовать для подбора.
+func (s *Service) VerifyPassword(ctx context.Context, userID, password string) error {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return errors.New("пользователь не найден")
+	}
+	if err := s.checkLockout(ctx, model.LockoutScopeAccount, user.UUID, time.Now()); err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		if err := s.registerFailure(ctx, model.LockoutScopeAccount, user.UUID, time.Now()); err != nil {
+			return err
+		}
+		// попытка, на которой достигнут порог, уже сообщает о блокировке
+		if err := s.checkLockout(ctx, model.LockoutScopeAccount, user.UUID, time.Now()); err != nil {
+			return err
+		}
+		return errors.New("неверный пароль")
 	}
-	return tokenStr, exp, nil
+	return nil
 }
 
 func (s *Service) GetByID(ctx context.Context, userID string) (*model.User, error) {
@@ -0,0 +1,110 @@
+package users
+
+import (
+	"BankingApp/internal/model"
+	"context"
+	"crypto/hmac"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultVerificationLinkTTL = 48 * time.Hour
+	defaultResendInterval      = 2 * time.Minute
+)
+
+var (
+	ErrEmailNotVerified          = errors.New("email не подтвержден")
+	ErrUserInactive              = errors.New("учетная запись неактивна")
+	ErrEmailAlreadyVerified      = errors.New("email уже подтвержден")
+	ErrInvalidVerificationLink   = errors.New("недействительная или истекшая ссылка подтверждения")
+	ErrVerificationResendTooSoon = errors.New("письмо уже отправлено, повторите позже")
+)
+
+// VerifyEmail подтверждает email по подписанной ссылке. Подпись покрывает текущий email,
+// поэтому после смены адреса старые ссылки перестают действовать.
+func (s *Service) VerifyEmail(ctx context.Context, userID string, expires int64, signature string) error {
+	if time.Now().Unix() > expires {
+		return ErrInvalidVerificationLink
+	}
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+	if user == nil {
+		return ErrInvalidVerificationLink
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, s.verificationSignature(user, expires)) {
+		return ErrInvalidVerificationLink
+	}
+	if user.Status != model.UserPendingVerification {
+		return ErrEmailAlreadyVerified
+	}
+	verified, err := s.repo.MarkEmailVerified(ctx, user.UUID, user.Email, time.Now())
+	if err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+	if !verified {
+		return ErrInvalidVerificationLink
+	}
+	return nil
+}
+
+// ResendVerification повторно отправляет ссылку, не чаще resend_interval
+func (s *Service) ResendVerification(ctx context.Context, userID string) error {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return errors.New("пользователь не найден")
+	}
+	if user.Status != model.UserPendingVerification {
+		return ErrEmailAlreadyVerified
+	}
+	return s.sendVerification(ctx, user)
+}
+
+// RequireVerified пропускает только активных пользователей: для не подтвердивших email возвращает
+// ErrEmailNotVerified, для остальных статусов, включая будущие, — ErrUserInactive
+func (s *Service) RequireVerified(ctx context.Context, userID string) error {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return errors.New("пользователь не найден")
+	}
+	switch user.Status {
+	case model.UserActive:
+		return nil
+	case model.UserPendingVerification:
+		return ErrEmailNotVerified
+	default:
+		return ErrUserInactive
+	}
+}
+
+func (s *Service) sendVerification(ctx context.Context, user *model.User) error {
+	now := time.Now()
+	marked, err := s.repo.MarkVerificationSent(ctx, user.UUID, now, s.resendInterval)
+	if err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+	if !marked {
+		return ErrVerificationResendTooSoon
+	}
+	expires := now.Add(s.verifyLinkTTL).Unix()
+	query := url.Values{
+		"uid": {user.UUID},
+		"exp": {strconv.FormatInt(expires, 10)},
+		"sig": {base64.RawURLEncoding.EncodeToString(s.verificationSignature(user, expires))},
+	}
+	link := s.verifyBaseURL + "/user/verify?" + query.Encode()
+	body := fmt.Sprintf("Для подтверждения email перейдите по ссылке:\n%s\nСсылка действует до %s.",
+		link, time.Unix(expires, 0).Format(time.RFC1123))
+	return s.notifier.SendEmail(ctx, user.Email, "Подтверждение email", body)
+}
+
+func (s *Service) verificationSignature(user *model.User, expires int64) []byte {
+	return s.keys.Fingerprint([]byte(fmt.Sprintf("email-verify|%s|%s|%d", user.UUID, user.Email, expires)))
+}
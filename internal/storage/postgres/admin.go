@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const adminActionColumns = "id, actor_id, action, target_type, target_id, amount, reason_code, comment, created_at"
+
+func (p *PostgresRepository) SetUserRole(ctx context.Context, userID, role string, action *model.AdminAction) error {
+	return p.withAdminAction(ctx, action, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, "UPDATE users SET role = $2 WHERE uuid = $1", userID, role)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return errors.New("user not found")
+		}
+		// роль зашита в выданные токены: без отзыва сессий пониженный сотрудник сохранял бы доступ до их истечения
+		_, err = tx.Exec(ctx, "UPDATE sessions SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+		return err
+	})
+}
+
+func (p *PostgresRepository) SetAccountFrozen(ctx context.Context, accountID int64, frozen bool, action *model.AdminAction) error {
+	return p.withAdminAction(ctx, action, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, "UPDATE accounts SET is_frozen = $2 WHERE id = $1", accountID, frozen)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return errors.New("account not found")
+		}
+		return nil
+	})
+}
+
+func (p *PostgresRepository) AdjustBalance(ctx context.Context, accountID int64, amount float64, action *model.AdminAction) error {
+	return p.withAdminAction(ctx, action, func(tx pgx.Tx) error {
+		var currency string
+		query := `
+			UPDATE accounts SET balance = balance + $2
+			WHERE id = $1 AND ($2 > 0 OR balance + overdraft_limit + $2 >= 0)
+			RETURNING currency
+		`
+		err := tx.QueryRow(ctx, query, accountID, amount).Scan(&currency)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("account not found or insufficient balance")
+		}
+		if err != nil {
+			return err
+		}
+		query = `
+			INSERT INTO transactions (account_id, amount, currency, type, status, description)
+			VALUES ($1, $2, $3, 'adjustment', 'success', $4)
+		`
+		_, err = tx.Exec(ctx, query, accountID, amount, currency, action.ReasonCode)
+		return err
+	})
+}
+
+func (p *PostgresRepository) ReverseTransaction(ctx context.Context, legs []model.ReversalLeg, description string, action *model.AdminAction) ([]*model.Transaction, error) {
+	var reversals []*model.Transaction
+	err := p.withAdminAction(ctx, action, func(tx pgx.Tx) error {
+		var err error
+		reversals, err = applyReversal(ctx, tx, legs, description)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reversals, nil
+}
+
+// applyReversal проводит компенсирующие проводки. Остаток исходной операции проверяется условием
+// в UPDATE, поэтому параллельные сторно не могут вернуть больше исходной суммы.
+func applyReversal(ctx context.Context, tx pgx.Tx, legs []model.ReversalLeg, description string) ([]*model.Transaction, error) {
+	reversals := make([]*model.Transaction, 0, len(legs))
+	for _, leg := range legs {
+		result, err := tx.Exec(ctx, `
+			UPDATE transactions SET reversed_amount = reversed_amount + ABS($2::NUMERIC)
+			WHERE id = $1 AND reversed_amount + ABS($2::NUMERIC) <= ABS(amount)
+		`, leg.TransactionID, leg.Amount)
+		if err != nil {
+			return nil, err
+		}
+		if result.RowsAffected() == 0 {
+			return nil, storage.ErrReversalExceeded
+		}
+		result, err = tx.Exec(ctx, `
+			UPDATE accounts SET balance = balance + $2
+			WHERE id = $1 AND is_active AND ($2 > 0 OR balance + overdraft_limit + $2 >= 0)
+		`, leg.AccountID, leg.Amount)
+		if err != nil {
+			return nil, err
+		}
+		if result.RowsAffected() == 0 {
+			if leg.Amount < 0 {
+				return nil, storage.ErrInsufficientFunds
+			}
+			return nil, storage.ErrAccountClosed
+		}
+		query := `
+			INSERT INTO transactions (account_id, amount, currency, type, status, description, reversal_of)
+			VALUES ($1, $2, $3, '` + model.TransactionReversal + `', '` + model.TransactionSuccess + `', NULLIF($4, ''), $5)
+			RETURNING ` + transactionColumns
+		reversal, err := scanTransaction(tx.QueryRow(ctx, query, leg.AccountID, leg.Amount, leg.Currency, description, leg.TransactionID))
+		if err != nil {
+			return nil, err
+		}
+		reversals = append(reversals, reversal)
+	}
+	return reversals, nil
+}
+
+func (p *PostgresRepository) GetAdminActions(ctx context.Context, targetType, targetID string) ([]*model.AdminAction, error) {
+	query := `
+		SELECT ` + adminActionColumns + `
+		FROM admin_actions
+		WHERE target_type = $1 AND target_id = $2
+		ORDER BY id DESC
+	`
+	rows, err := p.pool.Query(ctx, query, targetType, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("GetAdminActions: %w", err)
+	}
+	defer rows.Close()
+
+	var actions []*model.AdminAction
+	for rows.Next() {
+		a := &model.AdminAction{}
+		if err := rows.Scan(&a.ID, &a.ActorID, &a.Action, &a.TargetType, &a.TargetID, &a.Amount, &a.ReasonCode, &a.Comment, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("GetAdminActions scan: %w", err)
+		}
+		actions = append(actions, a)
+	}
+	return actions, rows.Err()
+}
+
+// withAdminAction выполняет изменение и запись в журнал в одной транзакции
+func (p *PostgresRepository) withAdminAction(ctx context.Context, action *model.AdminAction, apply func(tx pgx.Tx) error) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := apply(tx); err != nil {
+		return fmt.Errorf("%s: %w", action.Action, err)
+	}
+	query := `
+		INSERT INTO admin_actions (actor_id, action, target_type, target_id, amount, reason_code, comment)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	err = tx.QueryRow(ctx, query, action.ActorID, action.Action, action.TargetType, action.TargetID,
+		action.Amount, action.ReasonCode, action.Comment).Scan(&action.ID, &action.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("%s: audit: %w", action.Action, err)
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const aliasColumns = "id, user_id, type, masked_value, account_id, fingerprint, created_at, updated_at"
+
+func (p *PostgresRepository) SaveAlias(ctx context.Context, alias *model.Alias) (*model.Alias, error) {
+	query := `
+		INSERT INTO aliases (user_id, type, fingerprint, masked_value, account_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		ON CONFLICT (user_id, type) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint, masked_value = EXCLUDED.masked_value,
+			account_id = EXCLUDED.account_id, updated_at = EXCLUDED.updated_at
+		RETURNING ` + aliasColumns
+	saved, err := scanAlias(p.pool.QueryRow(ctx, query, alias.UserID, alias.Type, alias.Fingerprint, alias.MaskedValue,
+		alias.AccountID, alias.UpdatedAt))
+	if isUniqueViolation(err) {
+		return nil, storage.ErrAlreadyExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("SaveAlias: %w", err)
+	}
+	return saved, nil
+}
+
+func (p *PostgresRepository) GetAliasesByUser(ctx context.Context, userID string) ([]*model.Alias, error) {
+	rows, err := p.pool.Query(ctx, "SELECT "+aliasColumns+" FROM aliases WHERE user_id = $1 ORDER BY type", userID)
+	if err != nil {
+		return nil, fmt.Errorf("GetAliasesByUser: %w", err)
+	}
+	defer rows.Close()
+
+	aliases := make([]*model.Alias, 0)
+	for rows.Next() {
+		alias, err := scanAlias(rows)
+		if err != nil {
+			return nil, fmt.Errorf("GetAliasesByUser scan: %w", err)
+		}
+		aliases = append(aliases, alias)
+	}
+	return aliases, rows.Err()
+}
+
+func (p *PostgresRepository) FindAlias(ctx context.Context, fingerprint []byte) (*model.Alias, error) {
+	alias, err := scanAlias(p.pool.QueryRow(ctx, "SELECT "+aliasColumns+" FROM aliases WHERE fingerprint = $1", fingerprint))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("FindAlias: %w", err)
+	}
+	return alias, nil
+}
+
+func (p *PostgresRepository) DeleteAlias(ctx context.Context, userID, aliasType string) (bool, error) {
+	result, err := p.pool.Exec(ctx, "DELETE FROM aliases WHERE user_id = $1 AND type = $2", userID, aliasType)
+	if err != nil {
+		return false, fmt.Errorf("DeleteAlias: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+func scanAlias(row pgx.Row) (*model.Alias, error) {
+	var a model.Alias
+	err := row.Scan(&a.ID, &a.UserID, &a.Type, &a.MaskedValue, &a.AccountID, &a.Fingerprint, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"BankingApp/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const apiClientColumns = "id, owner_id, name, scopes, rate_limit, status, created_at, revoked_at"
+
+func (p *PostgresRepository) CreateAPIClient(ctx context.Context, client *model.APIClient, secretHash []byte) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("CreateAPIClient: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO api_clients (id, owner_id, name, scopes, rate_limit, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`
+	err = tx.QueryRow(ctx, query, client.ID, client.OwnerUserID, client.Name, client.Scopes, client.RateLimit, client.Status).Scan(&client.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("CreateAPIClient: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO api_client_secrets (secret_hash, client_id) VALUES ($1, $2)", secretHash, client.ID); err != nil {
+		return fmt.Errorf("CreateAPIClient secret: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+func (p *PostgresRepository) GetAPIClientsByOwner(ctx context.Context, ownerID string) ([]*model.APIClient, error) {
+	query := "SELECT " + apiClientColumns + " FROM api_clients WHERE owner_id = $1 ORDER BY created_at"
+	rows, err := p.pool.Query(ctx, query, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("GetAPIClientsByOwner: %w", err)
+	}
+	defer rows.Close()
+
+	clients := make([]*model.APIClient, 0)
+	for rows.Next() {
+		client, err := scanAPIClient(rows)
+		if err != nil {
+			return nil, fmt.Errorf("GetAPIClientsByOwner scan: %w", err)
+		}
+		clients = append(clients, client)
+	}
+	return clients, rows.Err()
+}
+
+func (p *PostgresRepository) GetAPIClient(ctx context.Context, clientID string) (*model.APIClient, error) {
+	query := "SELECT " + apiClientColumns + " FROM api_clients WHERE id = $1"
+	client, err := scanAPIClient(p.pool.QueryRow(ctx, query, clientID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetAPIClient: %w", err)
+	}
+	return client, nil
+}
+
+func (p *PostgresRepository) FindAPIClientBySecret(ctx context.Context, clientID string, secretHash []byte, now time.Time) (*model.APIClient, error) {
+	query := `
+		SELECT ` + prefixColumns("c", apiClientColumns) + `
+		FROM api_clients c
+		JOIN api_client_secrets s ON s.client_id = c.id
+		WHERE c.id = $1 AND s.secret_hash = $2 AND c.status = $3
+			AND (s.expires_at IS NULL OR s.expires_at > $4)
+	`
+	client, err := scanAPIClient(p.pool.QueryRow(ctx, query, clientID, secretHash, model.APIClientActive, now))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("FindAPIClientBySecret: %w", err)
+	}
+	return client, nil
+}
+
+func (p *PostgresRepository) AddAPIClientSecret(ctx context.Context, clientID string, secretHash []byte, expireOthersAt time.Time) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("AddAPIClientSecret: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE api_client_secrets SET expires_at = $2
+		WHERE client_id = $1 AND (expires_at IS NULL OR expires_at > $2)
+	`
+	if _, err := tx.Exec(ctx, query, clientID, expireOthersAt); err != nil {
+		return fmt.Errorf("AddAPIClientSecret expire: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO api_client_secrets (secret_hash, client_id) VALUES ($1, $2)", secretHash, clientID); err != nil {
+		return fmt.Errorf("AddAPIClientSecret: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+func (p *PostgresRepository) RevokeAPIClient(ctx context.Context, clientID, ownerID string) error {
+	query := `
+		UPDATE api_clients SET status = $3, revoked_at = now()
+		WHERE id = $1 AND owner_id = $2 AND status <> $3
+	`
+	result, err := p.pool.Exec(ctx, query, clientID, ownerID, model.APIClientRevoked)
+	if err != nil {
+		return fmt.Errorf("RevokeAPIClient: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("client not found")
+	}
+	return nil
+}
+
+func scanAPIClient(row pgx.Row) (*model.APIClient, error) {
+	var c model.APIClient
+	err := row.Scan(&c.ID, &c.OwnerUserID, &c.Name, &c.Scopes, &c.RateLimit, &c.Status, &c.CreatedAt, &c.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
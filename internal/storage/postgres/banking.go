@@ -4,49 +4,88 @@ import (
 	"BankingApp/internal/model"
 	"BankingApp/internal/storage"
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 )
 
-func (p *PostgresRepository) CreateAccount(ctx context.Context, userID string, currency string) (*model.Account, error) {
-	query := "INSERT INTO accounts (user_id, currency, balance) VALUES ($1, $2, 0.0) RETURNING id, user_id, currency, balance, is_active"
-	var acc model.Account
-	err := p.pool.QueryRow(ctx, query, userID, currency).Scan(
-		&acc.ID,
-		&acc.UserID,
-		&acc.Currency,
-		&acc.Balance,
-		&acc.IsActive,
-	)
+const transactionColumns = "id, account_id, amount, currency, type, status, COALESCE(description, ''), related_entity_id, fx_rate, " +
+	"counterpart_id, reversal_of, reversed_amount, created_at"
+
+const accountColumns = "id, user_id, COALESCE(number, ''), currency, type, balance, is_active, is_frozen, owner_frozen, created_at, closed_at, " +
+	"overdraft_limit, overdraft_rate, overdraft_interest, overdraft_warned, COALESCE(product, ''), interest_rate, interest_accrued, " +
+	"term_days, matures_on, early_penalty, rollover, payout_account_id"
+
+func (p *PostgresRepository) CreateAccount(ctx context.Context, userID, currency, number string) (*model.Account, error) {
+	query := "INSERT INTO accounts (user_id, number, currency, balance) VALUES ($1, $2, $3, 0.0) RETURNING " + accountColumns
+	acc, err := scanAccount(p.pool.QueryRow(ctx, query, userID, number, currency))
+	if isUniqueViolation(err) {
+		return nil, storage.ErrAlreadyExists
+	}
 	if err != nil {
 		return nil, fmt.Errorf("CreateAccount: %w", err)
 	}
-	return &acc, nil
+	return acc, nil
+}
+
+func (p *PostgresRepository) GetAccountByNumber(ctx context.Context, number string) (*model.Account, error) {
+	query := "SELECT " + accountColumns + " FROM accounts WHERE number = $1"
+	acc, err := scanAccount(p.pool.QueryRow(ctx, query, number))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetAccountByNumber: %w", err)
+	}
+	return acc, nil
+}
+
+func (p *PostgresRepository) GetAccountsWithoutNumber(ctx context.Context, limit int) ([]*model.Account, error) {
+	query := "SELECT " + accountColumns + " FROM accounts WHERE number IS NULL ORDER BY id LIMIT $1"
+	rows, err := p.pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetAccountsWithoutNumber: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []*model.Account
+	for rows.Next() {
+		acc, err := scanAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("GetAccountsWithoutNumber scan: %w", err)
+		}
+		accounts = append(accounts, acc)
+	}
+	return accounts, rows.Err()
+}
+
+func (p *PostgresRepository) SetAccountNumber(ctx context.Context, accountID int64, number string) error {
+	_, err := p.pool.Exec(ctx, "UPDATE accounts SET number = $2 WHERE id = $1 AND number IS NULL", accountID, number)
+	if isUniqueViolation(err) {
+		return storage.ErrAlreadyExists
+	}
+	if err != nil {
+		return fmt.Errorf("SetAccountNumber: %w", err)
+	}
+	return nil
 }
 
 func (p *PostgresRepository) GetAccountByID(ctx context.Context, accountID int64) (*model.Account, error) {
-	query := "SELECT id, user_id, currency, balance FROM accounts WHERE id=$1"
-	var acc model.Account
-	err := p.pool.QueryRow(ctx, query, accountID).Scan(
-		&acc.ID,
-		&acc.UserID,
-		&acc.Currency,
-		&acc.Balance,
-	)
-	if err == sql.ErrNoRows {
+	query := "SELECT " + accountColumns + " FROM accounts WHERE id=$1"
+	acc, err := scanAccount(p.pool.QueryRow(ctx, query, accountID))
+	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, errors.New("account not found")
 	}
 	if err != nil {
 		return nil, fmt.Errorf("GetAccountByID: %w", err)
 	}
-	return &acc, nil
+	return acc, nil
 }
 
 func (p *PostgresRepository) GetAccountsByUser(ctx context.Context, userID string) ([]*model.Account, error) {
-	query := "SELECT id, user_id, currency, balance FROM accounts WHERE user_id=$1"
+	query := "SELECT " + accountColumns + " FROM accounts WHERE user_id=$1 ORDER BY id"
 	rows, err := p.pool.Query(ctx, query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("GetAccountsByUser: %w", err)
@@ -55,26 +94,26 @@ func (p *PostgresRepository) GetAccountsByUser(ctx context.Context, userID strin
 
 	var accounts []*model.Account
 	for rows.Next() {
-		var acc model.Account
-		if err := rows.Scan(&acc.ID, &acc.UserID, &acc.Currency, &acc.Balance); err != nil {
+		acc, err := scanAccount(rows)
+		if err != nil {
 			return nil, fmt.Errorf("GetAccountsByUser scan: %w", err)
 		}
-		accounts = append(accounts, &acc)
+		accounts = append(accounts, acc)
 	}
 	return accounts, rows.Err()
 }
 
 func (p *PostgresRepository) UpdateAccountBalance(ctx context.Context, accountID int64, amount float64) error {
 	// This is a simple example. In practice you want to check for negative balances in a transaction!
-	query := "UPDATE accounts SET balance = balance + $1 WHERE id = $2"
-	result, err := p.pool.Exec(ctx, query, amount, accountID)
+	query := "UPDATE accounts SET balance = balance + $1 WHERE id = $2 AND is_active RETURNING currency"
+	var currency string
+	err := p.pool.QueryRow(ctx, query, amount, accountID).Scan(&currency)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errors.New("account not found")
+	}
 	if err != nil {
 		return fmt.Errorf("UpdateAccountBalance: %w", err)
 	}
-	rows := result.RowsAffected()
-	if rows == 0 {
-		return errors.New("account not found")
-	}
 	var transactionType string
 	switch {
 	case amount > 0:
@@ -87,10 +126,241 @@ func (p *PostgresRepository) UpdateAccountBalance(ctx context.Context, accountID
 		INTO transactions (account_id, amount, currency, type, status)
 		VALUES($1, $2, $3, $4, $5)
 	`
-	_, err = p.pool.Exec(ctx, query, &accountID, &amount, "RUB", &transactionType, "success")
+	_, err = p.pool.Exec(ctx, query, &accountID, &amount, &currency, &transactionType, "success")
+	return err
+}
+
+func (p *PostgresRepository) WithdrawFunds(ctx context.Context, accountID int64, amount float64, limits model.Limits, now time.Time) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("WithdrawFunds: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		currency  string
+		available float64
+		isActive  bool
+		blocked   bool
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT currency, balance + overdraft_limit, is_active, is_frozen OR owner_frozen FROM accounts WHERE id = $1 FOR UPDATE
+	`, accountID).Scan(&currency, &available, &isActive, &blocked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errors.New("account not found")
+	}
+	if err != nil {
+		return fmt.Errorf("WithdrawFunds: %w", err)
+	}
+	if !isActive {
+		return storage.ErrAccountClosed
+	}
+	if blocked || available < amount {
+		return storage.ErrInsufficientFunds
+	}
+	if err := checkLimits(ctx, tx, accountID, model.LimitWithdraw, amount, limits, now); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE accounts SET balance = balance - $2 WHERE id = $1", accountID, amount); err != nil {
+		return fmt.Errorf("WithdrawFunds: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO transactions (account_id, amount, currency, type, status, created_at)
+		VALUES ($1, $2, $3, 'withdraw', 'success', $4)
+	`, accountID, -amount, currency, now)
+	if err != nil {
+		return fmt.Errorf("WithdrawFunds transactions: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+func (p *PostgresRepository) TransferFunds(ctx context.Context, transfer *model.Transfer, limits model.Limits, now time.Time) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("TransferFunds: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if transfer.QuoteID != "" {
+		result, err := tx.Exec(ctx, `
+			UPDATE fx_quotes SET used_at = $2
+			WHERE id = $1 AND used_at IS NULL AND expires_at > $2
+		`, transfer.QuoteID, now)
+		if err != nil {
+			return fmt.Errorf("TransferFunds quote: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return storage.ErrQuoteUnavailable
+		}
+	}
+
+	// счет отправителя блокируется до подсчета использованных лимитов, чтобы параллельные переводы не прошли оба
+	if _, err := tx.Exec(ctx, "SELECT 1 FROM accounts WHERE id = $1 FOR UPDATE", transfer.FromAccountID); err != nil {
+		return fmt.Errorf("TransferFunds lock: %w", err)
+	}
+	if err := checkLimits(ctx, tx, transfer.FromAccountID, model.LimitTransfer, transfer.DebitAmount, limits, now); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(ctx, `
+		UPDATE accounts SET balance = balance - $2
+		WHERE id = $1 AND balance + overdraft_limit >= $2 AND is_active AND NOT is_frozen AND NOT owner_frozen
+	`, transfer.FromAccountID, transfer.DebitAmount)
+	if err != nil {
+		return fmt.Errorf("TransferFunds debit: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return storage.ErrInsufficientFunds
+	}
+	result, err = tx.Exec(ctx, "UPDATE accounts SET balance = balance + $2 WHERE id = $1 AND is_active", transfer.ToAccountID, transfer.CreditAmount)
+	if err != nil {
+		return fmt.Errorf("TransferFunds credit: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return storage.ErrAccountClosed
+	}
+
+	// параллельный перевод с тем же ключом дождется этой транзакции и получит нарушение уникальности
+	err = insertTransferLegs(ctx, tx, transfer)
+	if isUniqueViolation(err) {
+		return storage.ErrAlreadyExists
+	}
+	if err != nil {
+		return fmt.Errorf("TransferFunds transactions: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+func (p *PostgresRepository) HasTransfer(ctx context.Context, idempotencyKey string) (bool, error) {
+	var exists bool
+	err := p.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM transactions WHERE idempotency_key = $1)", idempotencyKey).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("HasTransfer: %w", err)
+	}
+	return exists, nil
+}
+
+// insertTransferLegs записывает списание и зачисление перевода; остатки счетов вызывающий уже изменил
+func insertTransferLegs(ctx context.Context, tx pgx.Tx, transfer *model.Transfer) error {
+	// ноги перевода ссылаются друг на друга через counterpart_id, чтобы сторно вернуло обе
+	query := `
+		INSERT INTO transactions (account_id, amount, currency, type, status, related_entity_id, fx_rate, description, counterpart_id,
+			idempotency_key)
+		VALUES ($1, $2, $3, 'transfer', 'success', $4, $5, $6, $7, NULLIF($8, ''))
+		RETURNING id
+	`
+	var debitID, creditID int64
+	err := tx.QueryRow(ctx, query, transfer.FromAccountID, -transfer.DebitAmount, transfer.DebitCurrency,
+		transfer.ToAccountID, transfer.Rate, transfer.Description, nil, transfer.IdempotencyKey).Scan(&debitID)
+	if err != nil {
+		return err
+	}
+	err = tx.QueryRow(ctx, query, transfer.ToAccountID, transfer.CreditAmount, transfer.CreditCurrency,
+		transfer.FromAccountID, transfer.Rate, transfer.Description, debitID, "").Scan(&creditID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "UPDATE transactions SET counterpart_id = $2 WHERE id = $1", debitID, creditID)
 	return err
 }
 
+func (p *PostgresRepository) CloseAccount(ctx context.Context, accountID int64, now time.Time) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("CloseAccount: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// блокируем счет, чтобы между проверкой остатка и закрытием не прошло зачисление
+	var (
+		balance  float64
+		interest float64
+		isActive bool
+	)
+	err = tx.QueryRow(ctx, "SELECT balance, overdraft_interest, is_active FROM accounts WHERE id = $1 FOR UPDATE", accountID).Scan(&balance, &interest, &isActive)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errors.New("account not found")
+	}
+	if err != nil {
+		return fmt.Errorf("CloseAccount: %w", err)
+	}
+	if !isActive {
+		return storage.ErrAccountClosed
+	}
+	// несписанные проценты за овердрафт — тоже задолженность по счету
+	if balance != 0 || interest != 0 {
+		return storage.ErrBalanceNotZero
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE accounts SET is_active = FALSE, closed_at = $2 WHERE id = $1", accountID, now); err != nil {
+		return fmt.Errorf("CloseAccount: %w", err)
+	}
+	// карты и псевдонимы счета закрываются вместе с ним
+	for _, query := range []string{
+		"DELETE FROM aliases WHERE account_id = $1",
+		"UPDATE cards SET is_active = FALSE WHERE account_id = $1",
+		"UPDATE card_tokens SET status = '" + model.TokenDeleted + "' WHERE card_id IN (SELECT id FROM cards WHERE account_id = $1)",
+	} {
+		if _, err := tx.Exec(ctx, query, accountID); err != nil {
+			return fmt.Errorf("CloseAccount: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (p *PostgresRepository) SetAccountOwnerFrozen(ctx context.Context, accountID int64, frozen bool) error {
+	result, err := p.pool.Exec(ctx, "UPDATE accounts SET owner_frozen = $2 WHERE id = $1 AND is_active", accountID, frozen)
+	if err != nil {
+		return fmt.Errorf("SetAccountOwnerFrozen: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return storage.ErrAccountClosed
+	}
+	return nil
+}
+
+func (p *PostgresRepository) GetTransactionsByAccount(ctx context.Context, accountID int64) ([]*model.Transaction, error) {
+	query := "SELECT " + transactionColumns + " FROM transactions WHERE account_id = $1 ORDER BY id"
+	rows, err := p.pool.Query(ctx, query, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("GetTransactionsByAccount: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := make([]*model.Transaction, 0)
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("GetTransactionsByAccount scan: %w", err)
+		}
+		transactions = append(transactions, t)
+	}
+	return transactions, rows.Err()
+}
+
+func (p *PostgresRepository) GetTransaction(ctx context.Context, transactionID int64) (*model.Transaction, error) {
+	query := "SELECT " + transactionColumns + " FROM transactions WHERE id = $1"
+	t, err := scanTransaction(p.pool.QueryRow(ctx, query, transactionID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetTransaction: %w", err)
+	}
+	return t, nil
+}
+
+func scanTransaction(row pgx.Row) (*model.Transaction, error) {
+	var t model.Transaction
+	err := row.Scan(&t.ID, &t.AccountID, &t.Amount, &t.Currency, &t.Type, &t.Status, &t.Description, &t.RelatedEntityID,
+		&t.FXRate, &t.CounterpartID, &t.ReversalOf, &t.ReversedAmount, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
 func (p *PostgresRepository) BeginTransaction(ctx context.Context) (storage.Transaction, error) {
 	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
@@ -98,3 +368,14 @@ func (p *PostgresRepository) BeginTransaction(ctx context.Context) (storage.Tran
 	}
 	return tx, nil
 }
+
+func scanAccount(row pgx.Row) (*model.Account, error) {
+	var acc model.Account
+	err := row.Scan(&acc.ID, &acc.UserID, &acc.Number, &acc.Currency, &acc.Type, &acc.Balance, &acc.IsActive, &acc.Frozen, &acc.OwnerFrozen, &acc.CreatedAt, &acc.ClosedAt,
+		&acc.OverdraftLimit, &acc.OverdraftRate, &acc.OverdraftInterest, &acc.OverdraftWarned, &acc.Product, &acc.InterestRate, &acc.Accrued,
+		&acc.TermDays, &acc.MaturesOn, &acc.EarlyPenalty, &acc.Rollover, &acc.PayoutID)
+	if err != nil {
+		return nil, err
+	}
+	return &acc, nil
+}
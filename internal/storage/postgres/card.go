@@ -2,91 +2,250 @@ package postgres
 
 import (
 	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
 	"context"
+	"errors"
 	"fmt"
 
-	"github.com/ProtonMail/gopenpgp/v3/crypto"
+	"github.com/jackc/pgx/v5"
 )
 
+const cardColumns = "id, account_id, encrypted_pan, pan_fingerprint, key_version, expiry_month, expiry_year , cardholder_name, dynamic_cvv, is_active, status, replaced_by_id, created_at"
+
 func (p *PostgresRepository) CreateVirtualCard(ctx context.Context, card *model.Card) (int64, error) {
-	encryptedPan, err := p.encryptWithPGP([]byte(card.PAN))
-	if err != nil {
-		return 0, fmt.Errorf("database error: %w", err)
-	}
+	return insertCard(ctx, p.pool, card)
+}
+
+func insertCard(ctx context.Context, q rowQuerier, card *model.Card) (int64, error) {
 	query := `
-		INSERT INTO cards (account_id, encrypted_pan, expiry_month, expiry_year , cardholder_name, is_active, created_at)
-		VALUES ($1, $2::bytea, $3, $4, $5, $6, $7)
+		INSERT INTO cards (account_id, encrypted_pan, pan_fingerprint, key_version, expiry_month, expiry_year , cardholder_name, dynamic_cvv, is_active, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id
 	`
 	var id int64
-	err = p.pool.QueryRow(ctx, query, &card.AccountID, &encryptedPan, &card.ExpiryMonth, &card.ExpiryYear, &card.CardholderName, &card.IsActive, &card.CreatedAt).Scan(&id)
+	err := q.QueryRow(ctx, query, &card.AccountID, &card.EncryptedPAN, &card.PANFingerprint, &card.KeyVersion, &card.ExpiryMonth, &card.ExpiryYear, &card.CardholderName, &card.DynamicCVV, &card.IsActive, &card.Status, &card.CreatedAt).Scan(&id)
+	if isUniqueViolation(err) {
+		return 0, storage.ErrAlreadyExists
+	}
 	return id, err
-
 }
 
 func (p *PostgresRepository) GetCardsByAccount(ctx context.Context, accountID int64) ([]*model.Card, error) {
 	query := `
-		SELECT id, encode(encrypted_pan, 'escape')::text, expiry_month, expiry_year , cardholder_name, is_active, created_at
+		SELECT ` + cardColumns + `
 		FROM cards 
 		WHERE account_id=$1
 	`
-	rows, err := p.pool.Query(ctx, query, accountID)
+	cards, err := p.queryCards(ctx, query, accountID)
 	if err != nil {
 		return nil, fmt.Errorf("GetCardsByAccount: %w", err)
 	}
+	return cards, nil
+}
+
+func (p *PostgresRepository) FindByPAN(ctx context.Context, panFingerprint []byte) (*model.Card, error) {
+	query := `
+		SELECT ` + cardColumns + `
+		FROM cards 
+		WHERE pan_fingerprint=$1
+	`
+	cards, err := p.queryCards(ctx, query, panFingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("FindByPAN: %w", err)
+	}
+	if len(cards) == 0 {
+		return nil, nil
+	}
+	return cards[0], nil
+}
+
+func (p *PostgresRepository) GetCardsWithStaleKey(ctx context.Context, activeVersion int, afterID int64, limit int) ([]*model.Card, error) {
+	query := `
+		SELECT ` + cardColumns + `
+		FROM cards 
+		WHERE (key_version <> $1 OR pan_fingerprint IS NULL) AND id > $2
+		ORDER BY id
+		LIMIT $3
+	`
+	cards, err := p.queryCards(ctx, query, activeVersion, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetCardsWithStaleKey: %w", err)
+	}
+	return cards, nil
+}
+
+func (p *PostgresRepository) UpdateEncryptedPAN(ctx context.Context, card *model.Card) error {
+	query := "UPDATE cards SET encrypted_pan = $1, pan_fingerprint = $2, key_version = $3 WHERE id = $4"
+	result, err := p.pool.Exec(ctx, query, card.EncryptedPAN, card.PANFingerprint, card.KeyVersion, card.ID)
+	if isUniqueViolation(err) {
+		return storage.ErrAlreadyExists
+	}
+	if err != nil {
+		return fmt.Errorf("UpdateEncryptedPAN: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("card not found")
+	}
+	return nil
+}
+
+func (p *PostgresRepository) queryCards(ctx context.Context, query string, args ...any) ([]*model.Card, error) {
+	rows, err := p.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	cards := make([]*model.Card, 0)
 	for rows.Next() {
-		var (
-			card          model.Card
-			encrypted_pan []byte
-		)
-		if err := rows.Scan(&card.ID, &encrypted_pan, &card.ExpiryMonth, &card.ExpiryYear, &card.CardholderName, &card.IsActive, &card.CreatedAt); err != nil {
-			return nil, fmt.Errorf("GetCardsByAccount scan: %w", err)
+		var card model.Card
+		if err := rows.Scan(&card.ID, &card.AccountID, &card.EncryptedPAN, &card.PANFingerprint, &card.KeyVersion, &card.ExpiryMonth, &card.ExpiryYear, &card.CardholderName, &card.DynamicCVV, &card.IsActive, &card.Status, &card.ReplacedByID, &card.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
 		}
-		pan, err := p.decryptWithPGP(encrypted_pan)
-		if err != nil {
-			return nil, fmt.Errorf("GetCardsByAccount decryption: %w", err)
-		}
-		card.PAN = string(pan)
-		card.AccountID = accountID
 		cards = append(cards, &card)
 	}
 	return cards, rows.Err()
 }
 
-// Encrypt plaintext message using a public key
-func (p *PostgresRepository) encryptWithPGP(data []byte) (string, error) {
-	pgp := crypto.PGP()
-	// Encrypt plaintext message using a public key
-	encHandle, err := pgp.Encryption().Recipient(p.publicKey).New()
+func (p *PostgresRepository) GetCardByID(ctx context.Context, cardID int64) (*model.Card, error) {
+	query := `
+		SELECT ` + cardColumns + `
+		FROM cards 
+		WHERE id=$1
+	`
+	cards, err := p.queryCards(ctx, query, cardID)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("GetCardByID: %w", err)
+	}
+	if len(cards) == 0 {
+		return nil, nil
+	}
+	return cards[0], nil
+}
+
+func (p *PostgresRepository) GetCardPIN(ctx context.Context, cardID int64) (*model.CardPIN, error) {
+	query := "SELECT card_id, pin_hash, failed_attempts, locked, updated_at FROM card_pins WHERE card_id=$1"
+	var pin model.CardPIN
+	err := p.pool.QueryRow(ctx, query, cardID).Scan(&pin.CardID, &pin.Hash, &pin.FailedAttempts, &pin.Locked, &pin.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
 	}
-	pgpMessage, err := encHandle.Encrypt(data)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("GetCardPIN: %w", err)
+	}
+	return &pin, nil
+}
+
+func (p *PostgresRepository) SetCardPIN(ctx context.Context, cardID int64, hash string) error {
+	query := `
+		INSERT INTO card_pins (card_id, pin_hash)
+		VALUES ($1, $2)
+		ON CONFLICT (card_id) DO UPDATE
+			SET pin_hash = EXCLUDED.pin_hash, failed_attempts = 0, locked = FALSE, updated_at = now()
+	`
+	if _, err := p.pool.Exec(ctx, query, cardID, hash); err != nil {
+		return fmt.Errorf("SetCardPIN: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) RegisterPINAttempt(ctx context.Context, cardID int64, success bool, maxAttempts int) (bool, error) {
+	query := `
+		UPDATE card_pins
+		SET failed_attempts = CASE WHEN $2 THEN 0 ELSE failed_attempts + 1 END,
+			locked = locked OR (NOT $2 AND failed_attempts + 1 >= $3)
+		WHERE card_id = $1 AND NOT locked
+		RETURNING locked
+	`
+	var locked bool
+	err := p.pool.QueryRow(ctx, query, cardID, success, maxAttempts).Scan(&locked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// PIN заблокирован параллельной попыткой после чтения вызывающим: попытка не учитывается
+		return true, nil
 	}
-	armored, err := pgpMessage.Armor()
 	if err != nil {
-		return "", err
+		return false, fmt.Errorf("RegisterPINAttempt: %w", err)
 	}
-	return armored, nil
+	return locked, nil
 }
 
-// Decrypt armored encrypted message using the private key and obtain the plaintext
-func (p *PostgresRepository) decryptWithPGP(encrypted []byte) (string, error) {
-	pgp := crypto.PGP()
+func (p *PostgresRepository) UnlockCardPIN(ctx context.Context, cardID int64) error {
+	query := "UPDATE card_pins SET failed_attempts = 0, locked = FALSE, updated_at = now() WHERE card_id = $1"
+	result, err := p.pool.Exec(ctx, query, cardID)
+	if err != nil {
+		return fmt.Errorf("UnlockCardPIN: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("pin not set")
+	}
+	return nil
+}
 
-	// Decrypt armored encrypted message using the private key and obtain the plaintext
-	decHandle, err := pgp.Decryption().DecryptionKey(p.privateKey).New()
+func (p *PostgresRepository) ExpireCards(ctx context.Context, year, month int) ([]*model.Card, error) {
+	query := `
+		UPDATE cards SET status = $1, is_active = FALSE
+		WHERE status = $2 AND (expiry_year, expiry_month) < ($3, $4)
+		RETURNING ` + cardColumns
+	cards, err := p.queryCards(ctx, query, model.CardExpired, model.CardActive, year, month)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("ExpireCards: %w", err)
 	}
-	decrypted, err := decHandle.Decrypt(encrypted, crypto.Armor)
+	return cards, nil
+}
+
+func (p *PostgresRepository) GetCardsDueForRenewal(ctx context.Context, year, month int, limit int) ([]*model.Card, error) {
+	query := `
+		SELECT ` + prefixColumns("c", cardColumns) + `
+		FROM cards c
+		JOIN accounts a ON a.id = c.account_id
+		WHERE c.status = $1 AND a.is_active AND c.replaced_by_id IS NULL
+			AND (c.expiry_year, c.expiry_month) <= ($2, $3)
+		ORDER BY c.id
+		LIMIT $4
+	`
+	cards, err := p.queryCards(ctx, query, model.CardActive, year, month, limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetCardsDueForRenewal: %w", err)
+	}
+	return cards, nil
+}
+
+func (p *PostgresRepository) UpdateCardExpiry(ctx context.Context, cardID int64, month, year int) error {
+	query := "UPDATE cards SET expiry_month = $1, expiry_year = $2 WHERE id = $3"
+	if _, err := p.pool.Exec(ctx, query, month, year, cardID); err != nil {
+		return fmt.Errorf("UpdateCardExpiry: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) CreateReplacementCard(ctx context.Context, cardID int64, replacement *model.Card) (int64, error) {
+	tx, err := p.pool.Begin(ctx)
 	if err != nil {
-		return "", err
+		return 0, fmt.Errorf("CreateReplacementCard: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var locked int64
+	err = tx.QueryRow(ctx, "SELECT id FROM cards WHERE id = $1 AND replaced_by_id IS NULL FOR UPDATE", cardID).Scan(&locked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// карта уже перевыпущена параллельным запуском
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("CreateReplacementCard: %w", err)
+	}
+	id, err := insertCard(ctx, tx, replacement)
+	if errors.Is(err, storage.ErrAlreadyExists) {
+		return 0, err
+	}
+	if err != nil {
+		return 0, fmt.Errorf("CreateReplacementCard: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "UPDATE cards SET replaced_by_id = $1 WHERE id = $2", id, cardID); err != nil {
+		return 0, fmt.Errorf("CreateReplacementCard: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("CreateReplacementCard: %w", err)
 	}
-	return decrypted.String(), nil
+	return id, nil
 }
@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+)
+
+const cardTokenColumns = "id, card_id, token_fingerprint, last_four, requestor_type, requestor_id, status, max_amount, expires_at, created_at"
+
+func (p *PostgresRepository) CreateCardToken(ctx context.Context, token *model.CardToken) (int64, error) {
+	query := `
+		INSERT INTO card_tokens (card_id, token_fingerprint, last_four, requestor_type, requestor_id, status, max_amount, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+	var id int64
+	err := p.pool.QueryRow(ctx, query,
+		token.CardID,
+		token.Fingerprint,
+		token.LastFour,
+		token.RequestorType,
+		token.RequestorID,
+		token.Status,
+		token.MaxAmount,
+		token.ExpiresAt,
+		token.CreatedAt,
+	).Scan(&id)
+	if isUniqueViolation(err) {
+		return 0, storage.ErrAlreadyExists
+	}
+	if err != nil {
+		return 0, fmt.Errorf("CreateCardToken: %w", err)
+	}
+	return id, nil
+}
+
+func (p *PostgresRepository) GetCardTokens(ctx context.Context, cardID int64) ([]*model.CardToken, error) {
+	query := "SELECT " + cardTokenColumns + " FROM card_tokens WHERE card_id=$1 ORDER BY id"
+	tokens, err := p.queryCardTokens(ctx, query, cardID)
+	if err != nil {
+		return nil, fmt.Errorf("GetCardTokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (p *PostgresRepository) FindCardToken(ctx context.Context, fingerprint []byte) (*model.CardToken, error) {
+	query := "SELECT " + cardTokenColumns + " FROM card_tokens WHERE token_fingerprint=$1"
+	tokens, err := p.queryCardTokens(ctx, query, fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("FindCardToken: %w", err)
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	return tokens[0], nil
+}
+
+func (p *PostgresRepository) UpdateCardTokenStatus(ctx context.Context, cardID, tokenID int64, status string) error {
+	query := "UPDATE card_tokens SET status = $1 WHERE id = $2 AND card_id = $3 AND status <> $4"
+	result, err := p.pool.Exec(ctx, query, status, tokenID, cardID, model.TokenDeleted)
+	if err != nil {
+		return fmt.Errorf("UpdateCardTokenStatus: %w", err)
+	}
+	if result.RowsAffected() > 0 {
+		return nil
+	}
+	var exists bool
+	err = p.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM card_tokens WHERE id = $1 AND card_id = $2)", tokenID, cardID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("UpdateCardTokenStatus: %w", err)
+	}
+	if exists {
+		return storage.ErrTokenDeleted
+	}
+	return errors.New("token not found")
+}
+
+func (p *PostgresRepository) queryCardTokens(ctx context.Context, query string, args ...any) ([]*model.CardToken, error) {
+	rows, err := p.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]*model.CardToken, 0)
+	for rows.Next() {
+		var token model.CardToken
+		if err := rows.Scan(
+			&token.ID,
+			&token.CardID,
+			&token.Fingerprint,
+			&token.LastFour,
+			&token.RequestorType,
+			&token.RequestorID,
+			&token.Status,
+			&token.MaxAmount,
+			&token.ExpiresAt,
+			&token.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		tokens = append(tokens, &token)
+	}
+	return tokens, rows.Err()
+}
@@ -56,3 +56,27 @@ func (p *PostgresRepository) IssuePayment(ctx context.Context, payment model.Pay
 
 	return paymentID, nil
 }
+
+func (p *PostgresRepository) GetCreditsByUser(ctx context.Context, userID string) ([]*model.Credit, error) {
+	const query = `
+		SELECT id, user_id, amount, currency, monthly_rate, term_months, status, created_at
+		FROM credits
+		WHERE user_id = $1
+		ORDER BY id
+	`
+	rows, err := p.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("GetCreditsByUser: %w", err)
+	}
+	defer rows.Close()
+
+	var credits []*model.Credit
+	for rows.Next() {
+		c := &model.Credit{}
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Amount, &c.Currency, &c.MonthlyRate, &c.TermMonths, &c.Status, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("GetCreditsByUser scan: %w", err)
+		}
+		credits = append(credits, c)
+	}
+	return credits, rows.Err()
+}
@@ -0,0 +1,207 @@
+package postgres
+
+import (
+	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const disputeColumns = "id, user_id, transaction_id, reason, description, status, resolution_comment, reversed_amount, " +
+	"COALESCE(resolved_by, ''), created_at, updated_at, resolved_at"
+
+const disputeAttachmentColumns = "id, dispute_id, file_name, content_type, size, created_at"
+
+func (p *PostgresRepository) CreateDispute(ctx context.Context, dispute *model.Dispute) (*model.Dispute, error) {
+	query := `
+		INSERT INTO disputes (user_id, transaction_id, reason, description, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		RETURNING ` + disputeColumns
+	created, err := scanDispute(p.pool.QueryRow(ctx, query, dispute.UserID, dispute.TransactionID, dispute.Reason,
+		dispute.Description, dispute.Status, dispute.CreatedAt))
+	if isUniqueViolation(err) {
+		return nil, storage.ErrAlreadyExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("CreateDispute: %w", err)
+	}
+	return created, nil
+}
+
+func (p *PostgresRepository) GetDisputesByUser(ctx context.Context, userID string) ([]*model.Dispute, error) {
+	query := "SELECT " + disputeColumns + " FROM disputes WHERE user_id = $1 ORDER BY id DESC"
+	return p.queryDisputes(ctx, "GetDisputesByUser", query, userID)
+}
+
+func (p *PostgresRepository) GetDisputes(ctx context.Context, status string, limit int) ([]*model.Dispute, error) {
+	query := `
+		SELECT ` + disputeColumns + `
+		FROM disputes
+		WHERE $1 = '' OR status = $1
+		ORDER BY id
+		LIMIT $2
+	`
+	return p.queryDisputes(ctx, "GetDisputes", query, status, limit)
+}
+
+func (p *PostgresRepository) GetDispute(ctx context.Context, disputeID int64) (*model.Dispute, error) {
+	query := "SELECT " + disputeColumns + " FROM disputes WHERE id = $1"
+	dispute, err := scanDispute(p.pool.QueryRow(ctx, query, disputeID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetDispute: %w", err)
+	}
+	return dispute, nil
+}
+
+func (p *PostgresRepository) SetDisputeStatus(ctx context.Context, disputeID int64, from []string, to string, now time.Time) (bool, error) {
+	query := "UPDATE disputes SET status = $3, updated_at = $4 WHERE id = $1 AND status = ANY($2)"
+	result, err := p.pool.Exec(ctx, query, disputeID, from, to, now)
+	if err != nil {
+		return false, fmt.Errorf("SetDisputeStatus: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+func (p *PostgresRepository) ResolveDispute(ctx context.Context, dispute *model.Dispute, legs []model.ReversalLeg, action *model.AdminAction) error {
+	return p.withAdminAction(ctx, action, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE disputes
+			SET status = $2, resolution_comment = $3, reversed_amount = $4, resolved_by = $5, resolved_at = $6, updated_at = $6
+			WHERE id = $1 AND status IN ('`+model.DisputeOpen+`', '`+model.DisputeInReview+`')
+		`, dispute.ID, dispute.Status, dispute.ResolutionComment, dispute.ReversedAmount, dispute.ResolvedBy, dispute.ResolvedAt)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return storage.ErrDisputeClosed
+		}
+		if len(legs) == 0 {
+			return nil
+		}
+		_, err = applyReversal(ctx, tx, legs, fmt.Sprintf("Сторно по обращению №%d", dispute.ID))
+		return err
+	})
+}
+
+func (p *PostgresRepository) AddDisputeAttachment(ctx context.Context, attachment *model.DisputeAttachment) error {
+	query := `
+		INSERT INTO dispute_attachments (dispute_id, file_name, content_type, size, encrypted_content, key_version)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	err := p.pool.QueryRow(ctx, query, attachment.DisputeID, attachment.FileName, attachment.ContentType, attachment.Size,
+		attachment.EncryptedContent, attachment.KeyVersion).Scan(&attachment.ID, &attachment.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("AddDisputeAttachment: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) GetDisputeAttachments(ctx context.Context, disputeID int64) ([]*model.DisputeAttachment, error) {
+	query := "SELECT " + disputeAttachmentColumns + " FROM dispute_attachments WHERE dispute_id = $1 ORDER BY id"
+	rows, err := p.pool.Query(ctx, query, disputeID)
+	if err != nil {
+		return nil, fmt.Errorf("GetDisputeAttachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := make([]*model.DisputeAttachment, 0)
+	for rows.Next() {
+		var a model.DisputeAttachment
+		if err := rows.Scan(&a.ID, &a.DisputeID, &a.FileName, &a.ContentType, &a.Size, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("GetDisputeAttachments scan: %w", err)
+		}
+		attachments = append(attachments, &a)
+	}
+	return attachments, rows.Err()
+}
+
+func (p *PostgresRepository) GetDisputeAttachment(ctx context.Context, attachmentID int64) (*model.DisputeAttachment, error) {
+	query := "SELECT " + disputeAttachmentColumns + ", encrypted_content, key_version FROM dispute_attachments WHERE id = $1"
+	attachments, err := p.queryDisputeAttachments(ctx, query, attachmentID)
+	if err != nil {
+		return nil, fmt.Errorf("GetDisputeAttachment: %w", err)
+	}
+	if len(attachments) == 0 {
+		return nil, nil
+	}
+	return attachments[0], nil
+}
+
+func (p *PostgresRepository) GetDisputeAttachmentsWithStaleKey(ctx context.Context, activeVersion int, afterID int64, limit int) ([]*model.DisputeAttachment, error) {
+	query := `
+		SELECT ` + disputeAttachmentColumns + `, encrypted_content, key_version
+		FROM dispute_attachments
+		WHERE key_version <> $1 AND id > $2
+		ORDER BY id
+		LIMIT $3
+	`
+	attachments, err := p.queryDisputeAttachments(ctx, query, activeVersion, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetDisputeAttachmentsWithStaleKey: %w", err)
+	}
+	return attachments, nil
+}
+
+func (p *PostgresRepository) UpdateDisputeAttachmentEncryption(ctx context.Context, attachment *model.DisputeAttachment) error {
+	query := "UPDATE dispute_attachments SET encrypted_content = $2, key_version = $3 WHERE id = $1"
+	_, err := p.pool.Exec(ctx, query, attachment.ID, attachment.EncryptedContent, attachment.KeyVersion)
+	if err != nil {
+		return fmt.Errorf("UpdateDisputeAttachmentEncryption: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) queryDisputes(ctx context.Context, op, query string, args ...any) ([]*model.Dispute, error) {
+	rows, err := p.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	disputes := make([]*model.Dispute, 0)
+	for rows.Next() {
+		dispute, err := scanDispute(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s scan: %w", op, err)
+		}
+		disputes = append(disputes, dispute)
+	}
+	return disputes, rows.Err()
+}
+
+// queryDisputeAttachments читает вложения вместе с зашифрованным содержимым
+func (p *PostgresRepository) queryDisputeAttachments(ctx context.Context, query string, args ...any) ([]*model.DisputeAttachment, error) {
+	rows, err := p.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []*model.DisputeAttachment
+	for rows.Next() {
+		var a model.DisputeAttachment
+		if err := rows.Scan(&a.ID, &a.DisputeID, &a.FileName, &a.ContentType, &a.Size, &a.CreatedAt, &a.EncryptedContent, &a.KeyVersion); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, &a)
+	}
+	return attachments, rows.Err()
+}
+
+func scanDispute(row pgx.Row) (*model.Dispute, error) {
+	var d model.Dispute
+	err := row.Scan(&d.ID, &d.UserID, &d.TransactionID, &d.Reason, &d.Description, &d.Status, &d.ResolutionComment,
+		&d.ReversedAmount, &d.ResolvedBy, &d.CreatedAt, &d.UpdatedAt, &d.ResolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"BankingApp/internal/model"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const fxQuoteColumns = "id, user_id, from_currency, to_currency, amount, converted_amount, rate, mid_rate, spread, rates_date, created_at, expires_at, used_at"
+
+func (p *PostgresRepository) CreateFXQuote(ctx context.Context, quote *model.FXQuote) error {
+	query := `
+		INSERT INTO fx_quotes (id, user_id, from_currency, to_currency, amount, converted_amount, rate, mid_rate, spread, rates_date, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+	_, err := p.pool.Exec(ctx, query, quote.ID, quote.UserID, quote.From, quote.To, quote.Amount, quote.ConvertedAmount,
+		quote.Rate, quote.MidRate, quote.Spread, quote.RatesDate, quote.CreatedAt, quote.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("CreateFXQuote: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) GetFXQuote(ctx context.Context, quoteID string) (*model.FXQuote, error) {
+	query := "SELECT " + fxQuoteColumns + " FROM fx_quotes WHERE id = $1"
+	quote, err := scanFXQuote(p.pool.QueryRow(ctx, query, quoteID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetFXQuote: %w", err)
+	}
+	return quote, nil
+}
+
+func scanFXQuote(row pgx.Row) (*model.FXQuote, error) {
+	var q model.FXQuote
+	err := row.Scan(&q.ID, &q.UserID, &q.From, &q.To, &q.Amount, &q.ConvertedAmount, &q.Rate, &q.MidRate, &q.Spread,
+		&q.RatesDate, &q.CreatedAt, &q.ExpiresAt, &q.UsedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
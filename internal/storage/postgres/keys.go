@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (p *PostgresRepository) GetDataKeys(ctx context.Context) ([]*model.DataKey, error) {
+	query := "SELECT version, purpose, wrapped_key, status, created_at FROM data_keys ORDER BY version"
+	rows, err := p.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("GetDataKeys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*model.DataKey, 0)
+	for rows.Next() {
+		var key model.DataKey
+		if err := rows.Scan(&key.Version, &key.Purpose, &key.WrappedKey, &key.Status, &key.CreatedAt); err != nil {
+			return nil, fmt.Errorf("GetDataKeys scan: %w", err)
+		}
+		keys = append(keys, &key)
+	}
+	return keys, rows.Err()
+}
+
+func (p *PostgresRepository) CreateDataKey(ctx context.Context, purpose string, wrappedKey []byte, previous int) (*model.DataKey, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("CreateDataKey: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// compare-and-set: активная версия блокируется, параллельная ротация дождется коммита и увидит новую версию
+	var active int
+	err = tx.QueryRow(ctx, "SELECT version FROM data_keys WHERE purpose = $1 AND status = $2 FOR UPDATE", purpose, model.DataKeyActive).Scan(&active)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("CreateDataKey: %w", err)
+	}
+	if active != previous {
+		return nil, storage.ErrAlreadyExists
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE data_keys SET status = $1 WHERE status = $2 AND purpose = $3", model.DataKeyRetired, model.DataKeyActive, purpose); err != nil {
+		return nil, fmt.Errorf("CreateDataKey retire: %w", err)
+	}
+	query := `
+		INSERT INTO data_keys (purpose, wrapped_key, status)
+		VALUES ($1, $2, $3)
+		RETURNING version, purpose, wrapped_key, status, created_at
+	`
+	var key model.DataKey
+	err = tx.QueryRow(ctx, query, purpose, wrappedKey, model.DataKeyActive).Scan(&key.Version, &key.Purpose, &key.WrappedKey, &key.Status, &key.CreatedAt)
+	if isUniqueViolation(err) {
+		// первую версию одновременно создал другой экземпляр
+		return nil, storage.ErrAlreadyExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("CreateDataKey: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("CreateDataKey commit: %w", err)
+	}
+	return &key, nil
+}
+
+func (p *PostgresRepository) GetSigningKeys(ctx context.Context, now time.Time) ([]*model.SigningKey, error) {
+	query := `
+		SELECT kid, algorithm, private_key, public_key, created_at, expires_at
+		FROM signing_keys
+		WHERE expires_at IS NULL OR expires_at > $1
+		ORDER BY created_at
+	`
+	rows, err := p.pool.Query(ctx, query, now)
+	if err != nil {
+		return nil, fmt.Errorf("GetSigningKeys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*model.SigningKey, 0)
+	for rows.Next() {
+		var key model.SigningKey
+		if err := rows.Scan(&key.KID, &key.Algorithm, &key.PrivateKey, &key.PublicKey, &key.CreatedAt, &key.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("GetSigningKeys scan: %w", err)
+		}
+		keys = append(keys, &key)
+	}
+	return keys, rows.Err()
+}
+
+func (p *PostgresRepository) CreateSigningKey(ctx context.Context, key *model.SigningKey, retireAt time.Time) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("CreateSigningKey: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "UPDATE signing_keys SET expires_at = $1 WHERE expires_at IS NULL", retireAt); err != nil {
+		return fmt.Errorf("CreateSigningKey retire: %w", err)
+	}
+	query := `
+		INSERT INTO signing_keys (kid, algorithm, private_key, public_key)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at
+	`
+	err = tx.QueryRow(ctx, query, key.KID, key.Algorithm, key.PrivateKey, key.PublicKey).Scan(&key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("CreateSigningKey: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("CreateSigningKey commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// rowQuerier — пул или открытая транзакция
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func (p *PostgresRepository) GetKYCStatus(ctx context.Context, userID string) (string, error) {
+	var status string
+	err := p.pool.QueryRow(ctx, "SELECT kyc_status FROM user_profiles WHERE user_id = $1", userID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.KYCNotStarted, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("GetKYCStatus: %w", err)
+	}
+	return status, nil
+}
+
+func (p *PostgresRepository) GetLimitUsage(ctx context.Context, accountID int64, operation string, now time.Time) (model.LimitUsage, error) {
+	usage, err := limitUsage(ctx, p.pool, accountID, operation, now)
+	if err != nil {
+		return usage, fmt.Errorf("GetLimitUsage: %w", err)
+	}
+	return usage, nil
+}
+
+func (p *PostgresRepository) GetLimitOverride(ctx context.Context, accountID int64, operation string, now time.Time) (*model.LimitOverride, error) {
+	query := `
+		SELECT account_id, operation, per_operation, daily_amount, monthly_amount, daily_count, monthly_count, expires_at, created_at
+		FROM account_limit_overrides
+		WHERE account_id = $1 AND operation = $2 AND expires_at > $3
+	`
+	var o model.LimitOverride
+	err := p.pool.QueryRow(ctx, query, accountID, operation, now).Scan(&o.AccountID, &o.Operation, &o.Limits.PerOperation,
+		&o.Limits.Daily, &o.Limits.Monthly, &o.Limits.DailyCount, &o.Limits.MonthlyCount, &o.ExpiresAt, &o.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetLimitOverride: %w", err)
+	}
+	return &o, nil
+}
+
+func (p *PostgresRepository) SaveLimitOverride(ctx context.Context, o *model.LimitOverride) error {
+	query := `
+		INSERT INTO account_limit_overrides (account_id, operation, per_operation, daily_amount, monthly_amount,
+			daily_count, monthly_count, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (account_id, operation) DO UPDATE SET
+			per_operation = EXCLUDED.per_operation, daily_amount = EXCLUDED.daily_amount,
+			monthly_amount = EXCLUDED.monthly_amount, daily_count = EXCLUDED.daily_count,
+			monthly_count = EXCLUDED.monthly_count, expires_at = EXCLUDED.expires_at, created_at = EXCLUDED.created_at
+	`
+	_, err := p.pool.Exec(ctx, query, o.AccountID, o.Operation, o.Limits.PerOperation, o.Limits.Daily, o.Limits.Monthly,
+		o.Limits.DailyCount, o.Limits.MonthlyCount, o.ExpiresAt, o.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("SaveLimitOverride: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) DeleteLimitOverride(ctx context.Context, accountID int64, operation string, now time.Time) (bool, error) {
+	// истекшая запись удаляется вместе с действующей, но об успехе сообщается только для действующей
+	var active bool
+	err := p.pool.QueryRow(ctx, `
+		DELETE FROM account_limit_overrides WHERE account_id = $1 AND operation = $2
+		RETURNING expires_at > $3
+	`, accountID, operation, now).Scan(&active)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("DeleteLimitOverride: %w", err)
+	}
+	return active, nil
+}
+
+// checkLimits сверяет списание с лимитами. Вызывается под блокировкой строки счета,
+// иначе параллельные списания увидят одно и то же использование и пройдут оба.
+func checkLimits(ctx context.Context, tx pgx.Tx, accountID int64, operation string, amount float64, limits model.Limits, now time.Time) error {
+	if limits == (model.Limits{}) {
+		return nil
+	}
+	usage, err := limitUsage(ctx, tx, accountID, operation, now)
+	if err != nil {
+		return fmt.Errorf("checkLimits: %w", err)
+	}
+	if code := limits.Exceeded(amount, usage); code != "" {
+		return &storage.LimitError{Code: code}
+	}
+	return nil
+}
+
+// limitUsage считает успешные списания операцией за сутки и 30 дней; полностью сторнированные не учитываются,
+// у частично сторнированных учитывается только несторнированная часть
+func limitUsage(ctx context.Context, q rowQuerier, accountID int64, operation string, now time.Time) (model.LimitUsage, error) {
+	var usage model.LimitUsage
+	err := q.QueryRow(ctx, `
+		SELECT
+			COALESCE(SUM(-amount - reversed_amount) FILTER (WHERE created_at > $3), 0),
+			COALESCE(SUM(-amount - reversed_amount), 0),
+			COUNT(*) FILTER (WHERE created_at > $3),
+			COUNT(*)
+		FROM transactions
+		WHERE account_id = $1 AND type = $2 AND status = 'success' AND amount < 0
+			AND -amount > reversed_amount AND created_at > $4
+	`, accountID, operation, now.Add(-model.LimitDailyWindow), now.Add(-model.LimitMonthlyWindow)).Scan(
+		&usage.DailyAmount, &usage.MonthlyAmount, &usage.DailyCount, &usage.MonthlyCount)
+	return usage, err
+}
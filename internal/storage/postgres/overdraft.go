@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (p *PostgresRepository) GetOverdraftAccrualStart(ctx context.Context, day time.Time) (*time.Time, error) {
+	var start *time.Time
+	err := p.pool.QueryRow(ctx, `
+		SELECT MIN(COALESCE(overdraft_accrued_on, created_at::date)) + 1
+		FROM accounts
+		WHERE overdraft_rate > 0 AND is_active AND COALESCE(overdraft_accrued_on, created_at::date) < $1
+	`, day).Scan(&start)
+	if err != nil {
+		return nil, fmt.Errorf("GetOverdraftAccrualStart: %w", err)
+	}
+	return start, nil
+}
+
+func (p *PostgresRepository) AccrueOverdraftInterest(ctx context.Context, day time.Time, daysInYear, limit int) (int, error) {
+	// отметка двигается и при положительном остатке, чтобы отставшая отметка всегда означала пропущенные дни.
+	// День начисляется только сразу после отметки: счет, пропущенный из-за SKIP LOCKED (по нему сейчас идет списание),
+	// не перескочит через день и догонит его при следующем запуске
+	result, err := p.pool.Exec(ctx, `
+		UPDATE accounts
+		SET overdraft_interest = overdraft_interest + GREATEST(-balance, 0) * overdraft_rate / $2,
+			overdraft_interest_since = CASE WHEN balance < 0 THEN COALESCE(overdraft_interest_since, $1) ELSE overdraft_interest_since END,
+			overdraft_accrued_on = $1
+		WHERE id IN (
+			SELECT id FROM accounts
+			WHERE overdraft_rate > 0 AND is_active AND COALESCE(overdraft_accrued_on, created_at::date) = $1::date - 1
+			ORDER BY id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+	`, day, daysInYear, limit)
+	if err != nil {
+		return 0, fmt.Errorf("AccrueOverdraftInterest: %w", err)
+	}
+	return int(result.RowsAffected()), nil
+}
+
+func (p *PostgresRepository) GetAccountsWithInterestDue(ctx context.Context, periodStart time.Time, limit int) ([]*model.Account, error) {
+	query := `
+		SELECT ` + accountColumns + `
+		FROM accounts
+		WHERE overdraft_interest_since < $1
+		ORDER BY id
+		LIMIT $2
+	`
+	rows, err := p.pool.Query(ctx, query, periodStart, limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetAccountsWithInterestDue: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []*model.Account
+	for rows.Next() {
+		acc, err := scanAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("GetAccountsWithInterestDue scan: %w", err)
+		}
+		accounts = append(accounts, acc)
+	}
+	return accounts, rows.Err()
+}
+
+func (p *PostgresRepository) PostOverdraftInterest(ctx context.Context, accountID int64, periodStart time.Time, description string, now time.Time) (*model.Transaction, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("PostOverdraftInterest: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		currency string
+		interest float64
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT currency, overdraft_interest FROM accounts
+		WHERE id = $1 AND overdraft_interest_since < $2
+		FOR UPDATE
+	`, accountID, periodStart).Scan(&currency, &interest)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// проценты уже списаны параллельным запуском
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("PostOverdraftInterest: %w", err)
+	}
+	// копейки, не дотянувшие до минимальной единицы валюты, не переносятся
+	amount := model.RoundAmount(interest, currency)
+	_, err = tx.Exec(ctx, `
+		UPDATE accounts SET balance = balance - $2, overdraft_interest = 0, overdraft_interest_since = NULL
+		WHERE id = $1
+	`, accountID, amount)
+	if err != nil {
+		return nil, fmt.Errorf("PostOverdraftInterest: %w", err)
+	}
+	var posted *model.Transaction
+	if amount > 0 {
+		query := `
+			INSERT INTO transactions (account_id, amount, currency, type, status, description, created_at)
+			VALUES ($1, $2, $3, '` + model.TransactionOverdraftInterest + `', '` + model.TransactionSuccess + `', $4, $5)
+			RETURNING ` + transactionColumns
+		posted, err = scanTransaction(tx.QueryRow(ctx, query, accountID, -amount, currency, description, now))
+		if err != nil {
+			return nil, fmt.Errorf("PostOverdraftInterest transactions: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("PostOverdraftInterest: %w", err)
+	}
+	return posted, nil
+}
+
+func (p *PostgresRepository) SetOverdraftWarned(ctx context.Context, accountID int64, from, to int) (bool, error) {
+	result, err := p.pool.Exec(ctx, "UPDATE accounts SET overdraft_warned = $3 WHERE id = $1 AND overdraft_warned = $2", accountID, from, to)
+	if err != nil {
+		return false, fmt.Errorf("SetOverdraftWarned: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+func (p *PostgresRepository) SetOverdraft(ctx context.Context, accountID int64, limit, rate float64, action *model.AdminAction) error {
+	return p.withAdminAction(ctx, action, func(tx pgx.Tx) error {
+		var (
+			balance  float64
+			isActive bool
+		)
+		err := tx.QueryRow(ctx, "SELECT balance, is_active FROM accounts WHERE id = $1 FOR UPDATE", accountID).Scan(&balance, &isActive)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("account not found")
+		}
+		if err != nil {
+			return err
+		}
+		if !isActive {
+			return storage.ErrAccountClosed
+		}
+		// новый лимит не может быть меньше уже использованного овердрафта
+		if balance+limit < 0 {
+			return storage.ErrInsufficientFunds
+		}
+		// при включении ставки дни без нее не начисляются задним числом
+		_, err = tx.Exec(ctx, `
+			UPDATE accounts SET overdraft_limit = $2, overdraft_rate = $3,
+				overdraft_accrued_on = CASE WHEN overdraft_rate = 0 THEN GREATEST(overdraft_accrued_on, CURRENT_DATE - 1) ELSE overdraft_accrued_on END
+			WHERE id = $1
+		`, accountID, limit, rate)
+		return err
+	})
+}
@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"BankingApp/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *PostgresRepository) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	result, err := r.pool.Exec(ctx, "UPDATE users SET password = $2 WHERE uuid = $1", userID, passwordHash)
+	if err != nil {
+		return fmt.Errorf("UpdatePassword: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+func (r *PostgresRepository) CreatePasswordResetToken(ctx context.Context, userID string, tokenHash []byte, expiresAt time.Time) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM password_reset_tokens WHERE user_id = $1 AND used_at IS NULL", userID); err != nil {
+		return fmt.Errorf("CreatePasswordResetToken: %w", err)
+	}
+	query := "INSERT INTO password_reset_tokens (token_hash, user_id, expires_at) VALUES ($1, $2, $3)"
+	if _, err := tx.Exec(ctx, query, tokenHash, userID, expiresAt); err != nil {
+		return fmt.Errorf("CreatePasswordResetToken: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *PostgresRepository) GetPasswordResetToken(ctx context.Context, tokenHash []byte) (*model.PasswordResetToken, error) {
+	query := "SELECT user_id, expires_at, used_at FROM password_reset_tokens WHERE token_hash = $1"
+	token := &model.PasswordResetToken{}
+	err := r.pool.QueryRow(ctx, query, tokenHash).Scan(&token.UserID, &token.ExpiresAt, &token.UsedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetPasswordResetToken: %w", err)
+	}
+	return token, nil
+}
+
+func (r *PostgresRepository) UsePasswordResetToken(ctx context.Context, tokenHash []byte, now time.Time) (bool, error) {
+	query := `
+		UPDATE password_reset_tokens SET used_at = $2
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+	`
+	result, err := r.pool.Exec(ctx, query, tokenHash, now)
+	if err != nil {
+		return false, fmt.Errorf("UsePasswordResetToken: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *PostgresRepository) GetLockout(ctx context.Context, scope, key string) (*model.LoginLockout, error) {
+	query := `
+		SELECT scope, key, failures, locked_until, last_failure_at
+		FROM login_failures
+		WHERE scope = $1 AND key = $2
+	`
+	lockout := &model.LoginLockout{}
+	err := r.pool.QueryRow(ctx, query, scope, key).Scan(&lockout.Scope, &lockout.Key, &lockout.Failures, &lockout.LockedUntil, &lockout.LastFailureAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetLockout: %w", err)
+	}
+	return lockout, nil
+}
+
+func (r *PostgresRepository) RegisterLoginFailure(ctx context.Context, scope, key string, now time.Time, window time.Duration) (*model.LoginLockout, error) {
+	query := `
+		INSERT INTO login_failures (scope, key, failures, last_failure_at)
+		VALUES ($1, $2, 1, $3)
+		ON CONFLICT (scope, key) DO UPDATE
+		SET failures = CASE
+				WHEN login_failures.last_failure_at < $3 - $4 * interval '1 second' THEN 1
+				ELSE login_failures.failures + 1
+			END,
+			last_failure_at = $3
+		RETURNING scope, key, failures, locked_until, last_failure_at
+	`
+	lockout := &model.LoginLockout{}
+	err := r.pool.QueryRow(ctx, query, scope, key, now, window.Seconds()).
+		Scan(&lockout.Scope, &lockout.Key, &lockout.Failures, &lockout.LockedUntil, &lockout.LastFailureAt)
+	if err != nil {
+		return nil, fmt.Errorf("RegisterLoginFailure: %w", err)
+	}
+	return lockout, nil
+}
+
+func (r *PostgresRepository) LockLogin(ctx context.Context, scope, key string, until time.Time) error {
+	query := "UPDATE login_failures SET locked_until = $3 WHERE scope = $1 AND key = $2"
+	if _, err := r.pool.Exec(ctx, query, scope, key, until); err != nil {
+		return fmt.Errorf("LockLogin: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ResetLoginFailures(ctx context.Context, scope, key string) error {
+	query := "DELETE FROM login_failures WHERE scope = $1 AND key = $2"
+	if _, err := r.pool.Exec(ctx, query, scope, key); err != nil {
+		return fmt.Errorf("ResetLoginFailures: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package postgres
+
+import (
+	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	payeeColumns = "id, user_id, nickname, type, COALESCE(account_number, ''), COALESCE(bic, ''), COALESCE(recipient_name, ''), " +
+		"COALESCE(alias_type, ''), COALESCE(alias_value, ''), alias_fingerprint, COALESCE(alias_masked, ''), created_at"
+	templateColumns = "id, user_id, name, payee_id, from_account_id, amount, COALESCE(description, ''), created_at"
+)
+
+func (p *PostgresRepository) CreatePayee(ctx context.Context, payee *model.Payee) (*model.Payee, error) {
+	query := `
+		INSERT INTO payees (user_id, nickname, type, account_number, bic, recipient_name, alias_type, alias_fingerprint, alias_masked)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), $8, NULLIF($9, ''))
+		RETURNING ` + payeeColumns
+	created, err := scanPayee(p.pool.QueryRow(ctx, query, payee.UserID, payee.Nickname, payee.Type, payee.AccountNumber,
+		payee.BIC, payee.RecipientName, payee.AliasType, payee.AliasFingerprint, payee.AliasMasked))
+	if isUniqueViolation(err) {
+		return nil, storage.ErrAlreadyExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("CreatePayee: %w", err)
+	}
+	return created, nil
+}
+
+func (p *PostgresRepository) GetPayees(ctx context.Context, userID string) ([]*model.Payee, error) {
+	rows, err := p.pool.Query(ctx, "SELECT "+payeeColumns+" FROM payees WHERE user_id = $1 ORDER BY nickname", userID)
+	if err != nil {
+		return nil, fmt.Errorf("GetPayees: %w", err)
+	}
+	defer rows.Close()
+
+	payees := make([]*model.Payee, 0)
+	for rows.Next() {
+		payee, err := scanPayee(rows)
+		if err != nil {
+			return nil, fmt.Errorf("GetPayees scan: %w", err)
+		}
+		payees = append(payees, payee)
+	}
+	return payees, rows.Err()
+}
+
+func (p *PostgresRepository) GetPayee(ctx context.Context, userID string, payeeID int64) (*model.Payee, error) {
+	query := "SELECT " + payeeColumns + " FROM payees WHERE id = $1 AND user_id = $2"
+	payee, err := scanPayee(p.pool.QueryRow(ctx, query, payeeID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetPayee: %w", err)
+	}
+	return payee, nil
+}
+
+func (p *PostgresRepository) GetPayeesWithPlainAlias(ctx context.Context, limit int) ([]*model.Payee, error) {
+	query := "SELECT " + payeeColumns + " FROM payees WHERE alias_value IS NOT NULL ORDER BY id LIMIT $1"
+	rows, err := p.pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetPayeesWithPlainAlias: %w", err)
+	}
+	defer rows.Close()
+
+	var payees []*model.Payee
+	for rows.Next() {
+		payee, err := scanPayee(rows)
+		if err != nil {
+			return nil, fmt.Errorf("GetPayeesWithPlainAlias scan: %w", err)
+		}
+		payees = append(payees, payee)
+	}
+	return payees, rows.Err()
+}
+
+func (p *PostgresRepository) SealPayeeAlias(ctx context.Context, payeeID int64, fingerprint []byte, masked string) error {
+	query := "UPDATE payees SET alias_fingerprint = $2, alias_masked = $3, alias_value = NULL WHERE id = $1"
+	if _, err := p.pool.Exec(ctx, query, payeeID, fingerprint, masked); err != nil {
+		return fmt.Errorf("SealPayeeAlias: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) DeletePayee(ctx context.Context, userID string, payeeID int64) (bool, error) {
+	result, err := p.pool.Exec(ctx, "DELETE FROM payees WHERE id = $1 AND user_id = $2", payeeID, userID)
+	if err != nil {
+		return false, fmt.Errorf("DeletePayee: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+func (p *PostgresRepository) CreateTemplate(ctx context.Context, template *model.PaymentTemplate) (*model.PaymentTemplate, error) {
+	query := `
+		INSERT INTO payment_templates (user_id, name, payee_id, from_account_id, amount, description)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+		RETURNING ` + templateColumns
+	created, err := scanTemplate(p.pool.QueryRow(ctx, query, template.UserID, template.Name, template.PayeeID,
+		template.FromAccountID, template.Amount, template.Description))
+	if isUniqueViolation(err) {
+		return nil, storage.ErrAlreadyExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("CreateTemplate: %w", err)
+	}
+	return created, nil
+}
+
+func (p *PostgresRepository) GetTemplates(ctx context.Context, userID string) ([]*model.PaymentTemplate, error) {
+	rows, err := p.pool.Query(ctx, "SELECT "+templateColumns+" FROM payment_templates WHERE user_id = $1 ORDER BY name", userID)
+	if err != nil {
+		return nil, fmt.Errorf("GetTemplates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := make([]*model.PaymentTemplate, 0)
+	for rows.Next() {
+		template, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("GetTemplates scan: %w", err)
+		}
+		templates = append(templates, template)
+	}
+	return templates, rows.Err()
+}
+
+func (p *PostgresRepository) GetTemplate(ctx context.Context, userID string, templateID int64) (*model.PaymentTemplate, error) {
+	query := "SELECT " + templateColumns + " FROM payment_templates WHERE id = $1 AND user_id = $2"
+	template, err := scanTemplate(p.pool.QueryRow(ctx, query, templateID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetTemplate: %w", err)
+	}
+	return template, nil
+}
+
+func (p *PostgresRepository) DeleteTemplate(ctx context.Context, userID string, templateID int64) (bool, error) {
+	result, err := p.pool.Exec(ctx, "DELETE FROM payment_templates WHERE id = $1 AND user_id = $2", templateID, userID)
+	if err != nil {
+		return false, fmt.Errorf("DeleteTemplate: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+func scanPayee(row pgx.Row) (*model.Payee, error) {
+	var p model.Payee
+	err := row.Scan(&p.ID, &p.UserID, &p.Nickname, &p.Type, &p.AccountNumber, &p.BIC, &p.RecipientName,
+		&p.AliasType, &p.AliasValue, &p.AliasFingerprint, &p.AliasMasked, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func scanTemplate(row pgx.Row) (*model.PaymentTemplate, error) {
+	var t model.PaymentTemplate
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.PayeeID, &t.FromAccountID, &t.Amount, &t.Description, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
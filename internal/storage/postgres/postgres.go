@@ -3,22 +3,18 @@ package postgres
 import (
 	"BankingApp/internal/config"
 	"context"
-	"crypto/rand"
+	"errors"
 	"fmt"
-	"time"
+	"strings"
 
-	"github.com/ProtonMail/go-crypto/openpgp/packet"
-	"github.com/ProtonMail/gopenpgp/v3/crypto"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-const passPhrase = "pass_phrase"
+const uniqueViolation = "23505"
 
 type PostgresRepository struct {
-	config     *packet.Config
-	privateKey *crypto.Key
-	publicKey  *crypto.Key
-	pool       *pgxpool.Pool
+	pool *pgxpool.Pool
 }
 
 // NewPostgresUserRepository конструктор для pgxpool
@@ -32,29 +28,8 @@ func NewPostgresRepository(ctx context.Context, cfg *config.Config) (*PostgresRe
 	if err = pool.Ping(ctx); err != nil {
 		return nil, err
 	}
-	// Configure key settings
-	pgpConfig := &packet.Config{
-		Algorithm:     packet.PubKeyAlgoRSA,
-		RSABits:       4096,
-		DefaultCipher: packet.CipherAES256,
-		Rand:          rand.Reader,
-		Time:          func() time.Time { return time.Now().UTC() },
-	}
-
-	publicKey, err := crypto.NewKeyFromArmored(config.GetEncryptionPublicKey())
-	if err != nil {
-		return nil, err
-	}
-
-	privateKey, err := crypto.NewPrivateKeyFromArmored(config.GetEncryptionPrivateKey(), []byte(passPhrase))
-	if err != nil {
-		return nil, err
-	}
 	repo := &PostgresRepository{
-		pool:       pool,
-		config:     pgpConfig,
-		privateKey: privateKey,
-		publicKey:  publicKey,
+		pool: pool,
 	}
 
 	return repo, nil
@@ -63,3 +38,17 @@ func NewPostgresRepository(ctx context.Context, cfg *config.Config) (*PostgresRe
 func (p *PostgresRepository) Close() {
 	p.pool.Close()
 }
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// prefixColumns добавляет псевдоним таблицы к списку колонок для запросов с JOIN
+func prefixColumns(alias, columns string) string {
+	parts := strings.Split(columns, ",")
+	for i, col := range parts {
+		parts[i] = alias + "." + strings.TrimSpace(col)
+	}
+	return strings.Join(parts, ", ")
+}
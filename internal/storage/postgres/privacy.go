@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (p *PostgresRepository) EraseUser(ctx context.Context, userID string, erased model.ErasedUser, action *model.AdminAction) error {
+	return p.withAdminAction(ctx, action, func(tx pgx.Tx) error {
+		// блокируем счета, чтобы между проверкой остатка и закрытием не прошло зачисление
+		var nonZero bool
+		query := `
+			SELECT COALESCE(bool_or(balance <> 0 OR overdraft_interest <> 0 OR interest_accrued <> 0), FALSE)
+			FROM (SELECT balance, overdraft_interest, interest_accrued FROM accounts WHERE user_id = $1 FOR UPDATE) a
+		`
+		if err := tx.QueryRow(ctx, query, userID).Scan(&nonZero); err != nil {
+			return err
+		}
+		if nonZero {
+			return storage.ErrBalanceNotZero
+		}
+
+		result, err := tx.Exec(ctx, `
+			UPDATE users
+			SET email = $2, username = $3, password = '', full_name = '', status = $4, verified_at = NULL, verification_sent_at = NULL
+			WHERE uuid = $1 AND status <> $4
+		`, userID, erased.Email, erased.Username, model.UserErased)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return errors.New("user not found or already erased")
+		}
+
+		for _, query := range []string{
+			"UPDATE accounts SET is_active = FALSE, closed_at = COALESCE(closed_at, now()) WHERE user_id = $1",
+			"UPDATE cards SET is_active = FALSE, cardholder_name = '' WHERE account_id IN (SELECT id FROM accounts WHERE user_id = $1)",
+			"UPDATE card_tokens SET status = '" + model.TokenDeleted + "' WHERE card_id IN (SELECT c.id FROM cards c JOIN accounts a ON a.id = c.account_id WHERE a.user_id = $1)",
+			"DELETE FROM card_pins WHERE card_id IN (SELECT c.id FROM cards c JOIN accounts a ON a.id = c.account_id WHERE a.user_id = $1)",
+			"UPDATE sessions SET revoked_at = COALESCE(revoked_at, now()), user_agent = '', ip = '' WHERE user_id = $1",
+			"UPDATE api_clients SET status = '" + model.APIClientRevoked + "', revoked_at = COALESCE(revoked_at, now()) WHERE owner_id = $1",
+			"DELETE FROM aliases WHERE user_id = $1",
+			"DELETE FROM standing_orders WHERE user_id = $1",
+			"DELETE FROM payees WHERE user_id = $1",
+			"DELETE FROM user_profiles WHERE user_id = $1",
+			"DELETE FROM kyc_documents WHERE user_id = $1",
+			"DELETE FROM dispute_attachments WHERE dispute_id IN (SELECT id FROM disputes WHERE user_id = $1)",
+			"UPDATE disputes SET description = '', status = CASE WHEN status IN ('" + model.DisputeOpen + "', '" + model.DisputeInReview + "') " +
+				"THEN '" + model.DisputeWithdrawn + "' ELSE status END WHERE user_id = $1",
+			"DELETE FROM user_totp WHERE user_id = $1",
+			"DELETE FROM recovery_codes WHERE user_id = $1",
+			"DELETE FROM password_reset_tokens WHERE user_id = $1",
+			"DELETE FROM login_failures WHERE scope = '" + model.LockoutScopeAccount + "' AND key = $1",
+			"DELETE FROM login_failures WHERE scope = '" + model.LockoutScopeAliasLookup + "' AND key = $1",
+		} {
+			if _, err := tx.Exec(ctx, query, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,322 @@
+package postgres
+
+import (
+	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (p *PostgresRepository) OpenProductAccount(ctx context.Context, open *model.OpenProduct, limits model.Limits, now time.Time) (*model.Account, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("OpenProductAccount: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	product := open.Product
+	// средства вклада по окончании срока возвращаются на счет, с которого он открыт
+	var payoutID *int64
+	if product.Type == model.AccountTermDeposit {
+		payoutID = &open.FundingID
+	}
+	if open.Amount > 0 {
+		// счет списания блокируется до подсчета использованных лимитов, как и при переводе
+		if _, err := tx.Exec(ctx, "SELECT 1 FROM accounts WHERE id = $1 FOR UPDATE", open.FundingID); err != nil {
+			return nil, fmt.Errorf("OpenProductAccount lock: %w", err)
+		}
+		if err := checkLimits(ctx, tx, open.FundingID, model.LimitTransfer, open.Amount, limits, now); err != nil {
+			return nil, err
+		}
+		// вклад открывается только на собственные средства, без овердрафта
+		result, err := tx.Exec(ctx, `
+			UPDATE accounts SET balance = balance - $2
+			WHERE id = $1 AND user_id = $3 AND currency = $4 AND balance >= $2
+				AND is_active AND NOT is_frozen AND NOT owner_frozen
+		`, open.FundingID, open.Amount, open.UserID, product.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("OpenProductAccount debit: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return nil, storage.ErrInsufficientFunds
+		}
+	}
+	query := `
+		INSERT INTO accounts (user_id, number, currency, balance, type, product, interest_rate, term_days, matures_on,
+			early_penalty, rollover, payout_account_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING ` + accountColumns
+	acc, err := scanAccount(tx.QueryRow(ctx, query, open.UserID, open.Number, product.Currency, open.Amount, product.Type,
+		product.Code, product.Rate, product.TermDays, open.MaturesOn, product.EarlyPenalty, open.Rollover, payoutID))
+	if isUniqueViolation(err) {
+		return nil, storage.ErrAlreadyExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("OpenProductAccount: %w", err)
+	}
+	if open.Amount > 0 {
+		err = insertTransferLegs(ctx, tx, &model.Transfer{
+			FromAccountID:  open.FundingID,
+			ToAccountID:    acc.ID,
+			DebitAmount:    open.Amount,
+			DebitCurrency:  product.Currency,
+			CreditAmount:   open.Amount,
+			CreditCurrency: product.Currency,
+			Description:    "Открытие счета «" + product.Name + "»",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("OpenProductAccount transactions: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("OpenProductAccount: %w", err)
+	}
+	return acc, nil
+}
+
+func (p *PostgresRepository) SetProductRate(ctx context.Context, product string, rate float64) (int, error) {
+	result, err := p.pool.Exec(ctx, `
+		UPDATE accounts SET interest_rate = $2
+		WHERE product = $1 AND type = '`+model.AccountSavings+`' AND is_active AND interest_rate <> $2
+	`, product, rate)
+	if err != nil {
+		return 0, fmt.Errorf("SetProductRate: %w", err)
+	}
+	return int(result.RowsAffected()), nil
+}
+
+func (p *PostgresRepository) GetInterestAccrualStart(ctx context.Context, day time.Time) (*time.Time, error) {
+	var start *time.Time
+	err := p.pool.QueryRow(ctx, `
+		SELECT MIN(COALESCE(interest_accrued_on, created_at::date)) + 1
+		FROM accounts
+		WHERE product IS NOT NULL AND is_active AND COALESCE(interest_accrued_on, created_at::date) < $1
+			AND (matures_on IS NULL OR matures_on > COALESCE(interest_accrued_on, created_at::date) + 1)
+	`, day).Scan(&start)
+	if err != nil {
+		return nil, fmt.Errorf("GetInterestAccrualStart: %w", err)
+	}
+	return start, nil
+}
+
+func (p *PostgresRepository) AccrueInterest(ctx context.Context, day time.Time, daysInYear, limit int) (int, error) {
+	// счет, открытый в течение дня, получает проценты со следующего дня; по вкладу — до дня окончания срока.
+	// Отметка двигается и без процентов, чтобы отставшая отметка всегда означала пропущенные дни; день начисляется
+	// только сразу после отметки, и счет, пропущенный из-за SKIP LOCKED, не перескочит через день
+	result, err := p.pool.Exec(ctx, `
+		UPDATE accounts
+		SET interest_accrued = interest_accrued + GREATEST(balance, 0) * interest_rate / $2,
+			interest_since = CASE WHEN balance > 0 AND interest_rate > 0 THEN COALESCE(interest_since, $1) ELSE interest_since END,
+			interest_accrued_on = $1
+		WHERE id IN (
+			SELECT id FROM accounts
+			WHERE product IS NOT NULL AND is_active AND COALESCE(interest_accrued_on, created_at::date) = $1::date - 1
+				AND (matures_on IS NULL OR matures_on > $1)
+			ORDER BY id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+	`, day, daysInYear, limit)
+	if err != nil {
+		return 0, fmt.Errorf("AccrueInterest: %w", err)
+	}
+	return int(result.RowsAffected()), nil
+}
+
+func (p *PostgresRepository) GetSavingsInterestDue(ctx context.Context, periodStart time.Time, limit int) ([]*model.Account, error) {
+	query := `
+		SELECT ` + accountColumns + `
+		FROM accounts
+		WHERE interest_since < $1 AND type = '` + model.AccountSavings + `'
+		ORDER BY id
+		LIMIT $2
+	`
+	rows, err := p.pool.Query(ctx, query, periodStart, limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetSavingsInterestDue: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []*model.Account
+	for rows.Next() {
+		acc, err := scanAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("GetSavingsInterestDue scan: %w", err)
+		}
+		accounts = append(accounts, acc)
+	}
+	return accounts, rows.Err()
+}
+
+func (p *PostgresRepository) GetMaturedDeposits(ctx context.Context, day time.Time, limit int) ([]*model.Account, error) {
+	query := `
+		SELECT ` + accountColumns + `
+		FROM accounts
+		WHERE matures_on <= $1 AND is_active AND type = '` + model.AccountTermDeposit + `'
+		ORDER BY id
+		LIMIT $2
+	`
+	rows, err := p.pool.Query(ctx, query, day, limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetMaturedDeposits: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []*model.Account
+	for rows.Next() {
+		acc, err := scanAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("GetMaturedDeposits scan: %w", err)
+		}
+		accounts = append(accounts, acc)
+	}
+	return accounts, rows.Err()
+}
+
+func (p *PostgresRepository) CapitalizeInterest(ctx context.Context, accountID int64, periodStart time.Time, description string, now time.Time) (*model.Transaction, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("CapitalizeInterest: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		currency string
+		accrued  float64
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT currency, interest_accrued FROM accounts
+		WHERE id = $1 AND interest_since < $2
+		FOR UPDATE
+	`, accountID, periodStart).Scan(&currency, &accrued)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// проценты уже выплачены параллельным запуском
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("CapitalizeInterest: %w", err)
+	}
+	credited, err := creditInterest(ctx, tx, accountID, model.RoundAmount(accrued, currency), currency, description, now)
+	if err != nil {
+		return nil, fmt.Errorf("CapitalizeInterest: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("CapitalizeInterest: %w", err)
+	}
+	return credited, nil
+}
+
+func (p *PostgresRepository) SettleDeposit(ctx context.Context, settlement *model.DepositSettlement, now time.Time) (bool, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("SettleDeposit: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		currency string
+		balance  float64
+		accrued  float64
+		isActive bool
+		payoutID *int64
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT currency, balance, interest_accrued, is_active, payout_account_id FROM accounts
+		WHERE id = $1 AND type = '`+model.AccountTermDeposit+`' AND matures_on = $2
+		FOR UPDATE
+	`, settlement.AccountID, settlement.MaturesOn).Scan(&currency, &balance, &accrued, &isActive, &payoutID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// вклад уже продлен параллельным запуском
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("SettleDeposit: %w", err)
+	}
+	if !isActive {
+		return false, storage.ErrAccountClosed
+	}
+	if settlement.PayoutID != nil {
+		payoutID = settlement.PayoutID
+	}
+	// копейки, не дотянувшие до минимальной единицы валюты, не переносятся
+	interest := model.RoundAmount(accrued*(1-settlement.Penalty), currency)
+	if _, err := creditInterest(ctx, tx, settlement.AccountID, interest, currency, settlement.Description, now); err != nil {
+		return false, fmt.Errorf("SettleDeposit: %w", err)
+	}
+
+	if settlement.Rollover {
+		_, err = tx.Exec(ctx, "UPDATE accounts SET matures_on = $2, interest_rate = $3 WHERE id = $1",
+			settlement.AccountID, settlement.NextMaturity, settlement.NextRate)
+		if err != nil {
+			return false, fmt.Errorf("SettleDeposit: %w", err)
+		}
+		return true, tx.Commit(ctx)
+	}
+
+	amount := balance + interest
+	if amount > 0 {
+		if payoutID == nil {
+			return false, storage.ErrAccountClosed
+		}
+		result, err := tx.Exec(ctx, "UPDATE accounts SET balance = balance + $2 WHERE id = $1 AND is_active AND currency = $3",
+			*payoutID, amount, currency)
+		if err != nil {
+			return false, fmt.Errorf("SettleDeposit payout: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return false, storage.ErrAccountClosed
+		}
+		err = insertTransferLegs(ctx, tx, &model.Transfer{
+			FromAccountID:  settlement.AccountID,
+			ToAccountID:    *payoutID,
+			DebitAmount:    amount,
+			DebitCurrency:  currency,
+			CreditAmount:   amount,
+			CreditCurrency: currency,
+			Description:    "Возврат средств вклада",
+		})
+		if err != nil {
+			return false, fmt.Errorf("SettleDeposit transactions: %w", err)
+		}
+	}
+	_, err = tx.Exec(ctx, "UPDATE accounts SET balance = 0, is_active = FALSE, closed_at = $2 WHERE id = $1", settlement.AccountID, now)
+	if err != nil {
+		return false, fmt.Errorf("SettleDeposit: %w", err)
+	}
+	// карты и псевдонимы у вклада не выпускаются, закрывать вместе с ним нечего
+	return true, tx.Commit(ctx)
+}
+
+func (p *PostgresRepository) SetDepositRollover(ctx context.Context, accountID int64, rollover bool) error {
+	result, err := p.pool.Exec(ctx, `
+		UPDATE accounts SET rollover = $2
+		WHERE id = $1 AND is_active AND type = '`+model.AccountTermDeposit+`'
+	`, accountID, rollover)
+	if err != nil {
+		return fmt.Errorf("SetDepositRollover: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return storage.ErrAccountClosed
+	}
+	return nil
+}
+
+// creditInterest зачисляет проценты на счет и обнуляет начисленные; счет уже заблокирован вызывающим
+func creditInterest(ctx context.Context, tx pgx.Tx, accountID int64, amount float64, currency, description string, now time.Time) (*model.Transaction, error) {
+	_, err := tx.Exec(ctx, `
+		UPDATE accounts SET balance = balance + $2, interest_accrued = 0, interest_since = NULL
+		WHERE id = $1
+	`, accountID, amount)
+	if err != nil || amount <= 0 {
+		return nil, err
+	}
+	query := `
+		INSERT INTO transactions (account_id, amount, currency, type, status, description, created_at)
+		VALUES ($1, $2, $3, '` + model.TransactionInterest + `', '` + model.TransactionSuccess + `', $4, $5)
+		RETURNING ` + transactionColumns
+	return scanTransaction(tx.QueryRow(ctx, query, accountID, amount, currency, description, now))
+}
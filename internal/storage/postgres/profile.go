@@ -0,0 +1,220 @@
+package postgres
+
+import (
+	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	profileColumns = "user_id, encrypted_data, key_version, phone_fingerprint, passport_fingerprint, inn_fingerprint, " +
+		"kyc_status, COALESCE(kyc_comment, ''), kyc_submitted_at, kyc_reviewed_at, updated_at"
+	kycDocumentColumns = "id, user_id, doc_type, file_name, content_type, size, created_at"
+)
+
+func (p *PostgresRepository) GetProfile(ctx context.Context, userID string) (*model.Profile, error) {
+	query := "SELECT " + profileColumns + " FROM user_profiles WHERE user_id = $1"
+	profile, err := scanProfile(p.pool.QueryRow(ctx, query, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetProfile: %w", err)
+	}
+	return profile, nil
+}
+
+func (p *PostgresRepository) SaveProfile(ctx context.Context, profile *model.Profile) (bool, error) {
+	query := `
+		INSERT INTO user_profiles (user_id, encrypted_data, key_version, phone_fingerprint, passport_fingerprint, inn_fingerprint, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, now())
+		ON CONFLICT (user_id) DO UPDATE SET
+			encrypted_data = EXCLUDED.encrypted_data,
+			key_version = EXCLUDED.key_version,
+			phone_fingerprint = EXCLUDED.phone_fingerprint,
+			passport_fingerprint = EXCLUDED.passport_fingerprint,
+			inn_fingerprint = EXCLUDED.inn_fingerprint,
+			updated_at = now()
+		WHERE user_profiles.kyc_status <> $7
+		RETURNING updated_at
+	`
+	err := p.pool.QueryRow(ctx, query, profile.UserID, profile.EncryptedData, profile.KeyVersion, profile.PhoneFingerprint,
+		profile.PassportFingerprint, profile.INNFingerprint, model.KYCPending).Scan(&profile.UpdatedAt)
+	if isUniqueViolation(err) {
+		return false, storage.ErrAlreadyExists
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("SaveProfile: %w", err)
+	}
+	return true, nil
+}
+
+func (p *PostgresRepository) SubmitKYC(ctx context.Context, userID string, now time.Time) (bool, error) {
+	query := `
+		UPDATE user_profiles SET kyc_status = $2, kyc_submitted_at = $3, kyc_comment = NULL
+		WHERE user_id = $1 AND kyc_status IN ($4, $5)
+	`
+	result, err := p.pool.Exec(ctx, query, userID, model.KYCPending, now, model.KYCNotStarted, model.KYCRejected)
+	if err != nil {
+		return false, fmt.Errorf("SubmitKYC: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+func (p *PostgresRepository) AddKYCDocument(ctx context.Context, doc *model.KYCDocument) error {
+	query := `
+		INSERT INTO kyc_documents (user_id, doc_type, file_name, content_type, size, encrypted_content, key_version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	err := p.pool.QueryRow(ctx, query, doc.UserID, doc.Type, doc.FileName, doc.ContentType, doc.Size, doc.EncryptedContent, doc.KeyVersion).
+		Scan(&doc.ID, &doc.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("AddKYCDocument: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) GetKYCDocuments(ctx context.Context, userID string) ([]*model.KYCDocument, error) {
+	query := "SELECT " + kycDocumentColumns + " FROM kyc_documents WHERE user_id = $1 ORDER BY id"
+	rows, err := p.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("GetKYCDocuments: %w", err)
+	}
+	defer rows.Close()
+
+	docs := make([]*model.KYCDocument, 0)
+	for rows.Next() {
+		var d model.KYCDocument
+		if err := rows.Scan(&d.ID, &d.UserID, &d.Type, &d.FileName, &d.ContentType, &d.Size, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("GetKYCDocuments scan: %w", err)
+		}
+		docs = append(docs, &d)
+	}
+	return docs, rows.Err()
+}
+
+func (p *PostgresRepository) GetKYCDocument(ctx context.Context, docID int64) (*model.KYCDocument, error) {
+	query := "SELECT " + kycDocumentColumns + ", encrypted_content, key_version FROM kyc_documents WHERE id = $1"
+	docs, err := p.queryKYCDocuments(ctx, query, docID)
+	if err != nil {
+		return nil, fmt.Errorf("GetKYCDocument: %w", err)
+	}
+	if len(docs) == 0 {
+		return nil, nil
+	}
+	return docs[0], nil
+}
+
+func (p *PostgresRepository) GetProfilesWithStaleKey(ctx context.Context, activeVersion int, afterUserID string, limit int) ([]*model.Profile, error) {
+	query := "SELECT " + profileColumns + " FROM user_profiles WHERE key_version <> $1 AND user_id > $2 ORDER BY user_id LIMIT $3"
+	rows, err := p.pool.Query(ctx, query, activeVersion, afterUserID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetProfilesWithStaleKey: %w", err)
+	}
+	defer rows.Close()
+
+	var profiles []*model.Profile
+	for rows.Next() {
+		profile, err := scanProfile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("GetProfilesWithStaleKey scan: %w", err)
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, rows.Err()
+}
+
+func (p *PostgresRepository) UpdateProfileEncryption(ctx context.Context, profile *model.Profile) error {
+	query := "UPDATE user_profiles SET encrypted_data = $2, key_version = $3 WHERE user_id = $1"
+	result, err := p.pool.Exec(ctx, query, profile.UserID, profile.EncryptedData, profile.KeyVersion)
+	if err != nil {
+		return fmt.Errorf("UpdateProfileEncryption: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("profile not found")
+	}
+	return nil
+}
+
+func (p *PostgresRepository) GetKYCDocumentsWithStaleKey(ctx context.Context, activeVersion int, afterID int64, limit int) ([]*model.KYCDocument, error) {
+	query := `
+		SELECT ` + kycDocumentColumns + `, encrypted_content, key_version
+		FROM kyc_documents
+		WHERE key_version <> $1 AND id > $2
+		ORDER BY id
+		LIMIT $3
+	`
+	docs, err := p.queryKYCDocuments(ctx, query, activeVersion, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetKYCDocumentsWithStaleKey: %w", err)
+	}
+	return docs, nil
+}
+
+func (p *PostgresRepository) UpdateKYCDocumentEncryption(ctx context.Context, doc *model.KYCDocument) error {
+	query := "UPDATE kyc_documents SET encrypted_content = $2, key_version = $3 WHERE id = $1"
+	result, err := p.pool.Exec(ctx, query, doc.ID, doc.EncryptedContent, doc.KeyVersion)
+	if err != nil {
+		return fmt.Errorf("UpdateKYCDocumentEncryption: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("document not found")
+	}
+	return nil
+}
+
+func (p *PostgresRepository) SetKYCStatus(ctx context.Context, userID, status, comment string, action *model.AdminAction) error {
+	return p.withAdminAction(ctx, action, func(tx pgx.Tx) error {
+		query := `
+			UPDATE user_profiles SET kyc_status = $2, kyc_comment = NULLIF($3, ''), kyc_reviewed_at = now()
+			WHERE user_id = $1 AND kyc_status = $4
+		`
+		result, err := tx.Exec(ctx, query, userID, status, comment, model.KYCPending)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return errors.New("profile is not pending review")
+		}
+		return nil
+	})
+}
+
+// queryKYCDocuments читает документы вместе с зашифрованным содержимым
+func (p *PostgresRepository) queryKYCDocuments(ctx context.Context, query string, args ...any) ([]*model.KYCDocument, error) {
+	rows, err := p.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var docs []*model.KYCDocument
+	for rows.Next() {
+		var d model.KYCDocument
+		err := rows.Scan(&d.ID, &d.UserID, &d.Type, &d.FileName, &d.ContentType, &d.Size, &d.CreatedAt, &d.EncryptedContent, &d.KeyVersion)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, &d)
+	}
+	return docs, rows.Err()
+}
+
+func scanProfile(row pgx.Row) (*model.Profile, error) {
+	var p model.Profile
+	err := row.Scan(&p.UserID, &p.EncryptedData, &p.KeyVersion, &p.PhoneFingerprint, &p.PassportFingerprint, &p.INNFingerprint,
+		&p.KYCStatus, &p.KYCComment, &p.KYCSubmittedAt, &p.KYCReviewedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
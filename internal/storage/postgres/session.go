@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"BankingApp/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (p *PostgresRepository) CreateSession(ctx context.Context, session *model.Session) error {
+	query := `
+		INSERT INTO sessions (id, user_id, user_agent, ip, created_at, last_used_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := p.pool.Exec(ctx, query, session.ID, session.UserID, session.UserAgent, session.IP, session.CreatedAt, session.LastUsedAt)
+	if err != nil {
+		return fmt.Errorf("CreateSession: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) GetSessionByID(ctx context.Context, sessionID string) (*model.Session, error) {
+	query := `
+		SELECT id, user_id, user_agent, ip, created_at, last_used_at, revoked_at
+		FROM sessions
+		WHERE id = $1
+	`
+	var s model.Session
+	err := p.pool.QueryRow(ctx, query, sessionID).Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastUsedAt, &s.RevokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetSessionByID: %w", err)
+	}
+	return &s, nil
+}
+
+func (p *PostgresRepository) GetSessionsByUser(ctx context.Context, userID string) ([]*model.Session, error) {
+	query := `
+		SELECT id, user_id, user_agent, ip, created_at, last_used_at, revoked_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY last_used_at DESC
+	`
+	rows, err := p.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("GetSessionsByUser: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]*model.Session, 0)
+	for rows.Next() {
+		var s model.Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastUsedAt, &s.RevokedAt); err != nil {
+			return nil, fmt.Errorf("GetSessionsByUser scan: %w", err)
+		}
+		sessions = append(sessions, &s)
+	}
+	return sessions, rows.Err()
+}
+
+func (p *PostgresRepository) AddRefreshToken(ctx context.Context, sessionID string, tokenHash []byte, expiresAt time.Time) error {
+	query := "INSERT INTO refresh_tokens (token_hash, session_id, expires_at) VALUES ($1, $2, $3)"
+	if _, err := p.pool.Exec(ctx, query, tokenHash, sessionID, expiresAt); err != nil {
+		return fmt.Errorf("AddRefreshToken: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) UseRefreshToken(ctx context.Context, tokenHash []byte) (*model.RefreshToken, error) {
+	query := `
+		WITH prev AS (
+			SELECT token_hash, session_id, expires_at, used_at
+			FROM refresh_tokens
+			WHERE token_hash = $1
+			FOR UPDATE
+		)
+		UPDATE refresh_tokens r
+		SET used_at = COALESCE(r.used_at, now())
+		FROM prev
+		WHERE r.token_hash = prev.token_hash
+		RETURNING prev.session_id, prev.expires_at, prev.used_at
+	`
+	var token model.RefreshToken
+	err := p.pool.QueryRow(ctx, query, tokenHash).Scan(&token.SessionID, &token.ExpiresAt, &token.UsedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("UseRefreshToken: %w", err)
+	}
+	return &token, nil
+}
+
+func (p *PostgresRepository) TouchSession(ctx context.Context, sessionID string, at time.Time) error {
+	query := "UPDATE sessions SET last_used_at = $1 WHERE id = $2"
+	if _, err := p.pool.Exec(ctx, query, at, sessionID); err != nil {
+		return fmt.Errorf("TouchSession: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) RevokeSession(ctx context.Context, sessionID, userID string) error {
+	query := `
+		UPDATE sessions SET revoked_at = now()
+		WHERE id = $1 AND ($2 = '' OR user_id = $2) AND revoked_at IS NULL
+	`
+	result, err := p.pool.Exec(ctx, query, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("RevokeSession: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("session not found")
+	}
+	return nil
+}
+
+func (p *PostgresRepository) RevokeUserSessions(ctx context.Context, userID, exceptSessionID string) error {
+	query := `
+		UPDATE sessions SET revoked_at = now()
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+	`
+	if _, err := p.pool.Exec(ctx, query, userID, exceptSessionID); err != nil {
+		return fmt.Errorf("RevokeUserSessions: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	query := "INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING"
+	if _, err := p.pool.Exec(ctx, query, jti, expiresAt); err != nil {
+		return fmt.Errorf("RevokeToken: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) IsTokenRevoked(ctx context.Context, jti, sessionID string) (bool, error) {
+	query := `
+		SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)
+			OR EXISTS (SELECT 1 FROM sessions WHERE id = $2 AND revoked_at IS NOT NULL)
+	`
+	var revoked bool
+	if err := p.pool.QueryRow(ctx, query, jti, sessionID).Scan(&revoked); err != nil {
+		return false, fmt.Errorf("IsTokenRevoked: %w", err)
+	}
+	return revoked, nil
+}
+
+func (p *PostgresRepository) PurgeExpiredTokens(ctx context.Context, now time.Time) (int64, error) {
+	var total int64
+	for _, query := range []string{
+		"DELETE FROM revoked_tokens WHERE expires_at < $1",
+		"DELETE FROM refresh_tokens WHERE expires_at < $1",
+		"DELETE FROM password_reset_tokens WHERE expires_at < $1",
+		"DELETE FROM mfa_challenges WHERE expires_at < $1",
+		"DELETE FROM login_failures WHERE last_failure_at < $1 - interval '30 days' AND (locked_until IS NULL OR locked_until < $1)",
+	} {
+		result, err := p.pool.Exec(ctx, query, now)
+		if err != nil {
+			return total, fmt.Errorf("PurgeExpiredTokens: %w", err)
+		}
+		total += result.RowsAffected()
+	}
+	return total, nil
+}
@@ -0,0 +1,161 @@
+package postgres
+
+import (
+	"BankingApp/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const standingOrderColumns = "id, user_id, from_account_id, payee_id, amount, COALESCE(description, ''), frequency, " +
+	"COALESCE(day_of_month, 0), weekday, business_day, start_date, end_date, COALESCE(max_occurrences, 0), occurrences, status, " +
+	"scheduled_for, next_run_at, retry_count, locked_until, created_at, updated_at"
+
+func (p *PostgresRepository) CreateStandingOrder(ctx context.Context, order *model.StandingOrder) (*model.StandingOrder, error) {
+	query := `
+		INSERT INTO standing_orders (user_id, from_account_id, payee_id, amount, description, frequency, day_of_month, weekday,
+			business_day, start_date, end_date, max_occurrences, status, scheduled_for, next_run_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, NULLIF($7, 0), $8, $9, $10, $11, NULLIF($12, 0), $13, $14, $15)
+		RETURNING ` + standingOrderColumns
+	created, err := scanStandingOrder(p.pool.QueryRow(ctx, query, order.UserID, order.FromAccountID, order.PayeeID, order.Amount,
+		order.Description, order.Frequency, order.DayOfMonth, order.Weekday, order.BusinessDay, order.StartDate, order.EndDate,
+		order.MaxOccurrences, order.Status, order.ScheduledFor, order.NextRunAt))
+	if err != nil {
+		return nil, fmt.Errorf("CreateStandingOrder: %w", err)
+	}
+	return created, nil
+}
+
+func (p *PostgresRepository) GetStandingOrders(ctx context.Context, userID string) ([]*model.StandingOrder, error) {
+	query := "SELECT " + standingOrderColumns + " FROM standing_orders WHERE user_id = $1 ORDER BY id"
+	return p.queryStandingOrders(ctx, "GetStandingOrders", query, userID)
+}
+
+func (p *PostgresRepository) GetStandingOrder(ctx context.Context, userID string, orderID int64) (*model.StandingOrder, error) {
+	query := "SELECT " + standingOrderColumns + " FROM standing_orders WHERE id = $1 AND user_id = $2"
+	order, err := scanStandingOrder(p.pool.QueryRow(ctx, query, orderID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetStandingOrder: %w", err)
+	}
+	return order, nil
+}
+
+func (p *PostgresRepository) UpdateStandingOrderSchedule(ctx context.Context, order *model.StandingOrder, fromStatus string) (bool, error) {
+	query := `
+		UPDATE standing_orders
+		SET status = $4, scheduled_for = $5, next_run_at = $6, retry_count = $7, updated_at = $8
+		WHERE id = $1 AND user_id = $2 AND status = $3
+	`
+	result, err := p.pool.Exec(ctx, query, order.ID, order.UserID, fromStatus, order.Status, order.ScheduledFor,
+		order.NextRunAt, order.RetryCount, order.UpdatedAt)
+	if err != nil {
+		return false, fmt.Errorf("UpdateStandingOrderSchedule: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+func (p *PostgresRepository) ClaimDueStandingOrders(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.StandingOrder, error) {
+	query := `
+		UPDATE standing_orders SET locked_until = $2
+		WHERE id IN (
+			SELECT id FROM standing_orders
+			WHERE status = '` + model.StandingOrderActive + `' AND next_run_at <= $1 AND (locked_until IS NULL OR locked_until < $1)
+			ORDER BY next_run_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + standingOrderColumns
+	return p.queryStandingOrders(ctx, "ClaimDueStandingOrders", query, now, now.Add(lease), limit)
+}
+
+func (p *PostgresRepository) RecordStandingOrderExecution(ctx context.Context, order *model.StandingOrder, execution *model.StandingOrderExecution) (bool, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("RecordStandingOrderExecution: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// расписание переносится, только пока резерв принадлежит этому исполнителю
+	result, err := tx.Exec(ctx, `
+		UPDATE standing_orders
+		SET occurrences = $2, retry_count = $3, scheduled_for = $4, next_run_at = $5, updated_at = $6, locked_until = NULL,
+			status = CASE WHEN status = '`+model.StandingOrderActive+`' THEN $7 ELSE status END
+		WHERE id = $1 AND locked_until = $8
+	`, order.ID, order.Occurrences, order.RetryCount, order.ScheduledFor, order.NextRunAt, order.UpdatedAt, order.Status, order.LockedUntil)
+	if err != nil {
+		return false, fmt.Errorf("RecordStandingOrderExecution update: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO standing_order_executions (order_id, scheduled_for, attempt, status, amount, error, executed_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+	`, execution.OrderID, execution.ScheduledFor, execution.Attempt, execution.Status, execution.Amount, execution.Error, execution.ExecutedAt)
+	if err != nil {
+		return false, fmt.Errorf("RecordStandingOrderExecution insert: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("RecordStandingOrderExecution commit: %w", err)
+	}
+	return true, nil
+}
+
+func (p *PostgresRepository) GetStandingOrderExecutions(ctx context.Context, orderID int64) ([]*model.StandingOrderExecution, error) {
+	query := `
+		SELECT id, order_id, scheduled_for, attempt, status, amount, COALESCE(error, ''), executed_at
+		FROM standing_order_executions
+		WHERE order_id = $1
+		ORDER BY id
+	`
+	rows, err := p.pool.Query(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("GetStandingOrderExecutions: %w", err)
+	}
+	defer rows.Close()
+
+	executions := make([]*model.StandingOrderExecution, 0)
+	for rows.Next() {
+		var e model.StandingOrderExecution
+		if err := rows.Scan(&e.ID, &e.OrderID, &e.ScheduledFor, &e.Attempt, &e.Status, &e.Amount, &e.Error, &e.ExecutedAt); err != nil {
+			return nil, fmt.Errorf("GetStandingOrderExecutions scan: %w", err)
+		}
+		executions = append(executions, &e)
+	}
+	return executions, rows.Err()
+}
+
+func (p *PostgresRepository) queryStandingOrders(ctx context.Context, op, query string, args ...any) ([]*model.StandingOrder, error) {
+	rows, err := p.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	orders := make([]*model.StandingOrder, 0)
+	for rows.Next() {
+		order, err := scanStandingOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s scan: %w", op, err)
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+func scanStandingOrder(row pgx.Row) (*model.StandingOrder, error) {
+	var o model.StandingOrder
+	err := row.Scan(&o.ID, &o.UserID, &o.FromAccountID, &o.PayeeID, &o.Amount, &o.Description, &o.Frequency, &o.DayOfMonth,
+		&o.Weekday, &o.BusinessDay, &o.StartDate, &o.EndDate, &o.MaxOccurrences, &o.Occurrences, &o.Status,
+		&o.ScheduledFor, &o.NextRunAt, &o.RetryCount, &o.LockedUntil, &o.CreatedAt, &o.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"BankingApp/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *PostgresRepository) GetTOTP(ctx context.Context, userID string) (*model.TOTP, error) {
+	query := "SELECT user_id, secret_encrypted, enabled, last_counter, enrolled_at FROM user_totp WHERE user_id = $1"
+	var t model.TOTP
+	err := r.pool.QueryRow(ctx, query, userID).Scan(&t.UserID, &t.EncryptedSecret, &t.Enabled, &t.LastCounter, &t.EnrolledAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetTOTP: %w", err)
+	}
+	return &t, nil
+}
+
+func (r *PostgresRepository) SetTOTPSecret(ctx context.Context, userID string, encryptedSecret []byte) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret_encrypted)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+			SET secret_encrypted = EXCLUDED.secret_encrypted, enabled = FALSE, last_counter = 0, enrolled_at = NULL
+	`
+	if _, err := r.pool.Exec(ctx, query, userID, encryptedSecret); err != nil {
+		return fmt.Errorf("SetTOTPSecret: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) EnableTOTP(ctx context.Context, userID string, counter int64, recoveryCodeHashes [][]byte) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("EnableTOTP: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := "UPDATE user_totp SET enabled = TRUE, last_counter = $2, enrolled_at = now() WHERE user_id = $1"
+	if _, err := tx.Exec(ctx, query, userID, counter); err != nil {
+		return fmt.Errorf("EnableTOTP: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("EnableTOTP delete codes: %w", err)
+	}
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.Exec(ctx, "INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, hash); err != nil {
+			return fmt.Errorf("EnableTOTP insert code: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *PostgresRepository) DisableTOTP(ctx context.Context, userID string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("DisableTOTP: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM user_totp WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("DisableTOTP: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("DisableTOTP delete codes: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *PostgresRepository) AdvanceTOTPCounter(ctx context.Context, userID string, counter int64) (bool, error) {
+	query := "UPDATE user_totp SET last_counter = $2 WHERE user_id = $1 AND last_counter < $2"
+	result, err := r.pool.Exec(ctx, query, userID, counter)
+	if err != nil {
+		return false, fmt.Errorf("AdvanceTOTPCounter: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *PostgresRepository) UseRecoveryCode(ctx context.Context, userID string, codeHash []byte) (bool, error) {
+	query := "UPDATE recovery_codes SET used_at = now() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL"
+	result, err := r.pool.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("UseRecoveryCode: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *PostgresRepository) CreateMFAChallenge(ctx context.Context, jti, userID string, expiresAt time.Time) error {
+	query := "INSERT INTO mfa_challenges (jti, user_id, expires_at) VALUES ($1, $2, $3)"
+	if _, err := r.pool.Exec(ctx, query, jti, userID, expiresAt); err != nil {
+		return fmt.Errorf("CreateMFAChallenge: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) UseMFAChallenge(ctx context.Context, jti, userID string, maxAttempts int, now time.Time) (bool, error) {
+	query := `
+		UPDATE mfa_challenges SET attempts = attempts + 1
+		WHERE jti = $1 AND user_id = $2 AND attempts < $3 AND expires_at > $4
+	`
+	result, err := r.pool.Exec(ctx, query, jti, userID, maxAttempts, now)
+	if err != nil {
+		return false, fmt.Errorf("UseMFAChallenge: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *PostgresRepository) DeleteMFAChallenge(ctx context.Context, jti string) (bool, error) {
+	result, err := r.pool.Exec(ctx, "DELETE FROM mfa_challenges WHERE jti = $1", jti)
+	if err != nil {
+		return false, fmt.Errorf("DeleteMFAChallenge: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
@@ -2,19 +2,27 @@ package postgres
 
 import (
 	"BankingApp/internal/model"
+	"BankingApp/internal/storage"
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 )
 
+const userColumns = "uuid, email, username, password, full_name, status, role, verified_at, created_at"
+
 func (r *PostgresRepository) CreateUser(ctx context.Context, user *model.User) error {
 	query := `
-		INSERT INTO users (uuid, email, password, full_name, created_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO users (uuid, email, username, password, full_name, status, role, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 
 	`
-	_, err := r.pool.Exec(ctx, query, user.UUID, user.Email, user.PasswordHash, user.FullName, user.CreatedAt)
+	_, err := r.pool.Exec(ctx, query, user.UUID, user.Email, user.Username, user.PasswordHash, user.FullName, user.Status, user.Role, user.CreatedAt)
+	if isUniqueViolation(err) {
+		return storage.ErrAlreadyExists
+	}
 	if err != nil {
 		return err
 	}
@@ -23,51 +31,63 @@ func (r *PostgresRepository) CreateUser(ctx context.Context, user *model.User) e
 
 func (r *PostgresRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
 	query := `
-		SELECT uuid, email, password, full_name, created_at
+		SELECT ` + userColumns + `
 		FROM users
 		WHERE email = $1
 		LIMIT 1;
 	`
-	row := r.pool.QueryRow(ctx, query, email)
-	user := &model.User{}
-	err := row.Scan(&user.UUID, &user.Email, &user.PasswordHash, &user.FullName, &user.CreatedAt)
-	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, err
-	}
-	return user, nil
+	return r.findUser(ctx, query, email)
 }
 
 func (r *PostgresRepository) FindByUsername(ctx context.Context, username string) (*model.User, error) {
 	query := `
-		SELECT uuid, email, password, full_name, created_at
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE full_name = $1
+		WHERE username = $1
 		LIMIT 1;
 	`
-	row := r.pool.QueryRow(ctx, query, username)
-	user := &model.User{}
-	err := row.Scan(&user.UUID, &user.Email, &user.PasswordHash, &user.FullName, &user.CreatedAt)
-	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, err
-	}
-	return user, nil
+	return r.findUser(ctx, query, username)
 }
 
 func (r *PostgresRepository) FindByID(ctx context.Context, userID string) (*model.User, error) {
 	query := `
-		SELECT uuid, email, password, full_name, created_at
+		SELECT ` + userColumns + `
 		FROM users
 		WHERE uuid = $1
 	`
-	row := r.pool.QueryRow(ctx, query, userID)
+	return r.findUser(ctx, query, userID)
+}
+
+func (r *PostgresRepository) MarkEmailVerified(ctx context.Context, userID, email string, at time.Time) (bool, error) {
+	query := `
+		UPDATE users SET status = $4, verified_at = $3
+		WHERE uuid = $1 AND email = $2 AND status = $5
+	`
+	result, err := r.pool.Exec(ctx, query, userID, email, at, model.UserActive, model.UserPendingVerification)
+	if err != nil {
+		return false, fmt.Errorf("MarkEmailVerified: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *PostgresRepository) MarkVerificationSent(ctx context.Context, userID string, now time.Time, minInterval time.Duration) (bool, error) {
+	query := `
+		UPDATE users SET verification_sent_at = $2
+		WHERE uuid = $1 AND status = $4
+			AND (verification_sent_at IS NULL OR verification_sent_at <= $2 - $3 * interval '1 second')
+	`
+	result, err := r.pool.Exec(ctx, query, userID, now, minInterval.Seconds(), model.UserPendingVerification)
+	if err != nil {
+		return false, fmt.Errorf("MarkVerificationSent: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// findUser возвращает nil без ошибки, если пользователь не найден
+func (r *PostgresRepository) findUser(ctx context.Context, query string, args ...any) (*model.User, error) {
+	row := r.pool.QueryRow(ctx, query, args...)
 	user := &model.User{}
-	err := row.Scan(&user.UUID, &user.Email, &user.PasswordHash, &user.FullName, &user.CreatedAt)
+	err := row.Scan(&user.UUID, &user.Email, &user.Username, &user.PasswordHash, &user.FullName, &user.Status, &user.Role, &user.VerifiedAt, &user.CreatedAt)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
 	}
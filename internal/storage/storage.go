@@ -3,34 +3,396 @@ package storage
 import (
 	"BankingApp/internal/model"
 	"context"
+	"errors"
+	"time"
 )
 
+var (
+	// ErrAlreadyExists — нарушено ограничение уникальности
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrBalanceNotZero — операция требует нулевого остатка на счете
+	ErrBalanceNotZero = errors.New("account balance is not zero")
+	// ErrInsufficientFunds — на счете недостаточно средств или списания с него запрещены
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrAccountClosed — счет закрыт
+	ErrAccountClosed = errors.New("account is closed")
+	// ErrQuoteUnavailable — котировка уже использована или истекла
+	ErrQuoteUnavailable = errors.New("fx quote is used or expired")
+	// ErrReversalExceeded — сумма сторно больше несторнированного остатка операции
+	ErrReversalExceeded = errors.New("reversal exceeds the unreversed amount")
+	// ErrDisputeClosed — обращение уже рассмотрено или отозвано
+	ErrDisputeClosed = errors.New("dispute is closed")
+	// ErrTokenDeleted — токен карты удален, его статус больше не меняется
+	ErrTokenDeleted = errors.New("card token is deleted")
+)
+
+// LimitError — списание нарушит лимит счета
+type LimitError struct {
+	// Code — какой лимит нарушен, одна из констант model.Limit*Exceeded
+	Code string
+}
+
+func (e *LimitError) Error() string {
+	return "limit exceeded: " + e.Code
+}
+
 // UserRepository — интерфейс взаимодействия с таблицей пользователей
 type UserStorage interface {
 	CreateUser(ctx context.Context, user *model.User) error
 	FindByEmail(ctx context.Context, email string) (*model.User, error)
 	FindByUsername(ctx context.Context, username string) (*model.User, error)
 	FindByID(ctx context.Context, userID string) (*model.User, error)
+	// GetTOTP возвращает второй фактор пользователя, nil — не подключался
+	GetTOTP(ctx context.Context, userID string) (*model.TOTP, error)
+	// SetTOTPSecret сохраняет новый секрет в неподтвержденном состоянии
+	SetTOTPSecret(ctx context.Context, userID string, encryptedSecret []byte) error
+	// EnableTOTP включает второй фактор и заменяет коды восстановления
+	EnableTOTP(ctx context.Context, userID string, counter int64, recoveryCodeHashes [][]byte) error
+	DisableTOTP(ctx context.Context, userID string) error
+	// AdvanceTOTPCounter принимает интервал, только если он новее последнего принятого
+	AdvanceTOTPCounter(ctx context.Context, userID string, counter int64) (bool, error)
+	// UseRecoveryCode погашает неиспользованный код восстановления
+	UseRecoveryCode(ctx context.Context, userID string, codeHash []byte) (bool, error)
+	CreateMFAChallenge(ctx context.Context, jti, userID string, expiresAt time.Time) error
+	// UseMFAChallenge атомарно учитывает попытку ввода второго фактора по частичному токену;
+	// false — токен уже использован, отозван после maxAttempts попыток или истек
+	UseMFAChallenge(ctx context.Context, jti, userID string, maxAttempts int, now time.Time) (bool, error)
+	// DeleteMFAChallenge погашает частичный токен после успешного входа, false — он уже погашен
+	DeleteMFAChallenge(ctx context.Context, jti string) (bool, error)
+	UpdatePassword(ctx context.Context, userID, passwordHash string) error
+	// MarkEmailVerified активирует пользователя, если его email не менялся с момента отправки ссылки
+	MarkEmailVerified(ctx context.Context, userID, email string, at time.Time) (bool, error)
+	// MarkVerificationSent фиксирует отправку письма, false — предыдущее отправлено менее minInterval назад
+	// или пользователь уже подтвержден
+	MarkVerificationSent(ctx context.Context, userID string, now time.Time, minInterval time.Duration) (bool, error)
+	// CreatePasswordResetToken сохраняет токен сброса, прежние неиспользованные токены пользователя удаляются
+	CreatePasswordResetToken(ctx context.Context, userID string, tokenHash []byte, expiresAt time.Time) error
+	// GetPasswordResetToken возвращает токен сброса, nil — токен не найден
+	GetPasswordResetToken(ctx context.Context, tokenHash []byte) (*model.PasswordResetToken, error)
+	// UsePasswordResetToken атомарно погашает действующий токен, false — токен уже использован или истек
+	UsePasswordResetToken(ctx context.Context, tokenHash []byte, now time.Time) (bool, error)
+}
+
+// LockoutStorage — счетчики неудачных попыток входа
+type LockoutStorage interface {
+	// GetLockout возвращает счетчик, nil — неудачных попыток не было
+	GetLockout(ctx context.Context, scope, key string) (*model.LoginLockout, error)
+	// RegisterLoginFailure атомарно увеличивает счетчик; если последняя неудача старше window, счет начинается заново
+	RegisterLoginFailure(ctx context.Context, scope, key string, now time.Time, window time.Duration) (*model.LoginLockout, error)
+	LockLogin(ctx context.Context, scope, key string, until time.Time) error
+	ResetLoginFailures(ctx context.Context, scope, key string) error
+}
+
+// SessionStorage — сессии пользователей, refresh-токены и список отозванных access-токенов
+type SessionStorage interface {
+	CreateSession(ctx context.Context, session *model.Session) error
+	// GetSessionByID возвращает сессию, nil — сессия не найдена
+	GetSessionByID(ctx context.Context, sessionID string) (*model.Session, error)
+	GetSessionsByUser(ctx context.Context, userID string) ([]*model.Session, error)
+	AddRefreshToken(ctx context.Context, sessionID string, tokenHash []byte, expiresAt time.Time) error
+	// UseRefreshToken атомарно помечает токен использованным и возвращает его прежнее состояние,
+	// nil — токен не найден
+	UseRefreshToken(ctx context.Context, tokenHash []byte) (*model.RefreshToken, error)
+	TouchSession(ctx context.Context, sessionID string, at time.Time) error
+	// RevokeSession отзывает сессию; userID пустой — без проверки владельца
+	RevokeSession(ctx context.Context, sessionID, userID string) error
+	// RevokeUserSessions отзывает все сессии пользователя, кроме exceptSessionID
+	RevokeUserSessions(ctx context.Context, userID, exceptSessionID string) error
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	// IsTokenRevoked — отозван ли access-токен сам по себе или вместе с его сессией
+	IsTokenRevoked(ctx context.Context, jti, sessionID string) (bool, error)
+	PurgeExpiredTokens(ctx context.Context, now time.Time) (int64, error)
+}
+
+// ProfileStorage — анкеты клиентов и документы для проверки личности
+type ProfileStorage interface {
+	// GetProfile возвращает анкету, nil — клиент ее еще не заполнял
+	GetProfile(ctx context.Context, userID string) (*model.Profile, error)
+	// SaveProfile создает или обновляет персональные данные; false — анкета на проверке и не может меняться.
+	// ErrAlreadyExists — телефон, паспорт или ИНН уже указаны другим клиентом.
+	SaveProfile(ctx context.Context, profile *model.Profile) (bool, error)
+	// SubmitKYC переводит анкету на проверку из статусов not_started и rejected
+	SubmitKYC(ctx context.Context, userID string, now time.Time) (bool, error)
+	AddKYCDocument(ctx context.Context, doc *model.KYCDocument) error
+	// GetKYCDocuments возвращает документы клиента без содержимого
+	GetKYCDocuments(ctx context.Context, userID string) ([]*model.KYCDocument, error)
+	// GetKYCDocument возвращает документ с зашифрованным содержимым, nil — не найден
+	GetKYCDocument(ctx context.Context, docID int64) (*model.KYCDocument, error)
+	// для фонового перешифрования при ротации ключей: анкеты с user_id больше afterUserID
+	// и документы с ID больше afterID, зашифрованные не версией activeVersion
+	GetProfilesWithStaleKey(ctx context.Context, activeVersion int, afterUserID string, limit int) ([]*model.Profile, error)
+	UpdateProfileEncryption(ctx context.Context, profile *model.Profile) error
+	GetKYCDocumentsWithStaleKey(ctx context.Context, activeVersion int, afterID int64, limit int) ([]*model.KYCDocument, error)
+	UpdateKYCDocumentEncryption(ctx context.Context, doc *model.KYCDocument) error
+}
+
+// PrivacyStorage — удаление учетной записи по запросу клиента
+type PrivacyStorage interface {
+	// EraseUser в одной транзакции закрывает счета и карты, отзывает сессии и клиентов API,
+	// удаляет анкету и второй фактор и обезличивает пользователя. Финансовые записи сохраняются.
+	// ErrBalanceNotZero — на одном из счетов есть остаток.
+	EraseUser(ctx context.Context, userID string, erased model.ErasedUser, action *model.AdminAction) error
+}
+
+// APIClientStorage — машинные клиенты и их секреты
+type APIClientStorage interface {
+	CreateAPIClient(ctx context.Context, client *model.APIClient, secretHash []byte) error
+	GetAPIClientsByOwner(ctx context.Context, ownerID string) ([]*model.APIClient, error)
+	// GetAPIClient возвращает клиента, nil — клиент не найден
+	GetAPIClient(ctx context.Context, clientID string) (*model.APIClient, error)
+	// FindAPIClientBySecret возвращает активного клиента, если секрет принадлежит ему и не истек, иначе nil
+	FindAPIClientBySecret(ctx context.Context, clientID string, secretHash []byte, now time.Time) (*model.APIClient, error)
+	// AddAPIClientSecret добавляет секрет, а действующим секретам клиента назначает срок expireOthersAt
+	AddAPIClientSecret(ctx context.Context, clientID string, secretHash []byte, expireOthersAt time.Time) error
+	RevokeAPIClient(ctx context.Context, clientID, ownerID string) error
 }
 
 type BankingStorage interface {
 	BeginTransaction(ctx context.Context) (Transaction, error)
-	CreateAccount(ctx context.Context, userID string, currency string) (*model.Account, error)
+	// CreateAccount открывает счет, ErrAlreadyExists — номер уже занят
+	CreateAccount(ctx context.Context, userID, currency, number string) (*model.Account, error)
 	GetAccountByID(ctx context.Context, accountID int64) (*model.Account, error)
+	// GetAccountByNumber возвращает счет по 20-значному номеру, nil — счет не найден
+	GetAccountByNumber(ctx context.Context, number string) (*model.Account, error)
+	// GetAccountsWithoutNumber — счета, открытые до появления номеров
+	GetAccountsWithoutNumber(ctx context.Context, limit int) ([]*model.Account, error)
+	// SetAccountNumber выдает номер счету без номера, ErrAlreadyExists — номер уже занят
+	SetAccountNumber(ctx context.Context, accountID int64, number string) error
 	GetAccountsByUser(ctx context.Context, userID string) ([]*model.Account, error)
 	UpdateAccountBalance(ctx context.Context, accountID int64, amount float64) error
+	// WithdrawFunds списывает amount со счета, если списание укладывается в остаток и лимиты.
+	// Лимиты проверяются под блокировкой счета в той же транзакции, что и списание.
+	// ErrInsufficientFunds — недостаточно средств или списания запрещены, ErrAccountClosed — счет закрыт,
+	// *LimitError — нарушен лимит.
+	WithdrawFunds(ctx context.Context, accountID int64, amount float64, limits model.Limits, now time.Time) error
+	// TransferFunds проводит обе ноги перевода в одной транзакции и погашает котировку transfer.QuoteID, если она указана.
+	// Лимиты счета отправителя проверяются так же, как в WithdrawFunds.
+	// ErrInsufficientFunds — недостаточно средств или списания запрещены, ErrAccountClosed — счет получателя закрыт,
+	// ErrQuoteUnavailable — котировка использована или истекла, *LimitError — нарушен лимит,
+	// ErrAlreadyExists — перевод с transfer.IdempotencyKey уже проведен.
+	TransferFunds(ctx context.Context, transfer *model.Transfer, limits model.Limits, now time.Time) error
+	// HasTransfer сообщает, проведен ли перевод с ключом idempotencyKey
+	HasTransfer(ctx context.Context, idempotencyKey string) (bool, error)
+	GetTransactionsByAccount(ctx context.Context, accountID int64) ([]*model.Transaction, error)
+	// GetTransaction возвращает операцию по ID, nil — не найдена
+	GetTransaction(ctx context.Context, transactionID int64) (*model.Transaction, error)
+	// CloseAccount закрывает счет с нулевым остатком вместе с его картами.
+	// ErrBalanceNotZero — на счете есть остаток, ErrAccountClosed — счет уже закрыт.
+	CloseAccount(ctx context.Context, accountID int64, now time.Time) error
+	// SetAccountOwnerFrozen замораживает или размораживает счет по просьбе владельца, ErrAccountClosed — счет закрыт
+	SetAccountOwnerFrozen(ctx context.Context, accountID int64, frozen bool) error
+
+	// GetKYCStatus возвращает статус проверки личности клиента, not_started — анкеты нет
+	GetKYCStatus(ctx context.Context, userID string) (string, error)
+	// GetLimitUsage — списания операцией operation за скользящие окна лимитов до now
+	GetLimitUsage(ctx context.Context, accountID int64, operation string, now time.Time) (model.LimitUsage, error)
+	// GetLimitOverride возвращает действующее снижение лимитов, nil — его нет или срок истек
+	GetLimitOverride(ctx context.Context, accountID int64, operation string, now time.Time) (*model.LimitOverride, error)
+	// SaveLimitOverride создает или заменяет снижение лимитов операции
+	SaveLimitOverride(ctx context.Context, override *model.LimitOverride) error
+	// DeleteLimitOverride отменяет снижение лимитов, false — действующего снижения не было
+	DeleteLimitOverride(ctx context.Context, accountID int64, operation string, now time.Time) (bool, error)
+
+	// GetOverdraftAccrualStart возвращает первый день, за который хотя бы одному счету со ставкой овердрафта
+	// не начислены проценты, nil — все начислено по day
+	GetOverdraftAccrualStart(ctx context.Context, day time.Time) (*time.Time, error)
+	// AccrueOverdraftInterest начисляет проценты за день day по ставке счета на отрицательный остаток
+	// не больше чем limit счетам, у которых начислено по предыдущий день; daysInYear — база расчета
+	AccrueOverdraftInterest(ctx context.Context, day time.Time, daysInYear, limit int) (int, error)
+	// GetAccountsWithInterestDue — счета с несписанными процентами, начисление которых началось до periodStart
+	GetAccountsWithInterestDue(ctx context.Context, periodStart time.Time, limit int) ([]*model.Account, error)
+	// PostOverdraftInterest списывает со счета начисленные проценты и обнуляет их.
+	// nil — проценты уже списаны или меньше минимальной единицы валюты.
+	PostOverdraftInterest(ctx context.Context, accountID int64, periodStart time.Time, description string, now time.Time) (*model.Transaction, error)
+	// SetOverdraftWarned запоминает число пересеченных порогов, false — значение уже изменено параллельно
+	SetOverdraftWarned(ctx context.Context, accountID int64, from, to int) (bool, error)
+
+	// OpenProductAccount открывает сберегательный счет или вклад и переводит на него Amount со счета FundingID.
+	// Лимиты перевода счета списания проверяются так же, как в TransferFunds.
+	// ErrInsufficientFunds — на счете списания недостаточно собственных средств, он чужой, закрыт или заморожен;
+	// ErrAlreadyExists — номер занят, *LimitError — нарушен лимит.
+	OpenProductAccount(ctx context.Context, open *model.OpenProduct, limits model.Limits, now time.Time) (*model.Account, error)
+	// SetProductRate переносит новую ставку продукта на открытые сберегательные счета
+	SetProductRate(ctx context.Context, product string, rate float64) (int, error)
+	// GetInterestAccrualStart возвращает первый день, за который хотя бы одному счету продукта
+	// не начислены проценты, nil — все начислено по day
+	GetInterestAccrualStart(ctx context.Context, day time.Time) (*time.Time, error)
+	// AccrueInterest начисляет проценты за день day на положительный остаток не больше чем limit счетам,
+	// у которых начислено по предыдущий день; daysInYear — база расчета
+	AccrueInterest(ctx context.Context, day time.Time, daysInYear, limit int) (int, error)
+	// GetSavingsInterestDue — сберегательные счета с невыплаченными процентами, начисление которых началось до periodStart
+	GetSavingsInterestDue(ctx context.Context, periodStart time.Time, limit int) ([]*model.Account, error)
+	// GetMaturedDeposits — открытые вклады, срок которых закончился не позже day
+	GetMaturedDeposits(ctx context.Context, day time.Time, limit int) ([]*model.Account, error)
+	// CapitalizeInterest зачисляет на счет начисленные проценты и обнуляет их.
+	// nil — проценты уже выплачены или меньше минимальной единицы валюты.
+	CapitalizeInterest(ctx context.Context, accountID int64, periodStart time.Time, description string, now time.Time) (*model.Transaction, error)
+	// SettleDeposit выплачивает проценты вклада и продлевает его или возвращает средства и закрывает.
+	// false — срок вклада уже изменен параллельно; ErrAccountClosed — вклад или счет выплаты закрыт.
+	SettleDeposit(ctx context.Context, settlement *model.DepositSettlement, now time.Time) (bool, error)
+	// SetDepositRollover включает или отключает продление вклада, ErrAccountClosed — вклад закрыт
+	SetDepositRollover(ctx context.Context, accountID int64, rollover bool) error
+}
+
+// AliasStorage — псевдонимы для переводов по телефону и email
+type AliasStorage interface {
+	// SaveAlias создает псевдоним или заменяет псевдоним того же типа у клиента.
+	// ErrAlreadyExists — значение уже привязано другим клиентом.
+	SaveAlias(ctx context.Context, alias *model.Alias) (*model.Alias, error)
+	GetAliasesByUser(ctx context.Context, userID string) ([]*model.Alias, error)
+	// FindAlias ищет псевдоним по отпечатку значения, nil — не найден
+	FindAlias(ctx context.Context, fingerprint []byte) (*model.Alias, error)
+	// DeleteAlias удаляет псевдоним, false — его не было
+	DeleteAlias(ctx context.Context, userID, aliasType string) (bool, error)
+}
+
+// PayeeStorage — сохраненные получатели и шаблоны платежей
+type PayeeStorage interface {
+	// CreatePayee сохраняет получателя, ErrAlreadyExists — название уже занято
+	CreatePayee(ctx context.Context, payee *model.Payee) (*model.Payee, error)
+	GetPayees(ctx context.Context, userID string) ([]*model.Payee, error)
+	// GetPayee возвращает получателя клиента, nil — не найден
+	GetPayee(ctx context.Context, userID string, payeeID int64) (*model.Payee, error)
+	// GetPayeesWithPlainAlias возвращает получателей, сохраненных до отпечатков, с телефоном или email в открытом виде
+	GetPayeesWithPlainAlias(ctx context.Context, limit int) ([]*model.Payee, error)
+	// SealPayeeAlias заменяет открытый телефон или email получателя отпечатком и маскированной формой
+	SealPayeeAlias(ctx context.Context, payeeID int64, fingerprint []byte, masked string) error
+	// DeletePayee удаляет получателя вместе с его шаблонами, false — его не было
+	DeletePayee(ctx context.Context, userID string, payeeID int64) (bool, error)
+	// CreateTemplate сохраняет шаблон, ErrAlreadyExists — название уже занято
+	CreateTemplate(ctx context.Context, template *model.PaymentTemplate) (*model.PaymentTemplate, error)
+	GetTemplates(ctx context.Context, userID string) ([]*model.PaymentTemplate, error)
+	// GetTemplate возвращает шаблон клиента, nil — не найден
+	GetTemplate(ctx context.Context, userID string, templateID int64) (*model.PaymentTemplate, error)
+	DeleteTemplate(ctx context.Context, userID string, templateID int64) (bool, error)
+}
+
+// StandingOrderStorage — регулярные переводы и история их исполнения
+type StandingOrderStorage interface {
+	CreateStandingOrder(ctx context.Context, order *model.StandingOrder) (*model.StandingOrder, error)
+	GetStandingOrders(ctx context.Context, userID string) ([]*model.StandingOrder, error)
+	// GetStandingOrder возвращает поручение клиента, nil — не найдено
+	GetStandingOrder(ctx context.Context, userID string, orderID int64) (*model.StandingOrder, error)
+	// UpdateStandingOrderSchedule меняет статус и расписание, если статус поручения все еще fromStatus
+	UpdateStandingOrderSchedule(ctx context.Context, order *model.StandingOrder, fromStatus string) (bool, error)
+	// ClaimDueStandingOrders берет активные поручения со сроком до now и резервирует их на lease,
+	// чтобы другой экземпляр исполнителя не провел платеж повторно
+	ClaimDueStandingOrders(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.StandingOrder, error)
+	// RecordStandingOrderExecution сохраняет попытку и новое расписание поручения и снимает резерв.
+	// Статус меняется, только если клиент не приостановил и не отменил поручение во время исполнения.
+	// false — резерв order.LockedUntil истек и поручение уже взял другой исполнитель, ничего не сохранено.
+	RecordStandingOrderExecution(ctx context.Context, order *model.StandingOrder, execution *model.StandingOrderExecution) (bool, error)
+	GetStandingOrderExecutions(ctx context.Context, orderID int64) ([]*model.StandingOrderExecution, error)
+}
+
+// FXStorage — котировки конвертации валют
+type FXStorage interface {
+	CreateFXQuote(ctx context.Context, quote *model.FXQuote) error
+	// GetFXQuote возвращает котировку, nil — котировка не найдена
+	GetFXQuote(ctx context.Context, quoteID string) (*model.FXQuote, error)
 }
 
 type CardStorage interface {
 	CreateVirtualCard(ctx context.Context, card *model.Card) (int64, error)
 	GetAccountByID(ctx context.Context, accountID int64) (*model.Account, error)
 	GetCardsByAccount(ctx context.Context, accountID int64) ([]*model.Card, error)
+	// GetCardByID возвращает карту без расшифровки PAN, nil — карта не найдена
+	GetCardByID(ctx context.Context, cardID int64) (*model.Card, error)
+	// FindByPAN ищет карту по HMAC-отпечатку номера, nil — карта не найдена
+	FindByPAN(ctx context.Context, panFingerprint []byte) (*model.Card, error)
+	// GetCardsWithStaleKey возвращает карты с ID больше afterID, PAN которых зашифрован не версией activeVersion
+	// или у которых еще нет отпечатка
+	GetCardsWithStaleKey(ctx context.Context, activeVersion int, afterID int64, limit int) ([]*model.Card, error)
+	UpdateEncryptedPAN(ctx context.Context, card *model.Card) error
+	// GetCardPIN возвращает PIN карты, nil — PIN не установлен
+	GetCardPIN(ctx context.Context, cardID int64) (*model.CardPIN, error)
+	// SetCardPIN сохраняет хэш PIN, сбрасывая счетчик попыток и блокировку
+	SetCardPIN(ctx context.Context, cardID int64, hash string) error
+	// RegisterPINAttempt атомарно учитывает попытку ввода PIN и возвращает признак блокировки.
+	// По уже заблокированному PIN попытка не учитывается, и возвращается locked = true.
+	RegisterPINAttempt(ctx context.Context, cardID int64, success bool, maxAttempts int) (locked bool, err error)
+	UnlockCardPIN(ctx context.Context, cardID int64) error
+	// ExpireCards переводит в expired активные карты со сроком действия раньше year/month
+	ExpireCards(ctx context.Context, year, month int) ([]*model.Card, error)
+	// GetCardsDueForRenewal возвращает активные неперевыпущенные карты активных счетов
+	// со сроком действия не позже year/month
+	GetCardsDueForRenewal(ctx context.Context, year, month int, limit int) ([]*model.Card, error)
+	UpdateCardExpiry(ctx context.Context, cardID int64, month, year int) error
+	// CreateReplacementCard в одной транзакции сохраняет карту replacement и связывает с ней карту cardID.
+	// 0 — карта cardID уже перевыпущена, ErrAlreadyExists — номер новой карты занят.
+	CreateReplacementCard(ctx context.Context, cardID int64, replacement *model.Card) (int64, error)
+	CreateCardToken(ctx context.Context, token *model.CardToken) (int64, error)
+	GetCardTokens(ctx context.Context, cardID int64) ([]*model.CardToken, error)
+	// FindCardToken ищет токен по HMAC-отпечатку, nil — токен не найден
+	FindCardToken(ctx context.Context, fingerprint []byte) (*model.CardToken, error)
+	// UpdateCardTokenStatus меняет статус токена; удаленный токен не меняется, возвращается ErrTokenDeleted
+	UpdateCardTokenStatus(ctx context.Context, cardID, tokenID int64, status string) error
+}
+
+// KeyStorage — хранилище обёрнутых ключей шифрования данных
+type KeyStorage interface {
+	GetDataKeys(ctx context.Context) ([]*model.DataKey, error)
+	// CreateDataKey сохраняет новую активную версию ключа назначения purpose, если сейчас активна версия previous
+	// (0 — активной версии нет); предыдущие версии того же назначения переводятся в retired.
+	// ErrAlreadyExists — активная версия уже сменилась, ключ выпустил другой экземпляр приложения.
+	CreateDataKey(ctx context.Context, purpose string, wrappedKey []byte, previous int) (*model.DataKey, error)
+}
+
+// SigningKeyStorage — ключи подписи JWT
+type SigningKeyStorage interface {
+	// GetSigningKeys возвращает ключи, не истекшие к моменту now
+	GetSigningKeys(ctx context.Context, now time.Time) ([]*model.SigningKey, error)
+	// CreateSigningKey сохраняет новый ключ подписи, а действующим ключам назначает срок retireAt
+	CreateSigningKey(ctx context.Context, key *model.SigningKey, retireAt time.Time) error
 }
 
 type CreditStorage interface {
 	BeginTransaction(ctx context.Context) (Transaction, error)
 	IssueCredit(ctx context.Context, credit model.Credit) (int64, error)
 	IssuePayment(ctx context.Context, payment model.PaymentSchedule) (int64, error)
+	GetCreditsByUser(ctx context.Context, userID string) ([]*model.Credit, error)
+}
+
+// AdminStorage — действия сотрудников; каждое изменение записывается в журнал в той же транзакции
+type AdminStorage interface {
+	// SetUserRole меняет роль и в той же транзакции отзывает все сессии пользователя
+	SetUserRole(ctx context.Context, userID, role string, action *model.AdminAction) error
+	SetAccountFrozen(ctx context.Context, accountID int64, frozen bool, action *model.AdminAction) error
+	// AdjustBalance изменяет баланс на amount; списание не может выйти за овердрафт счета
+	AdjustBalance(ctx context.Context, accountID int64, amount float64, action *model.AdminAction) error
+	// SetKYCStatus завершает проверку анкеты, находящейся в статусе pending
+	SetKYCStatus(ctx context.Context, userID, status, comment string, action *model.AdminAction) error
+	GetAdminActions(ctx context.Context, targetType, targetID string) ([]*model.AdminAction, error)
+	// ReverseTransaction проводит компенсирующие проводки и возвращает их.
+	// ErrReversalExceeded — сумма больше остатка, ErrInsufficientFunds — на счете не хватает средств для возврата.
+	ReverseTransaction(ctx context.Context, legs []model.ReversalLeg, description string, action *model.AdminAction) ([]*model.Transaction, error)
+	// SetOverdraft устанавливает лимит и ставку овердрафта. ErrAccountClosed — счет закрыт,
+	// ErrInsufficientFunds — лимит меньше уже использованного овердрафта.
+	SetOverdraft(ctx context.Context, accountID int64, limit, rate float64, action *model.AdminAction) error
+}
+
+// DisputeStorage — обращения клиентов по операциям и вложения к ним
+type DisputeStorage interface {
+	// CreateDispute — ErrAlreadyExists, если по операции уже есть открытое обращение
+	CreateDispute(ctx context.Context, dispute *model.Dispute) (*model.Dispute, error)
+	GetDisputesByUser(ctx context.Context, userID string) ([]*model.Dispute, error)
+	// GetDisputes — обращения в статусе status для бэк-офиса, пустой статус — все
+	GetDisputes(ctx context.Context, status string, limit int) ([]*model.Dispute, error)
+	// GetDispute возвращает обращение, nil — не найдено
+	GetDispute(ctx context.Context, disputeID int64) (*model.Dispute, error)
+	// SetDisputeStatus меняет статус, если текущий входит в from
+	SetDisputeStatus(ctx context.Context, disputeID int64, from []string, to string, now time.Time) (bool, error)
+	// ResolveDispute закрывает обращение решением и, если legs не пусты, сторнирует операцию в той же транзакции.
+	// ErrDisputeClosed — обращение уже закрыто.
+	ResolveDispute(ctx context.Context, dispute *model.Dispute, legs []model.ReversalLeg, action *model.AdminAction) error
+	AddDisputeAttachment(ctx context.Context, attachment *model.DisputeAttachment) error
+	// GetDisputeAttachments возвращает вложения без содержимого
+	GetDisputeAttachments(ctx context.Context, disputeID int64) ([]*model.DisputeAttachment, error)
+	// GetDisputeAttachment возвращает вложение с зашифрованным содержимым, nil — не найдено
+	GetDisputeAttachment(ctx context.Context, attachmentID int64) (*model.DisputeAttachment, error)
+	// для фонового перешифрования при ротации ключей: вложения с ID больше afterID, зашифрованные не версией activeVersion
+	GetDisputeAttachmentsWithStaleKey(ctx context.Context, activeVersion int, afterID int64, limit int) ([]*model.DisputeAttachment, error)
+	UpdateDisputeAttachmentEncryption(ctx context.Context, attachment *model.DisputeAttachment) error
 }
 
 type Transaction interface {
@@ -0,0 +1,22 @@
+package clock
+
+import "time"
+
+// Clock — источник текущего времени, подменяется в фоновых задачах и тестах
+type Clock interface {
+	Now() time.Time
+}
+
+// Real возвращает системное время
+type Real struct{}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// Fixed всегда возвращает одно и то же время
+type Fixed time.Time
+
+func (f Fixed) Now() time.Time {
+	return time.Time(f)
+}
@@ -2,11 +2,13 @@ package middleware
 
 import (
 	"context"
+	"crypto"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
@@ -14,55 +16,176 @@ import (
 
 type contextKey string
 
-const UserIDKey contextKey = "userID"
+const (
+	UserIDKey contextKey = "userID"
+	TokenKey  contextKey = "token"
+)
+
+// APIKeyHeader — заголовок с API-ключом машинного клиента
+const APIKeyHeader = "X-API-Key"
+
+// TokenInfo — данные access-токена текущего запроса.
+// Для машинного клиента заполнены ClientID и Scopes, а SessionID и Role пусты.
+type TokenInfo struct {
+	ID        string // jti, пуст при аутентификации API-ключом
+	SessionID string
+	Role      string
+	ClientID  string
+	Scopes    []string
+	ExpiresAt time.Time
+}
+
+// RevocationChecker проверяет список отозванных токенов и сессий
+type RevocationChecker interface {
+	IsTokenRevoked(ctx context.Context, jti, sessionID string) (bool, error)
+}
+
+// KeyResolver возвращает открытый ключ подписи по kid из заголовка токена
+type KeyResolver interface {
+	VerificationKey(ctx context.Context, kid, alg string) (crypto.PublicKey, error)
+}
+
+// APIKeyAuthenticator проверяет API-ключ и возвращает владельца, клиента и его scopes
+type APIKeyAuthenticator func(ctx context.Context, apiKey string) (userID, clientID string, scopes []string, err error)
+
+// TokenValidator — параметры проверки access-токена
+type TokenValidator struct {
+	Keys     KeyResolver
+	Issuer   string
+	Audience string
+	// LegacySecret — общий секрет HS256; пока он задан, принимаются и токены HS256
+	LegacySecret []byte
+	// APIKeys — проверка заголовка X-API-Key; nil — API-ключи не принимаются
+	APIKeys APIKeyAuthenticator
+}
 
 // NewAuthMiddleware создает middleware для валидации JWT-токена.
-func NewAuthMiddleware(secret string) func(http.Handler) http.Handler {
+// Помимо подписи проверяется, что токен и его сессия не отозваны.
+func NewAuthMiddleware(validator *TokenValidator, revocations RevocationChecker) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
+			if apiKey := r.Header.Get(APIKeyHeader); authHeader == "" && apiKey != "" && validator.APIKeys != nil {
+				userID, clientID, scopes, err := validator.APIKeys(r.Context(), apiKey)
+				if err != nil {
+					http.Error(w, "Unauthorized: invalid api key", http.StatusUnauthorized)
+					return
+				}
+				ctx := context.WithValue(r.Context(), UserIDKey, userID)
+				ctx = context.WithValue(ctx, TokenKey, TokenInfo{ClientID: clientID, Scopes: scopes})
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
 			if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
 				http.Error(w, "Unauthorized: missing bearer token", http.StatusUnauthorized)
 				return
 			}
 			tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
 
-			userID, err := validateJWT(tokenStr, secret)
+			userID, info, err := validateJWT(r.Context(), tokenStr, validator)
 			if err != nil {
 				http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
 				return
 			}
+			revoked, err := revocations.IsTokenRevoked(r.Context(), info.ID, info.SessionID)
+			if err != nil {
+				http.Error(w, "could not verify token", http.StatusInternalServerError)
+				return
+			}
+			if revoked {
+				http.Error(w, "Unauthorized: token revoked", http.StatusUnauthorized)
+				return
+			}
 
 			// userID передаётся дальше через context.Context
 			ctx := context.WithValue(r.Context(), UserIDKey, userID)
+			ctx = context.WithValue(ctx, TokenKey, info)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-// validateJWT разбирает токен и проверяет подпись, возвращает userID, если токен валиден.
-func validateJWT(tokenStr, secret string) (string, error) {
+// validateJWT разбирает токен, проверяет подпись и exp, а у токенов с асимметричной подписью — еще iss, aud и iat;
+// возвращает userID и данные токена, если токен валиден.
+func validateJWT(ctx context.Context, tokenStr string, v *TokenValidator) (string, TokenInfo, error) {
+	methods := []string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}
+	if v.LegacySecret != nil {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		// Проверяем алгоритм подписи
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+			return v.LegacySecret, nil
+		}
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" || v.Keys == nil {
+			return nil, errors.New("kid missing in token")
 		}
-		return []byte(secret), nil
-	})
+		return v.Keys.VerificationKey(ctx, kid, token.Method.Alg())
+	},
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+	)
 
 	if err != nil {
-		return "", err
+		return "", TokenInfo{}, err
+	}
+	// iss, aud и iat появились вместе с асимметричными ключами; токены HS256, выданные раньше,
+	// их не содержат и до закрытия окна совместимости проверяются по прежним правилам
+	if _, legacy := token.Method.(*jwt.SigningMethodHMAC); !legacy {
+		if err := validateRegisteredClaims(token.Claims, v.Issuer, v.Audience); err != nil {
+			return "", TokenInfo{}, err
+		}
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		// В данном примере ожидаем, что user_id числовой
+		if _, partial := claims["mfa"]; partial {
+			return "", TokenInfo{}, errors.New("second factor required")
+		}
 		UUID, err := claims.GetSubject()
-		if err != nil {
-			return "", errors.New("user_id missing in token")
+		if err != nil || UUID == "" {
+			return "", TokenInfo{}, errors.New("user_id missing in token")
+		}
+		jti, _ := claims["jti"].(string)
+		if jti == "" {
+			return "", TokenInfo{}, errors.New("jti missing in token")
+		}
+		info := TokenInfo{ID: jti}
+		info.SessionID, _ = claims["sid"].(string)
+		info.Role, _ = claims["role"].(string)
+		info.ClientID, _ = claims["client_id"].(string)
+		if scope, _ := claims["scope"].(string); scope != "" {
+			info.Scopes = strings.Fields(scope)
+		}
+		if info.ClientID != "" && (info.SessionID != "" || info.Role != "") {
+			return "", TokenInfo{}, errors.New("invalid client token")
 		}
-		return UUID, nil
+		if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+			info.ExpiresAt = exp.Time
+		}
+		return UUID, info, nil
+	}
+	return "", TokenInfo{}, errors.New("invalid token")
+}
+
+// validateRegisteredClaims проверяет iss, aud и обязательный iat токена, подписанного асимметричным ключом
+func validateRegisteredClaims(claims jwt.Claims, issuer, audience string) error {
+	err := jwt.NewValidator(jwt.WithIssuer(issuer), jwt.WithAudience(audience), jwt.WithIssuedAt()).Validate(claims)
+	if err != nil {
+		return err
+	}
+	if iat, err := claims.GetIssuedAt(); err != nil || iat == nil {
+		return errors.New("iat missing in token")
+	}
+	return nil
+}
+
+// ValidateToken возвращает данные access-токена, которым аутентифицирован запрос
+func ValidateToken(r *http.Request) (TokenInfo, error) {
+	info, ok := r.Context().Value(TokenKey).(TokenInfo)
+	if !ok {
+		return TokenInfo{}, fmt.Errorf("not authenticated user")
 	}
-	return "", errors.New("invalid token")
+	return info, nil
 }
 
 func ValidateUser(r *http.Request) (string, error) {
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter — счетчик запросов в фиксированных окнах. Состояние хранится в памяти процесса,
+// поэтому при нескольких экземплярах приложения лимит действует на каждый экземпляр отдельно.
+type RateLimiter struct {
+	window time.Duration
+
+	mu       sync.Mutex
+	counters map[string]*rateWindow
+	lastGC   time.Time
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func NewRateLimiter(window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		window:   window,
+		counters: make(map[string]*rateWindow),
+	}
+}
+
+// Allow учитывает запрос по ключу и сообщает, укладывается ли он в limit.
+// Если нет — возвращает время до начала следующего окна.
+func (l *RateLimiter) Allow(key string, limit int, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.gc(now)
+	w, ok := l.counters[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &rateWindow{start: now}
+		l.counters[key] = w
+	}
+	if w.count >= limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// gc удаляет истекшие окна не чаще раза в окно
+func (l *RateLimiter) gc(now time.Time) {
+	if now.Sub(l.lastGC) < l.window {
+		return
+	}
+	for key, w := range l.counters {
+		if now.Sub(w.start) >= l.window {
+			delete(l.counters, key)
+		}
+	}
+	l.lastGC = now
+}
+
+// ClientLimits возвращает лимит запросов машинного клиента за окно; 0 — клиент отозван
+type ClientLimits interface {
+	ClientRateLimit(ctx context.Context, clientID string) (int, error)
+}
+
+// NewClientRateLimit ограничивает частоту запросов машинных клиентов; запросы пользователей не ограничиваются.
+// Лимит читается при каждом запросе, поэтому отзыв клиента действует и на уже выданные ему токены.
+func NewClientRateLimit(limiter *RateLimiter, limits ClientLimits) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info, err := ValidateToken(r)
+			if err != nil || info.ClientID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limit, err := limits.ClientRateLimit(r.Context(), info.ClientID)
+			if err != nil {
+				http.Error(w, "could not verify client", http.StatusInternalServerError)
+				return
+			}
+			if limit <= 0 {
+				http.Error(w, "Unauthorized: client revoked", http.StatusUnauthorized)
+				return
+			}
+			allowed, retryAfter := limiter.Allow("client:"+info.ClientID, limit, time.Now())
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+)
+
+// RequireRoles пропускает запрос, только если роль из access-токена входит в roles.
+// Должен подключаться после middleware аутентификации.
+func RequireRoles(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, err := UserRole(r)
+			if err != nil {
+				http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+				return
+			}
+			if !slices.Contains(roles, role) {
+				http.Error(w, "Forbidden: insufficient role", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UserRole возвращает роль из access-токена, которым аутентифицирован запрос
+func UserRole(r *http.Request) (string, error) {
+	info, err := ValidateToken(r)
+	if err != nil {
+		return "", err
+	}
+	return info.Role, nil
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+)
+
+// RequireScope пропускает токены пользователей без ограничений, а токены машинных клиентов —
+// только если им выдана область доступа scope. Подключается после middleware аутентификации.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info, err := ValidateToken(r)
+			if err != nil {
+				http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+				return
+			}
+			if info.ClientID != "" && !slices.Contains(info.Scopes, scope) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+				http.Error(w, "Forbidden: insufficient scope", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireUserToken отклоняет машинных клиентов: управление профилем, сессиями
+// и самими клиентами доступно только пользователю.
+func RequireUserToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, err := ValidateToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if info.ClientID != "" {
+			http.Error(w, "Forbidden: user token required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}